@@ -0,0 +1,49 @@
+// paypay-sim はローカル開発用のPayPay API模擬サーバー。
+// バックエンドのPAYPAY_API_URLをこのサーバーに向けると、サンドボックス無しで購入フローを試せる。
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/paypay/paypaysim"
+)
+
+func getenv(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+func main() {
+	addr := getenv("PAYPAY_SIM_ADDR", ":8090")
+	apiKey := getenv("PAYPAY_API_KEY", "paypaysim-key")
+	apiSecret := getenv("PAYPAY_API_SECRET", "paypaysim-secret")
+
+	sim := paypaysim.New(apiKey, apiSecret)
+	// 決済画面のURL（ブラウザから開ける必要がある）
+	sim.PublicURL = getenv("PAYPAY_SIM_PUBLIC_URL", "http://localhost:8090")
+	// 空文字を指定するとWebhookを送らない
+	sim.WebhookURL = os.Getenv("PAYPAY_SIM_WEBHOOK_URL")
+	if _, ok := os.LookupEnv("PAYPAY_SIM_WEBHOOK_URL"); !ok {
+		sim.WebhookURL = "http://localhost:8080/paypay/webhook"
+	}
+	sim.WebhookSecret = os.Getenv("PAYPAY_WEBHOOK_SECRET")
+	if ttl := os.Getenv("PAYPAY_SIM_CODE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("invalid PAYPAY_SIM_CODE_TTL: %v", err)
+		}
+		sim.CodeTTL = d
+	}
+	sim.AsyncRefunds = os.Getenv("PAYPAY_SIM_ASYNC_REFUNDS") == "true"
+
+	log.Printf("[paypay-sim] listening on %s (webhook: %q)", addr, sim.WebhookURL)
+	if err := http.ListenAndServe(addr, sim); err != nil {
+		log.Fatal(err)
+	}
+}
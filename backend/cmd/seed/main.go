@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/usecase"
 	_ "github.com/lib/pq"
 	"golang.org/x/crypto/scrypt"
 	"golang.org/x/text/unicode/norm"
@@ -121,6 +122,11 @@ func main() {
 		}
 	}
 
+	// Seed roles (seed-user-1 is admin)
+	if err := q.AddUserRole(ctx, db.AddUserRoleParams{UserID: "seed-user-1", Role: "admin"}); err != nil {
+		log.Fatalf("failed to add admin role: %v", err)
+	}
+
 	// Seed category tags
 	tagNames := []string{"音楽", "お笑い", "グルメ", "その他"}
 	var tags []db.CategoryTag
@@ -252,5 +258,12 @@ func main() {
 		}
 	}
 
+	// Build search index for seeded programs
+	indexed, err := usecase.NewSearchUsecase(q).ReindexMissing(ctx)
+	if err != nil {
+		log.Fatalf("failed to build search index: %v", err)
+	}
+	log.Printf("seed indexed %d programs for search", indexed)
+
 	log.Println("seed completed")
 }
\ No newline at end of file
@@ -106,3 +106,34 @@ func (q *Queries) CreateCredentialAccount(ctx context.Context, arg CreateCredent
 	)
 	return i, err
 }
+
+const deleteExpiredSessions = `-- name: DeleteExpiredSessions :execrows
+DELETE FROM "session"
+WHERE "expiresAt" < $1
+`
+
+func (q *Queries) DeleteExpiredSessions(ctx context.Context, expiresat time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredSessions, expiresat)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getSessionByToken = `-- name: GetSessionByToken :one
+SELECT "userId", "expiresAt"
+FROM "session"
+WHERE token = $1
+`
+
+type GetSessionByTokenRow struct {
+	UserId    string    `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (q *Queries) GetSessionByToken(ctx context.Context, token string) (GetSessionByTokenRow, error) {
+	row := q.db.QueryRowContext(ctx, getSessionByToken, token)
+	var i GetSessionByTokenRow
+	err := row.Scan(&i.UserId, &i.ExpiresAt)
+	return i, err
+}
@@ -29,3 +29,102 @@ func (q *Queries) CreateCategoryTag(ctx context.Context, name string) (CategoryT
 	)
 	return i, err
 }
+
+const deleteCategoryTag = `-- name: DeleteCategoryTag :execrows
+DELETE FROM category_tags
+WHERE id = $1
+`
+
+func (q *Queries) DeleteCategoryTag(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteCategoryTag, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getCategoryTagByID = `-- name: GetCategoryTagByID :one
+SELECT id, name, created_at, updated_at
+FROM category_tags
+WHERE id = $1
+`
+
+func (q *Queries) GetCategoryTagByID(ctx context.Context, id int64) (CategoryTag, error) {
+	row := q.db.QueryRowContext(ctx, getCategoryTagByID, id)
+	var i CategoryTag
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listCategoryTagsWithProgramCount = `-- name: ListCategoryTagsWithProgramCount :many
+SELECT
+  ct.id,
+  ct.name,
+  (
+    SELECT COUNT(*)
+    FROM program_category_tags pct
+    JOIN programs p ON p.id = pct.program_id
+    WHERE pct.tag_id = ct.id AND p.is_public = true
+  )::bigint AS program_count
+FROM category_tags ct
+ORDER BY ct.name, ct.id
+`
+
+type ListCategoryTagsWithProgramCountRow struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	ProgramCount int64  `json:"program_count"`
+}
+
+func (q *Queries) ListCategoryTagsWithProgramCount(ctx context.Context) ([]ListCategoryTagsWithProgramCountRow, error) {
+	rows, err := q.db.QueryContext(ctx, listCategoryTagsWithProgramCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCategoryTagsWithProgramCountRow
+	for rows.Next() {
+		var i ListCategoryTagsWithProgramCountRow
+		if err := rows.Scan(&i.ID, &i.Name, &i.ProgramCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateCategoryTag = `-- name: UpdateCategoryTag :one
+UPDATE category_tags
+SET name = $2,
+    updated_at = now()
+WHERE id = $1
+RETURNING id, name, created_at, updated_at
+`
+
+type UpdateCategoryTagParams struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (q *Queries) UpdateCategoryTag(ctx context.Context, arg UpdateCategoryTagParams) (CategoryTag, error) {
+	row := q.db.QueryRowContext(ctx, updateCategoryTag, arg.ID, arg.Name)
+	var i CategoryTag
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
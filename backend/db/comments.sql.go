@@ -83,14 +83,39 @@ func (q *Queries) CreateCommentWithUserName(ctx context.Context, arg CreateComme
 	return i, err
 }
 
+const deleteComment = `-- name: DeleteComment :execrows
+DELETE FROM comments
+WHERE id = $1
+`
+
+func (q *Queries) DeleteComment(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteComment, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const listCommentsByProgramID = `-- name: ListCommentsByProgramID :many
 SELECT c.id, c.program_id, c.user_id, u.name AS user_name, c.content, c.created_at, c.updated_at
 FROM comments c
 LEFT JOIN "user" u ON c.user_id = u.id
 WHERE c.program_id = $1
-ORDER BY c.created_at DESC
+  AND (
+    $2::timestamptz IS NULL
+    OR (c.created_at, c.id) < ($2::timestamptz, $3::bigint)
+  )
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT $4::int
 `
 
+type ListCommentsByProgramIDParams struct {
+	ProgramID       int64         `json:"program_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        sql.NullInt64 `json:"cursor_id"`
+	PageLimit       int32         `json:"page_limit"`
+}
+
 type ListCommentsByProgramIDRow struct {
 	ID        int64          `json:"id"`
 	ProgramID int64          `json:"program_id"`
@@ -101,8 +126,13 @@ type ListCommentsByProgramIDRow struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 }
 
-func (q *Queries) ListCommentsByProgramID(ctx context.Context, programID int64) ([]ListCommentsByProgramIDRow, error) {
-	rows, err := q.db.QueryContext(ctx, listCommentsByProgramID, programID)
+func (q *Queries) ListCommentsByProgramID(ctx context.Context, arg ListCommentsByProgramIDParams) ([]ListCommentsByProgramIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, listCommentsByProgramID,
+		arg.ProgramID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,8 @@
+-- ユーザーごとのロール（viewer / uploader / moderator / admin）
+-- ロールを持たないログインユーザーはviewer扱い
+CREATE TABLE IF NOT EXISTS user_roles (
+  user_id TEXT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  role TEXT NOT NULL CONSTRAINT user_roles_role_check CHECK (role IN ('viewer', 'uploader', 'moderator', 'admin')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, role)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS permitted_program_users_user_created_at_id_idx;
+DROP INDEX IF EXISTS likes_user_created_at_id_idx;
+DROP INDEX IF EXISTS comments_program_created_at_id_idx;
+DROP INDEX IF EXISTS programs_public_created_at_id_idx;
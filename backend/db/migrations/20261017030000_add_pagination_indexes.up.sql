@@ -0,0 +1,12 @@
+-- カーソルページング（created_at DESC, id DESC）用のインデックス
+CREATE INDEX IF NOT EXISTS programs_public_created_at_id_idx
+  ON programs (created_at DESC, id DESC) WHERE is_public = true;
+
+CREATE INDEX IF NOT EXISTS comments_program_created_at_id_idx
+  ON comments (program_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS likes_user_created_at_id_idx
+  ON likes (user_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS permitted_program_users_user_created_at_id_idx
+  ON permitted_program_users (user_id, created_at DESC, id DESC);
@@ -0,0 +1,9 @@
+-- 番組検索用の索引（tsvectorはアプリ側でuni-gram/bi-gramに分割して生成する）
+CREATE TABLE IF NOT EXISTS program_search_documents (
+  program_id BIGINT PRIMARY KEY REFERENCES programs(id) ON DELETE CASCADE,
+  document TSVECTOR NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS program_search_documents_document_idx
+  ON program_search_documents USING GIN (document);
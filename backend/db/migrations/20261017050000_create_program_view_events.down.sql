@@ -0,0 +1 @@
+DROP TABLE IF EXISTS program_view_events;
@@ -0,0 +1,16 @@
+-- 再生開始ごとの視聴イベント（同一視聴者・同一時間枠は1件にまとめる）
+-- viewer_key はログインユーザーなら "user:<id>"、未ログインならIP/User-Agentのハッシュ
+CREATE TABLE IF NOT EXISTS program_view_events (
+  id BIGSERIAL PRIMARY KEY,
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  viewer_key TEXT NOT NULL,
+  bucket_start TIMESTAMPTZ NOT NULL,
+  aggregated_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (program_id, viewer_key, bucket_start)
+);
+
+-- 未集計イベントの取り出し用
+CREATE INDEX IF NOT EXISTS program_view_events_unaggregated_idx
+  ON program_view_events (id)
+  WHERE aggregated_at IS NULL;
@@ -0,0 +1,34 @@
+-- 番組ごとの日次集計（stat_dateはJSTの日付）
+-- views は視聴イベントから加算、likes/completions/purchases は元テーブルから直近分を再集計する
+CREATE TABLE IF NOT EXISTS program_daily_stats (
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  stat_date DATE NOT NULL,
+  views BIGINT NOT NULL DEFAULT 0,
+  likes BIGINT NOT NULL DEFAULT 0,
+  completions BIGINT NOT NULL DEFAULT 0,
+  purchases BIGINT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (program_id, stat_date)
+);
+
+-- 期間指定ランキング用
+CREATE INDEX IF NOT EXISTS program_daily_stats_stat_date_idx
+  ON program_daily_stats (stat_date);
+
+-- 既存データからlikes/completions/purchasesを埋める（視聴回数は日付情報がないため対象外）
+INSERT INTO program_daily_stats (program_id, stat_date, likes, completions, purchases)
+SELECT program_id, stat_date, SUM(likes), SUM(completions), SUM(purchases)
+FROM (
+  SELECT program_id, (created_at AT TIME ZONE 'Asia/Tokyo')::date AS stat_date, 1 AS likes, 0 AS completions, 0 AS purchases
+  FROM likes
+  UNION ALL
+  SELECT program_id, (last_watched_at AT TIME ZONE 'Asia/Tokyo')::date, 0, 1, 0
+  FROM watch_histories
+  WHERE is_completed = true
+  UNION ALL
+  SELECT program_id, (credited_at AT TIME ZONE 'Asia/Tokyo')::date, 0, 0, 1
+  FROM paypay_topups
+  WHERE credited_at IS NOT NULL AND program_id IS NOT NULL
+) s
+GROUP BY program_id, stat_date
+ON CONFLICT (program_id, stat_date) DO NOTHING;
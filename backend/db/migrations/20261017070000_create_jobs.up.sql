@@ -0,0 +1,36 @@
+-- バックグラウンドジョブのキュー（ワーカーは FOR UPDATE SKIP LOCKED で取り出す）
+CREATE TABLE IF NOT EXISTS jobs (
+  id BIGSERIAL PRIMARY KEY,
+  kind TEXT NOT NULL,
+  payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+  status TEXT NOT NULL DEFAULT 'queued',
+  attempts INT NOT NULL DEFAULT 0,
+  max_attempts INT NOT NULL DEFAULT 5,
+  run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  dedupe_key TEXT,
+  locked_by TEXT,
+  locked_at TIMESTAMPTZ,
+  last_error TEXT,
+  finished_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT jobs_status_check CHECK (status IN ('queued', 'running', 'succeeded', 'failed'))
+);
+
+-- 取り出し用（実行待ちのみ）
+CREATE INDEX IF NOT EXISTS jobs_queued_run_at_idx
+  ON jobs (run_at, id)
+  WHERE status = 'queued';
+
+-- 同じdedupe_keyの未完了ジョブは1件まで
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_dedupe_key_active_uq
+  ON jobs (dedupe_key)
+  WHERE dedupe_key IS NOT NULL AND status IN ('queued', 'running');
+
+-- 定期ジョブの次回実行時刻（複数プロセスで重複実行しないようDBで管理する）
+CREATE TABLE IF NOT EXISTS job_schedules (
+  name TEXT PRIMARY KEY,
+  next_run_at TIMESTAMPTZ NOT NULL,
+  last_enqueued_at TIMESTAMPTZ,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
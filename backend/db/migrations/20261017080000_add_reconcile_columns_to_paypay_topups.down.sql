@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS paypay_topups_pending_created_at_idx;
+ALTER TABLE paypay_topups
+  DROP COLUMN IF EXISTS reconcile_attempts,
+  DROP COLUMN IF EXISTS last_reconciled_at,
+  DROP COLUMN IF EXISTS code_expires_at;
@@ -0,0 +1,10 @@
+-- 決済コードの有効期限（PayPayのexpiryDate）と照合ジョブの実行状況
+ALTER TABLE paypay_topups
+  ADD COLUMN IF NOT EXISTS code_expires_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS last_reconciled_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS reconcile_attempts INT NOT NULL DEFAULT 0;
+
+-- 未完了topupの照合対象検索用
+CREATE INDEX IF NOT EXISTS paypay_topups_pending_created_at_idx
+  ON paypay_topups (created_at)
+  WHERE credited_at IS NULL AND status NOT IN ('COMPLETED', 'FAILED', 'CANCELED', 'EXPIRED');
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS paypay_topups_pending_created_at_idx;
+CREATE INDEX IF NOT EXISTS paypay_topups_pending_created_at_idx
+  ON paypay_topups (created_at)
+  WHERE credited_at IS NULL AND status NOT IN ('COMPLETED', 'FAILED', 'CANCELED', 'EXPIRED');
+
+ALTER TABLE paypay_topups DROP CONSTRAINT IF EXISTS paypay_topups_status_check;
+
+DROP TABLE IF EXISTS paypay_topup_events;
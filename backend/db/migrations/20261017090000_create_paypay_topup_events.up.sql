@@ -0,0 +1,30 @@
+-- topupのステータス遷移の監査ログ（追記のみ。更新・削除はしない）
+CREATE TABLE IF NOT EXISTS paypay_topup_events (
+  id BIGSERIAL PRIMARY KEY,
+  topup_id BIGINT NOT NULL REFERENCES paypay_topups(id) ON DELETE CASCADE,
+  from_status TEXT,
+  to_status TEXT NOT NULL,
+  source TEXT NOT NULL,
+  paypay_payment_id TEXT,
+  detail TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS paypay_topup_events_topup_id_idx ON paypay_topup_events (topup_id, id);
+
+-- 既存データを新しいステータスへ寄せる（決済コード発行済み・オーソリ済みは支払い待ち）
+UPDATE paypay_topups
+SET status = 'AWAITING'
+WHERE status = 'AUTHORIZED'
+   OR (status = 'CREATED' AND paypay_code_id IS NOT NULL);
+
+-- 既存の想定外ステータスは残したまま、以降の書き込みのみ検証する
+ALTER TABLE paypay_topups
+  ADD CONSTRAINT paypay_topups_status_check
+  CHECK (status IN ('CREATED', 'AWAITING', 'COMPLETED', 'FAILED', 'CANCELED', 'EXPIRED', 'REFUNDED'))
+  NOT VALID;
+
+DROP INDEX IF EXISTS paypay_topups_pending_created_at_idx;
+CREATE INDEX IF NOT EXISTS paypay_topups_pending_created_at_idx
+  ON paypay_topups (created_at)
+  WHERE status IN ('CREATED', 'AWAITING');
@@ -0,0 +1,3 @@
+ALTER TABLE paypay_topups DROP COLUMN IF EXISTS refunded_amount_yen;
+
+DROP TABLE IF EXISTS paypay_refunds;
@@ -0,0 +1,21 @@
+-- topupの返金履歴（一部返金は複数行になる）
+CREATE TABLE IF NOT EXISTS paypay_refunds (
+  id BIGSERIAL PRIMARY KEY,
+  topup_id BIGINT NOT NULL REFERENCES paypay_topups(id) ON DELETE CASCADE,
+  merchant_refund_id TEXT NOT NULL UNIQUE,
+  amount_yen INT NOT NULL CHECK (amount_yen > 0),
+  -- CREATED（PayPayで処理中）/ REFUNDED / FAILED
+  status TEXT NOT NULL DEFAULT 'CREATED' CHECK (status IN ('CREATED', 'REFUNDED', 'FAILED')),
+  reason TEXT NOT NULL DEFAULT '',
+  requested_by TEXT REFERENCES "user"(id) ON DELETE SET NULL,
+  error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  refunded_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS paypay_refunds_topup_id_idx ON paypay_refunds (topup_id, id);
+
+-- 返金済みの合計額（REFUNDEDになった返金のみ）
+ALTER TABLE paypay_topups
+  ADD COLUMN IF NOT EXISTS refunded_amount_yen INT NOT NULL DEFAULT 0;
@@ -0,0 +1,20 @@
+-- 受信したWebhookの生ボディと処理状況（再送の重複排除・失敗時の再処理用）
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  provider TEXT NOT NULL,
+  -- 同じイベントの再送を判定するキー（PayPayならnotification_type + order_id + state）
+  dedupe_key TEXT NOT NULL,
+  notification_type TEXT NOT NULL DEFAULT '',
+  body TEXT NOT NULL,
+  remote_ip TEXT NOT NULL DEFAULT '',
+  -- received（処理待ち）/ succeeded / failed
+  status TEXT NOT NULL DEFAULT 'received' CHECK (status IN ('received', 'succeeded', 'failed')),
+  attempts INT NOT NULL DEFAULT 0,
+  last_error TEXT,
+  received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  processed_at TIMESTAMPTZ,
+  UNIQUE (provider, dedupe_key)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_received_at_idx ON webhook_deliveries (received_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_status_received_at_idx ON webhook_deliveries (status, received_at DESC, id DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE paypay_topups DROP COLUMN IF EXISTS product_id;
+DROP TABLE IF EXISTS user_product_grants;
+DROP TABLE IF EXISTS product_programs;
+DROP TABLE IF EXISTS products;
@@ -0,0 +1,41 @@
+-- 複数番組の閲覧権限をまとめて販売する商品
+CREATE TABLE IF NOT EXISTS products (
+  id BIGSERIAL PRIMARY KEY,
+  -- bundle（指定した番組）/ performer_pack（出演者の限定公開番組すべて）/ pass（期間中すべての限定公開番組）
+  kind TEXT NOT NULL CHECK (kind IN ('bundle', 'performer_pack', 'pass')),
+  name TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  price INT NOT NULL CHECK (price > 0),
+  performer_id BIGINT REFERENCES performers(id),
+  -- 購入から閲覧できる日数（passのみ）
+  duration_days INT CHECK (duration_days > 0),
+  is_active BOOLEAN NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT products_performer_check CHECK ((kind = 'performer_pack') = (performer_id IS NOT NULL)),
+  CONSTRAINT products_duration_check CHECK ((kind = 'pass') = (duration_days IS NOT NULL))
+);
+
+-- bundleに含まれる番組
+CREATE TABLE IF NOT EXISTS product_programs (
+  product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  PRIMARY KEY (product_id, program_id)
+);
+
+CREATE INDEX IF NOT EXISTS product_programs_program_id_idx ON product_programs (program_id);
+
+-- 購入した商品の閲覧権限（expires_atがNULLなら無期限、返金されたらrevoked_atを記録）
+CREATE TABLE IF NOT EXISTS user_product_grants (
+  id BIGSERIAL PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  product_id BIGINT NOT NULL REFERENCES products(id),
+  topup_id BIGINT UNIQUE REFERENCES paypay_topups(id) ON DELETE SET NULL,
+  granted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS user_product_grants_user_id_idx ON user_product_grants (user_id, product_id);
+
+ALTER TABLE paypay_topups ADD COLUMN IF NOT EXISTS product_id BIGINT REFERENCES products(id);
@@ -0,0 +1,17 @@
+-- レンタル中の閲覧権限は買い切りとして残さない
+DELETE FROM permitted_program_users WHERE purchase_type = 'rental';
+
+ALTER TABLE paypay_topups
+  DROP CONSTRAINT IF EXISTS paypay_topups_rental_duration_hours,
+  DROP COLUMN IF EXISTS rental_duration_hours,
+  DROP COLUMN IF EXISTS purchase_type;
+
+ALTER TABLE permitted_program_users
+  DROP CONSTRAINT IF EXISTS permitted_program_users_rental_expires_at,
+  DROP COLUMN IF EXISTS expires_at,
+  DROP COLUMN IF EXISTS purchase_type;
+
+ALTER TABLE programs
+  DROP CONSTRAINT IF EXISTS programs_rental_price_duration_pair,
+  DROP COLUMN IF EXISTS rental_duration_hours,
+  DROP COLUMN IF EXISTS rental_price;
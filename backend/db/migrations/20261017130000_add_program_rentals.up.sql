@@ -0,0 +1,19 @@
+-- 番組のレンタル（期間限定の閲覧権限）
+ALTER TABLE programs
+  ADD COLUMN IF NOT EXISTS rental_price INTEGER CONSTRAINT programs_rental_price_positive CHECK (rental_price > 0),
+  ADD COLUMN IF NOT EXISTS rental_duration_hours INTEGER CONSTRAINT programs_rental_duration_hours_positive CHECK (rental_duration_hours > 0),
+  ADD CONSTRAINT programs_rental_price_duration_pair CHECK ((rental_price IS NULL) = (rental_duration_hours IS NULL));
+
+-- 既存の閲覧権限は全て買い切り
+ALTER TABLE permitted_program_users
+  ADD COLUMN IF NOT EXISTS purchase_type TEXT NOT NULL DEFAULT 'purchase'
+    CONSTRAINT permitted_program_users_purchase_type_check CHECK (purchase_type IN ('purchase', 'rental')),
+  ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ,
+  ADD CONSTRAINT permitted_program_users_rental_expires_at CHECK ((purchase_type = 'rental') = (expires_at IS NOT NULL));
+
+-- レンタル期間は購入時点の値を保持する（支払い完了から数える）
+ALTER TABLE paypay_topups
+  ADD COLUMN IF NOT EXISTS purchase_type TEXT NOT NULL DEFAULT 'purchase'
+    CONSTRAINT paypay_topups_purchase_type_check CHECK (purchase_type IN ('purchase', 'rental')),
+  ADD COLUMN IF NOT EXISTS rental_duration_hours INTEGER,
+  ADD CONSTRAINT paypay_topups_rental_duration_hours CHECK ((purchase_type = 'rental') = (rental_duration_hours IS NOT NULL));
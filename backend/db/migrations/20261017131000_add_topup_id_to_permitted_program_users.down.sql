@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS permitted_program_users_topup_id_idx;
+
+ALTER TABLE permitted_program_users
+  DROP COLUMN IF EXISTS topup_id;
@@ -0,0 +1,18 @@
+-- 閲覧権限の現在の内容（買い切り・レンタル期限）を付与したtopup。返金時はこのtopupの場合だけ取り消す
+ALTER TABLE permitted_program_users
+  ADD COLUMN IF NOT EXISTS topup_id BIGINT REFERENCES paypay_topups (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS permitted_program_users_topup_id_idx ON permitted_program_users (topup_id);
+
+-- 既存の閲覧権限は同じ購入種別で最後に付与したtopupに紐付ける
+UPDATE permitted_program_users ppu
+SET topup_id = t.id
+FROM (
+  SELECT DISTINCT ON (user_id, program_id, purchase_type) id, user_id, program_id, purchase_type
+  FROM paypay_topups
+  WHERE program_id IS NOT NULL AND credited_at IS NOT NULL
+  ORDER BY user_id, program_id, purchase_type, credited_at DESC, id DESC
+) t
+WHERE t.user_id = ppu.user_id
+  AND t.program_id = ppu.program_id
+  AND t.purchase_type = ppu.purchase_type;
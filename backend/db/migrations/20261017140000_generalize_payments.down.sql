@@ -0,0 +1,55 @@
+-- PayPay以外の決済はPayPay専用のテーブルに戻せないため、その決済で付与した視聴権限・購入記録・返金・履歴ごと削除する
+-- （クーポン・ギフト等の以降のマイグレーションを先に戻している前提）
+DELETE FROM permitted_program_users
+  WHERE payment_id IN (SELECT id FROM payments WHERE provider <> 'paypay');
+DELETE FROM user_product_grants
+  WHERE payment_id IN (SELECT id FROM payments WHERE provider <> 'paypay');
+DELETE FROM payment_refunds
+  WHERE payment_id IN (SELECT id FROM payments WHERE provider <> 'paypay');
+DELETE FROM payment_events
+  WHERE payment_id IN (SELECT id FROM payments WHERE provider <> 'paypay');
+DELETE FROM payments WHERE provider <> 'paypay';
+
+UPDATE jobs SET dedupe_key = 'schedule:paypay.reconcile_pending' WHERE dedupe_key = 'schedule:payments.reconcile_pending';
+UPDATE jobs SET dedupe_key = 'paypay.reconcile_refund:' || substr(dedupe_key, length('payments.reconcile_refund:') + 1)
+  WHERE dedupe_key LIKE 'payments.reconcile_refund:%';
+UPDATE jobs SET dedupe_key = 'paypay.reconcile:' || substr(dedupe_key, length('payments.reconcile:') + 1)
+  WHERE dedupe_key LIKE 'payments.reconcile:%';
+UPDATE jobs SET kind = 'paypay.reconcile_refund' WHERE kind = 'payments.reconcile_refund';
+UPDATE jobs SET kind = 'paypay.reconcile_pending' WHERE kind = 'payments.reconcile_pending';
+UPDATE jobs SET kind = 'paypay.reconcile_topup' WHERE kind = 'payments.reconcile';
+
+ALTER INDEX IF EXISTS permitted_program_users_payment_id_idx RENAME TO permitted_program_users_topup_id_idx;
+ALTER TABLE permitted_program_users RENAME COLUMN payment_id TO topup_id;
+
+ALTER TABLE user_product_grants RENAME COLUMN payment_id TO topup_id;
+
+ALTER SEQUENCE IF EXISTS payment_refunds_id_seq RENAME TO paypay_refunds_id_seq;
+ALTER INDEX IF EXISTS payment_refunds_payment_id_idx RENAME TO paypay_refunds_topup_id_idx;
+ALTER INDEX IF EXISTS payment_refunds_merchant_refund_id_key RENAME TO paypay_refunds_merchant_refund_id_key;
+ALTER INDEX IF EXISTS payment_refunds_pkey RENAME TO paypay_refunds_pkey;
+ALTER TABLE payment_refunds RENAME COLUMN payment_id TO topup_id;
+ALTER TABLE payment_refunds RENAME TO paypay_refunds;
+
+ALTER SEQUENCE IF EXISTS payment_events_id_seq RENAME TO paypay_topup_events_id_seq;
+ALTER INDEX IF EXISTS payment_events_payment_id_idx RENAME TO paypay_topup_events_topup_id_idx;
+ALTER INDEX IF EXISTS payment_events_pkey RENAME TO paypay_topup_events_pkey;
+ALTER TABLE payment_events RENAME COLUMN provider_payment_id TO paypay_payment_id;
+ALTER TABLE payment_events RENAME COLUMN payment_id TO topup_id;
+ALTER TABLE payment_events RENAME TO paypay_topup_events;
+
+DROP INDEX IF EXISTS payments_provider_payment_id_idx;
+ALTER TABLE payments DROP COLUMN IF EXISTS provider;
+
+ALTER SEQUENCE IF EXISTS payments_id_seq RENAME TO paypay_topups_id_seq;
+ALTER INDEX IF EXISTS payments_pending_created_at_idx RENAME TO paypay_topups_pending_created_at_idx;
+ALTER INDEX IF EXISTS payments_user_created_at_idx RENAME TO paypay_topups_user_created_at_idx;
+ALTER INDEX IF EXISTS payments_merchant_payment_id_key RENAME TO paypay_topups_merchant_payment_id_key;
+ALTER INDEX IF EXISTS payments_pkey RENAME TO paypay_topups_pkey;
+ALTER TABLE payments RENAME CONSTRAINT payments_rental_duration_hours TO paypay_topups_rental_duration_hours;
+ALTER TABLE payments RENAME CONSTRAINT payments_purchase_type_check TO paypay_topups_purchase_type_check;
+ALTER TABLE payments RENAME CONSTRAINT payments_status_check TO paypay_topups_status_check;
+ALTER TABLE payments RENAME COLUMN checkout_expires_at TO code_expires_at;
+ALTER TABLE payments RENAME COLUMN provider_checkout_id TO paypay_code_id;
+ALTER TABLE payments RENAME COLUMN provider_payment_id TO paypay_payment_id;
+ALTER TABLE payments RENAME TO paypay_topups;
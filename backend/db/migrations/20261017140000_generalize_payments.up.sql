@@ -0,0 +1,49 @@
+-- PayPay専用だった決済テーブルを決済サービス（provider）ごとに扱える形へ汎用化する
+ALTER TABLE paypay_topups RENAME TO payments;
+ALTER TABLE payments RENAME COLUMN paypay_payment_id TO provider_payment_id;
+ALTER TABLE payments RENAME COLUMN paypay_code_id TO provider_checkout_id;
+ALTER TABLE payments RENAME COLUMN code_expires_at TO checkout_expires_at;
+ALTER TABLE payments RENAME CONSTRAINT paypay_topups_status_check TO payments_status_check;
+ALTER TABLE payments RENAME CONSTRAINT paypay_topups_purchase_type_check TO payments_purchase_type_check;
+ALTER TABLE payments RENAME CONSTRAINT paypay_topups_rental_duration_hours TO payments_rental_duration_hours;
+ALTER INDEX IF EXISTS paypay_topups_pkey RENAME TO payments_pkey;
+ALTER INDEX IF EXISTS paypay_topups_merchant_payment_id_key RENAME TO payments_merchant_payment_id_key;
+ALTER INDEX IF EXISTS paypay_topups_user_created_at_idx RENAME TO payments_user_created_at_idx;
+ALTER INDEX IF EXISTS paypay_topups_pending_created_at_idx RENAME TO payments_pending_created_at_idx;
+ALTER SEQUENCE IF EXISTS paypay_topups_id_seq RENAME TO payments_id_seq;
+
+-- 既存の決済は全てPayPay
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS provider TEXT NOT NULL DEFAULT 'paypay';
+ALTER TABLE payments ALTER COLUMN provider DROP DEFAULT;
+CREATE UNIQUE INDEX IF NOT EXISTS payments_provider_payment_id_idx
+  ON payments (provider, provider_payment_id)
+  WHERE provider_payment_id IS NOT NULL;
+
+ALTER TABLE paypay_topup_events RENAME TO payment_events;
+ALTER TABLE payment_events RENAME COLUMN topup_id TO payment_id;
+ALTER TABLE payment_events RENAME COLUMN paypay_payment_id TO provider_payment_id;
+ALTER INDEX IF EXISTS paypay_topup_events_pkey RENAME TO payment_events_pkey;
+ALTER INDEX IF EXISTS paypay_topup_events_topup_id_idx RENAME TO payment_events_payment_id_idx;
+ALTER SEQUENCE IF EXISTS paypay_topup_events_id_seq RENAME TO payment_events_id_seq;
+
+ALTER TABLE paypay_refunds RENAME TO payment_refunds;
+ALTER TABLE payment_refunds RENAME COLUMN topup_id TO payment_id;
+ALTER INDEX IF EXISTS paypay_refunds_pkey RENAME TO payment_refunds_pkey;
+ALTER INDEX IF EXISTS paypay_refunds_merchant_refund_id_key RENAME TO payment_refunds_merchant_refund_id_key;
+ALTER INDEX IF EXISTS paypay_refunds_topup_id_idx RENAME TO payment_refunds_payment_id_idx;
+ALTER SEQUENCE IF EXISTS paypay_refunds_id_seq RENAME TO payment_refunds_id_seq;
+
+ALTER TABLE user_product_grants RENAME COLUMN topup_id TO payment_id;
+
+ALTER TABLE permitted_program_users RENAME COLUMN topup_id TO payment_id;
+ALTER INDEX IF EXISTS permitted_program_users_topup_id_idx RENAME TO permitted_program_users_payment_id_idx;
+
+-- 登録済みの照合ジョブを新しい種類へ移す
+UPDATE jobs SET kind = 'payments.reconcile' WHERE kind = 'paypay.reconcile_topup';
+UPDATE jobs SET kind = 'payments.reconcile_pending' WHERE kind = 'paypay.reconcile_pending';
+UPDATE jobs SET kind = 'payments.reconcile_refund' WHERE kind = 'paypay.reconcile_refund';
+UPDATE jobs SET dedupe_key = 'payments.reconcile:' || substr(dedupe_key, length('paypay.reconcile:') + 1)
+  WHERE dedupe_key LIKE 'paypay.reconcile:%';
+UPDATE jobs SET dedupe_key = 'payments.reconcile_refund:' || substr(dedupe_key, length('paypay.reconcile_refund:') + 1)
+  WHERE dedupe_key LIKE 'paypay.reconcile_refund:%';
+UPDATE jobs SET dedupe_key = 'schedule:payments.reconcile_pending' WHERE dedupe_key = 'schedule:paypay.reconcile_pending';
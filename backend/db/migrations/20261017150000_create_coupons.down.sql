@@ -0,0 +1,10 @@
+-- 0円の決済は元の制約を満たさない
+DELETE FROM payments WHERE amount_yen = 0;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_amount_yen_check;
+ALTER TABLE payments ADD CONSTRAINT paypay_topups_amount_yen_check CHECK (amount_yen > 0);
+DROP INDEX IF EXISTS payments_coupon_id_idx;
+ALTER TABLE payments DROP COLUMN IF EXISTS discount_yen;
+ALTER TABLE payments DROP COLUMN IF EXISTS coupon_id;
+DROP TABLE IF EXISTS coupon_tags;
+DROP TABLE IF EXISTS coupon_programs;
+DROP TABLE IF EXISTS coupons;
@@ -0,0 +1,41 @@
+-- 購入時に使える割引コード
+CREATE TABLE IF NOT EXISTS coupons (
+  id BIGSERIAL PRIMARY KEY,
+  -- 大文字に揃えて保存する
+  code TEXT NOT NULL UNIQUE,
+  -- percent（discount_value%引き）/ fixed（discount_value円引き）
+  discount_type TEXT NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+  discount_value INT NOT NULL CHECK (discount_value > 0),
+  -- 有効期間（NULLなら制限なし）
+  starts_at TIMESTAMPTZ,
+  ends_at TIMESTAMPTZ,
+  -- 全体・ユーザーごとの利用回数の上限（NULLなら無制限）
+  max_redemptions INT CHECK (max_redemptions > 0),
+  max_redemptions_per_user INT CHECK (max_redemptions_per_user > 0),
+  is_active BOOLEAN NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT coupons_percent_check CHECK (discount_type <> 'percent' OR discount_value <= 100),
+  CONSTRAINT coupons_period_check CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+-- 対象の番組・タグ（どちらも無ければ全ての番組・商品に使える）
+CREATE TABLE IF NOT EXISTS coupon_programs (
+  coupon_id BIGINT NOT NULL REFERENCES coupons(id) ON DELETE CASCADE,
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  PRIMARY KEY (coupon_id, program_id)
+);
+
+CREATE TABLE IF NOT EXISTS coupon_tags (
+  coupon_id BIGINT NOT NULL REFERENCES coupons(id) ON DELETE CASCADE,
+  tag_id BIGINT NOT NULL REFERENCES category_tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (coupon_id, tag_id)
+);
+
+-- 適用した割引（amount_yenは割引後の請求額。全額割引なら0円で決済サービスを通さない）
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS paypay_topups_amount_yen_check;
+ALTER TABLE payments ADD CONSTRAINT payments_amount_yen_check CHECK (amount_yen >= 0);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS coupon_id BIGINT REFERENCES coupons(id);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS discount_yen INT NOT NULL DEFAULT 0 CHECK (discount_yen >= 0);
+
+CREATE INDEX IF NOT EXISTS payments_coupon_id_idx ON payments (coupon_id, user_id) WHERE coupon_id IS NOT NULL;
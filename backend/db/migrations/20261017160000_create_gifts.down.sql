@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS gifts;
+ALTER TABLE payments DROP COLUMN IF EXISTS is_gift;
@@ -0,0 +1,20 @@
+-- 他のユーザーに贈る購入（支払い完了時に受け取り用のコードを発行する）
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS is_gift BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS gifts (
+  id BIGSERIAL PRIMARY KEY,
+  -- 受け取り用のコード（大文字英数字）
+  code TEXT NOT NULL UNIQUE,
+  payment_id BIGINT NOT NULL UNIQUE REFERENCES payments(id) ON DELETE CASCADE,
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  purchaser_id TEXT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  recipient_id TEXT REFERENCES "user"(id) ON DELETE SET NULL,
+  -- REDEEMABLE（受け取り待ち）/ REDEEMED（受け取り済み）/ REVOKED（返金により無効）
+  status TEXT NOT NULL DEFAULT 'REDEEMABLE' CHECK (status IN ('REDEEMABLE', 'REDEEMED', 'REVOKED')),
+  redeemed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS gifts_purchaser_id_idx ON gifts (purchaser_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS gifts_recipient_id_idx ON gifts (recipient_id, redeemed_at DESC) WHERE recipient_id IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS payments_user_id_created_at_idx;
+ALTER TABLE payments DROP COLUMN IF EXISTS receipt_number;
+DROP TABLE IF EXISTS payment_receipt_counter;
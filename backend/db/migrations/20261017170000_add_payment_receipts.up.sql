@@ -0,0 +1,25 @@
+-- 領収書番号（支払いが完了した決済に1から連番で振る）。
+-- シーケンスはロールバックしても戻らず欠番が出るため、1行のカウンターを決済と同じトランザクションで更新する
+CREATE TABLE IF NOT EXISTS payment_receipt_counter (
+  id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+  last_number BIGINT NOT NULL
+);
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS receipt_number BIGINT UNIQUE;
+
+-- 既存の支払い完了済みの決済に完了順で振る
+UPDATE payments p
+SET receipt_number = n.receipt_number
+FROM (
+  SELECT id, row_number() OVER (ORDER BY credited_at, id) AS receipt_number
+  FROM payments
+  WHERE credited_at IS NOT NULL
+) n
+WHERE p.id = n.id;
+
+INSERT INTO payment_receipt_counter (id, last_number)
+SELECT true, COALESCE(MAX(receipt_number), 0)
+FROM payments
+ON CONFLICT (id) DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS payments_user_id_created_at_idx ON payments (user_id, created_at DESC, id DESC);
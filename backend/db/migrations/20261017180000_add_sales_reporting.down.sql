@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS payments_program_id_created_at_idx;
+DROP INDEX IF EXISTS payments_credited_at_idx;
+ALTER TABLE program_daily_stats DROP COLUMN IF EXISTS detail_views;
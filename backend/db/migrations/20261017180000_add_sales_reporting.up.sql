@@ -0,0 +1,12 @@
+-- 購入転換率の集計用に、番組詳細の閲覧数（未購入の限定公開番組のみ）を日次で数える
+ALTER TABLE program_daily_stats ADD COLUMN IF NOT EXISTS detail_views BIGINT NOT NULL DEFAULT 0;
+
+-- 売上集計用（支払い完了日時で期間を絞る）
+CREATE INDEX IF NOT EXISTS payments_credited_at_idx
+  ON payments (credited_at)
+  WHERE credited_at IS NOT NULL;
+
+-- 購入転換率の集計用（番組ごとの決済開始数）
+CREATE INDEX IF NOT EXISTS payments_program_id_created_at_idx
+  ON payments (program_id, created_at)
+  WHERE program_id IS NOT NULL;
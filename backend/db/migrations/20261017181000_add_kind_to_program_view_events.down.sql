@@ -0,0 +1,5 @@
+DELETE FROM program_view_events WHERE kind <> 'play';
+ALTER TABLE program_view_events DROP CONSTRAINT IF EXISTS program_view_events_dedup_key;
+ALTER TABLE program_view_events ADD CONSTRAINT program_view_events_program_id_viewer_key_bucket_start_key
+  UNIQUE (program_id, viewer_key, bucket_start);
+ALTER TABLE program_view_events DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,8 @@
+-- 番組詳細の閲覧（未購入の限定公開番組のみ）も再生開始と同じく視聴者・時間枠ごとに重複を除いて数える
+-- kind: play=再生開始, detail=詳細閲覧（購入転換率の分母）
+ALTER TABLE program_view_events ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'play'
+  CHECK (kind IN ('play', 'detail'));
+
+ALTER TABLE program_view_events DROP CONSTRAINT IF EXISTS program_view_events_program_id_viewer_key_bucket_start_key;
+ALTER TABLE program_view_events ADD CONSTRAINT program_view_events_dedup_key
+  UNIQUE (program_id, viewer_key, kind, bucket_start);
@@ -0,0 +1,2 @@
+ALTER TABLE gifts DROP COLUMN IF EXISTS replaced_expires_at;
+ALTER TABLE gifts DROP COLUMN IF EXISTS replaced_payment_id;
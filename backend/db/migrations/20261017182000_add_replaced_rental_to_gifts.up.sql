@@ -0,0 +1,4 @@
+-- レンタル中の番組の贈り物を受け取ると買い切りに置き換わるため、贈り物が返金されたときに戻せるよう元のレンタルを記録する
+ALTER TABLE gifts
+  ADD COLUMN IF NOT EXISTS replaced_payment_id BIGINT REFERENCES payments(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS replaced_expires_at TIMESTAMPTZ;
@@ -6,6 +6,7 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
@@ -25,6 +26,19 @@ type Account struct {
 	UpdatedAt             time.Time      `json:"updatedAt"`
 }
 
+type ApiToken struct {
+	ID          int64        `json:"id"`
+	UserID      string       `json:"user_id"`
+	Name        string       `json:"name"`
+	TokenHash   string       `json:"token_hash"`
+	TokenPrefix string       `json:"token_prefix"`
+	Scopes      []string     `json:"scopes"`
+	LastUsedAt  sql.NullTime `json:"last_used_at"`
+	ExpiresAt   sql.NullTime `json:"expires_at"`
+	RevokedAt   sql.NullTime `json:"revoked_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
 type CategoryTag struct {
 	ID        int64     `json:"id"`
 	Name      string    `json:"name"`
@@ -41,6 +55,69 @@ type Comment struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 }
 
+type Coupon struct {
+	ID                    int64         `json:"id"`
+	Code                  string        `json:"code"`
+	DiscountType          string        `json:"discount_type"`
+	DiscountValue         int32         `json:"discount_value"`
+	StartsAt              sql.NullTime  `json:"starts_at"`
+	EndsAt                sql.NullTime  `json:"ends_at"`
+	MaxRedemptions        sql.NullInt32 `json:"max_redemptions"`
+	MaxRedemptionsPerUser sql.NullInt32 `json:"max_redemptions_per_user"`
+	IsActive              bool          `json:"is_active"`
+	CreatedAt             time.Time     `json:"created_at"`
+	UpdatedAt             time.Time     `json:"updated_at"`
+}
+
+type CouponProgram struct {
+	CouponID  int64 `json:"coupon_id"`
+	ProgramID int64 `json:"program_id"`
+}
+
+type CouponTag struct {
+	CouponID int64 `json:"coupon_id"`
+	TagID    int64 `json:"tag_id"`
+}
+
+type Gift struct {
+	ID                int64          `json:"id"`
+	Code              string         `json:"code"`
+	PaymentID         int64          `json:"payment_id"`
+	ProgramID         int64          `json:"program_id"`
+	PurchaserID       string         `json:"purchaser_id"`
+	RecipientID       sql.NullString `json:"recipient_id"`
+	Status            string         `json:"status"`
+	RedeemedAt        sql.NullTime   `json:"redeemed_at"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	ReplacedPaymentID sql.NullInt64  `json:"replaced_payment_id"`
+	ReplacedExpiresAt sql.NullTime   `json:"replaced_expires_at"`
+}
+
+type Job struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	MaxAttempts int32           `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	DedupeKey   sql.NullString  `json:"dedupe_key"`
+	LockedBy    sql.NullString  `json:"locked_by"`
+	LockedAt    sql.NullTime    `json:"locked_at"`
+	LastError   sql.NullString  `json:"last_error"`
+	FinishedAt  sql.NullTime    `json:"finished_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+type JobSchedule struct {
+	Name           string       `json:"name"`
+	NextRunAt      time.Time    `json:"next_run_at"`
+	LastEnqueuedAt sql.NullTime `json:"last_enqueued_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
 type Like struct {
 	ID        int64     `json:"id"`
 	UserID    string    `json:"user_id"`
@@ -48,18 +125,60 @@ type Like struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
-type PaypayTopup struct {
+type Payment struct {
+	ID                  int64          `json:"id"`
+	UserID              string         `json:"user_id"`
+	MerchantPaymentID   string         `json:"merchant_payment_id"`
+	AmountYen           int32          `json:"amount_yen"`
+	Status              string         `json:"status"`
+	ProviderCheckoutID  sql.NullString `json:"provider_checkout_id"`
+	ProviderPaymentID   sql.NullString `json:"provider_payment_id"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	CreditedAt          sql.NullTime   `json:"credited_at"`
+	ProgramID           sql.NullInt64  `json:"program_id"`
+	CheckoutExpiresAt   sql.NullTime   `json:"checkout_expires_at"`
+	LastReconciledAt    sql.NullTime   `json:"last_reconciled_at"`
+	ReconcileAttempts   int32          `json:"reconcile_attempts"`
+	RefundedAmountYen   int32          `json:"refunded_amount_yen"`
+	ProductID           sql.NullInt64  `json:"product_id"`
+	PurchaseType        string         `json:"purchase_type"`
+	RentalDurationHours sql.NullInt32  `json:"rental_duration_hours"`
+	Provider            string         `json:"provider"`
+	CouponID            sql.NullInt64  `json:"coupon_id"`
+	DiscountYen         int32          `json:"discount_yen"`
+	IsGift              bool           `json:"is_gift"`
+	ReceiptNumber       sql.NullInt64  `json:"receipt_number"`
+}
+
+type PaymentEvent struct {
 	ID                int64          `json:"id"`
-	UserID            string         `json:"user_id"`
-	MerchantPaymentID string         `json:"merchant_payment_id"`
-	AmountYen         int32          `json:"amount_yen"`
-	Status            string         `json:"status"`
-	PaypayCodeID      sql.NullString `json:"paypay_code_id"`
-	PaypayPaymentID   sql.NullString `json:"paypay_payment_id"`
+	PaymentID         int64          `json:"payment_id"`
+	FromStatus        sql.NullString `json:"from_status"`
+	ToStatus          string         `json:"to_status"`
+	Source            string         `json:"source"`
+	ProviderPaymentID sql.NullString `json:"provider_payment_id"`
+	Detail            string         `json:"detail"`
 	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
-	CreditedAt        sql.NullTime   `json:"credited_at"`
-	ProgramID         sql.NullInt64  `json:"program_id"`
+}
+
+type PaymentReceiptCounter struct {
+	ID         bool  `json:"id"`
+	LastNumber int64 `json:"last_number"`
+}
+
+type PaymentRefund struct {
+	ID               int64          `json:"id"`
+	PaymentID        int64          `json:"payment_id"`
+	MerchantRefundID string         `json:"merchant_refund_id"`
+	AmountYen        int32          `json:"amount_yen"`
+	Status           string         `json:"status"`
+	Reason           string         `json:"reason"`
+	RequestedBy      sql.NullString `json:"requested_by"`
+	Error            sql.NullString `json:"error"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	RefundedAt       sql.NullTime   `json:"refunded_at"`
 }
 
 type Performer struct {
@@ -74,24 +193,47 @@ type Performer struct {
 }
 
 type PermittedProgramUser struct {
-	ID        int64     `json:"id"`
-	UserID    string    `json:"user_id"`
-	ProgramID int64     `json:"program_id"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           int64         `json:"id"`
+	UserID       string        `json:"user_id"`
+	ProgramID    int64         `json:"program_id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	PurchaseType string        `json:"purchase_type"`
+	ExpiresAt    sql.NullTime  `json:"expires_at"`
+	PaymentID    sql.NullInt64 `json:"payment_id"`
+}
+
+type Product struct {
+	ID           int64         `json:"id"`
+	Kind         string        `json:"kind"`
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	Price        int32         `json:"price"`
+	PerformerID  sql.NullInt64 `json:"performer_id"`
+	DurationDays sql.NullInt32 `json:"duration_days"`
+	IsActive     bool          `json:"is_active"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+type ProductProgram struct {
+	ProductID int64 `json:"product_id"`
+	ProgramID int64 `json:"program_id"`
 }
 
 type Program struct {
-	ID               int64          `json:"id"`
-	Title            string         `json:"title"`
-	VideoPath        string         `json:"video_path"`
-	ThumbnailPath    sql.NullString `json:"thumbnail_path"`
-	Description      sql.NullString `json:"description"`
-	CreatedAt        time.Time      `json:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at"`
-	ViewCount        int32          `json:"view_count"`
-	IsLimitedRelease bool           `json:"is_limited_release"`
-	Price            int32          `json:"price"`
-	IsPublic         bool           `json:"is_public"`
+	ID                  int64          `json:"id"`
+	Title               string         `json:"title"`
+	VideoPath           string         `json:"video_path"`
+	ThumbnailPath       sql.NullString `json:"thumbnail_path"`
+	Description         sql.NullString `json:"description"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	ViewCount           int32          `json:"view_count"`
+	IsLimitedRelease    bool           `json:"is_limited_release"`
+	Price               int32          `json:"price"`
+	IsPublic            bool           `json:"is_public"`
+	RentalPrice         sql.NullInt32  `json:"rental_price"`
+	RentalDurationHours sql.NullInt32  `json:"rental_duration_hours"`
 }
 
 type ProgramCategoryTag struct {
@@ -99,11 +241,38 @@ type ProgramCategoryTag struct {
 	TagID     int64 `json:"tag_id"`
 }
 
+type ProgramDailyStat struct {
+	ProgramID   int64     `json:"program_id"`
+	StatDate    time.Time `json:"stat_date"`
+	Views       int64     `json:"views"`
+	Likes       int64     `json:"likes"`
+	Completions int64     `json:"completions"`
+	Purchases   int64     `json:"purchases"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	DetailViews int64     `json:"detail_views"`
+}
+
 type ProgramPerformer struct {
 	ProgramID   int64 `json:"program_id"`
 	PerformerID int64 `json:"performer_id"`
 }
 
+type ProgramSearchDocument struct {
+	ProgramID int64       `json:"program_id"`
+	Document  interface{} `json:"document"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+type ProgramViewEvent struct {
+	ID           int64        `json:"id"`
+	ProgramID    int64        `json:"program_id"`
+	ViewerKey    string       `json:"viewer_key"`
+	BucketStart  time.Time    `json:"bucket_start"`
+	AggregatedAt sql.NullTime `json:"aggregated_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Kind         string       `json:"kind"`
+}
+
 type Request struct {
 	ID        int64          `json:"id"`
 	UserID    sql.NullString `json:"user_id"`
@@ -135,6 +304,22 @@ type User struct {
 	UpdatedAt     time.Time      `json:"updatedAt"`
 }
 
+type UserProductGrant struct {
+	ID        int64         `json:"id"`
+	UserID    string        `json:"user_id"`
+	ProductID int64         `json:"product_id"`
+	PaymentID sql.NullInt64 `json:"payment_id"`
+	GrantedAt time.Time     `json:"granted_at"`
+	ExpiresAt sql.NullTime  `json:"expires_at"`
+	RevokedAt sql.NullTime  `json:"revoked_at"`
+}
+
+type UserRole struct {
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Verification struct {
 	ID         string    `json:"id"`
 	Identifier string    `json:"identifier"`
@@ -154,3 +339,17 @@ type WatchHistory struct {
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
+
+type WebhookDelivery struct {
+	ID               int64          `json:"id"`
+	Provider         string         `json:"provider"`
+	DedupeKey        string         `json:"dedupe_key"`
+	NotificationType string         `json:"notification_type"`
+	Body             string         `json:"body"`
+	RemoteIp         string         `json:"remote_ip"`
+	Status           string         `json:"status"`
+	Attempts         int32          `json:"attempts"`
+	LastError        sql.NullString `json:"last_error"`
+	ReceivedAt       time.Time      `json:"received_at"`
+	ProcessedAt      sql.NullTime   `json:"processed_at"`
+}
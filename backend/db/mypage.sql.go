@@ -8,6 +8,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const listLikedProgramsByUser = `-- name: ListLikedProgramsByUser :many
@@ -18,6 +19,8 @@ SELECT
   p.view_count,
   p.is_limited_release,
   p.price,
+  lk.created_at AS cursor_at,
+  lk.id AS cursor_id,
   COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
   COALESCE(
     jsonb_agg(DISTINCT jsonb_build_object(
@@ -31,6 +34,10 @@ JOIN programs p ON p.id = lk.program_id
 LEFT JOIN program_category_tags pct ON p.id = pct.program_id
 LEFT JOIN category_tags ct ON pct.tag_id = ct.id
 WHERE lk.user_id = $1 AND p.is_public = true
+  AND (
+    $2::timestamptz IS NULL
+    OR (lk.created_at, lk.id) < ($2::timestamptz, $3::bigint)
+  )
 GROUP BY
   p.id,
   p.title,
@@ -38,16 +45,17 @@ GROUP BY
   p.view_count,
   p.is_limited_release,
   p.price,
-  lk.created_at
-ORDER BY lk.created_at DESC
-LIMIT COALESCE($3::int, 50)
-OFFSET COALESCE($2::int, 0)
+  lk.created_at,
+  lk.id
+ORDER BY lk.created_at DESC, lk.id DESC
+LIMIT $4::int
 `
 
 type ListLikedProgramsByUserParams struct {
-	UserID string        `json:"user_id"`
-	Offset sql.NullInt32 `json:"offset"`
-	Limit  sql.NullInt32 `json:"limit"`
+	UserID    string        `json:"user_id"`
+	CursorAt  sql.NullTime  `json:"cursor_at"`
+	CursorID  sql.NullInt64 `json:"cursor_id"`
+	PageLimit int32         `json:"page_limit"`
 }
 
 type ListLikedProgramsByUserRow struct {
@@ -57,13 +65,20 @@ type ListLikedProgramsByUserRow struct {
 	ViewCount        int32          `json:"view_count"`
 	IsLimitedRelease bool           `json:"is_limited_release"`
 	Price            int32          `json:"price"`
+	CursorAt         time.Time      `json:"cursor_at"`
+	CursorID         int64          `json:"cursor_id"`
 	LikeCount        int64          `json:"like_count"`
 	CategoryTags     interface{}    `json:"category_tags"`
 }
 
 // 視聴回数はprogramsテーブルのview_countを参照
 func (q *Queries) ListLikedProgramsByUser(ctx context.Context, arg ListLikedProgramsByUserParams) ([]ListLikedProgramsByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, listLikedProgramsByUser, arg.UserID, arg.Offset, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, listLikedProgramsByUser,
+		arg.UserID,
+		arg.CursorAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +93,8 @@ func (q *Queries) ListLikedProgramsByUser(ctx context.Context, arg ListLikedProg
 			&i.ViewCount,
 			&i.IsLimitedRelease,
 			&i.Price,
+			&i.CursorAt,
+			&i.CursorID,
 			&i.LikeCount,
 			&i.CategoryTags,
 		); err != nil {
@@ -102,6 +119,10 @@ SELECT
   p.view_count,
   p.is_limited_release,
   p.price,
+  ppu.purchase_type,
+  ppu.expires_at,
+  ppu.created_at AS cursor_at,
+  ppu.id AS cursor_id,
   COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
   COALESCE(
     jsonb_agg(DISTINCT jsonb_build_object(
@@ -115,6 +136,12 @@ JOIN programs p ON p.id = ppu.program_id
 LEFT JOIN program_category_tags pct ON p.id = pct.program_id
 LEFT JOIN category_tags ct ON pct.tag_id = ct.id
 WHERE ppu.user_id = $1 AND p.is_public = true
+  -- 期限切れのレンタルは含めない
+  AND (ppu.expires_at IS NULL OR ppu.expires_at > now())
+  AND (
+    $2::timestamptz IS NULL
+    OR (ppu.created_at, ppu.id) < ($2::timestamptz, $3::bigint)
+  )
 GROUP BY
   p.id,
   p.title,
@@ -122,16 +149,19 @@ GROUP BY
   p.view_count,
   p.is_limited_release,
   p.price,
-  ppu.created_at
-ORDER BY ppu.created_at DESC
-LIMIT COALESCE($3::int, 50)
-OFFSET COALESCE($2::int, 0)
+  ppu.purchase_type,
+  ppu.expires_at,
+  ppu.created_at,
+  ppu.id
+ORDER BY ppu.created_at DESC, ppu.id DESC
+LIMIT $4::int
 `
 
 type ListPurchasedProgramsByUserParams struct {
-	UserID string        `json:"user_id"`
-	Offset sql.NullInt32 `json:"offset"`
-	Limit  sql.NullInt32 `json:"limit"`
+	UserID    string        `json:"user_id"`
+	CursorAt  sql.NullTime  `json:"cursor_at"`
+	CursorID  sql.NullInt64 `json:"cursor_id"`
+	PageLimit int32         `json:"page_limit"`
 }
 
 type ListPurchasedProgramsByUserRow struct {
@@ -141,12 +171,21 @@ type ListPurchasedProgramsByUserRow struct {
 	ViewCount        int32          `json:"view_count"`
 	IsLimitedRelease bool           `json:"is_limited_release"`
 	Price            int32          `json:"price"`
+	PurchaseType     string         `json:"purchase_type"`
+	ExpiresAt        sql.NullTime   `json:"expires_at"`
+	CursorAt         time.Time      `json:"cursor_at"`
+	CursorID         int64          `json:"cursor_id"`
 	LikeCount        int64          `json:"like_count"`
 	CategoryTags     interface{}    `json:"category_tags"`
 }
 
 func (q *Queries) ListPurchasedProgramsByUser(ctx context.Context, arg ListPurchasedProgramsByUserParams) ([]ListPurchasedProgramsByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, listPurchasedProgramsByUser, arg.UserID, arg.Offset, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, listPurchasedProgramsByUser,
+		arg.UserID,
+		arg.CursorAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -161,6 +200,10 @@ func (q *Queries) ListPurchasedProgramsByUser(ctx context.Context, arg ListPurch
 			&i.ViewCount,
 			&i.IsLimitedRelease,
 			&i.Price,
+			&i.PurchaseType,
+			&i.ExpiresAt,
+			&i.CursorAt,
+			&i.CursorID,
 			&i.LikeCount,
 			&i.CategoryTags,
 		); err != nil {
@@ -185,6 +228,8 @@ SELECT
   p.view_count,
   p.is_limited_release,
   p.price,
+  wh.last_watched_at AS cursor_at,
+  wh.id AS cursor_id,
   COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
   COALESCE(
     jsonb_agg(DISTINCT jsonb_build_object(
@@ -198,6 +243,10 @@ JOIN programs p ON p.id = wh.program_id
 LEFT JOIN program_category_tags pct ON p.id = pct.program_id
 LEFT JOIN category_tags ct ON pct.tag_id = ct.id
 WHERE wh.user_id = $1 AND wh.is_completed = FALSE AND p.is_public = true
+  AND (
+    $2::timestamptz IS NULL
+    OR (wh.last_watched_at, wh.id) < ($2::timestamptz, $3::bigint)
+  )
 GROUP BY
   p.id,
   p.title,
@@ -205,16 +254,17 @@ GROUP BY
   p.view_count,
   p.is_limited_release,
   p.price,
-  wh.last_watched_at
-ORDER BY wh.last_watched_at DESC
-LIMIT COALESCE($3::int, 50)
-OFFSET COALESCE($2::int, 0)
+  wh.last_watched_at,
+  wh.id
+ORDER BY wh.last_watched_at DESC, wh.id DESC
+LIMIT $4::int
 `
 
 type ListWatchingProgramsByUserParams struct {
-	UserID string        `json:"user_id"`
-	Offset sql.NullInt32 `json:"offset"`
-	Limit  sql.NullInt32 `json:"limit"`
+	UserID    string        `json:"user_id"`
+	CursorAt  sql.NullTime  `json:"cursor_at"`
+	CursorID  sql.NullInt64 `json:"cursor_id"`
+	PageLimit int32         `json:"page_limit"`
 }
 
 type ListWatchingProgramsByUserRow struct {
@@ -224,13 +274,20 @@ type ListWatchingProgramsByUserRow struct {
 	ViewCount        int32          `json:"view_count"`
 	IsLimitedRelease bool           `json:"is_limited_release"`
 	Price            int32          `json:"price"`
+	CursorAt         time.Time      `json:"cursor_at"`
+	CursorID         int64          `json:"cursor_id"`
 	LikeCount        int64          `json:"like_count"`
 	CategoryTags     interface{}    `json:"category_tags"`
 }
 
 // 視聴回数はprogramsテーブルのview_countを参照
 func (q *Queries) ListWatchingProgramsByUser(ctx context.Context, arg ListWatchingProgramsByUserParams) ([]ListWatchingProgramsByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, listWatchingProgramsByUser, arg.UserID, arg.Offset, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, listWatchingProgramsByUser,
+		arg.UserID,
+		arg.CursorAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -245,6 +302,8 @@ func (q *Queries) ListWatchingProgramsByUser(ctx context.Context, arg ListWatchi
 			&i.ViewCount,
 			&i.IsLimitedRelease,
 			&i.Price,
+			&i.CursorAt,
+			&i.CursorID,
 			&i.LikeCount,
 			&i.CategoryTags,
 		); err != nil {
@@ -8,6 +8,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const createPerformer = `-- name: CreatePerformer :one
@@ -52,3 +53,145 @@ func (q *Queries) CreatePerformer(ctx context.Context, arg CreatePerformerParams
 	)
 	return i, err
 }
+
+const getPerformerByID = `-- name: GetPerformerByID :one
+SELECT
+  pe.id,
+  pe.first_name,
+  pe.last_name,
+  pe.first_name_kana,
+  pe.last_name_kana,
+  pe.image_path,
+  pe.created_at,
+  pe.updated_at,
+  (
+    SELECT COUNT(*)
+    FROM program_performers pp
+    JOIN programs p ON p.id = pp.program_id
+    WHERE pp.performer_id = pe.id AND p.is_public = true
+  )::bigint AS program_count
+FROM performers pe
+WHERE pe.id = $1
+`
+
+type GetPerformerByIDRow struct {
+	ID            int64          `json:"id"`
+	FirstName     string         `json:"first_name"`
+	LastName      string         `json:"last_name"`
+	FirstNameKana string         `json:"first_name_kana"`
+	LastNameKana  string         `json:"last_name_kana"`
+	ImagePath     sql.NullString `json:"image_path"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	ProgramCount  int64          `json:"program_count"`
+}
+
+func (q *Queries) GetPerformerByID(ctx context.Context, id int64) (GetPerformerByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getPerformerByID, id)
+	var i GetPerformerByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.FirstName,
+		&i.LastName,
+		&i.FirstNameKana,
+		&i.LastNameKana,
+		&i.ImagePath,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProgramCount,
+	)
+	return i, err
+}
+
+const listPerformers = `-- name: ListPerformers :many
+SELECT
+  pe.id,
+  pe.first_name,
+  pe.last_name,
+  pe.first_name_kana,
+  pe.last_name_kana,
+  pe.image_path,
+  (pe.last_name_kana || pe.first_name_kana)::text AS sort_kana,
+  (
+    SELECT COUNT(*)
+    FROM program_performers pp
+    JOIN programs p ON p.id = pp.program_id
+    WHERE pp.performer_id = pe.id AND p.is_public = true
+  )::bigint AS program_count
+FROM performers pe
+WHERE
+  (
+    $1::text IS NULL
+    OR (pe.last_name || pe.first_name) ILIKE '%' || $1::text || '%'
+    OR (pe.last_name || pe.first_name) ILIKE '%' || $2::text || '%'
+    OR (pe.last_name_kana || pe.first_name_kana) ILIKE '%' || $3::text || '%'
+    OR (pe.last_name_kana || pe.first_name_kana) ILIKE '%' || $2::text || '%'
+  )
+  -- カーソル（前ページ末尾の sort_kana, id）より後ろのみ
+  AND (
+    $4::text IS NULL
+    OR (pe.last_name_kana || pe.first_name_kana, pe.id) > ($4::text, $5::bigint)
+  )
+ORDER BY pe.last_name_kana || pe.first_name_kana, pe.id
+LIMIT $6::int
+`
+
+type ListPerformersParams struct {
+	Name         sql.NullString `json:"name"`
+	KanaKatakana sql.NullString `json:"kana_katakana"`
+	KanaHiragana sql.NullString `json:"kana_hiragana"`
+	CursorKana   sql.NullString `json:"cursor_kana"`
+	CursorID     sql.NullInt64  `json:"cursor_id"`
+	PageLimit    int32          `json:"page_limit"`
+}
+
+type ListPerformersRow struct {
+	ID            int64          `json:"id"`
+	FirstName     string         `json:"first_name"`
+	LastName      string         `json:"last_name"`
+	FirstNameKana string         `json:"first_name_kana"`
+	LastNameKana  string         `json:"last_name_kana"`
+	ImagePath     sql.NullString `json:"image_path"`
+	SortKana      string         `json:"sort_kana"`
+	ProgramCount  int64          `json:"program_count"`
+}
+
+// 五十音順（姓・名のカナ）。name/kana_hiragana/kana_katakanaは正規化した検索語（ひらがな・カタカナ表記）で部分一致
+func (q *Queries) ListPerformers(ctx context.Context, arg ListPerformersParams) ([]ListPerformersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPerformers,
+		arg.Name,
+		arg.KanaKatakana,
+		arg.KanaHiragana,
+		arg.CursorKana,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPerformersRow
+	for rows.Next() {
+		var i ListPerformersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FirstName,
+			&i.LastName,
+			&i.FirstNameKana,
+			&i.LastNameKana,
+			&i.ImagePath,
+			&i.SortKana,
+			&i.ProgramCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
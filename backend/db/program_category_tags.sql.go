@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createProgramCategoryTag = `-- name: CreateProgramCategoryTag :exec
@@ -27,3 +29,139 @@ func (q *Queries) CreateProgramCategoryTag(ctx context.Context, arg CreateProgra
 	_, err := q.db.ExecContext(ctx, createProgramCategoryTag, arg.ProgramID, arg.TagID)
 	return err
 }
+
+const deleteProgramCategoryTagsByProgramID = `-- name: DeleteProgramCategoryTagsByProgramID :exec
+DELETE FROM program_category_tags
+WHERE program_id = $1
+`
+
+func (q *Queries) DeleteProgramCategoryTagsByProgramID(ctx context.Context, programID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteProgramCategoryTagsByProgramID, programID)
+	return err
+}
+
+const listProgramIDsByTagID = `-- name: ListProgramIDsByTagID :many
+SELECT program_id
+FROM program_category_tags
+WHERE tag_id = $1
+ORDER BY program_id
+`
+
+func (q *Queries) ListProgramIDsByTagID(ctx context.Context, tagID int64) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listProgramIDsByTagID, tagID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var program_id int64
+		if err := rows.Scan(&program_id); err != nil {
+			return nil, err
+		}
+		items = append(items, program_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProgramsByTagID = `-- name: ListProgramsByTagID :many
+SELECT
+  p.id AS program_id,
+  p.title,
+  p.thumbnail_path,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  p.created_at AS program_created_at,
+  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
+  COALESCE(
+    jsonb_agg(DISTINCT jsonb_build_object(
+      'id', ct.id,
+      'name', ct.name
+    )) FILTER (WHERE ct.id IS NOT NULL),
+    '[]'::jsonb
+  ) AS category_tags
+FROM program_category_tags tpct
+JOIN programs p ON p.id = tpct.program_id
+LEFT JOIN program_category_tags pct ON p.id = pct.program_id
+LEFT JOIN category_tags ct ON pct.tag_id = ct.id
+WHERE tpct.tag_id = $1
+  AND p.is_public = true
+  AND (
+    $2::timestamptz IS NULL
+    OR (p.created_at, p.id) < ($2::timestamptz, $3::bigint)
+  )
+GROUP BY
+  p.id,
+  p.title,
+  p.thumbnail_path,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  p.created_at
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT $4::int
+`
+
+type ListProgramsByTagIDParams struct {
+	TagID           int64         `json:"tag_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        sql.NullInt64 `json:"cursor_id"`
+	PageLimit       int32         `json:"page_limit"`
+}
+
+type ListProgramsByTagIDRow struct {
+	ProgramID        int64          `json:"program_id"`
+	Title            string         `json:"title"`
+	ThumbnailPath    sql.NullString `json:"thumbnail_path"`
+	ViewCount        int32          `json:"view_count"`
+	IsLimitedRelease bool           `json:"is_limited_release"`
+	Price            int32          `json:"price"`
+	ProgramCreatedAt time.Time      `json:"program_created_at"`
+	LikeCount        int64          `json:"like_count"`
+	CategoryTags     interface{}    `json:"category_tags"`
+}
+
+func (q *Queries) ListProgramsByTagID(ctx context.Context, arg ListProgramsByTagIDParams) ([]ListProgramsByTagIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, listProgramsByTagID,
+		arg.TagID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProgramsByTagIDRow
+	for rows.Next() {
+		var i ListProgramsByTagIDRow
+		if err := rows.Scan(
+			&i.ProgramID,
+			&i.Title,
+			&i.ThumbnailPath,
+			&i.ViewCount,
+			&i.IsLimitedRelease,
+			&i.Price,
+			&i.ProgramCreatedAt,
+			&i.LikeCount,
+			&i.CategoryTags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createProgramPerformer = `-- name: CreateProgramPerformer :exec
@@ -27,3 +29,109 @@ func (q *Queries) CreateProgramPerformer(ctx context.Context, arg CreateProgramP
 	_, err := q.db.ExecContext(ctx, createProgramPerformer, arg.ProgramID, arg.PerformerID)
 	return err
 }
+
+const deleteProgramPerformersByProgramID = `-- name: DeleteProgramPerformersByProgramID :exec
+DELETE FROM program_performers
+WHERE program_id = $1
+`
+
+func (q *Queries) DeleteProgramPerformersByProgramID(ctx context.Context, programID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteProgramPerformersByProgramID, programID)
+	return err
+}
+
+const listProgramsByPerformerID = `-- name: ListProgramsByPerformerID :many
+SELECT
+  p.id AS program_id,
+  p.title,
+  p.thumbnail_path,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  p.created_at AS program_created_at,
+  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
+  COALESCE(
+    jsonb_agg(DISTINCT jsonb_build_object(
+      'id', ct.id,
+      'name', ct.name
+    )) FILTER (WHERE ct.id IS NOT NULL),
+    '[]'::jsonb
+  ) AS category_tags
+FROM program_performers pp
+JOIN programs p ON p.id = pp.program_id
+LEFT JOIN program_category_tags pct ON p.id = pct.program_id
+LEFT JOIN category_tags ct ON pct.tag_id = ct.id
+WHERE pp.performer_id = $1
+  AND p.is_public = true
+  AND (
+    $2::timestamptz IS NULL
+    OR (p.created_at, p.id) < ($2::timestamptz, $3::bigint)
+  )
+GROUP BY
+  p.id,
+  p.title,
+  p.thumbnail_path,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  p.created_at
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT $4::int
+`
+
+type ListProgramsByPerformerIDParams struct {
+	PerformerID     int64         `json:"performer_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        sql.NullInt64 `json:"cursor_id"`
+	PageLimit       int32         `json:"page_limit"`
+}
+
+type ListProgramsByPerformerIDRow struct {
+	ProgramID        int64          `json:"program_id"`
+	Title            string         `json:"title"`
+	ThumbnailPath    sql.NullString `json:"thumbnail_path"`
+	ViewCount        int32          `json:"view_count"`
+	IsLimitedRelease bool           `json:"is_limited_release"`
+	Price            int32          `json:"price"`
+	ProgramCreatedAt time.Time      `json:"program_created_at"`
+	LikeCount        int64          `json:"like_count"`
+	CategoryTags     interface{}    `json:"category_tags"`
+}
+
+func (q *Queries) ListProgramsByPerformerID(ctx context.Context, arg ListProgramsByPerformerIDParams) ([]ListProgramsByPerformerIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, listProgramsByPerformerID,
+		arg.PerformerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProgramsByPerformerIDRow
+	for rows.Next() {
+		var i ListProgramsByPerformerIDRow
+		if err := rows.Scan(
+			&i.ProgramID,
+			&i.Title,
+			&i.ThumbnailPath,
+			&i.ViewCount,
+			&i.IsLimitedRelease,
+			&i.Price,
+			&i.ProgramCreatedAt,
+			&i.LikeCount,
+			&i.CategoryTags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
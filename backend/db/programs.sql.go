@@ -20,32 +20,38 @@ INSERT INTO programs (
   thumbnail_path,
   description,
   is_limited_release,
-  price
+  price,
+  rental_price,
+  rental_duration_hours
 ) VALUES (
-  $1, $2, $3, $4, $5, $6
+  $1, $2, $3, $4, $5, $6, $7, $8
 )
-RETURNING id, title, video_path, thumbnail_path, description, is_limited_release, price, created_at, updated_at
+RETURNING id, title, video_path, thumbnail_path, description, is_limited_release, price, rental_price, rental_duration_hours, created_at, updated_at
 `
 
 type CreateProgramParams struct {
-	Title            string         `json:"title"`
-	VideoPath        string         `json:"video_path"`
-	ThumbnailPath    sql.NullString `json:"thumbnail_path"`
-	Description      sql.NullString `json:"description"`
-	IsLimitedRelease bool           `json:"is_limited_release"`
-	Price            int32          `json:"price"`
+	Title               string         `json:"title"`
+	VideoPath           string         `json:"video_path"`
+	ThumbnailPath       sql.NullString `json:"thumbnail_path"`
+	Description         sql.NullString `json:"description"`
+	IsLimitedRelease    bool           `json:"is_limited_release"`
+	Price               int32          `json:"price"`
+	RentalPrice         sql.NullInt32  `json:"rental_price"`
+	RentalDurationHours sql.NullInt32  `json:"rental_duration_hours"`
 }
 
 type CreateProgramRow struct {
-	ID               int64          `json:"id"`
-	Title            string         `json:"title"`
-	VideoPath        string         `json:"video_path"`
-	ThumbnailPath    sql.NullString `json:"thumbnail_path"`
-	Description      sql.NullString `json:"description"`
-	IsLimitedRelease bool           `json:"is_limited_release"`
-	Price            int32          `json:"price"`
-	CreatedAt        time.Time      `json:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at"`
+	ID                  int64          `json:"id"`
+	Title               string         `json:"title"`
+	VideoPath           string         `json:"video_path"`
+	ThumbnailPath       sql.NullString `json:"thumbnail_path"`
+	Description         sql.NullString `json:"description"`
+	IsLimitedRelease    bool           `json:"is_limited_release"`
+	Price               int32          `json:"price"`
+	RentalPrice         sql.NullInt32  `json:"rental_price"`
+	RentalDurationHours sql.NullInt32  `json:"rental_duration_hours"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
 }
 
 func (q *Queries) CreateProgram(ctx context.Context, arg CreateProgramParams) (CreateProgramRow, error) {
@@ -56,6 +62,8 @@ func (q *Queries) CreateProgram(ctx context.Context, arg CreateProgramParams) (C
 		arg.Description,
 		arg.IsLimitedRelease,
 		arg.Price,
+		arg.RentalPrice,
+		arg.RentalDurationHours,
 	)
 	var i CreateProgramRow
 	err := row.Scan(
@@ -66,12 +74,27 @@ func (q *Queries) CreateProgram(ctx context.Context, arg CreateProgramParams) (C
 		&i.Description,
 		&i.IsLimitedRelease,
 		&i.Price,
+		&i.RentalPrice,
+		&i.RentalDurationHours,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const deleteProgram = `-- name: DeleteProgram :execrows
+DELETE FROM programs
+WHERE id = $1
+`
+
+func (q *Queries) DeleteProgram(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteProgram, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const existsProgram = `-- name: ExistsProgram :one
 SELECT EXISTS(
   SELECT 1
@@ -87,6 +110,104 @@ func (q *Queries) ExistsProgram(ctx context.Context, id int64) (bool, error) {
 	return exists, err
 }
 
+const getAdminProgramByID = `-- name: GetAdminProgramByID :one
+
+SELECT
+  p.id AS program_id,
+  p.title,
+  p.video_path,
+  p.thumbnail_path,
+  p.description,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  p.rental_price,
+  p.rental_duration_hours,
+  p.is_public,
+  p.created_at AS program_created_at,
+  p.updated_at AS program_updated_at,
+  COALESCE(
+    jsonb_agg(DISTINCT jsonb_build_object(
+      'id', ct.id,
+      'name', ct.name
+    )) FILTER (WHERE ct.id IS NOT NULL),
+    '[]'::jsonb
+  ) AS category_tags,
+  COALESCE(
+    jsonb_agg(DISTINCT jsonb_build_object(
+      'id', pe.id,
+      'first_name', pe.first_name,
+      'last_name', pe.last_name,
+      'first_name_kana', pe.first_name_kana,
+      'last_name_kana', pe.last_name_kana,
+      'image_path', pe.image_path
+    )) FILTER (WHERE pe.id IS NOT NULL),
+    '[]'::jsonb
+  ) AS performers
+FROM programs p
+LEFT JOIN program_category_tags pct ON p.id = pct.program_id
+LEFT JOIN category_tags ct ON pct.tag_id = ct.id
+LEFT JOIN program_performers pp ON p.id = pp.program_id
+LEFT JOIN performers pe ON pp.performer_id = pe.id
+WHERE p.id = $1
+GROUP BY
+  p.id,
+  p.title,
+  p.video_path,
+  p.thumbnail_path,
+  p.description,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  p.rental_price,
+  p.rental_duration_hours,
+  p.is_public,
+  p.created_at,
+  p.updated_at
+`
+
+type GetAdminProgramByIDRow struct {
+	ProgramID           int64          `json:"program_id"`
+	Title               string         `json:"title"`
+	VideoPath           string         `json:"video_path"`
+	ThumbnailPath       sql.NullString `json:"thumbnail_path"`
+	Description         sql.NullString `json:"description"`
+	ViewCount           int32          `json:"view_count"`
+	IsLimitedRelease    bool           `json:"is_limited_release"`
+	Price               int32          `json:"price"`
+	RentalPrice         sql.NullInt32  `json:"rental_price"`
+	RentalDurationHours sql.NullInt32  `json:"rental_duration_hours"`
+	IsPublic            bool           `json:"is_public"`
+	ProgramCreatedAt    time.Time      `json:"program_created_at"`
+	ProgramUpdatedAt    time.Time      `json:"program_updated_at"`
+	CategoryTags        interface{}    `json:"category_tags"`
+	Performers          interface{}    `json:"performers"`
+}
+
+// 管理画面用（is_publicに関わらず取得）
+func (q *Queries) GetAdminProgramByID(ctx context.Context, id int64) (GetAdminProgramByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getAdminProgramByID, id)
+	var i GetAdminProgramByIDRow
+	err := row.Scan(
+		&i.ProgramID,
+		&i.Title,
+		&i.VideoPath,
+		&i.ThumbnailPath,
+		&i.Description,
+		&i.ViewCount,
+		&i.IsLimitedRelease,
+		&i.Price,
+		&i.RentalPrice,
+		&i.RentalDurationHours,
+		&i.IsPublic,
+		&i.ProgramCreatedAt,
+		&i.ProgramUpdatedAt,
+		&i.CategoryTags,
+		&i.Performers,
+	)
+	return i, err
+}
+
 const getProgramByID = `-- name: GetProgramByID :one
 SELECT
   p.id AS program_id,
@@ -182,6 +303,8 @@ SELECT
   p.view_count,
   p.is_limited_release,
   p.price,
+  p.rental_price,
+  p.rental_duration_hours,
   COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
   EXISTS(
     SELECT 1
@@ -223,6 +346,8 @@ GROUP BY
   p.view_count,
   p.is_limited_release,
   p.price,
+  p.rental_price,
+  p.rental_duration_hours,
   p.created_at,
   p.updated_at
 `
@@ -233,20 +358,22 @@ type GetProgramDetailsByIDParams struct {
 }
 
 type GetProgramDetailsByIDRow struct {
-	ProgramID        int64          `json:"program_id"`
-	Title            string         `json:"title"`
-	VideoPath        string         `json:"video_path"`
-	ThumbnailPath    sql.NullString `json:"thumbnail_path"`
-	Description      sql.NullString `json:"description"`
-	ViewCount        int32          `json:"view_count"`
-	IsLimitedRelease bool           `json:"is_limited_release"`
-	Price            int32          `json:"price"`
-	LikeCount        int64          `json:"like_count"`
-	Liked            bool           `json:"liked"`
-	ProgramCreatedAt time.Time      `json:"program_created_at"`
-	ProgramUpdatedAt time.Time      `json:"program_updated_at"`
-	CategoryTags     interface{}    `json:"category_tags"`
-	Performers       interface{}    `json:"performers"`
+	ProgramID           int64          `json:"program_id"`
+	Title               string         `json:"title"`
+	VideoPath           string         `json:"video_path"`
+	ThumbnailPath       sql.NullString `json:"thumbnail_path"`
+	Description         sql.NullString `json:"description"`
+	ViewCount           int32          `json:"view_count"`
+	IsLimitedRelease    bool           `json:"is_limited_release"`
+	Price               int32          `json:"price"`
+	RentalPrice         sql.NullInt32  `json:"rental_price"`
+	RentalDurationHours sql.NullInt32  `json:"rental_duration_hours"`
+	LikeCount           int64          `json:"like_count"`
+	Liked               bool           `json:"liked"`
+	ProgramCreatedAt    time.Time      `json:"program_created_at"`
+	ProgramUpdatedAt    time.Time      `json:"program_updated_at"`
+	CategoryTags        interface{}    `json:"category_tags"`
+	Performers          interface{}    `json:"performers"`
 }
 
 // 視聴回数はprogramsテーブルのview_countを参照
@@ -262,6 +389,8 @@ func (q *Queries) GetProgramDetailsByID(ctx context.Context, arg GetProgramDetai
 		&i.ViewCount,
 		&i.IsLimitedRelease,
 		&i.Price,
+		&i.RentalPrice,
+		&i.RentalDurationHours,
 		&i.LikeCount,
 		&i.Liked,
 		&i.ProgramCreatedAt,
@@ -273,33 +402,118 @@ func (q *Queries) GetProgramDetailsByID(ctx context.Context, arg GetProgramDetai
 }
 
 const getProgramForPurchase = `-- name: GetProgramForPurchase :one
-SELECT id, is_limited_release, price
+SELECT id, is_limited_release, price, rental_price, rental_duration_hours
 FROM programs
 WHERE id = $1 AND is_public = true
 `
 
 type GetProgramForPurchaseRow struct {
-	ID               int64 `json:"id"`
-	IsLimitedRelease bool  `json:"is_limited_release"`
-	Price            int32 `json:"price"`
+	ID                  int64         `json:"id"`
+	IsLimitedRelease    bool          `json:"is_limited_release"`
+	Price               int32         `json:"price"`
+	RentalPrice         sql.NullInt32 `json:"rental_price"`
+	RentalDurationHours sql.NullInt32 `json:"rental_duration_hours"`
 }
 
 func (q *Queries) GetProgramForPurchase(ctx context.Context, id int64) (GetProgramForPurchaseRow, error) {
 	row := q.db.QueryRowContext(ctx, getProgramForPurchase, id)
 	var i GetProgramForPurchaseRow
-	err := row.Scan(&i.ID, &i.IsLimitedRelease, &i.Price)
+	err := row.Scan(
+		&i.ID,
+		&i.IsLimitedRelease,
+		&i.Price,
+		&i.RentalPrice,
+		&i.RentalDurationHours,
+	)
 	return i, err
 }
 
 const getPrograms = `-- name: GetPrograms :many
+WITH filtered AS (
+  SELECT
+    p.id,
+    p.title,
+    p.thumbnail_path,
+    p.view_count,
+    p.is_limited_release,
+    p.price,
+    p.created_at
+  FROM programs p
+  WHERE
+    p.is_public = true
+    AND ($1::text IS NULL OR p.title ILIKE '%' || $1::text || '%')
+    AND (
+      $2::bigint[] IS NULL
+      OR p.id IN (
+        SELECT pct2.program_id
+        FROM program_category_tags pct2
+        WHERE pct2.tag_id = ANY($2::bigint[])
+        GROUP BY pct2.program_id
+        -- tag_match=anyならいずれか1つ、allなら全てのタグを持つ番組
+        HAVING $3::boolean
+          OR COUNT(DISTINCT pct2.tag_id) = array_length($2::bigint[], 1)
+      )
+    )
+    AND (
+      $4::bigint[] IS NULL
+      OR EXISTS (
+        SELECT 1
+        FROM program_performers pp2
+        WHERE pp2.program_id = p.id AND pp2.performer_id = ANY($4::bigint[])
+      )
+    )
+    AND (
+      $5::boolean IS NULL
+      OR (p.is_limited_release AND p.price > 0) = $5::boolean
+    )
+    AND ($6::timestamptz IS NULL OR p.created_at >= $6::timestamptz)
+    AND ($7::timestamptz IS NULL OR p.created_at < $7::timestamptz)
+),
+keyed AS (
+  SELECT
+    f.id,
+    f.title,
+    f.thumbnail_path,
+    f.view_count,
+    f.is_limited_release,
+    f.price,
+    f.created_at,
+    (CASE $8::text
+      WHEN 'most_viewed' THEN f.view_count::bigint
+      WHEN 'most_liked' THEN (SELECT COUNT(*) FROM likes l WHERE l.program_id = f.id)::bigint
+      WHEN 'price_asc' THEN -f.price::bigint
+      ELSE f.price::bigint
+    END)::bigint AS sort_key
+  FROM filtered f
+),
+paged AS (
+  SELECT
+    k.id,
+    k.title,
+    k.thumbnail_path,
+    k.view_count,
+    k.is_limited_release,
+    k.price,
+    k.created_at,
+    k.sort_key
+  FROM keyed k
+  -- カーソル（前ページ末尾の sort_key, id）より後ろのみ
+  WHERE
+    $9::bigint IS NULL
+    OR (k.sort_key, k.id) < ($9::bigint, $10::bigint)
+  ORDER BY k.sort_key DESC, k.id DESC
+  LIMIT $11::int
+)
 SELECT
-  p.id AS program_id,
-  p.title,
-  p.thumbnail_path,
-  p.view_count,
-  p.is_limited_release,
-  p.price,
-  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
+  pg.id AS program_id,
+  pg.title,
+  pg.thumbnail_path,
+  pg.view_count,
+  pg.is_limited_release,
+  pg.price,
+  pg.created_at AS program_created_at,
+  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = pg.id), 0)::bigint AS like_count,
+  pg.sort_key,
   COALESCE(
     jsonb_agg(DISTINCT jsonb_build_object(
       'id', ct.id,
@@ -307,34 +521,33 @@ SELECT
     )) FILTER (WHERE ct.id IS NOT NULL),
     '[]'::jsonb
   ) AS category_tags
-FROM programs p
-LEFT JOIN program_category_tags pct ON p.id = pct.program_id
+FROM paged pg
+LEFT JOIN program_category_tags pct ON pg.id = pct.program_id
 LEFT JOIN category_tags ct ON pct.tag_id = ct.id
-WHERE
-  p.is_public = true
-  AND ($1::text IS NULL OR p.title ILIKE '%' || $1::text || '%')
-  AND (
-    $2::bigint[] IS NULL
-    OR p.id IN (
-      SELECT pct2.program_id
-      FROM program_category_tags pct2
-      WHERE pct2.tag_id = ANY($2::bigint[])
-      GROUP BY pct2.program_id
-      HAVING COUNT(DISTINCT pct2.tag_id) = array_length($2::bigint[], 1)
-    )
-  )
 GROUP BY
-  p.id,
-  p.title,
-  p.thumbnail_path,
-  p.view_count,
-  p.is_limited_release,
-  p.price
+  pg.id,
+  pg.title,
+  pg.thumbnail_path,
+  pg.view_count,
+  pg.is_limited_release,
+  pg.price,
+  pg.created_at,
+  pg.sort_key
+ORDER BY pg.sort_key DESC, pg.id DESC
 `
 
 type GetProgramsParams struct {
-	Title  sql.NullString `json:"title"`
-	TagIds []int64        `json:"tag_ids"`
+	Title         sql.NullString `json:"title"`
+	TagIds        []int64        `json:"tag_ids"`
+	TagMatchAny   bool           `json:"tag_match_any"`
+	PerformerIds  []int64        `json:"performer_ids"`
+	IsPaid        sql.NullBool   `json:"is_paid"`
+	CreatedFrom   sql.NullTime   `json:"created_from"`
+	CreatedTo     sql.NullTime   `json:"created_to"`
+	Sort          string         `json:"sort"`
+	CursorSortKey sql.NullInt64  `json:"cursor_sort_key"`
+	CursorID      sql.NullInt64  `json:"cursor_id"`
+	PageLimit     int32          `json:"page_limit"`
 }
 
 type GetProgramsRow struct {
@@ -344,13 +557,28 @@ type GetProgramsRow struct {
 	ViewCount        int32          `json:"view_count"`
 	IsLimitedRelease bool           `json:"is_limited_release"`
 	Price            int32          `json:"price"`
+	ProgramCreatedAt time.Time      `json:"program_created_at"`
 	LikeCount        int64          `json:"like_count"`
+	SortKey          int64          `json:"sort_key"`
 	CategoryTags     interface{}    `json:"category_tags"`
 }
 
-// 視聴回数はprogramsテーブルのview_countを参照
+// 新着順以外（再生数・いいね数・価格順）の一覧。並び順はsort_key DESC, id DESCに統一する（昇順のソートはsort_keyを負にする）
+// いいね順のソートキーは表示するいいね数と同じくlikesから数える（カーソルと表示の値を一致させる）
 func (q *Queries) GetPrograms(ctx context.Context, arg GetProgramsParams) ([]GetProgramsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPrograms, arg.Title, pq.Array(arg.TagIds))
+	rows, err := q.db.QueryContext(ctx, getPrograms,
+		arg.Title,
+		pq.Array(arg.TagIds),
+		arg.TagMatchAny,
+		pq.Array(arg.PerformerIds),
+		arg.IsPaid,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.Sort,
+		arg.CursorSortKey,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -365,6 +593,157 @@ func (q *Queries) GetPrograms(ctx context.Context, arg GetProgramsParams) ([]Get
 			&i.ViewCount,
 			&i.IsLimitedRelease,
 			&i.Price,
+			&i.ProgramCreatedAt,
+			&i.LikeCount,
+			&i.SortKey,
+			&i.CategoryTags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProgramsNewest = `-- name: GetProgramsNewest :many
+WITH paged AS (
+  SELECT
+    p.id,
+    p.title,
+    p.thumbnail_path,
+    p.view_count,
+    p.is_limited_release,
+    p.price,
+    p.created_at
+  FROM programs p
+  WHERE
+    p.is_public = true
+    AND ($1::text IS NULL OR p.title ILIKE '%' || $1::text || '%')
+    AND (
+      $2::bigint[] IS NULL
+      OR p.id IN (
+        SELECT pct2.program_id
+        FROM program_category_tags pct2
+        WHERE pct2.tag_id = ANY($2::bigint[])
+        GROUP BY pct2.program_id
+        -- tag_match=anyならいずれか1つ、allなら全てのタグを持つ番組
+        HAVING $3::boolean
+          OR COUNT(DISTINCT pct2.tag_id) = array_length($2::bigint[], 1)
+      )
+    )
+    AND (
+      $4::bigint[] IS NULL
+      OR EXISTS (
+        SELECT 1
+        FROM program_performers pp2
+        WHERE pp2.program_id = p.id AND pp2.performer_id = ANY($4::bigint[])
+      )
+    )
+    AND (
+      $5::boolean IS NULL
+      OR (p.is_limited_release AND p.price > 0) = $5::boolean
+    )
+    AND ($6::timestamptz IS NULL OR p.created_at >= $6::timestamptz)
+    AND ($7::timestamptz IS NULL OR p.created_at < $7::timestamptz)
+    -- カーソル（前ページ末尾の created_at, id）より後ろのみ
+    AND (
+      $8::timestamptz IS NULL
+      OR (p.created_at, p.id) < ($8::timestamptz, $9::bigint)
+    )
+  ORDER BY p.created_at DESC, p.id DESC
+  LIMIT $10::int
+)
+SELECT
+  pg.id AS program_id,
+  pg.title,
+  pg.thumbnail_path,
+  pg.view_count,
+  pg.is_limited_release,
+  pg.price,
+  pg.created_at AS program_created_at,
+  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = pg.id), 0)::bigint AS like_count,
+  COALESCE(
+    jsonb_agg(DISTINCT jsonb_build_object(
+      'id', ct.id,
+      'name', ct.name
+    )) FILTER (WHERE ct.id IS NOT NULL),
+    '[]'::jsonb
+  ) AS category_tags
+FROM paged pg
+LEFT JOIN program_category_tags pct ON pg.id = pct.program_id
+LEFT JOIN category_tags ct ON pct.tag_id = ct.id
+GROUP BY
+  pg.id,
+  pg.title,
+  pg.thumbnail_path,
+  pg.view_count,
+  pg.is_limited_release,
+  pg.price,
+  pg.created_at
+ORDER BY pg.created_at DESC, pg.id DESC
+`
+
+type GetProgramsNewestParams struct {
+	Title           sql.NullString `json:"title"`
+	TagIds          []int64        `json:"tag_ids"`
+	TagMatchAny     bool           `json:"tag_match_any"`
+	PerformerIds    []int64        `json:"performer_ids"`
+	IsPaid          sql.NullBool   `json:"is_paid"`
+	CreatedFrom     sql.NullTime   `json:"created_from"`
+	CreatedTo       sql.NullTime   `json:"created_to"`
+	CursorCreatedAt sql.NullTime   `json:"cursor_created_at"`
+	CursorID        sql.NullInt64  `json:"cursor_id"`
+	PageLimit       int32          `json:"page_limit"`
+}
+
+type GetProgramsNewestRow struct {
+	ProgramID        int64          `json:"program_id"`
+	Title            string         `json:"title"`
+	ThumbnailPath    sql.NullString `json:"thumbnail_path"`
+	ViewCount        int32          `json:"view_count"`
+	IsLimitedRelease bool           `json:"is_limited_release"`
+	Price            int32          `json:"price"`
+	ProgramCreatedAt time.Time      `json:"program_created_at"`
+	LikeCount        int64          `json:"like_count"`
+	CategoryTags     interface{}    `json:"category_tags"`
+}
+
+// 新着順（既定）の一覧。(created_at, id) のキーセットでprograms_public_created_at_id_idxを使い、
+// いいね数はページ内の番組だけ数える
+func (q *Queries) GetProgramsNewest(ctx context.Context, arg GetProgramsNewestParams) ([]GetProgramsNewestRow, error) {
+	rows, err := q.db.QueryContext(ctx, getProgramsNewest,
+		arg.Title,
+		pq.Array(arg.TagIds),
+		arg.TagMatchAny,
+		pq.Array(arg.PerformerIds),
+		arg.IsPaid,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetProgramsNewestRow
+	for rows.Next() {
+		var i GetProgramsNewestRow
+		if err := rows.Scan(
+			&i.ProgramID,
+			&i.Title,
+			&i.ThumbnailPath,
+			&i.ViewCount,
+			&i.IsLimitedRelease,
+			&i.Price,
+			&i.ProgramCreatedAt,
 			&i.LikeCount,
 			&i.CategoryTags,
 		); err != nil {
@@ -585,13 +964,94 @@ func (q *Queries) GetTopViewedPrograms(ctx context.Context, limit sql.NullInt32)
 	return items, nil
 }
 
-const incrementProgramViewCount = `-- name: IncrementProgramViewCount :exec
+const updateProgram = `-- name: UpdateProgram :one
 UPDATE programs
-SET view_count = view_count + 1
+SET title = $2,
+    video_path = $3,
+    thumbnail_path = $4,
+    description = $5,
+    is_limited_release = $6,
+    price = $7,
+    rental_price = $8,
+    rental_duration_hours = $9,
+    updated_at = now()
 WHERE id = $1
+RETURNING id, title, video_path, thumbnail_path, description, created_at, updated_at, view_count, is_limited_release, price, is_public, rental_price, rental_duration_hours
 `
 
-func (q *Queries) IncrementProgramViewCount(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, incrementProgramViewCount, id)
-	return err
+type UpdateProgramParams struct {
+	ID                  int64          `json:"id"`
+	Title               string         `json:"title"`
+	VideoPath           string         `json:"video_path"`
+	ThumbnailPath       sql.NullString `json:"thumbnail_path"`
+	Description         sql.NullString `json:"description"`
+	IsLimitedRelease    bool           `json:"is_limited_release"`
+	Price               int32          `json:"price"`
+	RentalPrice         sql.NullInt32  `json:"rental_price"`
+	RentalDurationHours sql.NullInt32  `json:"rental_duration_hours"`
+}
+
+func (q *Queries) UpdateProgram(ctx context.Context, arg UpdateProgramParams) (Program, error) {
+	row := q.db.QueryRowContext(ctx, updateProgram,
+		arg.ID,
+		arg.Title,
+		arg.VideoPath,
+		arg.ThumbnailPath,
+		arg.Description,
+		arg.IsLimitedRelease,
+		arg.Price,
+		arg.RentalPrice,
+		arg.RentalDurationHours,
+	)
+	var i Program
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.VideoPath,
+		&i.ThumbnailPath,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ViewCount,
+		&i.IsLimitedRelease,
+		&i.Price,
+		&i.IsPublic,
+		&i.RentalPrice,
+		&i.RentalDurationHours,
+	)
+	return i, err
+}
+
+const updateProgramVisibility = `-- name: UpdateProgramVisibility :one
+UPDATE programs
+SET is_public = $2,
+    updated_at = now()
+WHERE id = $1
+RETURNING id, title, video_path, thumbnail_path, description, created_at, updated_at, view_count, is_limited_release, price, is_public, rental_price, rental_duration_hours
+`
+
+type UpdateProgramVisibilityParams struct {
+	ID       int64 `json:"id"`
+	IsPublic bool  `json:"is_public"`
+}
+
+func (q *Queries) UpdateProgramVisibility(ctx context.Context, arg UpdateProgramVisibilityParams) (Program, error) {
+	row := q.db.QueryRowContext(ctx, updateProgramVisibility, arg.ID, arg.IsPublic)
+	var i Program
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.VideoPath,
+		&i.ThumbnailPath,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ViewCount,
+		&i.IsLimitedRelease,
+		&i.Price,
+		&i.IsPublic,
+		&i.RentalPrice,
+		&i.RentalDurationHours,
+	)
+	return i, err
 }
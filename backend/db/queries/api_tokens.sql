@@ -0,0 +1,31 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListAPITokensByUserID :many
+SELECT *
+FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC;
+
+-- name: GetActiveAPITokenByHash :one
+SELECT id, user_id, scopes
+FROM api_tokens
+WHERE token_hash = $1
+  AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > now());
+
+-- name: RevokeAPIToken :execrows
+UPDATE api_tokens
+SET revoked_at = now()
+WHERE id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL;
+
+-- name: TouchAPIToken :exec
+-- last_used_atの更新は1分に1回までに抑える
+UPDATE api_tokens
+SET last_used_at = now()
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
@@ -26,3 +26,12 @@ INSERT INTO "account" (
   $1, $2, 'credential', $3, $4, now(), now()
 )
 RETURNING id, "accountId", "providerId", "userId", password, "createdAt", "updatedAt";
+
+-- name: GetSessionByToken :one
+SELECT "userId", "expiresAt"
+FROM "session"
+WHERE token = $1;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM "session"
+WHERE "expiresAt" < $1;
@@ -5,3 +5,32 @@ INSERT INTO category_tags (
   $1
 )
 RETURNING id, name, created_at, updated_at;
+
+-- name: ListCategoryTagsWithProgramCount :many
+SELECT
+  ct.id,
+  ct.name,
+  (
+    SELECT COUNT(*)
+    FROM program_category_tags pct
+    JOIN programs p ON p.id = pct.program_id
+    WHERE pct.tag_id = ct.id AND p.is_public = true
+  )::bigint AS program_count
+FROM category_tags ct
+ORDER BY ct.name, ct.id;
+
+-- name: GetCategoryTagByID :one
+SELECT id, name, created_at, updated_at
+FROM category_tags
+WHERE id = $1;
+
+-- name: UpdateCategoryTag :one
+UPDATE category_tags
+SET name = $2,
+    updated_at = now()
+WHERE id = $1
+RETURNING id, name, created_at, updated_at;
+
+-- name: DeleteCategoryTag :execrows
+DELETE FROM category_tags
+WHERE id = $1;
@@ -4,7 +4,12 @@ SELECT c.id, c.program_id, c.user_id, u.name AS user_name, c.content, c.created_
 FROM comments c
 LEFT JOIN "user" u ON c.user_id = u.id
 WHERE c.program_id = $1
-ORDER BY c.created_at DESC;
+  AND (
+    sqlc.narg('cursor_created_at')::timestamptz IS NULL
+    OR (c.created_at, c.id) < (sqlc.narg('cursor_created_at')::timestamptz, sqlc.narg('cursor_id')::bigint)
+  )
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg('page_limit')::int;
 
 
 -- name: CreateComment :one
@@ -25,3 +30,7 @@ LEFT JOIN "user" u ON c.user_id = u.id
 WHERE c.id = (
   SELECT c2.id FROM comments c2 WHERE c2.program_id = $1 AND c2.user_id = $2 AND c2.content = $3 ORDER BY c2.created_at DESC LIMIT 1
 );
+
+-- name: DeleteComment :execrows
+DELETE FROM comments
+WHERE id = $1;
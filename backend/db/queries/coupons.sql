@@ -0,0 +1,125 @@
+-- Coupons (discount codes applied at checkout)
+
+-- name: CreateCoupon :one
+INSERT INTO coupons (
+  code,
+  discount_type,
+  discount_value,
+  starts_at,
+  ends_at,
+  max_redemptions,
+  max_redemptions_per_user,
+  is_active
+) VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8
+)
+RETURNING *;
+
+-- 利用済みの決済と金額が食い違わないよう、コードと割引内容は変更できない
+-- name: UpdateCoupon :one
+UPDATE coupons
+SET starts_at = $2,
+    ends_at = $3,
+    max_redemptions = $4,
+    max_redemptions_per_user = $5,
+    is_active = $6,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetCoupon :one
+SELECT *
+FROM coupons
+WHERE id = $1;
+
+-- 利用回数の上限を超えないよう、決済を作るトランザクション内でロックする
+-- name: GetCouponByCodeForUpdate :one
+SELECT *
+FROM coupons
+WHERE code = $1
+FOR UPDATE;
+
+-- name: ListCoupons :many
+SELECT *
+FROM coupons
+ORDER BY id;
+
+-- name: DeleteCouponPrograms :exec
+DELETE FROM coupon_programs
+WHERE coupon_id = $1;
+
+-- name: CreateCouponProgram :exec
+INSERT INTO coupon_programs (
+  coupon_id,
+  program_id
+) VALUES (
+  $1,
+  $2
+)
+ON CONFLICT (coupon_id, program_id) DO NOTHING;
+
+-- name: ListCouponProgramIDs :many
+SELECT program_id
+FROM coupon_programs
+WHERE coupon_id = $1
+ORDER BY program_id;
+
+-- name: DeleteCouponTags :exec
+DELETE FROM coupon_tags
+WHERE coupon_id = $1;
+
+-- name: CreateCouponTag :exec
+INSERT INTO coupon_tags (
+  coupon_id,
+  tag_id
+) VALUES (
+  $1,
+  $2
+)
+ON CONFLICT (coupon_id, tag_id) DO NOTHING;
+
+-- name: ListCouponTagIDs :many
+SELECT tag_id
+FROM coupon_tags
+WHERE coupon_id = $1
+ORDER BY tag_id;
+
+-- 対象の番組・タグが無い割引は全ての番組に使える。商品（program_idがNULL）には対象を絞っていない割引だけ使える
+-- name: IsCouponApplicable :one
+SELECT (
+  NOT EXISTS (SELECT 1 FROM coupon_programs cp WHERE cp.coupon_id = sqlc.arg(coupon_id))
+  AND NOT EXISTS (SELECT 1 FROM coupon_tags ct WHERE ct.coupon_id = sqlc.arg(coupon_id))
+) OR EXISTS (
+  SELECT 1 FROM coupon_programs cp
+  WHERE cp.coupon_id = sqlc.arg(coupon_id)
+    AND cp.program_id = sqlc.narg(program_id)
+) OR EXISTS (
+  SELECT 1 FROM coupon_tags ct
+  JOIN program_category_tags pct ON pct.tag_id = ct.tag_id
+  WHERE ct.coupon_id = sqlc.arg(coupon_id)
+    AND pct.program_id = sqlc.narg(program_id)
+) AS applicable;
+
+-- 支払いが完了した決済（返金済みを含む）と、支払いページの有効期限内の支払い待ちの決済を利用回数に数える。
+-- 放棄された決済で上限を使い切らないよう、期限を過ぎた支払い待ちは照合でEXPIREDになる前でも数えない
+-- （有効期限が分からない決済はpending_created_after以降に作られたものを有効期限内とみなす）
+-- name: CountCouponRedemptions :one
+SELECT
+  COUNT(*)::int AS total,
+  COUNT(*) FILTER (WHERE user_id = sqlc.arg(user_id))::int AS by_user
+FROM payments
+WHERE coupon_id = sqlc.arg(coupon_id)
+  AND (
+    status IN ('COMPLETED', 'REFUNDED')
+    OR (
+      status IN ('CREATED', 'AWAITING')
+      AND COALESCE(checkout_expires_at > sqlc.arg(now)::timestamptz, created_at > sqlc.arg(pending_created_after)::timestamptz)
+    )
+  );
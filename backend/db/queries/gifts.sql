@@ -0,0 +1,73 @@
+-- Gifts (programs purchased for another user and redeemed with a code)
+
+-- name: CreateGift :one
+INSERT INTO gifts (
+  code,
+  payment_id,
+  program_id,
+  purchaser_id
+) VALUES (
+  $1,
+  $2,
+  $3,
+  $4
+)
+RETURNING *;
+
+-- name: GetGiftByPaymentID :one
+SELECT *
+FROM gifts
+WHERE payment_id = $1;
+
+-- 同じコードを同時に受け取れないよう、受け取りのトランザクション内でロックする
+-- name: GetGiftByCodeForUpdate :one
+SELECT *
+FROM gifts
+WHERE code = $1
+FOR UPDATE;
+
+-- replaced_*には受け取りで買い切りに置き換えたレンタル（期限内のもの）を記録する
+-- name: RedeemGift :exec
+UPDATE gifts
+SET status = 'REDEEMED',
+    recipient_id = $2,
+    redeemed_at = now(),
+    replaced_payment_id = $3,
+    replaced_expires_at = $4,
+    updated_at = now()
+WHERE id = $1;
+
+-- 返金された贈り物は受け取れなくする（受け取り済みでも閲覧権限は決済と一緒に取り消す）
+-- name: RevokeGiftByPayment :exec
+UPDATE gifts
+SET status = 'REVOKED',
+    updated_at = now()
+WHERE payment_id = $1
+  AND status <> 'REVOKED';
+
+-- name: ListSentGifts :many
+SELECT
+  g.id,
+  g.code,
+  g.program_id,
+  p.title AS program_title,
+  g.status,
+  g.redeemed_at,
+  g.created_at
+FROM gifts g
+JOIN programs p ON p.id = g.program_id
+WHERE g.purchaser_id = $1
+ORDER BY g.created_at DESC, g.id DESC;
+
+-- name: ListReceivedGifts :many
+SELECT
+  g.id,
+  g.program_id,
+  p.title AS program_title,
+  g.status,
+  g.redeemed_at,
+  g.created_at
+FROM gifts g
+JOIN programs p ON p.id = g.program_id
+WHERE g.recipient_id = $1
+ORDER BY g.redeemed_at DESC, g.id DESC;
@@ -0,0 +1,95 @@
+-- 同じdedupe_keyの未完了ジョブがある場合は登録せず0行を返す
+-- name: EnqueueJob :one
+INSERT INTO jobs (
+  kind,
+  payload,
+  max_attempts,
+  run_at,
+  dedupe_key
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (dedupe_key) WHERE dedupe_key IS NOT NULL AND status IN ('queued', 'running')
+DO NOTHING
+RETURNING id;
+
+-- name: ClaimJobs :many
+UPDATE jobs
+SET status = 'running',
+    attempts = attempts + 1,
+    locked_by = sqlc.arg(worker_id)::text,
+    locked_at = now(),
+    updated_at = now()
+WHERE id IN (
+  SELECT j.id
+  FROM jobs j
+  WHERE j.status = 'queued'
+    AND j.run_at <= now()
+    AND j.kind = ANY(sqlc.arg(kinds)::text[])
+  ORDER BY j.run_at, j.id
+  LIMIT sqlc.arg(batch_size)::int
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, kind, payload, attempts, max_attempts;
+
+-- name: CompleteJob :exec
+UPDATE jobs
+SET status = 'succeeded',
+    locked_by = NULL,
+    locked_at = NULL,
+    finished_at = now(),
+    updated_at = now()
+WHERE id = $1;
+
+-- name: RetryJob :exec
+UPDATE jobs
+SET status = 'queued',
+    run_at = $2,
+    last_error = sqlc.arg(last_error)::text,
+    locked_by = NULL,
+    locked_at = NULL,
+    updated_at = now()
+WHERE id = $1;
+
+-- name: FailJob :exec
+UPDATE jobs
+SET status = 'failed',
+    last_error = sqlc.arg(last_error)::text,
+    locked_by = NULL,
+    locked_at = NULL,
+    finished_at = now(),
+    updated_at = now()
+WHERE id = $1;
+
+-- プロセスが落ちて実行中のまま残ったジョブを実行待ちに戻す。
+-- 試行回数を使い切ったジョブ（ワーカーごと落とすジョブ等）は再実行せずfailedにする
+-- name: RequeueStaleJobs :execrows
+UPDATE jobs
+SET status = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'queued' END,
+    last_error = CASE WHEN attempts >= max_attempts THEN 'worker lost while running' ELSE last_error END,
+    finished_at = CASE WHEN attempts >= max_attempts THEN now() ELSE finished_at END,
+    locked_by = NULL,
+    locked_at = NULL,
+    updated_at = now()
+WHERE status = 'running'
+  AND locked_at < sqlc.arg(locked_before)::timestamptz;
+
+-- name: DeleteFinishedJobs :execrows
+DELETE FROM jobs
+WHERE status IN ('succeeded', 'failed')
+  AND finished_at < sqlc.arg(finished_before)::timestamptz;
+
+-- name: EnsureJobSchedule :exec
+INSERT INTO job_schedules (name, next_run_at)
+VALUES ($1, $2)
+ON CONFLICT (name) DO NOTHING;
+
+-- 実行時刻を過ぎていれば次回時刻に進める。進めたプロセスだけが行を受け取りジョブを登録する
+-- name: ClaimDueJobSchedule :one
+UPDATE job_schedules
+SET next_run_at = sqlc.arg(next_run_at),
+    last_enqueued_at = now(),
+    updated_at = now()
+WHERE name = sqlc.arg(name)
+  AND next_run_at <= now()
+RETURNING name;
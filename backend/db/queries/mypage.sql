@@ -6,6 +6,8 @@ SELECT
   p.view_count,
   p.is_limited_release,
   p.price,
+  wh.last_watched_at AS cursor_at,
+  wh.id AS cursor_id,
   COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
   COALESCE(
     jsonb_agg(DISTINCT jsonb_build_object(
@@ -20,6 +22,10 @@ JOIN programs p ON p.id = wh.program_id
 LEFT JOIN program_category_tags pct ON p.id = pct.program_id
 LEFT JOIN category_tags ct ON pct.tag_id = ct.id
 WHERE wh.user_id = $1 AND wh.is_completed = FALSE AND p.is_public = true
+  AND (
+    sqlc.narg('cursor_at')::timestamptz IS NULL
+    OR (wh.last_watched_at, wh.id) < (sqlc.narg('cursor_at')::timestamptz, sqlc.narg('cursor_id')::bigint)
+  )
 GROUP BY
   p.id,
   p.title,
@@ -27,10 +33,10 @@ GROUP BY
   p.view_count,
   p.is_limited_release,
   p.price,
-  wh.last_watched_at
-ORDER BY wh.last_watched_at DESC
-LIMIT COALESCE(sqlc.narg('limit')::int, 50)
-OFFSET COALESCE(sqlc.narg('offset')::int, 0);
+  wh.last_watched_at,
+  wh.id
+ORDER BY wh.last_watched_at DESC, wh.id DESC
+LIMIT sqlc.arg('page_limit')::int;
 
 -- name: ListLikedProgramsByUser :many
 SELECT
@@ -40,6 +46,8 @@ SELECT
   p.view_count,
   p.is_limited_release,
   p.price,
+  lk.created_at AS cursor_at,
+  lk.id AS cursor_id,
   COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
   COALESCE(
     jsonb_agg(DISTINCT jsonb_build_object(
@@ -54,6 +62,10 @@ JOIN programs p ON p.id = lk.program_id
 LEFT JOIN program_category_tags pct ON p.id = pct.program_id
 LEFT JOIN category_tags ct ON pct.tag_id = ct.id
 WHERE lk.user_id = $1 AND p.is_public = true
+  AND (
+    sqlc.narg('cursor_at')::timestamptz IS NULL
+    OR (lk.created_at, lk.id) < (sqlc.narg('cursor_at')::timestamptz, sqlc.narg('cursor_id')::bigint)
+  )
 GROUP BY
   p.id,
   p.title,
@@ -61,10 +73,10 @@ GROUP BY
   p.view_count,
   p.is_limited_release,
   p.price,
-  lk.created_at
-ORDER BY lk.created_at DESC
-LIMIT COALESCE(sqlc.narg('limit')::int, 50)
-OFFSET COALESCE(sqlc.narg('offset')::int, 0);
+  lk.created_at,
+  lk.id
+ORDER BY lk.created_at DESC, lk.id DESC
+LIMIT sqlc.arg('page_limit')::int;
 
 -- name: ListPurchasedProgramsByUser :many
 SELECT
@@ -74,6 +86,10 @@ SELECT
   p.view_count,
   p.is_limited_release,
   p.price,
+  ppu.purchase_type,
+  ppu.expires_at,
+  ppu.created_at AS cursor_at,
+  ppu.id AS cursor_id,
   COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
   COALESCE(
     jsonb_agg(DISTINCT jsonb_build_object(
@@ -87,6 +103,12 @@ JOIN programs p ON p.id = ppu.program_id
 LEFT JOIN program_category_tags pct ON p.id = pct.program_id
 LEFT JOIN category_tags ct ON pct.tag_id = ct.id
 WHERE ppu.user_id = $1 AND p.is_public = true
+  -- 期限切れのレンタルは含めない
+  AND (ppu.expires_at IS NULL OR ppu.expires_at > now())
+  AND (
+    sqlc.narg('cursor_at')::timestamptz IS NULL
+    OR (ppu.created_at, ppu.id) < (sqlc.narg('cursor_at')::timestamptz, sqlc.narg('cursor_id')::bigint)
+  )
 GROUP BY
   p.id,
   p.title,
@@ -94,7 +116,9 @@ GROUP BY
   p.view_count,
   p.is_limited_release,
   p.price,
-  ppu.created_at
-ORDER BY ppu.created_at DESC
-LIMIT COALESCE(sqlc.narg('limit')::int, 50)
-OFFSET COALESCE(sqlc.narg('offset')::int, 0);
+  ppu.purchase_type,
+  ppu.expires_at,
+  ppu.created_at,
+  ppu.id
+ORDER BY ppu.created_at DESC, ppu.id DESC
+LIMIT sqlc.arg('page_limit')::int;
@@ -0,0 +1,73 @@
+-- Payment refunds (admin refunds of completed payments)
+
+-- name: CreatePaymentRefund :one
+INSERT INTO payment_refunds (
+  payment_id,
+  merchant_refund_id,
+  amount_yen,
+  reason,
+  requested_by
+) VALUES (
+  sqlc.arg(payment_id),
+  sqlc.arg(merchant_refund_id),
+  sqlc.arg(amount_yen),
+  sqlc.arg(reason),
+  sqlc.narg(requested_by)
+)
+RETURNING *;
+
+-- name: GetPaymentRefundByMerchantRefundIDForUpdate :one
+SELECT *
+FROM payment_refunds
+WHERE merchant_refund_id = $1
+FOR UPDATE;
+
+-- 返金の照合で呼び出す決済サービスを決める
+-- name: GetPaymentRefundProvider :one
+SELECT p.provider
+FROM payment_refunds r
+JOIN payments p ON p.id = r.payment_id
+WHERE r.merchant_refund_id = $1;
+
+-- name: ListPaymentRefundsByPayment :many
+SELECT *
+FROM payment_refunds
+WHERE payment_id = $1
+ORDER BY id;
+
+-- 返金可能額の計算用（処理中と完了済みの合計）
+-- name: SumActivePaymentRefunds :one
+SELECT COALESCE(SUM(amount_yen), 0)::int AS amount_yen
+FROM payment_refunds
+WHERE payment_id = $1
+  AND status IN ('CREATED', 'REFUNDED');
+
+-- name: UpdatePaymentRefundStatus :exec
+UPDATE payment_refunds
+SET status = sqlc.arg(status),
+    error = sqlc.narg(error),
+    refunded_at = CASE WHEN sqlc.arg(status)::text = 'REFUNDED' THEN COALESCE(refunded_at, now()) ELSE refunded_at END,
+    updated_at = now()
+WHERE id = sqlc.arg(id);
+
+-- Transaction通知のREFUNDEDで全額返金済みになっている場合に二重計上しないよう上限を設ける
+-- name: AddPaymentRefundedAmount :one
+UPDATE payments
+SET refunded_amount_yen = LEAST(refunded_amount_yen + sqlc.arg(amount_yen), amount_yen),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING refunded_amount_yen;
+
+-- 結果が確定しないまま残っている返金（updated_atを照合の最終時刻として使う）
+-- name: ListPaymentRefundsToReconcile :many
+SELECT merchant_refund_id
+FROM payment_refunds
+WHERE status = 'CREATED'
+  AND updated_at < sqlc.arg(updated_before)::timestamptz
+ORDER BY id
+LIMIT sqlc.arg(batch_size)::int;
+
+-- name: MarkPaymentRefundReconciled :exec
+UPDATE payment_refunds
+SET updated_at = now()
+WHERE merchant_refund_id = $1;
@@ -0,0 +1,192 @@
+-- Payments (user purchases programs/products via a payment provider such as PayPay)
+
+-- name: CreatePayment :one
+INSERT INTO payments (
+  user_id,
+  merchant_payment_id,
+  amount_yen,
+  status,
+  program_id,
+  product_id,
+  purchase_type,
+  rental_duration_hours,
+  provider,
+  coupon_id,
+  discount_yen,
+  is_gift
+) VALUES (
+  $1,
+  $2,
+  $3,
+  'CREATED',
+  $4,
+  $5,
+  $6,
+  $7,
+  $8,
+  $9,
+  $10,
+  $11
+)
+RETURNING *;
+
+-- name: SetPaymentCheckout :exec
+UPDATE payments
+SET provider_checkout_id = $3,
+    checkout_expires_at = $4,
+    updated_at = now()
+WHERE user_id = $1
+  AND merchant_payment_id = $2;
+
+-- name: GetPaymentForUpdate :one
+SELECT *
+FROM payments
+WHERE user_id = $1
+  AND merchant_payment_id = $2
+FOR UPDATE;
+
+-- Webhook用クエリ (merchant_payment_idのみで検索、userIdはWebhookに含まれないため)
+
+-- name: GetPaymentByMerchantPaymentIDForUpdate :one
+SELECT *
+FROM payments
+WHERE merchant_payment_id = $1
+FOR UPDATE;
+
+-- name: GetPaymentByMerchantPaymentID :one
+SELECT *
+FROM payments
+WHERE merchant_payment_id = $1;
+
+-- name: GetPaymentByIDForUpdate :one
+SELECT *
+FROM payments
+WHERE id = $1
+FOR UPDATE;
+
+-- ステータス遷移（状態機械からのみ呼ぶ）。遷移元ステータスが一致する場合のみ更新する
+-- COMPLETEDへの遷移で番組・商品が紐づいていればcredited_atを記録し、REFUNDEDへの遷移では全額を返金済みにする
+-- name: TransitionPaymentStatus :execrows
+UPDATE payments
+SET status = sqlc.arg(to_status)::text,
+    provider_payment_id = COALESCE(sqlc.narg(provider_payment_id), provider_payment_id),
+    credited_at = CASE
+      WHEN sqlc.arg(to_status)::text = 'COMPLETED' AND (program_id IS NOT NULL OR product_id IS NOT NULL) THEN COALESCE(credited_at, now())
+      ELSE credited_at
+    END,
+    refunded_amount_yen = CASE
+      WHEN sqlc.arg(to_status)::text = 'REFUNDED' THEN GREATEST(refunded_amount_yen, amount_yen)
+      ELSE refunded_amount_yen
+    END,
+    updated_at = now()
+WHERE merchant_payment_id = sqlc.arg(merchant_payment_id)
+  AND status = sqlc.arg(from_status)::text;
+
+-- name: InsertPaymentEvent :exec
+INSERT INTO payment_events (
+  payment_id,
+  from_status,
+  to_status,
+  source,
+  provider_payment_id,
+  detail
+) VALUES (
+  sqlc.arg(payment_id),
+  sqlc.narg(from_status),
+  sqlc.arg(to_status),
+  sqlc.arg(source),
+  sqlc.narg(provider_payment_id),
+  sqlc.arg(detail)
+);
+
+-- name: ListPaymentEvents :many
+SELECT *
+FROM payment_events
+WHERE payment_id = $1
+ORDER BY id;
+
+-- 照合ジョブ用クエリ
+
+-- 作成から一定時間経っても支払い待ちのままの決済を古い順に返す
+-- name: ListPaymentsToReconcile :many
+SELECT merchant_payment_id
+FROM payments
+WHERE status IN ('CREATED', 'AWAITING')
+  AND created_at < sqlc.arg(created_before)::timestamptz
+  AND (last_reconciled_at IS NULL OR last_reconciled_at < sqlc.arg(reconciled_before)::timestamptz)
+ORDER BY created_at
+LIMIT sqlc.arg(batch_size)::int;
+
+-- name: MarkPaymentReconciled :exec
+UPDATE payments
+SET last_reconciled_at = now(),
+    reconcile_attempts = reconcile_attempts + 1
+WHERE merchant_payment_id = $1;
+
+-- 支払い完了時に領収書番号を振る（カウンターの行ロックで採番を直列化する）
+-- name: AssignPaymentReceiptNumber :exec
+WITH next AS (
+  UPDATE payment_receipt_counter
+  SET last_number = last_number + 1
+  WHERE id = true
+  RETURNING last_number
+)
+UPDATE payments
+SET receipt_number = (SELECT last_number FROM next)
+WHERE payments.id = $1
+  AND payments.receipt_number IS NULL;
+
+-- 購入履歴（未完了・失敗・返金済みも含む）
+-- name: ListPaymentsByUser :many
+SELECT
+  pm.id,
+  pm.merchant_payment_id,
+  pm.status,
+  pm.amount_yen,
+  pm.discount_yen,
+  pm.refunded_amount_yen,
+  pm.purchase_type,
+  pm.is_gift,
+  pm.provider,
+  pm.provider_payment_id,
+  pm.receipt_number,
+  pm.program_id,
+  pr.title AS program_title,
+  pm.product_id,
+  pd.name AS product_name,
+  pm.created_at,
+  pm.updated_at,
+  pm.credited_at
+FROM payments pm
+LEFT JOIN programs pr ON pr.id = pm.program_id
+LEFT JOIN products pd ON pd.id = pm.product_id
+WHERE pm.user_id = $1
+  AND (
+    sqlc.narg('cursor_at')::timestamptz IS NULL
+    OR (pm.created_at, pm.id) < (sqlc.narg('cursor_at')::timestamptz, sqlc.narg('cursor_id')::bigint)
+  )
+ORDER BY pm.created_at DESC, pm.id DESC
+LIMIT sqlc.arg('page_limit')::int;
+
+-- name: GetPaymentReceipt :one
+SELECT
+  pm.merchant_payment_id,
+  pm.status,
+  pm.amount_yen,
+  pm.discount_yen,
+  pm.refunded_amount_yen,
+  pm.purchase_type,
+  pm.is_gift,
+  pm.provider,
+  pm.provider_payment_id,
+  pm.receipt_number,
+  pm.credited_at,
+  pr.title AS program_title,
+  pd.name AS product_name,
+  u.name AS user_name
+FROM payments pm
+JOIN "user" u ON u.id = pm.user_id
+LEFT JOIN programs pr ON pr.id = pm.program_id
+LEFT JOIN products pd ON pd.id = pm.product_id
+WHERE pm.user_id = $1
+  AND pm.merchant_payment_id = $2;
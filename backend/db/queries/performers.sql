@@ -9,3 +9,55 @@ INSERT INTO performers (
   $1, $2, $3, $4, $5
 )
 RETURNING id, first_name, last_name, first_name_kana, last_name_kana, image_path, created_at, updated_at;
+
+-- name: ListPerformers :many
+-- 五十音順（姓・名のカナ）。name/kana_hiragana/kana_katakanaは正規化した検索語（ひらがな・カタカナ表記）で部分一致
+SELECT
+  pe.id,
+  pe.first_name,
+  pe.last_name,
+  pe.first_name_kana,
+  pe.last_name_kana,
+  pe.image_path,
+  (pe.last_name_kana || pe.first_name_kana)::text AS sort_kana,
+  (
+    SELECT COUNT(*)
+    FROM program_performers pp
+    JOIN programs p ON p.id = pp.program_id
+    WHERE pp.performer_id = pe.id AND p.is_public = true
+  )::bigint AS program_count
+FROM performers pe
+WHERE
+  (
+    sqlc.narg('name')::text IS NULL
+    OR (pe.last_name || pe.first_name) ILIKE '%' || sqlc.narg('name')::text || '%'
+    OR (pe.last_name || pe.first_name) ILIKE '%' || sqlc.narg('kana_katakana')::text || '%'
+    OR (pe.last_name_kana || pe.first_name_kana) ILIKE '%' || sqlc.narg('kana_hiragana')::text || '%'
+    OR (pe.last_name_kana || pe.first_name_kana) ILIKE '%' || sqlc.narg('kana_katakana')::text || '%'
+  )
+  -- カーソル（前ページ末尾の sort_kana, id）より後ろのみ
+  AND (
+    sqlc.narg('cursor_kana')::text IS NULL
+    OR (pe.last_name_kana || pe.first_name_kana, pe.id) > (sqlc.narg('cursor_kana')::text, sqlc.narg('cursor_id')::bigint)
+  )
+ORDER BY pe.last_name_kana || pe.first_name_kana, pe.id
+LIMIT sqlc.arg('page_limit')::int;
+
+-- name: GetPerformerByID :one
+SELECT
+  pe.id,
+  pe.first_name,
+  pe.last_name,
+  pe.first_name_kana,
+  pe.last_name_kana,
+  pe.image_path,
+  pe.created_at,
+  pe.updated_at,
+  (
+    SELECT COUNT(*)
+    FROM program_performers pp
+    JOIN programs p ON p.id = pp.program_id
+    WHERE pp.performer_id = pe.id AND p.is_public = true
+  )::bigint AS program_count
+FROM performers pe
+WHERE pe.id = $1;
@@ -1,14 +1,86 @@
+-- 番組単体の購入（レンタルは期限内のみ）に加え、有効な商品（bundle・performer_pack・pass）の閲覧権限も含めて判定する
 -- name: IsUserPermittedForProgram :one
-SELECT EXISTS (
-  SELECT 1 FROM permitted_program_users
-  WHERE user_id = $1 AND program_id = $2
-) AS is_permitted;
+SELECT (
+  EXISTS (
+    SELECT 1 FROM permitted_program_users ppu
+    WHERE ppu.user_id = $1 AND ppu.program_id = $2
+      AND (ppu.expires_at IS NULL OR ppu.expires_at > now())
+  )
+  OR EXISTS (
+    SELECT 1
+    FROM user_product_grants g
+    JOIN products p ON p.id = g.product_id
+    WHERE g.user_id = $1
+      AND g.revoked_at IS NULL
+      AND (g.expires_at IS NULL OR g.expires_at > now())
+      AND (
+        (p.kind = 'bundle' AND EXISTS (
+          SELECT 1 FROM product_programs pp WHERE pp.product_id = p.id AND pp.program_id = $2
+        ))
+        OR (p.kind = 'performer_pack' AND EXISTS (
+          SELECT 1 FROM program_performers pf
+          JOIN programs pr ON pr.id = pf.program_id
+          WHERE pf.performer_id = p.performer_id AND pf.program_id = $2 AND pr.is_limited_release = true
+        ))
+        OR (p.kind = 'pass' AND EXISTS (
+          SELECT 1 FROM programs pr WHERE pr.id = $2 AND pr.is_limited_release = true
+        ))
+      )
+  )
+)::boolean AS is_permitted;
 
+-- レンタル中（期限切れを含む）の番組を買い切りにした場合は期限を外す
 -- name: AddPermittedProgramUser :exec
-INSERT INTO permitted_program_users (user_id, program_id)
-VALUES ($1, $2)
-ON CONFLICT (user_id, program_id) DO NOTHING;
+INSERT INTO permitted_program_users (user_id, program_id, payment_id)
+VALUES (sqlc.arg(user_id), sqlc.arg(program_id), sqlc.narg(payment_id))
+ON CONFLICT (user_id, program_id) DO UPDATE SET
+  purchase_type = 'purchase',
+  expires_at = NULL,
+  payment_id = EXCLUDED.payment_id;
 
--- name: RemovePermittedProgramUser :exec
+-- 期限はレンタルの支払い完了時点から数える。買い切り済みの番組は変更しない
+-- name: AddProgramRental :exec
+INSERT INTO permitted_program_users (user_id, program_id, purchase_type, expires_at, payment_id)
+VALUES (
+  sqlc.arg(user_id),
+  sqlc.arg(program_id),
+  'rental',
+  now() + make_interval(hours => sqlc.arg(duration_hours)::int),
+  sqlc.narg(payment_id)
+)
+ON CONFLICT (user_id, program_id) DO UPDATE SET
+  expires_at = EXCLUDED.expires_at,
+  payment_id = EXCLUDED.payment_id,
+  created_at = now()
+WHERE permitted_program_users.purchase_type = 'rental';
+
+-- 贈り物の受け取りで買い切りに置き換えたレンタルを、贈り物の返金時に元の期限で戻す
+-- （元のレンタルの決済が返金済みなら戻さない）
+-- name: RestoreRentalReplacedByGift :exec
+UPDATE permitted_program_users ppu
+SET purchase_type = 'rental',
+    expires_at = g.replaced_expires_at,
+    payment_id = g.replaced_payment_id
+FROM gifts g
+WHERE g.payment_id = sqlc.arg(payment_id)
+  AND g.replaced_expires_at IS NOT NULL
+  AND ppu.user_id = g.recipient_id
+  AND ppu.program_id = g.program_id
+  AND ppu.payment_id = g.payment_id
+  AND (
+    g.replaced_payment_id IS NULL
+    OR EXISTS (SELECT 1 FROM payments p WHERE p.id = g.replaced_payment_id AND p.status = 'COMPLETED')
+  );
+
+-- 返金されたtopupが現在の閲覧権限を付与している場合だけ取り消す
+-- （レンタル後に買い切りにした場合や、レンタルを延長した後に前のレンタルを返金した場合は残す）
+-- name: RemovePermittedProgramUserByPayment :exec
 DELETE FROM permitted_program_users
-WHERE user_id = $1 AND program_id = $2;
+WHERE payment_id = $1;
+
+-- 期限内の番組単体の閲覧権限
+-- name: GetActivePermittedProgramUser :one
+SELECT *
+FROM permitted_program_users
+WHERE user_id = $1 AND program_id = $2
+  AND (expires_at IS NULL OR expires_at > now());
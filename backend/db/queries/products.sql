@@ -0,0 +1,111 @@
+-- Products (bundles, performer packs and passes)
+
+-- name: CreateProduct :one
+INSERT INTO products (
+  kind,
+  name,
+  description,
+  price,
+  performer_id,
+  duration_days,
+  is_active
+) VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7
+)
+RETURNING *;
+
+-- name: UpdateProduct :one
+UPDATE products
+SET name = $2,
+    description = $3,
+    price = $4,
+    performer_id = $5,
+    duration_days = $6,
+    is_active = $7,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetProduct :one
+SELECT *
+FROM products
+WHERE id = $1;
+
+-- name: ListProducts :many
+SELECT *
+FROM products
+WHERE (NOT sqlc.arg(active_only)::boolean OR is_active = true)
+ORDER BY id;
+
+-- name: DeleteProductPrograms :exec
+DELETE FROM product_programs
+WHERE product_id = $1;
+
+-- name: CreateProductProgram :exec
+INSERT INTO product_programs (
+  product_id,
+  program_id
+) VALUES (
+  $1,
+  $2
+)
+ON CONFLICT (product_id, program_id) DO NOTHING;
+
+-- name: ListProductProgramIDs :many
+SELECT program_id
+FROM product_programs
+WHERE product_id = $1
+ORDER BY program_id;
+
+-- 購入した商品の閲覧権限
+
+-- passは購入時点からduration_days日間有効
+-- name: CreateUserProductGrant :exec
+INSERT INTO user_product_grants (
+  user_id,
+  product_id,
+  payment_id,
+  expires_at
+)
+SELECT
+  sqlc.arg(user_id),
+  p.id,
+  sqlc.arg(payment_id),
+  CASE WHEN p.duration_days IS NULL THEN NULL ELSE now() + make_interval(days => p.duration_days) END
+FROM products p
+WHERE p.id = sqlc.arg(product_id)
+ON CONFLICT (payment_id) DO NOTHING;
+
+-- name: RevokeUserProductGrantByPayment :exec
+UPDATE user_product_grants
+SET revoked_at = now()
+WHERE payment_id = $1
+  AND revoked_at IS NULL;
+
+-- name: HasActiveUserProductGrant :one
+SELECT EXISTS (
+  SELECT 1 FROM user_product_grants
+  WHERE user_id = $1
+    AND product_id = $2
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > now())
+) AS has_grant;
+
+-- name: ListActiveUserProductGrants :many
+SELECT
+  g.id AS grant_id,
+  g.granted_at,
+  g.expires_at,
+  sqlc.embed(p)
+FROM user_product_grants g
+JOIN products p ON p.id = g.product_id
+WHERE g.user_id = $1
+  AND g.revoked_at IS NULL
+  AND (g.expires_at IS NULL OR g.expires_at > now())
+ORDER BY g.granted_at DESC, g.id DESC;
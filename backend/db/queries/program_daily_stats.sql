@@ -0,0 +1,66 @@
+-- likes/completions/purchasesは取り消し（いいね解除等）を反映するため、対象期間を0に戻してから再集計する
+-- name: ResetProgramDailyStatsCounters :exec
+UPDATE program_daily_stats
+SET likes = 0,
+    completions = 0,
+    purchases = 0,
+    updated_at = now()
+WHERE stat_date >= sqlc.arg(from_date)::date;
+
+-- name: RefreshProgramDailyStatsCounters :exec
+INSERT INTO program_daily_stats (program_id, stat_date, likes, completions, purchases)
+SELECT program_id, stat_date, SUM(likes), SUM(completions), SUM(purchases)
+FROM (
+  SELECT l.program_id, (l.created_at AT TIME ZONE 'Asia/Tokyo')::date AS stat_date, 1 AS likes, 0 AS completions, 0 AS purchases
+  FROM likes l
+  WHERE l.created_at >= (sqlc.arg(from_date)::date)::timestamp AT TIME ZONE 'Asia/Tokyo'
+  UNION ALL
+  SELECT wh.program_id, (wh.last_watched_at AT TIME ZONE 'Asia/Tokyo')::date, 0, 1, 0
+  FROM watch_histories wh
+  WHERE wh.is_completed = true
+    AND wh.last_watched_at >= (sqlc.arg(from_date)::date)::timestamp AT TIME ZONE 'Asia/Tokyo'
+  UNION ALL
+  SELECT t.program_id, (t.credited_at AT TIME ZONE 'Asia/Tokyo')::date, 0, 0, 1
+  FROM payments t
+  WHERE t.program_id IS NOT NULL
+    AND t.credited_at >= (sqlc.arg(from_date)::date)::timestamp AT TIME ZONE 'Asia/Tokyo'
+) s
+GROUP BY program_id, stat_date
+ON CONFLICT (program_id, stat_date) DO UPDATE SET
+  likes = EXCLUDED.likes,
+  completions = EXCLUDED.completions,
+  purchases = EXCLUDED.purchases,
+  updated_at = now();
+
+-- 期間内の日次集計でランキングする。half_life_days > 0 の場合は日数に応じて減衰させる（トレンド）
+-- 期間内の集計値が0の番組は含めない
+-- name: GetTopProgramsByDailyStats :many
+WITH window_stats AS (
+  SELECT
+    s.program_id,
+    SUM(
+      CASE WHEN sqlc.arg(metric)::text = 'likes' THEN s.likes ELSE s.views END
+      * CASE
+          WHEN sqlc.arg(half_life_days)::float8 > 0
+            THEN POWER(0.5, (sqlc.arg(today)::date - s.stat_date)::float8 / sqlc.arg(half_life_days)::float8)
+          ELSE 1
+        END
+    )::float8 AS score
+  FROM program_daily_stats s
+  WHERE s.stat_date >= sqlc.arg(from_date)::date
+  GROUP BY s.program_id
+)
+SELECT
+  p.id AS program_id,
+  p.title,
+  p.thumbnail_path,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
+  ws.score
+FROM programs p
+JOIN window_stats ws ON ws.program_id = p.id
+WHERE p.is_public = true AND ws.score > 0
+ORDER BY ws.score DESC, p.created_at DESC, p.id DESC
+LIMIT sqlc.arg(page_limit)::int;
@@ -5,3 +5,45 @@ INSERT INTO program_performers (
 ) VALUES (
   $1, $2
 );
+
+-- name: DeleteProgramPerformersByProgramID :exec
+DELETE FROM program_performers
+WHERE program_id = $1;
+
+-- name: ListProgramsByPerformerID :many
+SELECT
+  p.id AS program_id,
+  p.title,
+  p.thumbnail_path,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  p.created_at AS program_created_at,
+  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
+  COALESCE(
+    jsonb_agg(DISTINCT jsonb_build_object(
+      'id', ct.id,
+      'name', ct.name
+    )) FILTER (WHERE ct.id IS NOT NULL),
+    '[]'::jsonb
+  ) AS category_tags
+FROM program_performers pp
+JOIN programs p ON p.id = pp.program_id
+LEFT JOIN program_category_tags pct ON p.id = pct.program_id
+LEFT JOIN category_tags ct ON pct.tag_id = ct.id
+WHERE pp.performer_id = $1
+  AND p.is_public = true
+  AND (
+    sqlc.narg('cursor_created_at')::timestamptz IS NULL
+    OR (p.created_at, p.id) < (sqlc.narg('cursor_created_at')::timestamptz, sqlc.narg('cursor_id')::bigint)
+  )
+GROUP BY
+  p.id,
+  p.title,
+  p.thumbnail_path,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  p.created_at
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT sqlc.arg('page_limit')::int;
@@ -0,0 +1,73 @@
+-- 同一視聴者の同じ種類（再生開始/詳細閲覧）のイベントがdedup_after以降に記録済みなら追加しない（スライディングウィンドウ）。
+-- 同時に届いたイベントが両方とも記録されないよう、時間枠（bucket_start）を含む一意キーでも重複を防ぐ
+-- name: InsertProgramViewEvent :execrows
+INSERT INTO program_view_events (
+  program_id,
+  viewer_key,
+  kind,
+  bucket_start,
+  created_at
+)
+SELECT sqlc.arg(program_id)::bigint, sqlc.arg(viewer_key)::text, sqlc.arg(kind)::text, sqlc.arg(bucket_start)::timestamptz, sqlc.arg(viewed_at)::timestamptz
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM program_view_events e
+  WHERE e.program_id = sqlc.arg(program_id)::bigint
+    AND e.viewer_key = sqlc.arg(viewer_key)::text
+    AND e.kind = sqlc.arg(kind)::text
+    AND e.created_at > sqlc.arg(dedup_after)::timestamptz
+)
+ON CONFLICT (program_id, viewer_key, kind, bucket_start) DO NOTHING;
+
+-- 未集計イベントをまとめて日次集計に加算し、加算したイベント数を返す
+-- 再生開始はprograms.view_countとviews、詳細閲覧はdetail_viewsに加算する
+-- name: AggregateProgramViewEvents :one
+WITH picked AS (
+  SELECT id
+  FROM program_view_events
+  WHERE aggregated_at IS NULL
+  ORDER BY id
+  LIMIT sqlc.arg(batch_size)::int
+  FOR UPDATE SKIP LOCKED
+),
+marked AS (
+  UPDATE program_view_events e
+  SET aggregated_at = now()
+  FROM picked
+  WHERE e.id = picked.id
+  RETURNING e.program_id, e.kind, e.bucket_start
+),
+counts AS (
+  SELECT program_id, COUNT(*) AS n, COUNT(*) FILTER (WHERE kind = 'play') AS plays
+  FROM marked
+  GROUP BY program_id
+),
+updated AS (
+  UPDATE programs p
+  SET view_count = p.view_count + counts.plays
+  FROM counts
+  WHERE p.id = counts.program_id AND counts.plays > 0
+  RETURNING p.id
+),
+daily AS (
+  INSERT INTO program_daily_stats (program_id, stat_date, views, detail_views)
+  SELECT
+    program_id,
+    (bucket_start AT TIME ZONE 'Asia/Tokyo')::date,
+    COUNT(*) FILTER (WHERE kind = 'play'),
+    COUNT(*) FILTER (WHERE kind = 'detail')
+  FROM marked
+  GROUP BY program_id, (bucket_start AT TIME ZONE 'Asia/Tokyo')::date
+  ON CONFLICT (program_id, stat_date) DO UPDATE SET
+    views = program_daily_stats.views + EXCLUDED.views,
+    detail_views = program_daily_stats.detail_views + EXCLUDED.detail_views,
+    updated_at = now()
+  RETURNING program_id
+)
+SELECT COALESCE(SUM(n), 0)::bigint AS aggregated
+FROM counts;
+
+-- 重複判定に使わなくなった集計済みイベントを削除する
+-- name: DeleteAggregatedProgramViewEvents :execrows
+DELETE FROM program_view_events
+WHERE aggregated_at IS NOT NULL AND bucket_start < $1;
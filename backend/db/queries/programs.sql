@@ -5,11 +5,13 @@ INSERT INTO programs (
   thumbnail_path,
   description,
   is_limited_release,
-  price
+  price,
+  rental_price,
+  rental_duration_hours
 ) VALUES (
-  $1, $2, $3, $4, $5, $6
+  $1, $2, $3, $4, $5, $6, $7, $8
 )
-RETURNING id, title, video_path, thumbnail_path, description, is_limited_release, price, created_at, updated_at;
+RETURNING id, title, video_path, thumbnail_path, description, is_limited_release, price, rental_price, rental_duration_hours, created_at, updated_at;
 
 -- name: GetProgramByID :one
 SELECT
@@ -70,6 +72,8 @@ SELECT
   p.view_count,
   p.is_limited_release,
   p.price,
+  p.rental_price,
+  p.rental_duration_hours,
   COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
   EXISTS(
     SELECT 1
@@ -112,18 +116,70 @@ GROUP BY
   p.view_count,
   p.is_limited_release,
   p.price,
+  p.rental_price,
+  p.rental_duration_hours,
   p.created_at,
   p.updated_at;
 
--- name: GetPrograms :many
+-- 新着順（既定）の一覧。(created_at, id) のキーセットでprograms_public_created_at_id_idxを使い、
+-- いいね数はページ内の番組だけ数える
+-- name: GetProgramsNewest :many
+WITH paged AS (
+  SELECT
+    p.id,
+    p.title,
+    p.thumbnail_path,
+    p.view_count,
+    p.is_limited_release,
+    p.price,
+    p.created_at
+  FROM programs p
+  WHERE
+    p.is_public = true
+    AND (sqlc.narg('title')::text IS NULL OR p.title ILIKE '%' || sqlc.narg('title')::text || '%')
+    AND (
+      sqlc.narg('tag_ids')::bigint[] IS NULL
+      OR p.id IN (
+        SELECT pct2.program_id
+        FROM program_category_tags pct2
+        WHERE pct2.tag_id = ANY(sqlc.narg('tag_ids')::bigint[])
+        GROUP BY pct2.program_id
+        -- tag_match=anyならいずれか1つ、allなら全てのタグを持つ番組
+        HAVING sqlc.arg('tag_match_any')::boolean
+          OR COUNT(DISTINCT pct2.tag_id) = array_length(sqlc.narg('tag_ids')::bigint[], 1)
+      )
+    )
+    AND (
+      sqlc.narg('performer_ids')::bigint[] IS NULL
+      OR EXISTS (
+        SELECT 1
+        FROM program_performers pp2
+        WHERE pp2.program_id = p.id AND pp2.performer_id = ANY(sqlc.narg('performer_ids')::bigint[])
+      )
+    )
+    AND (
+      sqlc.narg('is_paid')::boolean IS NULL
+      OR (p.is_limited_release AND p.price > 0) = sqlc.narg('is_paid')::boolean
+    )
+    AND (sqlc.narg('created_from')::timestamptz IS NULL OR p.created_at >= sqlc.narg('created_from')::timestamptz)
+    AND (sqlc.narg('created_to')::timestamptz IS NULL OR p.created_at < sqlc.narg('created_to')::timestamptz)
+    -- カーソル（前ページ末尾の created_at, id）より後ろのみ
+    AND (
+      sqlc.narg('cursor_created_at')::timestamptz IS NULL
+      OR (p.created_at, p.id) < (sqlc.narg('cursor_created_at')::timestamptz, sqlc.narg('cursor_id')::bigint)
+    )
+  ORDER BY p.created_at DESC, p.id DESC
+  LIMIT sqlc.arg('page_limit')::int
+)
 SELECT
-  p.id AS program_id,
-  p.title,
-  p.thumbnail_path,
-  p.view_count,
-  p.is_limited_release,
-  p.price,
-  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
+  pg.id AS program_id,
+  pg.title,
+  pg.thumbnail_path,
+  pg.view_count,
+  pg.is_limited_release,
+  pg.price,
+  pg.created_at AS program_created_at,
+  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = pg.id), 0)::bigint AS like_count,
   COALESCE(
     jsonb_agg(DISTINCT jsonb_build_object(
       'id', ct.id,
@@ -131,30 +187,127 @@ SELECT
     )) FILTER (WHERE ct.id IS NOT NULL),
     '[]'::jsonb
   ) AS category_tags
-FROM programs p
--- 視聴回数はprogramsテーブルのview_countを参照
-LEFT JOIN program_category_tags pct ON p.id = pct.program_id
+FROM paged pg
+LEFT JOIN program_category_tags pct ON pg.id = pct.program_id
 LEFT JOIN category_tags ct ON pct.tag_id = ct.id
-WHERE
-  p.is_public = true
-  AND (sqlc.narg('title')::text IS NULL OR p.title ILIKE '%' || sqlc.narg('title')::text || '%')
-  AND (
-    sqlc.narg('tag_ids')::bigint[] IS NULL
-    OR p.id IN (
-      SELECT pct2.program_id
-      FROM program_category_tags pct2
-      WHERE pct2.tag_id = ANY(sqlc.narg('tag_ids')::bigint[])
-      GROUP BY pct2.program_id
-      HAVING COUNT(DISTINCT pct2.tag_id) = array_length(sqlc.narg('tag_ids')::bigint[], 1)
+GROUP BY
+  pg.id,
+  pg.title,
+  pg.thumbnail_path,
+  pg.view_count,
+  pg.is_limited_release,
+  pg.price,
+  pg.created_at
+ORDER BY pg.created_at DESC, pg.id DESC;
+
+-- 新着順以外（再生数・いいね数・価格順）の一覧。並び順はsort_key DESC, id DESCに統一する（昇順のソートはsort_keyを負にする）
+-- いいね順のソートキーは表示するいいね数と同じくlikesから数える（カーソルと表示の値を一致させる）
+-- name: GetPrograms :many
+WITH filtered AS (
+  SELECT
+    p.id,
+    p.title,
+    p.thumbnail_path,
+    p.view_count,
+    p.is_limited_release,
+    p.price,
+    p.created_at
+  FROM programs p
+  WHERE
+    p.is_public = true
+    AND (sqlc.narg('title')::text IS NULL OR p.title ILIKE '%' || sqlc.narg('title')::text || '%')
+    AND (
+      sqlc.narg('tag_ids')::bigint[] IS NULL
+      OR p.id IN (
+        SELECT pct2.program_id
+        FROM program_category_tags pct2
+        WHERE pct2.tag_id = ANY(sqlc.narg('tag_ids')::bigint[])
+        GROUP BY pct2.program_id
+        -- tag_match=anyならいずれか1つ、allなら全てのタグを持つ番組
+        HAVING sqlc.arg('tag_match_any')::boolean
+          OR COUNT(DISTINCT pct2.tag_id) = array_length(sqlc.narg('tag_ids')::bigint[], 1)
+      )
+    )
+    AND (
+      sqlc.narg('performer_ids')::bigint[] IS NULL
+      OR EXISTS (
+        SELECT 1
+        FROM program_performers pp2
+        WHERE pp2.program_id = p.id AND pp2.performer_id = ANY(sqlc.narg('performer_ids')::bigint[])
+      )
+    )
+    AND (
+      sqlc.narg('is_paid')::boolean IS NULL
+      OR (p.is_limited_release AND p.price > 0) = sqlc.narg('is_paid')::boolean
     )
-  )
+    AND (sqlc.narg('created_from')::timestamptz IS NULL OR p.created_at >= sqlc.narg('created_from')::timestamptz)
+    AND (sqlc.narg('created_to')::timestamptz IS NULL OR p.created_at < sqlc.narg('created_to')::timestamptz)
+),
+keyed AS (
+  SELECT
+    f.id,
+    f.title,
+    f.thumbnail_path,
+    f.view_count,
+    f.is_limited_release,
+    f.price,
+    f.created_at,
+    (CASE sqlc.arg('sort')::text
+      WHEN 'most_viewed' THEN f.view_count::bigint
+      WHEN 'most_liked' THEN (SELECT COUNT(*) FROM likes l WHERE l.program_id = f.id)::bigint
+      WHEN 'price_asc' THEN -f.price::bigint
+      ELSE f.price::bigint
+    END)::bigint AS sort_key
+  FROM filtered f
+),
+paged AS (
+  SELECT
+    k.id,
+    k.title,
+    k.thumbnail_path,
+    k.view_count,
+    k.is_limited_release,
+    k.price,
+    k.created_at,
+    k.sort_key
+  FROM keyed k
+  -- カーソル（前ページ末尾の sort_key, id）より後ろのみ
+  WHERE
+    sqlc.narg('cursor_sort_key')::bigint IS NULL
+    OR (k.sort_key, k.id) < (sqlc.narg('cursor_sort_key')::bigint, sqlc.narg('cursor_id')::bigint)
+  ORDER BY k.sort_key DESC, k.id DESC
+  LIMIT sqlc.arg('page_limit')::int
+)
+SELECT
+  pg.id AS program_id,
+  pg.title,
+  pg.thumbnail_path,
+  pg.view_count,
+  pg.is_limited_release,
+  pg.price,
+  pg.created_at AS program_created_at,
+  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = pg.id), 0)::bigint AS like_count,
+  pg.sort_key,
+  COALESCE(
+    jsonb_agg(DISTINCT jsonb_build_object(
+      'id', ct.id,
+      'name', ct.name
+    )) FILTER (WHERE ct.id IS NOT NULL),
+    '[]'::jsonb
+  ) AS category_tags
+FROM paged pg
+LEFT JOIN program_category_tags pct ON pg.id = pct.program_id
+LEFT JOIN category_tags ct ON pct.tag_id = ct.id
 GROUP BY
-  p.id,
-  p.title,
-  p.thumbnail_path,
-  p.view_count,
-  p.is_limited_release,
-  p.price;
+  pg.id,
+  pg.title,
+  pg.thumbnail_path,
+  pg.view_count,
+  pg.is_limited_release,
+  pg.price,
+  pg.created_at,
+  pg.sort_key
+ORDER BY pg.sort_key DESC, pg.id DESC;
 
 -- name: GetTopPrograms :many
 SELECT
@@ -242,12 +395,88 @@ SELECT EXISTS(
 ) AS exists;
 
 
--- name: IncrementProgramViewCount :exec
-UPDATE programs
-SET view_count = view_count + 1
-WHERE id = $1;
-
 -- name: GetProgramForPurchase :one
-SELECT id, is_limited_release, price
+SELECT id, is_limited_release, price, rental_price, rental_duration_hours
 FROM programs
-WHERE id = $1 AND is_public = true;
\ No newline at end of file
+WHERE id = $1 AND is_public = true;
+
+-- 管理画面用（is_publicに関わらず取得）
+
+-- name: GetAdminProgramByID :one
+SELECT
+  p.id AS program_id,
+  p.title,
+  p.video_path,
+  p.thumbnail_path,
+  p.description,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  p.rental_price,
+  p.rental_duration_hours,
+  p.is_public,
+  p.created_at AS program_created_at,
+  p.updated_at AS program_updated_at,
+  COALESCE(
+    jsonb_agg(DISTINCT jsonb_build_object(
+      'id', ct.id,
+      'name', ct.name
+    )) FILTER (WHERE ct.id IS NOT NULL),
+    '[]'::jsonb
+  ) AS category_tags,
+  COALESCE(
+    jsonb_agg(DISTINCT jsonb_build_object(
+      'id', pe.id,
+      'first_name', pe.first_name,
+      'last_name', pe.last_name,
+      'first_name_kana', pe.first_name_kana,
+      'last_name_kana', pe.last_name_kana,
+      'image_path', pe.image_path
+    )) FILTER (WHERE pe.id IS NOT NULL),
+    '[]'::jsonb
+  ) AS performers
+FROM programs p
+LEFT JOIN program_category_tags pct ON p.id = pct.program_id
+LEFT JOIN category_tags ct ON pct.tag_id = ct.id
+LEFT JOIN program_performers pp ON p.id = pp.program_id
+LEFT JOIN performers pe ON pp.performer_id = pe.id
+WHERE p.id = $1
+GROUP BY
+  p.id,
+  p.title,
+  p.video_path,
+  p.thumbnail_path,
+  p.description,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  p.rental_price,
+  p.rental_duration_hours,
+  p.is_public,
+  p.created_at,
+  p.updated_at;
+
+-- name: UpdateProgram :one
+UPDATE programs
+SET title = $2,
+    video_path = $3,
+    thumbnail_path = $4,
+    description = $5,
+    is_limited_release = $6,
+    price = $7,
+    rental_price = $8,
+    rental_duration_hours = $9,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateProgramVisibility :one
+UPDATE programs
+SET is_public = $2,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteProgram :execrows
+DELETE FROM programs
+WHERE id = $1;
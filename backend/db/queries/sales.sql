@@ -0,0 +1,104 @@
+-- Sales reporting (admin)
+-- 売上は支払いが完了した決済（返金済みを含む）を支払い完了日時（JST）で集計する。
+-- 返金額は返金日ではなく元の売上から差し引く（net_yen = 請求額 - 返金額）
+
+-- period は 'day' または 'month'
+-- name: GetSalesByPeriod :many
+SELECT
+  date_trunc(sqlc.arg(period)::text, pm.credited_at AT TIME ZONE 'Asia/Tokyo')::date AS period_start,
+  COUNT(*)::bigint AS payments,
+  COALESCE(SUM(pm.amount_yen), 0)::bigint AS gross_yen,
+  COALESCE(SUM(pm.discount_yen), 0)::bigint AS discount_yen,
+  COALESCE(SUM(pm.refunded_amount_yen), 0)::bigint AS refunded_yen,
+  COALESCE(SUM(pm.amount_yen - pm.refunded_amount_yen), 0)::bigint AS net_yen
+FROM payments pm
+WHERE pm.credited_at >= sqlc.arg(from_at)::timestamptz
+  AND pm.credited_at < sqlc.arg(to_at)::timestamptz
+GROUP BY period_start
+ORDER BY period_start;
+
+-- 番組の購入（ギフト・レンタルを含む）のみ。商品（パス等）の売上は含めない
+-- name: GetSalesByProgram :many
+SELECT
+  p.id,
+  p.title AS name,
+  COUNT(*)::bigint AS payments,
+  COALESCE(SUM(pm.amount_yen), 0)::bigint AS gross_yen,
+  COALESCE(SUM(pm.discount_yen), 0)::bigint AS discount_yen,
+  COALESCE(SUM(pm.refunded_amount_yen), 0)::bigint AS refunded_yen,
+  COALESCE(SUM(pm.amount_yen - pm.refunded_amount_yen), 0)::bigint AS net_yen
+FROM payments pm
+JOIN programs p ON p.id = pm.program_id
+WHERE pm.credited_at >= sqlc.arg(from_at)::timestamptz
+  AND pm.credited_at < sqlc.arg(to_at)::timestamptz
+GROUP BY p.id, p.title
+ORDER BY net_yen DESC, p.id;
+
+-- 複数の出演者がいる番組の売上は、それぞれの出演者に全額を計上する
+-- name: GetSalesByPerformer :many
+SELECT
+  pf.id,
+  (pf.last_name || pf.first_name)::text AS name,
+  COUNT(*)::bigint AS payments,
+  COALESCE(SUM(pm.amount_yen), 0)::bigint AS gross_yen,
+  COALESCE(SUM(pm.discount_yen), 0)::bigint AS discount_yen,
+  COALESCE(SUM(pm.refunded_amount_yen), 0)::bigint AS refunded_yen,
+  COALESCE(SUM(pm.amount_yen - pm.refunded_amount_yen), 0)::bigint AS net_yen
+FROM payments pm
+JOIN program_performers pp ON pp.program_id = pm.program_id
+JOIN performers pf ON pf.id = pp.performer_id
+WHERE pm.credited_at >= sqlc.arg(from_at)::timestamptz
+  AND pm.credited_at < sqlc.arg(to_at)::timestamptz
+GROUP BY pf.id, pf.last_name, pf.first_name
+ORDER BY net_yen DESC, pf.id;
+
+-- 複数のタグが付いた番組の売上は、それぞれのタグに全額を計上する
+-- name: GetSalesByTag :many
+SELECT
+  t.id,
+  t.name,
+  COUNT(*)::bigint AS payments,
+  COALESCE(SUM(pm.amount_yen), 0)::bigint AS gross_yen,
+  COALESCE(SUM(pm.discount_yen), 0)::bigint AS discount_yen,
+  COALESCE(SUM(pm.refunded_amount_yen), 0)::bigint AS refunded_yen,
+  COALESCE(SUM(pm.amount_yen - pm.refunded_amount_yen), 0)::bigint AS net_yen
+FROM payments pm
+JOIN program_category_tags pct ON pct.program_id = pm.program_id
+JOIN category_tags t ON t.id = pct.tag_id
+WHERE pm.credited_at >= sqlc.arg(from_at)::timestamptz
+  AND pm.credited_at < sqlc.arg(to_at)::timestamptz
+GROUP BY t.id, t.name
+ORDER BY net_yen DESC, t.id;
+
+-- 番組ごとの詳細閲覧数・決済開始数・支払い完了数。
+-- 支払い完了数は期間内に開始した決済のうち完了したもの（返金済みを含む）
+-- name: GetProgramConversions :many
+WITH views AS (
+  SELECT s.program_id, SUM(s.detail_views)::bigint AS detail_views
+  FROM program_daily_stats s
+  WHERE s.stat_date >= sqlc.arg(from_date)::date
+    AND s.stat_date <= sqlc.arg(to_date)::date
+  GROUP BY s.program_id
+),
+checkouts AS (
+  SELECT
+    pm.program_id,
+    COUNT(*)::bigint AS checkouts,
+    (COUNT(*) FILTER (WHERE pm.credited_at IS NOT NULL))::bigint AS completed
+  FROM payments pm
+  WHERE pm.program_id IS NOT NULL
+    AND pm.created_at >= sqlc.arg(from_at)::timestamptz
+    AND pm.created_at < sqlc.arg(to_at)::timestamptz
+  GROUP BY pm.program_id
+)
+SELECT
+  p.id AS program_id,
+  p.title,
+  COALESCE(v.detail_views, 0)::bigint AS detail_views,
+  COALESCE(c.checkouts, 0)::bigint AS checkouts,
+  COALESCE(c.completed, 0)::bigint AS completed
+FROM programs p
+LEFT JOIN views v ON v.program_id = p.id
+LEFT JOIN checkouts c ON c.program_id = p.id
+WHERE COALESCE(v.detail_views, 0) > 0 OR c.program_id IS NOT NULL
+ORDER BY completed DESC, checkouts DESC, detail_views DESC, p.id;
@@ -0,0 +1,79 @@
+-- name: GetProgramSearchSource :one
+-- 検索索引の元になる番組・タグ・出演者の文字列
+SELECT
+  p.id AS program_id,
+  p.title,
+  p.description,
+  COALESCE(
+    (SELECT array_agg(ct.name ORDER BY ct.id)
+     FROM program_category_tags pct
+     JOIN category_tags ct ON pct.tag_id = ct.id
+     WHERE pct.program_id = p.id),
+    '{}'
+  )::text[] AS tag_names,
+  COALESCE(
+    (SELECT array_agg(pe.last_name || pe.first_name || ' ' || pe.last_name_kana || pe.first_name_kana ORDER BY pe.id)
+     FROM program_performers pp
+     JOIN performers pe ON pp.performer_id = pe.id
+     WHERE pp.program_id = p.id),
+    '{}'
+  )::text[] AS performer_names
+FROM programs p
+WHERE p.id = $1;
+
+-- name: UpsertProgramSearchDocument :exec
+INSERT INTO program_search_documents (program_id, document)
+VALUES (sqlc.arg('program_id'), sqlc.arg('document')::text::tsvector)
+ON CONFLICT (program_id) DO UPDATE
+SET document = EXCLUDED.document,
+    updated_at = now();
+
+-- name: ListProgramIDsWithoutSearchDocument :many
+SELECT p.id
+FROM programs p
+LEFT JOIN program_search_documents d ON d.program_id = p.id
+WHERE d.program_id IS NULL
+ORDER BY p.id;
+
+-- name: SearchPrograms :many
+-- queryはsearch.BuildQueryで組み立てたtsqueryリテラル
+WITH hits AS (
+  SELECT
+    d.program_id,
+    ts_rank(d.document, sqlc.arg('query')::text::tsquery) AS rank
+  FROM program_search_documents d
+  JOIN programs p ON p.id = d.program_id
+  WHERE p.is_public = true
+    AND d.document @@ sqlc.arg('query')::text::tsquery
+  ORDER BY rank DESC, d.program_id DESC
+  LIMIT sqlc.arg('page_limit')::int
+)
+SELECT
+  p.id AS program_id,
+  p.title,
+  p.thumbnail_path,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  h.rank::real AS rank,
+  COALESCE((SELECT COUNT(*) FROM likes l WHERE l.program_id = p.id), 0)::bigint AS like_count,
+  COALESCE(
+    jsonb_agg(DISTINCT jsonb_build_object(
+      'id', ct.id,
+      'name', ct.name
+    )) FILTER (WHERE ct.id IS NOT NULL),
+    '[]'::jsonb
+  ) AS category_tags
+FROM hits h
+JOIN programs p ON p.id = h.program_id
+LEFT JOIN program_category_tags pct ON p.id = pct.program_id
+LEFT JOIN category_tags ct ON pct.tag_id = ct.id
+GROUP BY
+  p.id,
+  p.title,
+  p.thumbnail_path,
+  p.view_count,
+  p.is_limited_release,
+  p.price,
+  h.rank
+ORDER BY h.rank DESC, p.id DESC;
@@ -0,0 +1,63 @@
+-- Webhook deliveries (raw webhook bodies and their processing status)
+
+-- 重複（同じdedupe_key）の場合は何も返さない
+-- name: InsertWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+  provider,
+  dedupe_key,
+  notification_type,
+  body,
+  remote_ip
+) VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+)
+ON CONFLICT (provider, dedupe_key) DO NOTHING
+RETURNING id;
+
+-- name: GetWebhookDelivery :one
+SELECT *
+FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: GetWebhookDeliveryForUpdate :one
+SELECT *
+FROM webhook_deliveries
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListWebhookDeliveries :many
+SELECT *
+FROM webhook_deliveries
+WHERE (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
+  AND (
+    sqlc.narg('cursor_at')::timestamptz IS NULL
+    OR (received_at, id) < (sqlc.narg('cursor_at')::timestamptz, sqlc.narg('cursor_id')::bigint)
+  )
+ORDER BY received_at DESC, id DESC
+LIMIT sqlc.arg(page_limit)::int;
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'succeeded',
+    attempts = attempts + 1,
+    last_error = NULL,
+    processed_at = now()
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET status = 'failed',
+    attempts = attempts + 1,
+    last_error = $2,
+    processed_at = now()
+WHERE id = $1;
+
+-- 再処理のため処理待ちに戻す
+-- name: ResetWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = 'received'
+WHERE id = $1;
@@ -0,0 +1,44 @@
+package authz
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// APIトークンのスコープ
+// Bearer認証のリクエストは、GET/HEADならread、それ以外ならwriteスコープが必要
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// APITokenPrefix は発行するトークンの接頭辞（漏洩時にスキャナで検出しやすくするため）
+const APITokenPrefix = "szer_pat_"
+
+// IsValidScope は定義済みのスコープ名かどうかを返す
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeRead, ScopeWrite, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// GenerateAPIToken は新しいトークンを生成し、平文・ハッシュ・表示用プレフィックスを返す
+func GenerateAPIToken() (token, hash, displayPrefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	token = APITokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashAPIToken(token), token[:len(APITokenPrefix)+6], nil
+}
+
+// HashAPIToken はDBに保存・照合するためのトークンハッシュを返す
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
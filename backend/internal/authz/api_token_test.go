@@ -0,0 +1,19 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateAPIToken(t *testing.T) {
+	token, hash, prefix, err := GenerateAPIToken()
+	assert.NoError(t, err)
+	assert.Contains(t, token, APITokenPrefix)
+	assert.Equal(t, HashAPIToken(token), hash)
+	assert.Equal(t, token[:len(prefix)], prefix)
+
+	other, _, _, err := GenerateAPIToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
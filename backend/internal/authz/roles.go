@@ -0,0 +1,18 @@
+// Package authz はロール・スコープなど、middlewareとusecaseの両方で使う認可の定義をまとめる
+package authz
+
+const (
+	RoleViewer    = "viewer"
+	RoleUploader  = "uploader"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// IsValidRole は定義済みのロール名かどうかを返す
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleViewer, RoleUploader, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
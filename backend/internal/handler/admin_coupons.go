@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminCouponsHandler struct {
+	coupons *usecase.CouponsUsecase
+}
+
+// 更新時はcode・discount_type・discount_valueを省略する（変更はできない）。
+// 更新時に省略した項目は変更せず、nullを指定した期間・上限は外す
+type adminCouponRequest struct {
+	Code                  string     `json:"code"`
+	DiscountType          string     `json:"discount_type"`
+	DiscountValue         int32      `json:"discount_value"`
+	StartsAt              *time.Time `json:"starts_at"`
+	EndsAt                *time.Time `json:"ends_at"`
+	MaxRedemptions        *int32     `json:"max_redemptions"`
+	MaxRedemptionsPerUser *int32     `json:"max_redemptions_per_user"`
+	IsActive              *bool      `json:"is_active"`
+	ProgramIDs            []int64    `json:"program_ids"`
+	TagIDs                []int64    `json:"tag_ids"`
+}
+
+func (r adminCouponRequest) toInput() usecase.CouponInput {
+	return usecase.CouponInput{
+		Code:                  r.Code,
+		DiscountType:          r.DiscountType,
+		DiscountValue:         r.DiscountValue,
+		StartsAt:              r.StartsAt,
+		EndsAt:                r.EndsAt,
+		MaxRedemptions:        r.MaxRedemptions,
+		MaxRedemptionsPerUser: r.MaxRedemptionsPerUser,
+		IsActive:              r.IsActive,
+		ProgramIDs:            r.ProgramIDs,
+		TagIDs:                r.TagIDs,
+	}
+}
+
+// toUpdateInput はbodyに含まれていた項目だけを更新対象にする
+func (r adminCouponRequest) toUpdateInput(fields map[string]json.RawMessage) usecase.CouponUpdateInput {
+	has := func(key string) bool {
+		_, ok := fields[key]
+		return ok
+	}
+	return usecase.CouponUpdateInput{
+		CouponInput:              r.toInput(),
+		HasStartsAt:              has("starts_at"),
+		HasEndsAt:                has("ends_at"),
+		HasMaxRedemptions:        has("max_redemptions"),
+		HasMaxRedemptionsPerUser: has("max_redemptions_per_user"),
+		HasProgramIDs:            has("program_ids"),
+		HasTagIDs:                has("tag_ids"),
+	}
+}
+
+func NewAdminCouponsHandler(coupons *usecase.CouponsUsecase) *AdminCouponsHandler {
+	return &AdminCouponsHandler{coupons: coupons}
+}
+
+// GET /admin/coupons（無効なものも含む）
+func (h *AdminCouponsHandler) ListCoupons(c *gin.Context) {
+	coupons, err := h.coupons.ListCoupons(c.Request.Context())
+	if err != nil {
+		h.respondError(c, "AdminListCoupons", 0, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"coupons": coupons})
+}
+
+// POST /admin/coupons
+func (h *AdminCouponsHandler) CreateCoupon(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	req, _, ok := decodeAdminCouponRequest(c)
+	if !ok {
+		return
+	}
+
+	coupon, err := h.coupons.CreateCoupon(c.Request.Context(), req.toInput())
+	if err != nil {
+		h.respondError(c, "AdminCreateCoupon", 0, err)
+		return
+	}
+	log.Printf("[AdminCreateCoupon] created. userID=%s, couponID=%d, code=%s", userID, coupon.ID, coupon.Code)
+	c.JSON(http.StatusCreated, gin.H{"coupon": coupon})
+}
+
+// PUT /admin/coupons/:id
+func (h *AdminCouponsHandler) UpdateCoupon(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	id, ok := parseCouponIDParam(c, "AdminUpdateCoupon")
+	if !ok {
+		return
+	}
+	req, fields, ok := decodeAdminCouponRequest(c)
+	if !ok {
+		return
+	}
+
+	coupon, err := h.coupons.UpdateCoupon(c.Request.Context(), id, req.toUpdateInput(fields))
+	if err != nil {
+		h.respondError(c, "AdminUpdateCoupon", id, err)
+		return
+	}
+	log.Printf("[AdminUpdateCoupon] updated. userID=%s, couponID=%d", userID, id)
+	c.JSON(http.StatusOK, gin.H{"coupon": coupon})
+}
+
+func (h *AdminCouponsHandler) respondError(c *gin.Context, name string, id int64, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrCouponNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "coupon not found"})
+	case errors.Is(err, usecase.ErrInvalidCoupon):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid coupon"})
+	case errors.Is(err, usecase.ErrCouponCodeTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "coupon code already exists"})
+	case errors.Is(err, usecase.ErrProgramNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown program"})
+	case errors.Is(err, usecase.ErrCategoryTagNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown tag"})
+	default:
+		log.Printf("[%s] InternalServerError: couponID=%d, err=%v", name, id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process coupon"})
+	}
+}
+
+// decodeAdminCouponRequest はbodyと、bodyに含まれていた項目を返す
+func decodeAdminCouponRequest(c *gin.Context) (adminCouponRequest, map[string]json.RawMessage, bool) {
+	var req adminCouponRequest
+	var fields map[string]json.RawMessage
+	body, err := io.ReadAll(c.Request.Body)
+	if err == nil {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&req)
+	}
+	if err == nil {
+		err = json.Unmarshal(body, &fields)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return req, nil, false
+	}
+	return req, fields, true
+}
+
+func parseCouponIDParam(c *gin.Context, name string) (int64, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		log.Printf("[%s] BadRequest: invalid id. idStr=%s, err=%v", name, idStr, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminPayPayHandler struct {
+	paypay *usecase.PaymentsUsecase
+}
+
+type adminRefundRequest struct {
+	// 省略または0なら残額を全額返金
+	AmountYen int32  `json:"amount_yen"`
+	Reason    string `json:"reason"`
+}
+
+func NewAdminPayPayHandler(paypay *usecase.PaymentsUsecase) *AdminPayPayHandler {
+	return &AdminPayPayHandler{paypay: paypay}
+}
+
+// GET /admin/paypay/topups/:merchantPaymentId/refunds
+func (h *AdminPayPayHandler) ListRefunds(c *gin.Context) {
+	merchantPaymentID := strings.TrimSpace(c.Param("merchantPaymentId"))
+
+	res, err := h.paypay.ListPaymentRefunds(c.Request.Context(), merchantPaymentID)
+	if err != nil {
+		h.respondError(c, "AdminListRefunds", merchantPaymentID, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"topup": res})
+}
+
+// POST /admin/paypay/topups/:merchantPaymentId/refunds
+func (h *AdminPayPayHandler) Refund(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+	merchantPaymentID := strings.TrimSpace(c.Param("merchantPaymentId"))
+
+	var req adminRefundRequest
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	refund, err := h.paypay.RefundPayment(c.Request.Context(), userID, merchantPaymentID, req.AmountYen, req.Reason)
+	if err != nil {
+		if errors.Is(err, usecase.ErrPaymentRefundFailed) {
+			log.Printf("[AdminRefund] refund failed. userID=%s, merchantPaymentID=%s, merchantRefundID=%s", userID, merchantPaymentID, refund.MerchantRefundID)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "paypay refund failed", "refund": refund})
+			return
+		}
+		h.respondError(c, "AdminRefund", merchantPaymentID, err)
+		return
+	}
+	log.Printf("[AdminRefund] requested. userID=%s, merchantPaymentID=%s, merchantRefundID=%s, amount=%d, status=%s",
+		userID, merchantPaymentID, refund.MerchantRefundID, refund.AmountYen, refund.Status)
+	c.JSON(http.StatusCreated, gin.H{"refund": refund})
+}
+
+// POST /admin/paypay/topups/:merchantPaymentId/cancel
+func (h *AdminPayPayHandler) Cancel(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+	merchantPaymentID := strings.TrimSpace(c.Param("merchantPaymentId"))
+
+	status, err := h.paypay.CancelPayment(c.Request.Context(), merchantPaymentID)
+	if err != nil {
+		h.respondError(c, "AdminCancelTopup", merchantPaymentID, err)
+		return
+	}
+	log.Printf("[AdminCancelTopup] canceled. userID=%s, merchantPaymentID=%s", userID, merchantPaymentID)
+	c.JSON(http.StatusOK, gin.H{"merchant_payment_id": merchantPaymentID, "status": status})
+}
+
+// POST /admin/paypay/topups/:merchantPaymentId/revoke（割引で0円になった決済の閲覧権限を取り消す）
+func (h *AdminPayPayHandler) Revoke(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+	merchantPaymentID := strings.TrimSpace(c.Param("merchantPaymentId"))
+
+	status, err := h.paypay.RevokeFreePayment(c.Request.Context(), userID, merchantPaymentID)
+	if err != nil {
+		h.respondError(c, "AdminRevokeTopup", merchantPaymentID, err)
+		return
+	}
+	log.Printf("[AdminRevokeTopup] revoked. userID=%s, merchantPaymentID=%s", userID, merchantPaymentID)
+	c.JSON(http.StatusOK, gin.H{"merchant_payment_id": merchantPaymentID, "status": status})
+}
+
+func (h *AdminPayPayHandler) respondError(c *gin.Context, name, merchantPaymentID string, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrPaymentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "topup not found"})
+	case errors.Is(err, usecase.ErrInvalidRefundAmount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid refund amount"})
+	case errors.Is(err, usecase.ErrPaymentNotRefundable):
+		c.JSON(http.StatusConflict, gin.H{"error": "topup is not refundable"})
+	case errors.Is(err, usecase.ErrPaymentNotCancelable):
+		c.JSON(http.StatusConflict, gin.H{"error": "topup is not cancelable"})
+	case errors.Is(err, usecase.ErrPaymentNotRevocable):
+		c.JSON(http.StatusConflict, gin.H{"error": "topup is not revocable"})
+	case errors.Is(err, usecase.ErrPaymentNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "payment provider not configured"})
+	default:
+		log.Printf("[%s] InternalServerError: merchantPaymentID=%s, err=%v", name, merchantPaymentID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process topup"})
+	}
+}
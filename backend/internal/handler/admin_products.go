@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminProductsHandler struct {
+	products *usecase.ProductsUsecase
+}
+
+type adminProductRequest struct {
+	Kind         string  `json:"kind"`
+	Name         string  `json:"name"`
+	Description  string  `json:"description"`
+	Price        int32   `json:"price"`
+	PerformerID  *int64  `json:"performer_id"`
+	DurationDays *int32  `json:"duration_days"`
+	IsActive     *bool   `json:"is_active"`
+	ProgramIDs   []int64 `json:"program_ids"`
+}
+
+func (r adminProductRequest) toInput() usecase.ProductInput {
+	return usecase.ProductInput{
+		Kind:         r.Kind,
+		Name:         r.Name,
+		Description:  r.Description,
+		Price:        r.Price,
+		PerformerID:  r.PerformerID,
+		DurationDays: r.DurationDays,
+		IsActive:     r.IsActive,
+		ProgramIDs:   r.ProgramIDs,
+	}
+}
+
+func NewAdminProductsHandler(products *usecase.ProductsUsecase) *AdminProductsHandler {
+	return &AdminProductsHandler{products: products}
+}
+
+// GET /admin/products（販売停止中も含む）
+func (h *AdminProductsHandler) ListProducts(c *gin.Context) {
+	products, err := h.products.ListProducts(c.Request.Context(), false)
+	if err != nil {
+		h.respondError(c, "AdminListProducts", 0, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"products": products})
+}
+
+// POST /admin/products
+func (h *AdminProductsHandler) CreateProduct(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	req, ok := decodeAdminProductRequest(c)
+	if !ok {
+		return
+	}
+
+	product, err := h.products.CreateProduct(c.Request.Context(), req.toInput())
+	if err != nil {
+		h.respondError(c, "AdminCreateProduct", 0, err)
+		return
+	}
+	log.Printf("[AdminCreateProduct] created. userID=%s, productID=%d, kind=%s", userID, product.ID, product.Kind)
+	c.JSON(http.StatusCreated, gin.H{"product": product})
+}
+
+// PUT /admin/products/:id
+func (h *AdminProductsHandler) UpdateProduct(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	id, ok := parseProductIDParam(c, "AdminUpdateProduct")
+	if !ok {
+		return
+	}
+	req, ok := decodeAdminProductRequest(c)
+	if !ok {
+		return
+	}
+
+	product, err := h.products.UpdateProduct(c.Request.Context(), id, req.toInput())
+	if err != nil {
+		h.respondError(c, "AdminUpdateProduct", id, err)
+		return
+	}
+	log.Printf("[AdminUpdateProduct] updated. userID=%s, productID=%d", userID, id)
+	c.JSON(http.StatusOK, gin.H{"product": product})
+}
+
+func (h *AdminProductsHandler) respondError(c *gin.Context, name string, id int64, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+	case errors.Is(err, usecase.ErrInvalidProduct):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product"})
+	case errors.Is(err, usecase.ErrProgramNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown program"})
+	case errors.Is(err, usecase.ErrPerformerNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown performer"})
+	default:
+		log.Printf("[%s] InternalServerError: productID=%d, err=%v", name, id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process product"})
+	}
+}
+
+func decodeAdminProductRequest(c *gin.Context) (adminProductRequest, bool) {
+	var req adminProductRequest
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return req, false
+	}
+	return req, true
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminProgramsHandler struct {
+	programs *usecase.AdminProgramsUsecase
+}
+
+type adminProgramRequest struct {
+	Title            string  `json:"title"`
+	VideoPath        string  `json:"video_path"`
+	ThumbnailPath    *string `json:"thumbnail_path"`
+	Description      *string `json:"description"`
+	IsLimitedRelease bool    `json:"is_limited_release"`
+	Price            int32   `json:"price"`
+	RentalPrice      *int32  `json:"rental_price"`
+	// レンタル期間（時間）
+	RentalDurationHours *int32  `json:"rental_duration_hours"`
+	IsPublic            *bool   `json:"is_public"`
+	CategoryTagIDs      []int64 `json:"category_tag_ids"`
+	PerformerIDs        []int64 `json:"performer_ids"`
+}
+
+func NewAdminProgramsHandler(programs *usecase.AdminProgramsUsecase) *AdminProgramsHandler {
+	return &AdminProgramsHandler{programs: programs}
+}
+
+// GET /admin/programs/:id
+func (h *AdminProgramsHandler) GetProgram(c *gin.Context) {
+	id, ok := parseProgramIDParam(c, "AdminGetProgram")
+	if !ok {
+		return
+	}
+
+	program, err := h.programs.GetProgram(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, "AdminGetProgram", id, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"program": program})
+}
+
+// POST /admin/programs
+func (h *AdminProgramsHandler) CreateProgram(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	req, ok := decodeAdminProgramRequest(c)
+	if !ok {
+		return
+	}
+
+	program, err := h.programs.CreateProgram(c.Request.Context(), req.toInput())
+	if err != nil {
+		h.respondError(c, "AdminCreateProgram", 0, err)
+		return
+	}
+	log.Printf("[AdminCreateProgram] created. userID=%s, programID=%d", userID, program.ProgramID)
+	c.JSON(http.StatusCreated, gin.H{"program": program})
+}
+
+// PUT /admin/programs/:id
+func (h *AdminProgramsHandler) UpdateProgram(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	id, ok := parseProgramIDParam(c, "AdminUpdateProgram")
+	if !ok {
+		return
+	}
+	req, ok := decodeAdminProgramRequest(c)
+	if !ok {
+		return
+	}
+
+	program, err := h.programs.UpdateProgram(c.Request.Context(), id, req.toInput())
+	if err != nil {
+		h.respondError(c, "AdminUpdateProgram", id, err)
+		return
+	}
+	log.Printf("[AdminUpdateProgram] updated. userID=%s, programID=%d", userID, id)
+	c.JSON(http.StatusOK, gin.H{"program": program})
+}
+
+// DELETE /admin/programs/:id
+func (h *AdminProgramsHandler) DeleteProgram(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	id, ok := parseProgramIDParam(c, "AdminDeleteProgram")
+	if !ok {
+		return
+	}
+
+	if err := h.programs.DeleteProgram(c.Request.Context(), id); err != nil {
+		h.respondError(c, "AdminDeleteProgram", id, err)
+		return
+	}
+	log.Printf("[AdminDeleteProgram] deleted. userID=%s, programID=%d", userID, id)
+	c.Status(http.StatusNoContent)
+}
+
+// POST /admin/programs/:id/publish
+func (h *AdminProgramsHandler) PublishProgram(c *gin.Context) {
+	h.setVisibility(c, "AdminPublishProgram", true)
+}
+
+// POST /admin/programs/:id/unpublish
+func (h *AdminProgramsHandler) UnpublishProgram(c *gin.Context) {
+	h.setVisibility(c, "AdminUnpublishProgram", false)
+}
+
+func (h *AdminProgramsHandler) setVisibility(c *gin.Context, name string, isPublic bool) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	id, ok := parseProgramIDParam(c, name)
+	if !ok {
+		return
+	}
+
+	program, err := h.programs.SetProgramVisibility(c.Request.Context(), id, isPublic)
+	if err != nil {
+		h.respondError(c, name, id, err)
+		return
+	}
+	log.Printf("[%s] is_public=%v. userID=%s, programID=%d", name, isPublic, userID, id)
+	c.JSON(http.StatusOK, gin.H{"program": program})
+}
+
+func (h *AdminProgramsHandler) respondError(c *gin.Context, name string, id int64, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrProgramNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
+	case errors.Is(err, usecase.ErrProgramTitleRequired),
+		errors.Is(err, usecase.ErrProgramVideoPathRequired),
+		errors.Is(err, usecase.ErrInvalidProgramPrice),
+		errors.Is(err, usecase.ErrInvalidProgramRental),
+		errors.Is(err, usecase.ErrInvalidProgramRelation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrProgramHasPurchases):
+		c.JSON(http.StatusConflict, gin.H{"error": "program has purchases"})
+	default:
+		log.Printf("[%s] InternalServerError: programID=%d, err=%v", name, id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process program"})
+	}
+}
+
+func (r adminProgramRequest) toInput() usecase.AdminProgramInput {
+	return usecase.AdminProgramInput{
+		Title:               r.Title,
+		VideoPath:           r.VideoPath,
+		ThumbnailPath:       r.ThumbnailPath,
+		Description:         r.Description,
+		IsLimitedRelease:    r.IsLimitedRelease,
+		Price:               r.Price,
+		RentalPrice:         r.RentalPrice,
+		RentalDurationHours: r.RentalDurationHours,
+		IsPublic:            r.IsPublic,
+		CategoryTagIDs:      r.CategoryTagIDs,
+		PerformerIDs:        r.PerformerIDs,
+	}
+}
+
+func decodeAdminProgramRequest(c *gin.Context) (adminProgramRequest, bool) {
+	var req adminProgramRequest
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return req, false
+	}
+	return req, true
+}
+
+func parseProgramIDParam(c *gin.Context, name string) (int64, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		log.Printf("[%s] BadRequest: invalid id. idStr=%s, err=%v", name, idStr, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupAdminProgramsRouter は管理者API用のルーターを作る（認証はMockOptionalAuthで代替）
+func setupAdminProgramsRouter(dbConn *sql.DB, q *db.Queries, userID string) *gin.Engine {
+	h := NewAdminProgramsHandler(usecase.NewAdminProgramsUsecase(dbConn, q))
+	r := gin.New()
+	r.Use(MockOptionalAuth(userID))
+	r.POST("/admin/programs", h.CreateProgram)
+	r.GET("/admin/programs/:id", h.GetProgram)
+	r.PUT("/admin/programs/:id", h.UpdateProgram)
+	r.DELETE("/admin/programs/:id", h.DeleteProgram)
+	r.POST("/admin/programs/:id/publish", h.PublishProgram)
+	r.POST("/admin/programs/:id/unpublish", h.UnpublishProgram)
+	return r
+}
+
+type adminProgramResponse struct {
+	Program struct {
+		ProgramID        int64                    `json:"program_id"`
+		Title            string                   `json:"title"`
+		VideoPath        string                   `json:"video_path"`
+		Description      *string                  `json:"description"`
+		IsLimitedRelease bool                     `json:"is_limited_release"`
+		Price            int32                    `json:"price"`
+		IsPublic         bool                     `json:"is_public"`
+		CategoryTags     []map[string]interface{} `json:"category_tags"`
+		Performers       []map[string]interface{} `json:"performers"`
+	} `json:"program"`
+}
+
+// =============================================================================
+// POST /admin/programs
+// =============================================================================
+
+func TestAdminCreateProgram_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	r := setupAdminProgramsRouter(dbConn, q, "admin-user")
+	programsUC := usecase.NewProgramsUsecase(q, nil)
+
+	var tagID int64
+	err := dbConn.QueryRow(`INSERT INTO category_tags (name) VALUES ($1) RETURNING id`, "admin-tag").Scan(&tagID)
+	if err != nil {
+		t.Fatalf("failed to insert test tag: %v", err)
+	}
+	var performerID int64
+	err = dbConn.QueryRow(
+		`INSERT INTO performers (first_name, last_name, first_name_kana, last_name_kana) VALUES ($1, $2, $3, $4) RETURNING id`,
+		"花子", "山田", "ハナコ", "ヤマダ",
+	).Scan(&performerID)
+	if err != nil {
+		t.Fatalf("failed to insert test performer: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"title":"admin-created","video_path":"/video/admin.mp4","description":"admin-desc","is_limited_release":true,"price":800,"category_tag_ids":[%d],"performer_ids":[%d]}`, tagID, performerID)
+	req, _ := http.NewRequest("POST", "/admin/programs", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp adminProgramResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	assert.NotZero(t, resp.Program.ProgramID)
+	assert.Equal(t, "admin-created", resp.Program.Title)
+	assert.Equal(t, true, resp.Program.IsLimitedRelease)
+	assert.Equal(t, int32(800), resp.Program.Price)
+	// is_public未指定なら下書き（非公開）
+	assert.Equal(t, false, resp.Program.IsPublic)
+	assert.Len(t, resp.Program.CategoryTags, 1)
+	assert.Equal(t, "admin-tag", resp.Program.CategoryTags[0]["name"])
+	assert.Len(t, resp.Program.Performers, 1)
+	assert.Equal(t, "山田花子", resp.Program.Performers[0]["full_name"])
+
+	// 非公開なので一般向けの詳細APIからは見えない
+	_, err = programsUC.GetProgramDetails(req.Context(), "", resp.Program.ProgramID)
+	assert.ErrorIs(t, err, usecase.ErrProgramNotFound)
+}
+
+func TestAdminCreateProgram_Validation_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	r := setupAdminProgramsRouter(dbConn, q, "admin-user")
+
+	cases := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{name: "title missing", body: `{"video_path":"/video/a.mp4"}`, wantErr: "title is required"},
+		{name: "video_path missing", body: `{"title":"a"}`, wantErr: "video_path is required"},
+		{name: "limited release without price", body: `{"title":"a","video_path":"/video/a.mp4","is_limited_release":true,"price":0}`, wantErr: "invalid price"},
+		{name: "rental price without duration", body: `{"title":"a","video_path":"/video/a.mp4","is_limited_release":true,"price":800,"rental_price":300}`, wantErr: "invalid rental_price or rental_duration_hours"},
+		{name: "rental on free program", body: `{"title":"a","video_path":"/video/a.mp4","rental_price":300,"rental_duration_hours":48}`, wantErr: "invalid rental_price or rental_duration_hours"},
+		{name: "unknown tag", body: `{"title":"a","video_path":"/video/a.mp4","category_tag_ids":[999999]}`, wantErr: "unknown category tag or performer"},
+		{name: "unknown field", body: `{"title":"a","video_path":"/video/a.mp4","foo":1}`, wantErr: "invalid body"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/admin/programs", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var resp map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			assert.Equal(t, tc.wantErr, resp["error"])
+		})
+	}
+}
+
+// =============================================================================
+// PUT /admin/programs/:id
+// =============================================================================
+
+func TestAdminUpdateProgram_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	r := setupAdminProgramsRouter(dbConn, q, "admin-user")
+
+	var programID int64
+	err := dbConn.QueryRow(`INSERT INTO programs (title, video_path) VALUES ($1, $2) RETURNING id`,
+		"before-update", "/video/before.mp4").Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+	var oldTagID, newTagID int64
+	if err := dbConn.QueryRow(`INSERT INTO category_tags (name) VALUES ($1) RETURNING id`, "old-tag").Scan(&oldTagID); err != nil {
+		t.Fatalf("failed to insert tag: %v", err)
+	}
+	if err := dbConn.QueryRow(`INSERT INTO category_tags (name) VALUES ($1) RETURNING id`, "new-tag").Scan(&newTagID); err != nil {
+		t.Fatalf("failed to insert tag: %v", err)
+	}
+	if _, err := dbConn.Exec(`INSERT INTO program_category_tags (program_id, tag_id) VALUES ($1, $2)`, programID, oldTagID); err != nil {
+		t.Fatalf("failed to insert program_category_tags: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"title":"after-update","video_path":"/video/after.mp4","is_limited_release":true,"price":300,"category_tag_ids":[%d]}`, newTagID)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/admin/programs/%d", programID), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp adminProgramResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	assert.Equal(t, "after-update", resp.Program.Title)
+	assert.Equal(t, "/video/after.mp4", resp.Program.VideoPath)
+	assert.Equal(t, int32(300), resp.Program.Price)
+	// is_public未指定なら公開状態は変わらない
+	assert.Equal(t, true, resp.Program.IsPublic)
+	// タグは置き換えられる
+	assert.Len(t, resp.Program.CategoryTags, 1)
+	assert.Equal(t, "new-tag", resp.Program.CategoryTags[0]["name"])
+}
+
+func TestAdminUpdateProgram_NotFound_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	r := setupAdminProgramsRouter(dbConn, q, "admin-user")
+
+	req, _ := http.NewRequest("PUT", "/admin/programs/999999", strings.NewReader(`{"title":"x","video_path":"/video/x.mp4"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// =============================================================================
+// POST /admin/programs/:id/publish, /unpublish
+// =============================================================================
+
+func TestAdminPublishProgram_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	r := setupAdminProgramsRouter(dbConn, q, "admin-user")
+	programsUC := usecase.NewProgramsUsecase(q, nil)
+
+	var programID int64
+	err := dbConn.QueryRow(`INSERT INTO programs (title, video_path, is_public) VALUES ($1, $2, false) RETURNING id`,
+		"draft-program", "/video/draft.mp4").Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/admin/programs/%d/publish", programID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, err = programsUC.GetProgramDetails(req.Context(), "", programID)
+	assert.NoError(t, err)
+
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/admin/programs/%d/unpublish", programID), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp adminProgramResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	assert.Equal(t, false, resp.Program.IsPublic)
+	_, err = programsUC.GetProgramDetails(req.Context(), "", programID)
+	assert.ErrorIs(t, err, usecase.ErrProgramNotFound)
+}
+
+// =============================================================================
+// DELETE /admin/programs/:id
+// =============================================================================
+
+func TestAdminDeleteProgram_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	r := setupAdminProgramsRouter(dbConn, q, "admin-user")
+
+	var programID int64
+	err := dbConn.QueryRow(`INSERT INTO programs (title, video_path) VALUES ($1, $2) RETURNING id`,
+		"delete-me", "/video/delete.mp4").Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/admin/programs/%d", programID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	var exists bool
+	if err := dbConn.QueryRow(`SELECT EXISTS(SELECT 1 FROM programs WHERE id = $1)`, programID).Scan(&exists); err != nil {
+		t.Fatalf("failed to check program: %v", err)
+	}
+	assert.False(t, exists)
+
+	// 2回目は404
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/admin/programs/%d", programID), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminDeleteProgram_WithPurchases_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	r := setupAdminProgramsRouter(dbConn, q, "admin-user")
+
+	userID := "admin-delete-buyer"
+	_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified") VALUES ($1, $2, $3, true)`,
+		userID, "購入者", "admin-delete-buyer@example.com")
+	if err != nil {
+		t.Fatalf("failed to insert test user: %v", err)
+	}
+	var programID int64
+	err = dbConn.QueryRow(`INSERT INTO programs (title, video_path, is_limited_release, price) VALUES ($1, $2, true, 500) RETURNING id`,
+		"purchased-program", "/video/purchased.mp4").Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+	_, err = dbConn.Exec(`INSERT INTO payments (provider, user_id, merchant_payment_id, amount_yen, status, program_id) VALUES ('paypay', $1, $2, 500, 'COMPLETED', $3)`,
+		userID, "admin-delete-merchant-id", programID)
+	if err != nil {
+		t.Fatalf("failed to insert test topup: %v", err)
+	}
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/admin/programs/%d", programID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminSalesHandler struct {
+	sales *usecase.SalesUsecase
+}
+
+func NewAdminSalesHandler(sales *usecase.SalesUsecase) *AdminSalesHandler {
+	return &AdminSalesHandler{sales: sales}
+}
+
+// GET /admin/sales?group_by=day|month|program|performer|tag&from=2026-10-01&to=2026-10-31
+func (h *AdminSalesHandler) GetSales(c *gin.Context) {
+	report, ok := h.getSalesReport(c, "AdminGetSales")
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GET /admin/sales/export（GET /admin/sales と同じ条件のCSV。会計用）
+func (h *AdminSalesHandler) ExportSales(c *gin.Context) {
+	report, ok := h.getSalesReport(c, "AdminExportSales")
+	if !ok {
+		return
+	}
+
+	// ファイル名の期間は最終日を含む日付で表す
+	last := report.To.Add(-time.Nanosecond)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="sales-%s-%s-%s.csv"`,
+		report.GroupBy, report.From.In(usecase.JST).Format("20060102"), last.In(usecase.JST).Format("20060102")))
+	// Excelで文字化けしないようBOMを付ける
+	c.Status(http.StatusOK)
+	_, _ = c.Writer.WriteString("\ufeff")
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"key", "label", "payments", "gross_yen", "discount_yen", "refunded_yen", "net_yen"})
+	for _, row := range report.Rows {
+		_ = w.Write(salesCSVRecord(row.Key, row.Label, row.SalesAmounts))
+	}
+	if report.Total != nil {
+		_ = w.Write(salesCSVRecord("total", "", *report.Total))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Printf("[AdminExportSales] failed to write csv. err=%v", err)
+	}
+}
+
+// GET /admin/sales/conversions?from=2026-10-01&to=2026-10-31
+func (h *AdminSalesHandler) GetProgramConversions(c *gin.Context) {
+	r, ok := parseSalesRange(c, "AdminGetProgramConversions")
+	if !ok {
+		return
+	}
+
+	report, err := h.sales.GetProgramConversions(c.Request.Context(), r, time.Now())
+	if err != nil {
+		h.respondError(c, "AdminGetProgramConversions", err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *AdminSalesHandler) getSalesReport(c *gin.Context, name string) (usecase.SalesReport, bool) {
+	r, ok := parseSalesRange(c, name)
+	if !ok {
+		return usecase.SalesReport{}, false
+	}
+
+	report, err := h.sales.GetSalesReport(c.Request.Context(), c.Query("group_by"), r, time.Now())
+	if err != nil {
+		h.respondError(c, name, err)
+		return usecase.SalesReport{}, false
+	}
+	return report, true
+}
+
+func (h *AdminSalesHandler) respondError(c *gin.Context, name string, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidSalesGroupBy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_by"})
+	case errors.Is(err, usecase.ErrInvalidSalesRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+	default:
+		log.Printf("[%s] InternalServerError: err=%v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sales"})
+	}
+}
+
+// parseSalesRange はfrom/toを読み取る（日付のみの指定ならtoの日を含む）
+func parseSalesRange(c *gin.Context, name string) (usecase.SalesRange, bool) {
+	var r usecase.SalesRange
+	var ok bool
+	if r.From, ok = parseTimeQuery(c, name, "from", false); !ok {
+		return r, false
+	}
+	if r.To, ok = parseTimeQuery(c, name, "to", true); !ok {
+		return r, false
+	}
+	return r, true
+}
+
+func salesCSVRecord(key, label string, a usecase.SalesAmounts) []string {
+	return []string{
+		key,
+		csvSafe(label),
+		strconv.FormatInt(a.Payments, 10),
+		strconv.FormatInt(a.GrossYen, 10),
+		strconv.FormatInt(a.DiscountYen, 10),
+		strconv.FormatInt(a.RefundedYen, 10),
+		strconv.FormatInt(a.NetYen, 10),
+	}
+}
+
+// csvSafe は番組名等が表計算ソフトで数式として解釈されないよう、先頭が記号の値に'を付ける
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAdminSalesTestRouter(uc *usecase.SalesUsecase) *gin.Engine {
+	h := NewAdminSalesHandler(uc)
+	r := gin.New()
+	r.GET("/admin/sales", h.GetSales)
+	r.GET("/admin/sales/export", h.ExportSales)
+	r.GET("/admin/sales/conversions", h.GetProgramConversions)
+	return r
+}
+
+// seedSale は決済を直接作る（creditedAtがnilなら未完了）
+func seedSale(t *testing.T, dbConn *sql.DB, merchantPaymentID string, programID int64, amount, discount, refunded int32, createdAt time.Time, creditedAt *time.Time) {
+	t.Helper()
+	status := "AWAITING"
+	var credited sql.NullTime
+	if creditedAt != nil {
+		status = "COMPLETED"
+		credited = sql.NullTime{Time: *creditedAt, Valid: true}
+	}
+	_, err := dbConn.Exec(`INSERT INTO payments (provider, user_id, merchant_payment_id, amount_yen, discount_yen, refunded_amount_yen, status, program_id, created_at, credited_at) VALUES ('paypay', $1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		"sales-buyer", merchantPaymentID, amount, discount, refunded, status, programID, createdAt, credited)
+	require.NoError(t, err)
+}
+
+func getSalesReport(t *testing.T, r *gin.Engine, query string) usecase.SalesReport {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/sales"+query, nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report usecase.SalesReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return report
+}
+
+// =============================================================================
+// GET /admin/sales
+// =============================================================================
+
+func TestAdminSales_Aggregates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	r := newAdminSalesTestRouter(usecase.NewSalesUsecase(q))
+
+	programID := seedPurchasableProgram(t, dbConn, "sales-buyer", 1000)
+	otherProgramID := seedPurchasableProgram(t, dbConn, "sales-buyer-2", 500)
+	var tagID, performerID int64
+	require.NoError(t, dbConn.QueryRow(`INSERT INTO category_tags (name) VALUES ('sales-tag') RETURNING id`).Scan(&tagID))
+	require.NoError(t, dbConn.QueryRow(`INSERT INTO performers (first_name, last_name, first_name_kana, last_name_kana) VALUES ('花子', '山田', 'はなこ', 'やまだ') RETURNING id`).Scan(&performerID))
+	_, err := dbConn.Exec(`INSERT INTO program_category_tags (program_id, tag_id) VALUES ($1, $2), ($3, $2)`, programID, tagID, otherProgramID)
+	require.NoError(t, err)
+	_, err = dbConn.Exec(`INSERT INTO program_performers (program_id, performer_id) VALUES ($1, $2)`, programID, performerID)
+	require.NoError(t, err)
+
+	// JSTの9/30 23:30（UTCでは9/30 14:30）と10/1 10:00
+	sep30 := time.Date(2026, 9, 30, 23, 30, 0, 0, usecase.JST)
+	oct1 := time.Date(2026, 10, 1, 10, 0, 0, 0, usecase.JST)
+	seedSale(t, dbConn, "sales-1", programID, 1000, 0, 0, sep30, &sep30)
+	seedSale(t, dbConn, "sales-2", programID, 800, 200, 300, oct1, &oct1) // クーポン割引 + 一部返金
+	seedSale(t, dbConn, "sales-3", otherProgramID, 500, 0, 500, oct1, &oct1)
+	seedSale(t, dbConn, "sales-pending", programID, 1000, 0, 0, oct1, nil)
+
+	day := getSalesReport(t, r, "?from=2026-09-01&to=2026-10-31")
+	assert.Equal(t, usecase.SalesGroupByDay, day.GroupBy)
+	require.Len(t, day.Rows, 2)
+	assert.Equal(t, "2026-09-30", day.Rows[0].Key)
+	assert.Equal(t, int64(1000), day.Rows[0].NetYen)
+	assert.Equal(t, "2026-10-01", day.Rows[1].Key)
+	assert.Equal(t, int64(2), day.Rows[1].Payments)
+	assert.Equal(t, int64(1300), day.Rows[1].GrossYen)
+	assert.Equal(t, int64(200), day.Rows[1].DiscountYen)
+	assert.Equal(t, int64(800), day.Rows[1].RefundedYen)
+	assert.Equal(t, int64(500), day.Rows[1].NetYen)
+	require.NotNil(t, day.Total)
+	assert.Equal(t, int64(3), day.Total.Payments)
+	assert.Equal(t, int64(1500), day.Total.NetYen)
+
+	month := getSalesReport(t, r, "?group_by=month&from=2026-09-01&to=2026-10-31")
+	require.Len(t, month.Rows, 2)
+	assert.Equal(t, "2026-09", month.Rows[0].Key)
+	assert.Equal(t, "2026-10", month.Rows[1].Key)
+
+	byProgram := getSalesReport(t, r, "?group_by=program&from=2026-09-01&to=2026-10-31")
+	require.Len(t, byProgram.Rows, 2)
+	assert.Equal(t, fmt.Sprint(programID), byProgram.Rows[0].Key)
+	assert.Equal(t, int64(1500), byProgram.Rows[0].NetYen)
+	assert.Equal(t, int64(0), byProgram.Rows[1].NetYen)
+	assert.Nil(t, byProgram.Total)
+
+	byPerformer := getSalesReport(t, r, "?group_by=performer&from=2026-09-01&to=2026-10-31")
+	require.Len(t, byPerformer.Rows, 1)
+	assert.Equal(t, "山田花子", byPerformer.Rows[0].Label)
+	assert.Equal(t, int64(2), byPerformer.Rows[0].Payments)
+
+	byTag := getSalesReport(t, r, "?group_by=tag&from=2026-09-01&to=2026-10-31")
+	require.Len(t, byTag.Rows, 1)
+	assert.Equal(t, int64(3), byTag.Rows[0].Payments)
+	assert.Equal(t, int64(1500), byTag.Rows[0].NetYen)
+
+	// 日付のみのtoはその日を含む
+	only := getSalesReport(t, r, "?from=2026-09-30&to=2026-09-30")
+	require.Len(t, only.Rows, 1)
+	assert.Equal(t, "2026-09-30", only.Rows[0].Key)
+
+	// CSV
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/sales/export?group_by=month&from=2026-09-01&to=2026-10-31", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="sales-month-20260901-20261031.csv"`)
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(w.Body.String(), "\ufeff"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, []string{"key", "label", "payments", "gross_yen", "discount_yen", "refunded_yen", "net_yen"}, records[0])
+	assert.Equal(t, []string{"2026-10", "", "2", "1300", "200", "800", "500"}, records[2])
+	assert.Equal(t, []string{"total", "", "3", "2300", "200", "800", "1500"}, records[3])
+}
+
+func TestAdminSales_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, q := setupTestDB(t)
+	r := newAdminSalesTestRouter(usecase.NewSalesUsecase(q))
+
+	for _, query := range []string{"?group_by=year", "?from=2026-10-02&to=2026-10-01", "?from=yesterday"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/sales"+query, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+// =============================================================================
+// GET /admin/sales/conversions
+// =============================================================================
+
+func TestAdminSales_ProgramConversions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	r := newAdminSalesTestRouter(usecase.NewSalesUsecase(q))
+
+	programID := seedPurchasableProgram(t, dbConn, "sales-buyer", 1000)
+
+	// 未購入のユーザーが詳細を開くと数える（同一視聴者は時間枠内で1回）
+	programsUC := usecase.NewProgramsUsecase(q, nil)
+	openDetail := func(userID string) {
+		programs := gin.New()
+		programs.Use(MockOptionalAuth(userID))
+		programs.GET("/programs/:id", NewProgramsHandler(programsUC).ProgramDetails)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/programs/%d", programID), nil)
+		programs.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	for i := 0; i < 4; i++ {
+		openDetail("sales-viewer")
+	}
+	openDetail("")
+
+	// 詳細閲覧はバックグラウンドで記録され、集計ジョブでdetail_viewsに反映される
+	require.Eventually(t, func() bool {
+		var n int
+		require.NoError(t, dbConn.QueryRow(`SELECT COUNT(*) FROM program_view_events WHERE program_id = $1 AND kind = 'detail'`, programID).Scan(&n))
+		return n == 2
+	}, 5*time.Second, 20*time.Millisecond)
+	_, err := usecase.NewStatsAggregator(dbConn, q).AggregateOnce(context.Background())
+	require.NoError(t, err)
+
+	var viewCount int
+	require.NoError(t, dbConn.QueryRow(`SELECT view_count FROM programs WHERE id = $1`, programID).Scan(&viewCount))
+	assert.Equal(t, 0, viewCount)
+
+	now := time.Now()
+	seedSale(t, dbConn, "conv-completed", programID, 1000, 0, 0, now, &now)
+	seedSale(t, dbConn, "conv-pending", programID, 1000, 0, 0, now, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/sales/conversions", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report usecase.ProgramConversionReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.Len(t, report.Programs, 1)
+	conv := report.Programs[0]
+	assert.Equal(t, programID, conv.ProgramID)
+	assert.Equal(t, int64(2), conv.DetailViews)
+	assert.Equal(t, int64(2), conv.Checkouts)
+	assert.Equal(t, int64(1), conv.Completed)
+	require.NotNil(t, conv.CheckoutRate)
+	assert.InDelta(t, 1.0, *conv.CheckoutRate, 1e-9)
+	require.NotNil(t, conv.CompletionRate)
+	assert.InDelta(t, 0.5, *conv.CompletionRate, 1e-9)
+	require.NotNil(t, conv.ConversionRate)
+	assert.InDelta(t, 0.5, *conv.ConversionRate, 1e-9)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminTagsHandler struct {
+	tags *usecase.TagsUsecase
+}
+
+type adminTagRequest struct {
+	Name string `json:"name"`
+}
+
+func NewAdminTagsHandler(tags *usecase.TagsUsecase) *AdminTagsHandler {
+	return &AdminTagsHandler{tags: tags}
+}
+
+// POST /admin/tags
+func (h *AdminTagsHandler) CreateTag(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	req, ok := decodeAdminTagRequest(c)
+	if !ok {
+		return
+	}
+
+	tag, err := h.tags.CreateTag(c.Request.Context(), req.Name)
+	if err != nil {
+		h.respondError(c, "AdminCreateTag", 0, err)
+		return
+	}
+	log.Printf("[AdminCreateTag] created. userID=%s, tagID=%d", userID, tag.ID)
+	c.JSON(http.StatusCreated, gin.H{"tag": tag})
+}
+
+// PUT /admin/tags/:id
+func (h *AdminTagsHandler) UpdateTag(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	id, ok := parseTagIDParam(c, "AdminUpdateTag")
+	if !ok {
+		return
+	}
+	req, ok := decodeAdminTagRequest(c)
+	if !ok {
+		return
+	}
+
+	tag, err := h.tags.UpdateTag(c.Request.Context(), id, req.Name)
+	if err != nil {
+		h.respondError(c, "AdminUpdateTag", id, err)
+		return
+	}
+	log.Printf("[AdminUpdateTag] updated. userID=%s, tagID=%d", userID, id)
+	c.JSON(http.StatusOK, gin.H{"tag": tag})
+}
+
+// DELETE /admin/tags/:id
+func (h *AdminTagsHandler) DeleteTag(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	id, ok := parseTagIDParam(c, "AdminDeleteTag")
+	if !ok {
+		return
+	}
+
+	if err := h.tags.DeleteTag(c.Request.Context(), id); err != nil {
+		h.respondError(c, "AdminDeleteTag", id, err)
+		return
+	}
+	log.Printf("[AdminDeleteTag] deleted. userID=%s, tagID=%d", userID, id)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminTagsHandler) respondError(c *gin.Context, name string, id int64, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrCategoryTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+	case errors.Is(err, usecase.ErrInvalidCategoryTagName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrCategoryTagNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "tag name already exists"})
+	default:
+		log.Printf("[%s] InternalServerError: tagID=%d, err=%v", name, id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process tag"})
+	}
+}
+
+func decodeAdminTagRequest(c *gin.Context) (adminTagRequest, bool) {
+	var req adminTagRequest
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return req, false
+	}
+	return req, true
+}
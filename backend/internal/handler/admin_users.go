@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminUsersHandler struct {
+	users *usecase.AdminUsersUsecase
+}
+
+type setUserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+func NewAdminUsersHandler(users *usecase.AdminUsersUsecase) *AdminUsersHandler {
+	return &AdminUsersHandler{users: users}
+}
+
+// GET /admin/users/:id/roles
+func (h *AdminUsersHandler) ListUserRoles(c *gin.Context) {
+	targetUserID := strings.TrimSpace(c.Param("id"))
+
+	roles, err := h.users.ListUserRoles(c.Request.Context(), targetUserID)
+	if err != nil {
+		log.Printf("[AdminListUserRoles] InternalServerError: targetUserID=%s, err=%v", targetUserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list roles"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": targetUserID, "roles": roles})
+}
+
+// PUT /admin/users/:id/roles
+func (h *AdminUsersHandler) SetUserRoles(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+	targetUserID := strings.TrimSpace(c.Param("id"))
+
+	var req setUserRolesRequest
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	roles, err := h.users.SetUserRoles(c.Request.Context(), targetUserID, req.Roles)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			return
+		}
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		log.Printf("[AdminSetUserRoles] InternalServerError: targetUserID=%s, roles=%v, err=%v", targetUserID, req.Roles, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set roles"})
+		return
+	}
+	log.Printf("[AdminSetUserRoles] updated. userID=%s, targetUserID=%s, roles=%v", userID, targetUserID, roles)
+	c.JSON(http.StatusOK, gin.H{"user_id": targetUserID, "roles": roles})
+}
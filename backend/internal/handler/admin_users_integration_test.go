@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chan-shizu/SZer/internal/authz"
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// middleware.RequireRole
+// =============================================================================
+
+func TestRequireRole_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	users := []struct {
+		id   string
+		role string
+	}{
+		{id: "role-viewer", role: ""},
+		{id: "role-moderator", role: authz.RoleModerator},
+		{id: "role-admin", role: authz.RoleAdmin},
+	}
+	for _, u := range users {
+		_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified") VALUES ($1, $2, $3, true)`,
+			u.id, u.id, u.id+"@example.com")
+		if err != nil {
+			t.Fatalf("failed to insert test user: %v", err)
+		}
+		if u.role != "" {
+			if _, err := dbConn.Exec(`INSERT INTO user_roles (user_id, role) VALUES ($1, $2)`, u.id, u.role); err != nil {
+				t.Fatalf("failed to insert user role: %v", err)
+			}
+		}
+	}
+
+	cases := []struct {
+		userID string
+		want   int
+	}{
+		{userID: "role-viewer", want: http.StatusForbidden},
+		{userID: "role-moderator", want: http.StatusOK},
+		// adminは全ロールを兼ねる
+		{userID: "role-admin", want: http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.userID, func(t *testing.T) {
+			r := gin.New()
+			r.Use(MockOptionalAuth(tc.userID))
+			r.GET("/admin/ping", middleware.RequireRole(q, authz.RoleModerator), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"roles": middleware.RolesFromContext(c)})
+			})
+
+			req, _ := http.NewRequest("GET", "/admin/ping", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.want, w.Code)
+		})
+	}
+}
+
+func TestRequireRole_Unauthenticated_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, q := setupTestDB(t)
+
+	r := gin.New()
+	r.Use(MockOptionalAuth(""))
+	r.GET("/admin/ping", middleware.RequireRole(q, authz.RoleAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req, _ := http.NewRequest("GET", "/admin/ping", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// =============================================================================
+// GET/PUT /admin/users/:id/roles
+// =============================================================================
+
+func TestAdminSetUserRoles_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	targetUserID := "roles-target-user"
+	_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified") VALUES ($1, $2, $3, true)`,
+		targetUserID, "ロール対象ユーザー", "roles-target@example.com")
+	if err != nil {
+		t.Fatalf("failed to insert test user: %v", err)
+	}
+
+	h := NewAdminUsersHandler(usecase.NewAdminUsersUsecase(dbConn, q))
+	r := gin.New()
+	r.Use(MockOptionalAuth("admin-user"))
+	r.GET("/admin/users/:id/roles", h.ListUserRoles)
+	r.PUT("/admin/users/:id/roles", h.SetUserRoles)
+
+	req, _ := http.NewRequest("PUT", "/admin/users/"+targetUserID+"/roles", strings.NewReader(`{"roles":["uploader","moderator"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	req, _ = http.NewRequest("GET", "/admin/users/"+targetUserID+"/roles", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Roles []string `json:"roles"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	assert.Equal(t, []string{"moderator", "uploader"}, resp.Roles)
+
+	// 置き換えなので空配列で全ロール剥奪
+	req, _ = http.NewRequest("PUT", "/admin/users/"+targetUserID+"/roles", strings.NewReader(`{"roles":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	assert.Empty(t, resp.Roles)
+}
+
+func TestAdminSetUserRoles_InvalidRole_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	h := NewAdminUsersHandler(usecase.NewAdminUsersUsecase(dbConn, q))
+	r := gin.New()
+	r.Use(MockOptionalAuth("admin-user"))
+	r.PUT("/admin/users/:id/roles", h.SetUserRoles)
+
+	req, _ := http.NewRequest("PUT", "/admin/users/someone/roles", strings.NewReader(`{"roles":["superuser"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("PUT", "/admin/users/no-such-user/roles", strings.NewReader(`{"roles":["admin"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
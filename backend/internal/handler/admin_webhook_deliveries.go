@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminWebhookDeliveriesHandler struct {
+	uc *usecase.WebhookDeliveriesUsecase
+}
+
+func NewAdminWebhookDeliveriesHandler(uc *usecase.WebhookDeliveriesUsecase) *AdminWebhookDeliveriesHandler {
+	return &AdminWebhookDeliveriesHandler{uc: uc}
+}
+
+// GET /admin/webhook-deliveries?status=failed
+func (h *AdminWebhookDeliveriesHandler) ListDeliveries(c *gin.Context) {
+	page, ok := parsePageRequest(c, "AdminListWebhookDeliveries")
+	if !ok {
+		return
+	}
+
+	res, err := h.uc.ListDeliveries(c.Request.Context(), c.Query("status"), page)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidWebhookDeliveryStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		case errors.Is(err, usecase.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		default:
+			log.Printf("[AdminListWebhookDeliveries] InternalServerError: err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhook deliveries"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": res.Deliveries, "next_cursor": res.NextCursor})
+}
+
+// GET /admin/webhook-deliveries/:id
+func (h *AdminWebhookDeliveriesHandler) GetDelivery(c *gin.Context) {
+	id, ok := parseWebhookDeliveryID(c, "AdminGetWebhookDelivery")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.uc.GetDelivery(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrWebhookDeliveryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook delivery not found"})
+			return
+		}
+		log.Printf("[AdminGetWebhookDelivery] InternalServerError: id=%d, err=%v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get webhook delivery"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"delivery": delivery})
+}
+
+// POST /admin/webhook-deliveries/:id/replay（処理に失敗したものだけ再処理できる）
+func (h *AdminWebhookDeliveriesHandler) ReplayDelivery(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+	id, ok := parseWebhookDeliveryID(c, "AdminReplayWebhookDelivery")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.uc.ReplayDelivery(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrWebhookDeliveryNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook delivery not found"})
+		case errors.Is(err, usecase.ErrWebhookDeliveryNotReplayable):
+			c.JSON(http.StatusConflict, gin.H{"error": "webhook delivery is not failed"})
+		default:
+			log.Printf("[AdminReplayWebhookDelivery] InternalServerError: id=%d, err=%v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to replay webhook delivery"})
+		}
+		return
+	}
+	log.Printf("[AdminReplayWebhookDelivery] replay enqueued. userID=%s, id=%d", userID, id)
+	c.JSON(http.StatusAccepted, gin.H{"delivery": delivery})
+}
+
+func parseWebhookDeliveryID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.Printf("[%s] BadRequest: invalid id. id=%s, err=%v", name, c.Param("id"), err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook delivery id"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayPayWebhook_FailedDeliveryCanBeReplayed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("PAYPAY_WEBHOOK_SECRET", "testsecret")
+	dbConn, q := setupTestDB(t)
+
+	_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified", "createdAt", "updatedAt") VALUES ($1, $2, $3, $4, now(), now()) ON CONFLICT (id) DO NOTHING`, "replay-user", "replay-user", "replay@example.com", true)
+	require.NoError(t, err)
+
+	uc := usecase.NewWebhookDeliveriesUsecase(dbConn, q)
+	h := NewAdminWebhookDeliveriesHandler(uc)
+	r := gin.New()
+	r.Use(MockOptionalAuth("replay-user"))
+	r.POST("/api/paypay/webhook", NewPayPayWebhookHandler(dbConn, q).Handle)
+	r.GET("/admin/webhook-deliveries", h.ListDeliveries)
+	r.GET("/admin/webhook-deliveries/:id", h.GetDelivery)
+	r.POST("/admin/webhook-deliveries/:id/replay", h.ReplayDelivery)
+
+	// まだtopupが無いので処理に失敗する
+	body := `{"notification_type":"Transaction","merchant_id":"test-merchant","order_id":"replay-payment-id","merchant_order_id":"replay-merchant-id","order_amount":"100","state":"COMPLETED"}`
+	w := sendPayPayWebhook(t, r, body)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// 受信と同時に処理ジョブが登録される
+	var jobCount int
+	require.NoError(t, dbConn.QueryRow(`SELECT COUNT(*) FROM jobs WHERE kind = $1`, usecase.JobWebhookProcessDelivery).Scan(&jobCount))
+	assert.Equal(t, 1, jobCount)
+
+	// 同じイベントの再送は保存しない
+	w = sendPayPayWebhook(t, r, body)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	processWebhookDeliveries(t, dbConn, q)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/webhook-deliveries?status=failed", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list struct {
+		Deliveries []usecase.WebhookDelivery `json:"deliveries"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Deliveries, 1)
+	delivery := list.Deliveries[0]
+	assert.Equal(t, "Transaction:replay-payment-id:COMPLETED", delivery.DedupeKey)
+	assert.Equal(t, int32(1), delivery.Attempts)
+	require.NotNil(t, delivery.LastError)
+	assert.Contains(t, *delivery.LastError, "topup not found")
+
+	replay := func(id int64) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/admin/webhook-deliveries/%d/replay", id), nil)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusNotFound, replay(delivery.ID+1000))
+
+	// topupを作ってから再処理すると権限が付与される
+	var programID int64
+	require.NoError(t, dbConn.QueryRow(`INSERT INTO programs (title, video_path, is_limited_release, price) VALUES ($1, $2, $3, $4) RETURNING id`,
+		"replay-program", "/video/replay.mp4", true, 100).Scan(&programID))
+	_, err = dbConn.Exec(`INSERT INTO payments (provider, user_id, merchant_payment_id, amount_yen, status, program_id) VALUES ('paypay', $1, $2, $3, 'AWAITING', $4)`, "replay-user", "replay-merchant-id", 100, programID)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusAccepted, replay(delivery.ID))
+	processWebhookDeliveries(t, dbConn, q)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/admin/webhook-deliveries/%d", delivery.ID), nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var got struct {
+		Delivery usecase.WebhookDelivery `json:"delivery"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "succeeded", got.Delivery.Status)
+	assert.Equal(t, int32(2), got.Delivery.Attempts)
+	assert.Nil(t, got.Delivery.LastError)
+	assert.NotNil(t, got.Delivery.ProcessedAt)
+	assert.True(t, isPermitted(t, dbConn, "replay-user", programID))
+
+	// 成功したものは再処理できない
+	assert.Equal(t, http.StatusConflict, replay(delivery.ID))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/admin/webhook-deliveries?status=unknown", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type APITokensHandler struct {
+	tokens *usecase.APITokensUsecase
+}
+
+type createAPITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expires_in_days"`
+}
+
+func NewAPITokensHandler(tokens *usecase.APITokensUsecase) *APITokensHandler {
+	return &APITokensHandler{tokens: tokens}
+}
+
+// POST /me/tokens
+func (h *APITokensHandler) CreateToken(c *gin.Context) {
+	userID, err := middleware.UserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req createAPITokenRequest
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	token, err := h.tokens.CreateToken(c.Request.Context(), userID, usecase.CreateAPITokenInput{
+		Name:          req.Name,
+		Scopes:        req.Scopes,
+		ExpiresInDays: req.ExpiresInDays,
+	})
+	if err != nil {
+		if errors.Is(err, usecase.ErrAPITokenNameRequired) ||
+			errors.Is(err, usecase.ErrInvalidAPITokenScope) ||
+			errors.Is(err, usecase.ErrInvalidAPITokenExpiry) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("[CreateAPIToken] InternalServerError: userID=%s, err=%v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		return
+	}
+	log.Printf("[CreateAPIToken] created. userID=%s, tokenID=%d, scopes=%v", userID, token.ID, token.Scopes)
+	c.JSON(http.StatusCreated, gin.H{"token": token})
+}
+
+// GET /me/tokens
+func (h *APITokensHandler) ListTokens(c *gin.Context) {
+	userID, err := middleware.UserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tokens, err := h.tokens.ListTokens(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("[ListAPITokens] InternalServerError: userID=%s, err=%v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// DELETE /me/tokens/:id
+func (h *APITokensHandler) RevokeToken(c *gin.Context) {
+	userID, err := middleware.UserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	idStr := c.Param("id")
+	tokenID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || tokenID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.tokens.RevokeToken(c.Request.Context(), userID, tokenID); err != nil {
+		if errors.Is(err, usecase.ErrAPITokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
+			return
+		}
+		log.Printf("[RevokeAPIToken] InternalServerError: userID=%s, tokenID=%d, err=%v", userID, tokenID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke token"})
+		return
+	}
+	log.Printf("[RevokeAPIToken] revoked. userID=%s, tokenID=%d", userID, tokenID)
+	c.Status(http.StatusNoContent)
+}
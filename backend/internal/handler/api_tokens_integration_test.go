@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/chan-shizu/SZer/internal/authz"
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// noSessionVerifier はCookieセッションを常に無効とする（Bearer認証のテスト用）
+type noSessionVerifier struct{}
+
+func (noSessionVerifier) VerifySession(ctx context.Context, cookieHeader string) (string, error) {
+	return "", middleware.ErrNoSession
+}
+
+// =============================================================================
+// POST/GET/DELETE /me/tokens
+// =============================================================================
+
+func TestAPITokens_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	userID := "token-user"
+	_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified") VALUES ($1, $2, $3, true)`,
+		userID, userID, userID+"@example.com")
+	if err != nil {
+		t.Fatalf("failed to insert test user: %v", err)
+	}
+
+	h := NewAPITokensHandler(usecase.NewAPITokensUsecase(q))
+	r := gin.New()
+	r.Use(MockOptionalAuth(userID))
+	r.POST("/me/tokens", h.CreateToken)
+	r.GET("/me/tokens", h.ListTokens)
+	r.DELETE("/me/tokens/:id", h.RevokeToken)
+
+	// 不正なスコープは400
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/me/tokens", strings.NewReader(`{"name":"cli","scopes":["root"]}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// viewerはadminスコープを発行できない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/me/tokens", strings.NewReader(`{"name":"cli","scopes":["admin"]}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 発行（平文トークンはこのレスポンスでのみ返る）
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/me/tokens", strings.NewReader(`{"name":"cli","scopes":["read"],"expires_in_days":30}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Token struct {
+			ID          int64    `json:"id"`
+			Token       string   `json:"token"`
+			TokenPrefix string   `json:"token_prefix"`
+			Scopes      []string `json:"scopes"`
+			ExpiresAt   *string  `json:"expires_at"`
+		} `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.Token.Token, authz.APITokenPrefix))
+	assert.True(t, strings.HasPrefix(created.Token.Token, created.Token.TokenPrefix))
+	assert.Equal(t, []string{"read"}, created.Token.Scopes)
+	assert.NotNil(t, created.Token.ExpiresAt)
+
+	// DBにはハッシュのみ保存される
+	var storedHash string
+	err = dbConn.QueryRow(`SELECT token_hash FROM api_tokens WHERE id = $1`, created.Token.ID).Scan(&storedHash)
+	assert.NoError(t, err)
+	assert.Equal(t, authz.HashAPIToken(created.Token.Token), storedHash)
+
+	// 一覧には平文トークンを含まない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/tokens", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), created.Token.Token)
+	assert.Contains(t, w.Body.String(), created.Token.TokenPrefix)
+
+	// Bearerトークンで認証できる。readスコープのみなのでPOSTは403
+	auth := middleware.NewAuthenticator(noSessionVerifier{}, q)
+	br := gin.New()
+	br.GET("/me/ping", auth.RequireAuth(), func(c *gin.Context) {
+		uid, _ := middleware.UserIDFromContext(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": uid})
+	})
+	br.POST("/me/ping", auth.RequireAuth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/ping", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Token.Token)
+	br.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), userID)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/me/ping", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Token.Token)
+	br.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 失効後は401
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/me/tokens/"+strconv.FormatInt(created.Token.ID, 10), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/ping", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Token.Token)
+	br.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 失効済みの再失効は404
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/me/tokens/"+strconv.FormatInt(created.Token.ID, 10), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -27,13 +28,21 @@ func (h *CommentsHandler) ListComments(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid program id"})
 		return
 	}
-	comments, err := h.uc.ListCommentsByProgramID(c, programID)
+	page, ok := parsePageRequest(c, "コメント一覧取得")
+	if !ok {
+		return
+	}
+	result, err := h.uc.ListCommentsByProgramID(c, programID, page)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
 		log.Printf("[コメント一覧取得] programID=%d err=%v", programID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get comments"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"comments": comments})
+	c.JSON(http.StatusOK, gin.H{"comments": result.Comments, "next_cursor": result.NextCursor})
 }
 
 // POST /programs/:id/comments
@@ -67,6 +76,26 @@ func (h *CommentsHandler) PostComment(c *gin.Context) {
 	       c.JSON(http.StatusOK, gin.H{"comment": comment})
 }
 
+// DELETE /admin/comments/:id（モデレーター以上）
+func (h *CommentsHandler) DeleteComment(c *gin.Context) {
+	commentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.Printf("[コメント削除] commentIDパースエラー: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment id"})
+		return
+	}
+	if err := h.uc.DeleteComment(c, commentID); err != nil {
+		if errors.Is(err, usecase.ErrCommentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+			return
+		}
+		log.Printf("[コメント削除] commentID=%d err=%v", commentID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete comment"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
 func sqlNullString(s string) sql.NullString {
        if s == "" {
 	       return sql.NullString{Valid: false}
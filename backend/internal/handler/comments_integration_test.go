@@ -107,6 +107,54 @@ func TestListComments_Empty_Integration(t *testing.T) {
 	assert.Empty(t, resp.Comments)
 }
 
+func TestListComments_Pagination_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	var programID int64
+	err := dbConn.QueryRow(`INSERT INTO programs (title, video_path) VALUES ($1, $2) RETURNING id`,
+		"paged-comment-program", "/video/pagedcomment.mp4").Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		_, err = dbConn.Exec(`INSERT INTO comments (program_id, content) VALUES ($1, $2)`, programID, fmt.Sprintf("comment-%d", i))
+		if err != nil {
+			t.Fatalf("failed to insert comment: %v", err)
+		}
+	}
+
+	h := NewCommentsHandler(q)
+	r := gin.New()
+	r.Use(MockOptionalAuth(""))
+	r.GET("/programs/:id/comments", h.ListComments)
+
+	var resp struct {
+		Comments   []map[string]interface{} `json:"comments"`
+		NextCursor *string                  `json:"next_cursor"`
+	}
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/programs/%d/comments?limit=2", programID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Comments, 2)
+	assert.Equal(t, "comment-3", resp.Comments[0]["content"])
+	if !assert.NotNil(t, resp.NextCursor) {
+		return
+	}
+
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/programs/%d/comments?limit=2&cursor=%s", programID, *resp.NextCursor), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp.NextCursor = nil
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Comments, 1)
+	assert.Equal(t, "comment-1", resp.Comments[0]["content"])
+	assert.Nil(t, resp.NextCursor)
+}
+
 func TestListComments_InvalidID_Integration(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	_, q := setupTestDB(t)
@@ -256,3 +304,40 @@ func TestPostComment_InvalidProgramID_Integration(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &resp)
 	assert.Equal(t, "invalid program id", resp["error"])
 }
+
+// =============================================================================
+// DELETE /admin/comments/:id (DeleteComment)
+// =============================================================================
+
+func TestDeleteComment_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	var programID int64
+	err := dbConn.QueryRow(`INSERT INTO programs (title, video_path) VALUES ($1, $2) RETURNING id`,
+		"moderated-program", "/video/moderated.mp4").Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+	var commentID int64
+	err = dbConn.QueryRow(`INSERT INTO comments (program_id, content) VALUES ($1, $2) RETURNING id`,
+		programID, "不適切なコメント").Scan(&commentID)
+	if err != nil {
+		t.Fatalf("failed to insert comment: %v", err)
+	}
+
+	h := NewCommentsHandler(q)
+	r := gin.New()
+	r.Use(MockOptionalAuth("moderator-user"))
+	r.DELETE("/admin/comments/:id", h.DeleteComment)
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/admin/comments/%d", commentID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// 削除済みなので2回目は404
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/payments"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type couponCheckoutResponse struct {
+	MerchantPaymentID string `json:"merchant_payment_id"`
+	AmountYen         int32  `json:"amount_yen"`
+	DiscountYen       int32  `json:"discount_yen"`
+	Granted           bool   `json:"granted"`
+}
+
+func postAdminTopup(t *testing.T, r *gin.Engine, merchantPaymentID, action, body string) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/paypay/topups/"+merchantPaymentID+"/"+action, strings.NewReader(body))
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func checkoutWithCoupon(t *testing.T, r *gin.Engine, programID int64, code string) (int, couponCheckoutResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"program_id": %d, "provider": "fake", "coupon_code": %q}`, programID, code)
+	req, _ := http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(body))
+	r.ServeHTTP(w, req)
+	var res couponCheckoutResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	}
+	return w.Code, res
+}
+
+// =============================================================================
+// 割引コード
+// =============================================================================
+
+func TestCouponCheckout_Discounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	coupons := usecase.NewCouponsUsecase(dbConn, q)
+	uc := usecase.NewPaymentsUsecaseWithProviders(dbConn, q, nil, payments.NewRegistry(payments.NewFakeProvider()))
+	programID := seedPurchasableProgram(t, dbConn, "coupon-buyer", 1000)
+	r := newPayPayTestRouter(uc, "coupon-buyer")
+
+	// 定率割引（コードは大文字小文字を区別しない）
+	_, err := coupons.CreateCoupon(ctx, usecase.CouponInput{Code: "off30", DiscountType: usecase.CouponDiscountPercent, DiscountValue: 30})
+	require.NoError(t, err)
+	code, res := checkoutWithCoupon(t, r, programID, "OFF30")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int32(700), res.AmountYen)
+	assert.Equal(t, int32(300), res.DiscountYen)
+	assert.False(t, res.Granted)
+
+	payment, err := q.GetPaymentByMerchantPaymentID(ctx, res.MerchantPaymentID)
+	require.NoError(t, err)
+	assert.Equal(t, int32(700), payment.AmountYen)
+	assert.Equal(t, int32(300), payment.DiscountYen)
+	assert.True(t, payment.CouponID.Valid)
+	paidID := payment.MerchantPaymentID
+
+	// 価格以上の定額割引は決済サービスを通さずに閲覧権限を付与する
+	_, err = coupons.CreateCoupon(ctx, usecase.CouponInput{Code: "FREE", DiscountType: usecase.CouponDiscountFixed, DiscountValue: 5000})
+	require.NoError(t, err)
+	code, res = checkoutWithCoupon(t, r, programID, "free")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int32(0), res.AmountYen)
+	assert.Equal(t, int32(1000), res.DiscountYen)
+	assert.True(t, res.Granted)
+	assert.True(t, isPermitted(t, dbConn, "coupon-buyer", programID))
+
+	payment, err = q.GetPaymentByMerchantPaymentID(ctx, res.MerchantPaymentID)
+	require.NoError(t, err)
+	assert.Equal(t, usecase.PaymentProviderFree, payment.Provider)
+	assert.Equal(t, "COMPLETED", payment.Status)
+	assert.Equal(t, []string{"CREATED", "COMPLETED"}, topupEvents(t, q, res.MerchantPaymentID))
+
+	// 0円の決済は返金できないが、管理者が取り消すと閲覧権限も取り消す
+	assert.Equal(t, http.StatusConflict, postAdminTopup(t, r, res.MerchantPaymentID, "refunds", `{}`))
+	assert.Equal(t, http.StatusOK, postAdminTopup(t, r, res.MerchantPaymentID, "revoke", ""))
+	assert.False(t, isPermitted(t, dbConn, "coupon-buyer", programID))
+	assert.Equal(t, []string{"CREATED", "COMPLETED", "REFUNDED"}, topupEvents(t, q, res.MerchantPaymentID))
+	assert.Equal(t, http.StatusConflict, postAdminTopup(t, r, res.MerchantPaymentID, "revoke", ""))
+	// 決済サービスを通した決済は取り消しではなく返金する
+	assert.Equal(t, http.StatusConflict, postAdminTopup(t, r, paidID, "revoke", ""))
+
+	// 存在しないコード
+	code, _ = checkoutWithCoupon(t, r, seedPurchasableProgram(t, dbConn, "coupon-buyer", 1000), "UNKNOWN")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestCouponCheckout_FreeWithoutProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	// 決済サービスの設定が無くても全額割引なら購入できる
+	t.Setenv("PAYPAY_API_KEY", "")
+	uc := usecase.NewPaymentsUsecase(dbConn, q, nil)
+	programID := seedPurchasableProgram(t, dbConn, "free-buyer", 500)
+	r := newPayPayTestRouter(uc, "free-buyer")
+
+	_, err := usecase.NewCouponsUsecase(dbConn, q).CreateCoupon(ctx, usecase.CouponInput{Code: "ALL", DiscountType: usecase.CouponDiscountPercent, DiscountValue: 100})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(fmt.Sprintf(`{"program_id": %d, "coupon_code": "ALL"}`, programID)))
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, isPermitted(t, dbConn, "free-buyer", programID))
+
+	var created couponCheckoutResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	res := confirm(t, r, created.MerchantPaymentID)
+	assert.Equal(t, "COMPLETED", res.Status)
+}
+
+func TestCouponCheckout_Limits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	coupons := usecase.NewCouponsUsecase(dbConn, q)
+	uc := usecase.NewPaymentsUsecaseWithProviders(dbConn, q, nil, payments.NewRegistry(payments.NewFakeProvider()))
+	r := newPayPayTestRouter(uc, "limit-buyer")
+	other := newPayPayTestRouter(uc, "limit-other")
+
+	one := int32(1)
+	two := int32(2)
+	_, err := coupons.CreateCoupon(ctx, usecase.CouponInput{Code: "LIMITED", DiscountType: usecase.CouponDiscountFixed, DiscountValue: 100, MaxRedemptions: &two, MaxRedemptionsPerUser: &one})
+	require.NoError(t, err)
+
+	code, _ := checkoutWithCoupon(t, r, seedPurchasableProgram(t, dbConn, "limit-buyer", 500), "LIMITED")
+	require.Equal(t, http.StatusOK, code)
+	// ユーザーごとの上限
+	code, _ = checkoutWithCoupon(t, r, seedPurchasableProgram(t, dbConn, "limit-buyer", 500), "LIMITED")
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, _ = checkoutWithCoupon(t, other, seedPurchasableProgram(t, dbConn, "limit-other", 500), "LIMITED")
+	require.Equal(t, http.StatusOK, code)
+	// 全体の上限
+	third := newPayPayTestRouter(uc, "limit-third")
+	code, _ = checkoutWithCoupon(t, third, seedPurchasableProgram(t, dbConn, "limit-third", 500), "LIMITED")
+	assert.Equal(t, http.StatusConflict, code)
+
+	// 有効期間外
+	past := time.Now().Add(-48 * time.Hour)
+	ended := time.Now().Add(-time.Hour)
+	_, err = coupons.CreateCoupon(ctx, usecase.CouponInput{Code: "ENDED", DiscountType: usecase.CouponDiscountFixed, DiscountValue: 100, StartsAt: &past, EndsAt: &ended})
+	require.NoError(t, err)
+	code, _ = checkoutWithCoupon(t, third, seedPurchasableProgram(t, dbConn, "limit-third", 500), "ENDED")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestCouponCheckout_ExpiredPendingNotCounted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	uc := usecase.NewPaymentsUsecaseWithProviders(dbConn, q, nil, payments.NewRegistry(payments.NewFakeProvider()))
+	one := int32(1)
+	_, err := usecase.NewCouponsUsecase(dbConn, q).CreateCoupon(ctx, usecase.CouponInput{Code: "ONCE", DiscountType: usecase.CouponDiscountFixed, DiscountValue: 100, MaxRedemptions: &one})
+	require.NoError(t, err)
+
+	code, res := checkoutWithCoupon(t, newPayPayTestRouter(uc, "abandon-buyer"), seedPurchasableProgram(t, dbConn, "abandon-buyer", 500), "ONCE")
+	require.Equal(t, http.StatusOK, code)
+
+	// 支払い待ちの決済は有効期限内なら利用回数に数える
+	other := newPayPayTestRouter(uc, "abandon-other")
+	otherProgramID := seedPurchasableProgram(t, dbConn, "abandon-other", 500)
+	code, _ = checkoutWithCoupon(t, other, otherProgramID, "ONCE")
+	assert.Equal(t, http.StatusConflict, code)
+
+	// 支払われないまま期限を過ぎた決済は、照合でEXPIREDになる前でも数えない
+	_, err = dbConn.Exec(`UPDATE payments SET checkout_expires_at = now() - interval '1 minute' WHERE merchant_payment_id = $1`, res.MerchantPaymentID)
+	require.NoError(t, err)
+	code, _ = checkoutWithCoupon(t, other, otherProgramID, "ONCE")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestCouponCheckout_Scoping(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	coupons := usecase.NewCouponsUsecase(dbConn, q)
+	uc := usecase.NewPaymentsUsecaseWithProviders(dbConn, q, nil, payments.NewRegistry(payments.NewFakeProvider()))
+	r := newPayPayTestRouter(uc, "scope-buyer")
+
+	targetProgramID := seedPurchasableProgram(t, dbConn, "scope-buyer", 500)
+	taggedProgramID := seedPurchasableProgram(t, dbConn, "scope-buyer", 500)
+	otherProgramID := seedPurchasableProgram(t, dbConn, "scope-buyer", 500)
+	var tagID int64
+	require.NoError(t, dbConn.QueryRow(`INSERT INTO category_tags (name) VALUES ('coupon-tag') RETURNING id`).Scan(&tagID))
+	_, err := dbConn.Exec(`INSERT INTO program_category_tags (program_id, tag_id) VALUES ($1, $2)`, taggedProgramID, tagID)
+	require.NoError(t, err)
+
+	coupon, err := coupons.CreateCoupon(ctx, usecase.CouponInput{Code: "SCOPED", DiscountType: usecase.CouponDiscountFixed, DiscountValue: 100, ProgramIDs: []int64{targetProgramID}, TagIDs: []int64{tagID}})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{targetProgramID}, coupon.ProgramIDs)
+	assert.Equal(t, []int64{tagID}, coupon.TagIDs)
+
+	code, _ := checkoutWithCoupon(t, r, targetProgramID, "SCOPED")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = checkoutWithCoupon(t, r, taggedProgramID, "SCOPED")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = checkoutWithCoupon(t, r, otherProgramID, "SCOPED")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// コードと割引内容は変更できない
+	_, err = coupons.UpdateCoupon(ctx, coupon.ID, usecase.CouponUpdateInput{CouponInput: usecase.CouponInput{DiscountType: usecase.CouponDiscountPercent}})
+	assert.ErrorIs(t, err, usecase.ErrInvalidCoupon)
+
+	inactive := false
+	updated, err := coupons.UpdateCoupon(ctx, coupon.ID, usecase.CouponUpdateInput{CouponInput: usecase.CouponInput{IsActive: &inactive}})
+	require.NoError(t, err)
+	assert.False(t, updated.IsActive)
+	assert.Equal(t, []int64{targetProgramID}, updated.ProgramIDs)
+	assert.Equal(t, int32(2), updated.RedemptionCount)
+
+	code, _ = checkoutWithCoupon(t, r, otherProgramID, "SCOPED")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestAdminUpdateCoupon_PartialKeepsScopeAndLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	coupons := usecase.NewCouponsUsecase(dbConn, q)
+	h := NewAdminCouponsHandler(coupons)
+	r := gin.New()
+	r.PUT("/admin/coupons/:id", h.UpdateCoupon)
+
+	programID := seedPurchasableProgram(t, dbConn, "partial-coupon", 500)
+	var tagID int64
+	require.NoError(t, dbConn.QueryRow(`INSERT INTO category_tags (name) VALUES ('partial-coupon-tag') RETURNING id`).Scan(&tagID))
+	endsAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	limit, perUser := int32(10), int32(1)
+	inactive := false
+	coupon, err := coupons.CreateCoupon(ctx, usecase.CouponInput{
+		Code: "PARTIAL", DiscountType: usecase.CouponDiscountFixed, DiscountValue: 100,
+		EndsAt: &endsAt, MaxRedemptions: &limit, MaxRedemptionsPerUser: &perUser, IsActive: &inactive,
+		ProgramIDs: []int64{programID}, TagIDs: []int64{tagID},
+	})
+	require.NoError(t, err)
+
+	update := func(body string) usecase.Coupon {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/admin/coupons/%d", coupon.ID), strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var res struct {
+			Coupon usecase.Coupon `json:"coupon"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res.Coupon
+	}
+
+	// 有効化だけを指定しても対象・期間・上限は変わらない
+	updated := update(`{"is_active": true}`)
+	assert.True(t, updated.IsActive)
+	assert.Equal(t, []int64{programID}, updated.ProgramIDs)
+	assert.Equal(t, []int64{tagID}, updated.TagIDs)
+	require.NotNil(t, updated.EndsAt)
+	assert.True(t, endsAt.Equal(*updated.EndsAt))
+	require.NotNil(t, updated.MaxRedemptions)
+	assert.Equal(t, limit, *updated.MaxRedemptions)
+	require.NotNil(t, updated.MaxRedemptionsPerUser)
+	assert.Equal(t, perUser, *updated.MaxRedemptionsPerUser)
+
+	// nullを指定した項目だけ外す
+	updated = update(`{"ends_at": null, "tag_ids": []}`)
+	assert.True(t, updated.IsActive)
+	assert.Nil(t, updated.EndsAt)
+	assert.Empty(t, updated.TagIDs)
+	assert.Equal(t, []int64{programID}, updated.ProgramIDs)
+	require.NotNil(t, updated.MaxRedemptions)
+	assert.Equal(t, limit, *updated.MaxRedemptions)
+}
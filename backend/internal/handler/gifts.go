@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type GiftsHandler struct {
+	gifts *usecase.GiftsUsecase
+}
+
+func NewGiftsHandler(gifts *usecase.GiftsUsecase) *GiftsHandler {
+	return &GiftsHandler{gifts: gifts}
+}
+
+type redeemGiftRequest struct {
+	Code string `json:"code"`
+}
+
+// GET /me/gifts（贈った贈り物と受け取った贈り物）
+func (h *GiftsHandler) ListMyGifts(c *gin.Context) {
+	userID, err := middleware.UserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	gifts, err := h.gifts.ListMyGifts(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("[ListMyGifts] InternalServerError: userID=%s, err=%v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list gifts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sent": gifts.Sent, "received": gifts.Received})
+}
+
+// POST /me/gifts/redeem
+func (h *GiftsHandler) RedeemGift(c *gin.Context) {
+	userID, err := middleware.UserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req redeemGiftRequest
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	gift, err := h.gifts.RedeemGift(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrGiftNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "gift not found"})
+		case errors.Is(err, usecase.ErrGiftAlreadyRedeemed):
+			c.JSON(http.StatusConflict, gin.H{"error": "gift already redeemed"})
+		case errors.Is(err, usecase.ErrGiftRevoked):
+			c.JSON(http.StatusGone, gin.H{"error": "gift revoked"})
+		case errors.Is(err, usecase.ErrOwnGift):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot redeem own gift"})
+		case errors.Is(err, usecase.ErrAlreadyPurchased):
+			c.JSON(http.StatusConflict, gin.H{"error": "already purchased"})
+		default:
+			log.Printf("[RedeemGift] InternalServerError: userID=%s, err=%v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to redeem gift"})
+		}
+		return
+	}
+	log.Printf("[RedeemGift] redeemed. userID=%s, giftID=%d, programID=%d", userID, gift.ID, gift.ProgramID)
+	c.JSON(http.StatusOK, gin.H{"gift": gift})
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/payments"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGiftsTestRouter(uc *usecase.GiftsUsecase, userID string) *gin.Engine {
+	h := NewGiftsHandler(uc)
+	r := gin.New()
+	r.Use(MockOptionalAuth(userID))
+	r.GET("/me/gifts", h.ListMyGifts)
+	r.POST("/me/gifts/redeem", h.RedeemGift)
+	return r
+}
+
+func redeemGift(r *gin.Engine, code string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/me/gifts/redeem", strings.NewReader(fmt.Sprintf(`{"code": %q}`, code)))
+	r.ServeHTTP(w, req)
+	return w
+}
+
+type myGiftsResponse struct {
+	Sent []struct {
+		Code   string `json:"code"`
+		Status string `json:"status"`
+	} `json:"sent"`
+	Received []struct {
+		ProgramID int64  `json:"program_id"`
+		Status    string `json:"status"`
+	} `json:"received"`
+}
+
+func listMyGifts(t *testing.T, r *gin.Engine) myGiftsResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/me/gifts", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res myGiftsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return res
+}
+
+// checkoutGift は贈り物の決済を作って支払いを完了させ、受け取り用のコードを返す
+func checkoutGift(t *testing.T, r *gin.Engine, fake *payments.FakeProvider, programID int64) (string, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(fmt.Sprintf(`{"program_id": %d, "provider": "fake", "gift": true}`, programID)))
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var created struct {
+		MerchantPaymentID string `json:"merchant_payment_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	require.NoError(t, fake.SetStatus(created.MerchantPaymentID, payments.StatusCompleted))
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/paypay/payments/"+created.MerchantPaymentID, nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var confirmed struct {
+		Status   string `json:"status"`
+		Granted  bool   `json:"granted"`
+		GiftCode string `json:"gift_code"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &confirmed))
+	require.Equal(t, "COMPLETED", confirmed.Status)
+	assert.False(t, confirmed.Granted)
+	require.NotEmpty(t, confirmed.GiftCode)
+	return created.MerchantPaymentID, confirmed.GiftCode
+}
+
+// =============================================================================
+// 贈り物
+// =============================================================================
+
+func TestGift_CheckoutAndRedeem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	fake := payments.NewFakeProvider()
+	uc := usecase.NewPaymentsUsecaseWithProviders(dbConn, q, nil, payments.NewRegistry(fake))
+	gifts := usecase.NewGiftsUsecase(dbConn, q)
+	programID := seedPurchasableProgram(t, dbConn, "gift-buyer", 600)
+	seedPurchasableProgram(t, dbConn, "gift-recipient", 600)
+	buyer := newPayPayTestRouter(uc, "gift-buyer")
+
+	_, code := checkoutGift(t, buyer, fake, programID)
+	// 購入者には付与しない
+	assert.False(t, isPermitted(t, dbConn, "gift-buyer", programID))
+
+	buyerGifts := newGiftsTestRouter(gifts, "gift-buyer")
+	recipient := newGiftsTestRouter(gifts, "gift-recipient")
+
+	sent := listMyGifts(t, buyerGifts).Sent
+	require.Len(t, sent, 1)
+	assert.Equal(t, code, sent[0].Code)
+	assert.Equal(t, usecase.GiftRedeemable, sent[0].Status)
+
+	// 自分の贈り物は受け取れない
+	assert.Equal(t, http.StatusBadRequest, redeemGift(buyerGifts, code).Code)
+	assert.Equal(t, http.StatusNotFound, redeemGift(recipient, "NOSUCHCODE").Code)
+
+	// コードは大文字小文字を区別しない
+	w := redeemGift(recipient, strings.ToLower(code))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, isPermitted(t, dbConn, "gift-recipient", programID))
+
+	// 一度しか受け取れない
+	assert.Equal(t, http.StatusConflict, redeemGift(recipient, code).Code)
+
+	assert.Equal(t, usecase.GiftRedeemed, listMyGifts(t, buyerGifts).Sent[0].Status)
+	received := listMyGifts(t, recipient).Received
+	require.Len(t, received, 1)
+	assert.Equal(t, programID, received[0].ProgramID)
+	assert.Equal(t, usecase.GiftRedeemed, received[0].Status)
+}
+
+func TestGift_ConcurrentRedeem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	fake := payments.NewFakeProvider()
+	uc := usecase.NewPaymentsUsecaseWithProviders(dbConn, q, nil, payments.NewRegistry(fake))
+	gifts := usecase.NewGiftsUsecase(dbConn, q)
+	programID := seedPurchasableProgram(t, dbConn, "race-buyer", 600)
+	_, code := checkoutGift(t, newPayPayTestRouter(uc, "race-buyer"), fake, programID)
+
+	// 同じコードを同時に受け取っても付与されるのは一人だけ
+	const n = 5
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		userID := fmt.Sprintf("race-recipient-%d", i)
+		seedPurchasableProgram(t, dbConn, userID, 100)
+		wg.Add(1)
+		go func(i int, userID string) {
+			defer wg.Done()
+			_, errs[i] = gifts.RedeemGift(context.Background(), userID, code)
+		}(i, userID)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, usecase.ErrGiftAlreadyRedeemed)
+	}
+	assert.Equal(t, 1, succeeded)
+
+	var permitted int
+	require.NoError(t, dbConn.QueryRow(`SELECT COUNT(*) FROM permitted_program_users WHERE program_id = $1`, programID).Scan(&permitted))
+	assert.Equal(t, 1, permitted)
+}
+
+func TestGift_RefundRevokes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	fake := payments.NewFakeProvider()
+	uc := usecase.NewPaymentsUsecaseWithProviders(dbConn, q, nil, payments.NewRegistry(fake))
+	gifts := usecase.NewGiftsUsecase(dbConn, q)
+	programID := seedPurchasableProgram(t, dbConn, "revoke-buyer", 600)
+	seedPurchasableProgram(t, dbConn, "revoke-recipient", 600)
+	buyer := newPayPayTestRouter(uc, "revoke-buyer")
+
+	mpid, code := checkoutGift(t, buyer, fake, programID)
+	recipient := newGiftsTestRouter(gifts, "revoke-recipient")
+	require.Equal(t, http.StatusOK, redeemGift(recipient, code).Code)
+	require.True(t, isPermitted(t, dbConn, "revoke-recipient", programID))
+
+	// 全額返金で受け取った人の閲覧権限も取り消す
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/paypay/topups/"+mpid+"/refunds", strings.NewReader(`{}`))
+	buyer.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.False(t, isPermitted(t, dbConn, "revoke-recipient", programID))
+	assert.Equal(t, usecase.GiftRevoked, listMyGifts(t, recipient).Received[0].Status)
+
+	// 受け取る前に返金された贈り物は受け取れない
+	mpid, code = checkoutGift(t, buyer, fake, programID)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/paypay/topups/"+mpid+"/refunds", strings.NewReader(`{}`))
+	buyer.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusGone, redeemGift(newGiftsTestRouter(gifts, "revoke-other"), code).Code)
+}
+
+func TestGift_RefundRestoresRental(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	fake := payments.NewFakeProvider()
+	uc := usecase.NewPaymentsUsecaseWithProviders(dbConn, q, nil, payments.NewRegistry(fake))
+	programID := seedPurchasableProgram(t, dbConn, "rental-gift-buyer", 600)
+	seedPurchasableProgram(t, dbConn, "rental-gift-recipient", 600)
+	buyer := newPayPayTestRouter(uc, "rental-gift-buyer")
+
+	// 受け取る人はレンタル中
+	var rentalPaymentID int64
+	require.NoError(t, dbConn.QueryRow(`INSERT INTO payments (provider, user_id, merchant_payment_id, amount_yen, status, program_id, purchase_type, rental_duration_hours, credited_at) VALUES ('fake', $1, 'rental-gift-rental', 200, 'COMPLETED', $2, 'rental', 48, now()) RETURNING id`,
+		"rental-gift-recipient", programID).Scan(&rentalPaymentID))
+	var expiresAt time.Time
+	require.NoError(t, dbConn.QueryRow(`INSERT INTO permitted_program_users (user_id, program_id, purchase_type, expires_at, payment_id) VALUES ($1, $2, 'rental', date_trunc('second', now()) + interval '48 hours', $3) RETURNING expires_at`,
+		"rental-gift-recipient", programID, rentalPaymentID).Scan(&expiresAt))
+
+	mpid, code := checkoutGift(t, buyer, fake, programID)
+	require.Equal(t, http.StatusOK, redeemGift(newGiftsTestRouter(usecase.NewGiftsUsecase(dbConn, q), "rental-gift-recipient"), code).Code)
+	var purchaseType string
+	require.NoError(t, dbConn.QueryRow(`SELECT purchase_type FROM permitted_program_users WHERE user_id = $1 AND program_id = $2`, "rental-gift-recipient", programID).Scan(&purchaseType))
+	assert.Equal(t, usecase.PurchaseTypePurchase, purchaseType)
+
+	// 贈り物が返金されても、元のレンタルは期限ごと残る
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/paypay/topups/"+mpid+"/refunds", strings.NewReader(`{}`))
+	buyer.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.True(t, isPermitted(t, dbConn, "rental-gift-recipient", programID))
+
+	var restoredType string
+	var restoredExpiresAt time.Time
+	var restoredPaymentID int64
+	require.NoError(t, dbConn.QueryRow(`SELECT purchase_type, expires_at, payment_id FROM permitted_program_users WHERE user_id = $1 AND program_id = $2`, "rental-gift-recipient", programID).Scan(&restoredType, &restoredExpiresAt, &restoredPaymentID))
+	assert.Equal(t, usecase.PurchaseTypeRental, restoredType)
+	assert.True(t, expiresAt.Equal(restoredExpiresAt))
+	assert.Equal(t, rentalPaymentID, restoredPaymentID)
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentHistoryHandler struct {
+	payments *usecase.PaymentsUsecase
+}
+
+func NewPaymentHistoryHandler(payments *usecase.PaymentsUsecase) *PaymentHistoryHandler {
+	return &PaymentHistoryHandler{payments: payments}
+}
+
+// GET /me/payments（未完了・失敗・返金済みも含む購入履歴）
+func (h *PaymentHistoryHandler) ListMyPayments(c *gin.Context) {
+	userID, err := middleware.UserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	page, ok := parsePageRequest(c, "ListMyPayments")
+	if !ok {
+		return
+	}
+
+	result, err := h.payments.ListMyPayments(c.Request.Context(), userID, page)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		log.Printf("[ListMyPayments] InternalServerError: userID=%s, err=%v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list payments"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"payments": result.Payments, "next_cursor": result.NextCursor})
+}
+
+// GET /me/payments/:merchantPaymentId/receipt（印刷用のHTML）
+func (h *PaymentHistoryHandler) GetReceipt(c *gin.Context) {
+	userID, err := middleware.UserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	merchantPaymentID := strings.TrimSpace(c.Param("merchantPaymentId"))
+	receipt, err := h.payments.GetReceipt(c.Request.Context(), userID, merchantPaymentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrPaymentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		case errors.Is(err, usecase.ErrReceiptNotAvailable):
+			c.JSON(http.StatusConflict, gin.H{"error": "payment is not completed"})
+		default:
+			log.Printf("[GetReceipt] InternalServerError: userID=%s, merchantPaymentID=%s, err=%v", userID, merchantPaymentID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get receipt"})
+		}
+		return
+	}
+
+	var b strings.Builder
+	if err := receiptTemplate.Execute(&b, receipt); err != nil {
+		log.Printf("[GetReceipt] InternalServerError: render failed. merchantPaymentID=%s, err=%v", merchantPaymentID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get receipt"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="receipt-%s.html"`, receipt.ReceiptNumber))
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(b.String()))
+}
+
+// formatYen は金額を3桁区切りにする
+func formatYen(v int32) string {
+	s := strconv.FormatInt(int64(v), 10)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	if neg {
+		s = "-" + s
+	}
+	return "¥" + s
+}
+
+var receiptTemplate = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"yen": formatYen,
+	"jst": func(t time.Time) string { return t.In(usecase.JST).Format("2006年1月2日 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>領収書 {{.ReceiptNumber}}</title>
+<style>
+  body { font-family: sans-serif; max-width: 640px; margin: 40px auto; color: #222; }
+  h1 { text-align: center; letter-spacing: 0.5em; }
+  table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+  th, td { padding: 8px; border-bottom: 1px solid #ccc; text-align: left; }
+  td.amount { text-align: right; }
+  .total { font-size: 1.4em; font-weight: bold; }
+  @media print { .no-print { display: none; } }
+</style>
+</head>
+<body>
+<h1>領収書</h1>
+<p>領収書番号: {{.ReceiptNumber}}<br>お支払い日: {{jst .PaidAt}}</p>
+<p>{{.IssuedTo}} 様</p>
+<p class="total">{{yen .AmountYen}}</p>
+<p>但し {{.Description}} 代として</p>
+<table>
+  <tr><th>小計</th><td class="amount">{{yen .SubtotalYen}}</td></tr>
+  {{if .DiscountYen}}<tr><th>割引</th><td class="amount">-{{yen .DiscountYen}}</td></tr>{{end}}
+  <tr><th>お支払い金額</th><td class="amount">{{yen .AmountYen}}</td></tr>
+  {{if .RefundedAmountYen}}<tr><th>返金済み</th><td class="amount">-{{yen .RefundedAmountYen}}</td></tr>{{end}}
+</table>
+<table>
+  <tr><th>注文番号</th><td>{{.MerchantPaymentID}}</td></tr>
+  <tr><th>お支払い方法</th><td>{{.Provider}}</td></tr>
+  {{if .ProviderPaymentID}}<tr><th>決済ID</th><td>{{.ProviderPaymentID}}</td></tr>{{end}}
+</table>
+<p>SZer</p>
+<button class="no-print" onclick="window.print()">印刷する</button>
+</body>
+</html>
+`))
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chan-shizu/SZer/internal/payments"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPaymentHistoryTestRouter(uc *usecase.PaymentsUsecase, userID string) *gin.Engine {
+	r := newPayPayTestRouter(uc, userID)
+	h := NewPaymentHistoryHandler(uc)
+	r.GET("/me/payments", h.ListMyPayments)
+	r.GET("/me/payments/:merchantPaymentId/receipt", h.GetReceipt)
+	return r
+}
+
+type paymentHistoryResponse struct {
+	Payments []struct {
+		MerchantPaymentID string  `json:"merchant_payment_id"`
+		Status            string  `json:"status"`
+		AmountYen         int32   `json:"amount_yen"`
+		ProgramID         *int64  `json:"program_id"`
+		ProgramTitle      *string `json:"program_title"`
+		ProviderPaymentID *string `json:"provider_payment_id"`
+		ReceiptNumber     *string `json:"receipt_number"`
+	} `json:"payments"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+func listMyPayments(t *testing.T, r *gin.Engine, query string) paymentHistoryResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/me/payments"+query, nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res paymentHistoryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return res
+}
+
+func fakeCheckout(t *testing.T, r *gin.Engine, programID int64) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(fmt.Sprintf(`{"program_id": %d, "provider": "fake"}`, programID)))
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res struct {
+		MerchantPaymentID string `json:"merchant_payment_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return res.MerchantPaymentID
+}
+
+// =============================================================================
+// 購入履歴・領収書
+// =============================================================================
+
+func TestPaymentHistoryAndReceipt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	fake := payments.NewFakeProvider()
+	uc := usecase.NewPaymentsUsecaseWithProviders(dbConn, q, nil, payments.NewRegistry(fake))
+	firstProgramID := seedPurchasableProgram(t, dbConn, "history-buyer", 1200)
+	secondProgramID := seedPurchasableProgram(t, dbConn, "history-buyer", 800)
+	pendingProgramID := seedPurchasableProgram(t, dbConn, "history-buyer", 500)
+	r := newPaymentHistoryTestRouter(uc, "history-buyer")
+
+	first := fakeCheckout(t, r, firstProgramID)
+	require.NoError(t, fake.SetStatus(first, payments.StatusCompleted))
+	confirm(t, r, first)
+	second := fakeCheckout(t, r, secondProgramID)
+	require.NoError(t, fake.SetStatus(second, payments.StatusCompleted))
+	confirm(t, r, second)
+	pending := fakeCheckout(t, r, pendingProgramID)
+
+	// 未完了の決済も新しい順に含める
+	res := listMyPayments(t, r, "")
+	require.Len(t, res.Payments, 3)
+	assert.Nil(t, res.NextCursor)
+	assert.Equal(t, pending, res.Payments[0].MerchantPaymentID)
+	assert.Equal(t, "AWAITING", res.Payments[0].Status)
+	assert.Nil(t, res.Payments[0].ReceiptNumber)
+	assert.Equal(t, second, res.Payments[1].MerchantPaymentID)
+	assert.Equal(t, "COMPLETED", res.Payments[1].Status)
+	assert.Equal(t, int32(800), res.Payments[1].AmountYen)
+	require.NotNil(t, res.Payments[1].ProgramID)
+	assert.Equal(t, secondProgramID, *res.Payments[1].ProgramID)
+	assert.NotNil(t, res.Payments[1].ProgramTitle)
+	assert.NotNil(t, res.Payments[1].ProviderPaymentID)
+
+	// 領収書番号は支払い完了順の連番
+	require.NotNil(t, res.Payments[1].ReceiptNumber)
+	require.NotNil(t, res.Payments[2].ReceiptNumber)
+	var firstNumber, secondNumber int64
+	_, err := fmt.Sscanf(*res.Payments[2].ReceiptNumber, "SZ-%d", &firstNumber)
+	require.NoError(t, err)
+	_, err = fmt.Sscanf(*res.Payments[1].ReceiptNumber, "SZ-%d", &secondNumber)
+	require.NoError(t, err)
+	assert.Equal(t, firstNumber+1, secondNumber)
+
+	// ページング
+	page := listMyPayments(t, r, "?limit=2")
+	require.Len(t, page.Payments, 2)
+	require.NotNil(t, page.NextCursor)
+	rest := listMyPayments(t, r, "?limit=2&cursor="+*page.NextCursor)
+	require.Len(t, rest.Payments, 1)
+	assert.Equal(t, first, rest.Payments[0].MerchantPaymentID)
+
+	// 領収書
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/me/payments/"+first+"/receipt", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), *res.Payments[2].ReceiptNumber)
+	assert.Contains(t, w.Body.String(), "¥1,200")
+
+	// 未完了の決済には発行しない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/payments/"+pending+"/receipt", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 他のユーザーの決済は見えない
+	seedPurchasableProgram(t, dbConn, "history-other", 100)
+	other := newPaymentHistoryTestRouter(uc, "history-other")
+	assert.Empty(t, listMyPayments(t, other, "").Payments)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/payments/"+first+"/receipt", nil)
+	other.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -12,15 +12,23 @@ import (
 )
 
 type PayPayHandler struct {
-	paypay *usecase.PayPayUsecase
+	paypay *usecase.PaymentsUsecase
 }
 
-func NewPayPayHandler(paypay *usecase.PayPayUsecase) *PayPayHandler {
+func NewPayPayHandler(paypay *usecase.PaymentsUsecase) *PayPayHandler {
 	return &PayPayHandler{paypay: paypay}
 }
 
+// program_idとproduct_idはどちらか一方を指定する。purchase_typeは番組のみ（purchase / rental、省略時は買い切り）。
+// providerは決済サービス（省略時はpaypay）、coupon_codeは割引コード（省略可）。
+// giftがtrueなら番組を他のユーザーに贈る（買い切りのみ）
 type payPayCheckoutRequest struct {
-	ProgramID int64 `json:"program_id"`
+	ProgramID    int64  `json:"program_id"`
+	ProductID    int64  `json:"product_id"`
+	PurchaseType string `json:"purchase_type"`
+	Provider     string `json:"provider"`
+	CouponCode   string `json:"coupon_code"`
+	Gift         bool   `json:"gift"`
 }
 
 func (h *PayPayHandler) PayPayCheckout(c *gin.Context) {
@@ -38,20 +46,56 @@ func (h *PayPayHandler) PayPayCheckout(c *gin.Context) {
 		return
 	}
 
-	if req.ProgramID <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "program_id is required"})
+	if (req.ProgramID > 0) == (req.ProductID > 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "either program_id or product_id is required"})
+		return
+	}
+	if req.ProductID > 0 && req.PurchaseType != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "purchase_type is only for programs"})
+		return
+	}
+	if req.Gift && (req.ProductID > 0 || req.PurchaseType == usecase.PurchaseTypeRental) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "gifts are only for program purchases"})
 		return
 	}
 
 	redirectBase := strings.TrimRight(middleware.FrontendBaseURL(), "/")
 
-	res, err := h.paypay.Checkout(c.Request.Context(), userID, req.ProgramID, redirectBase)
+	var res usecase.PaymentCheckoutResult
+	switch {
+	case req.ProductID > 0:
+		res, err = h.paypay.CheckoutProduct(c.Request.Context(), userID, req.ProductID, req.Provider, req.CouponCode, redirectBase)
+	case req.Gift:
+		res, err = h.paypay.CheckoutGift(c.Request.Context(), userID, req.ProgramID, req.Provider, req.CouponCode, redirectBase)
+	default:
+		res, err = h.paypay.Checkout(c.Request.Context(), userID, req.ProgramID, req.PurchaseType, req.Provider, req.CouponCode, redirectBase)
+	}
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidPurchaseType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid purchase_type"})
+			return
+		}
+		if errors.Is(err, usecase.ErrUnknownPaymentProvider) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown provider"})
+			return
+		}
+		if errors.Is(err, usecase.ErrPaymentNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "payment provider not configured"})
+			return
+		}
 		if errors.Is(err, usecase.ErrProgramNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
 			return
 		}
+		if errors.Is(err, usecase.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+			return
+		}
 		if errors.Is(err, usecase.ErrNotPurchasable) {
+			if req.ProductID > 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "product is not purchasable"})
+				return
+			}
 			c.JSON(http.StatusBadRequest, gin.H{"error": "program is not purchasable"})
 			return
 		}
@@ -59,6 +103,22 @@ func (h *PayPayHandler) PayPayCheckout(c *gin.Context) {
 			c.JSON(http.StatusConflict, gin.H{"error": "already purchased"})
 			return
 		}
+		if errors.Is(err, usecase.ErrCouponNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "coupon not found"})
+			return
+		}
+		if errors.Is(err, usecase.ErrCouponNotActive) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "coupon is not active"})
+			return
+		}
+		if errors.Is(err, usecase.ErrCouponNotApplicable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "coupon is not applicable"})
+			return
+		}
+		if errors.Is(err, usecase.ErrCouponLimitReached) {
+			c.JSON(http.StatusConflict, gin.H{"error": "coupon redemption limit reached"})
+			return
+		}
 		println(err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create paypay checkout"})
 		return
@@ -68,6 +128,10 @@ func (h *PayPayHandler) PayPayCheckout(c *gin.Context) {
 		"merchant_payment_id": res.MerchantPaymentID,
 		"url":                 res.URL,
 		"deeplink":            res.Deeplink,
+		"amount_yen":          res.AmountYen,
+		"discount_yen":        res.DiscountYen,
+		"granted":             res.Granted,
+		"gift_code":           res.GiftCode,
 	})
 }
 
@@ -81,10 +145,14 @@ func (h *PayPayHandler) PayPayGetPayment(c *gin.Context) {
 	merchantPaymentID := strings.TrimSpace(c.Param("merchantPaymentId"))
 	result, err := h.paypay.ConfirmAndGrant(c.Request.Context(), userID, merchantPaymentID)
 	if err != nil {
-		if errors.Is(err, usecase.ErrPayPayTopupNotFound) {
+		if errors.Is(err, usecase.ErrPaymentNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
 		}
+		if errors.Is(err, usecase.ErrPaymentNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "payment provider not configured"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to confirm paypay payment"})
 		return
 	}
@@ -93,5 +161,7 @@ func (h *PayPayHandler) PayPayGetPayment(c *gin.Context) {
 		"status":     result.Status,
 		"granted":    result.Granted,
 		"program_id": result.ProgramID,
+		"product_id": result.ProductID,
+		"gift_code":  result.GiftCode,
 	})
 }
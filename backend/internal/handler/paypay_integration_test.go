@@ -0,0 +1,296 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/payments"
+	"github.com/chan-shizu/SZer/internal/paypay/paypaysim"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPayPaySim はPayPay模擬サーバーを立ててPaymentsUsecaseの接続先をそこに向ける
+func setupPayPaySim(t *testing.T) *paypaysim.Server {
+	t.Helper()
+	sim := paypaysim.New("sim-key", "sim-secret")
+	srv := httptest.NewServer(sim)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("PAYPAY_API_URL", srv.URL)
+	t.Setenv("PAYPAY_API_KEY", "sim-key")
+	t.Setenv("PAYPAY_API_SECRET", "sim-secret")
+	return sim
+}
+
+// seedPurchasableProgram は購入用のユーザーと有料番組を作り、番組IDを返す
+func seedPurchasableProgram(t *testing.T, dbConn *sql.DB, userID string, price int32) int64 {
+	t.Helper()
+	_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified", "createdAt", "updatedAt") VALUES ($1, $2, $3, $4, now(), now()) ON CONFLICT (id) DO NOTHING`, userID, userID, userID+"@example.com", true)
+	require.NoError(t, err)
+	var programID int64
+	err = dbConn.QueryRow(`INSERT INTO programs (title, video_path, is_limited_release, price) VALUES ($1, $2, true, $3) RETURNING id`,
+		"paid-"+userID, "/video/paid.mp4", price).Scan(&programID)
+	require.NoError(t, err)
+	return programID
+}
+
+func newPayPayTestRouter(uc *usecase.PaymentsUsecase, userID string) *gin.Engine {
+	h := NewPayPayHandler(uc)
+	admin := NewAdminPayPayHandler(uc)
+	r := gin.New()
+	r.Use(MockOptionalAuth(userID))
+	r.POST("/me/paypay/checkout", h.PayPayCheckout)
+	r.GET("/me/paypay/payments/:merchantPaymentId", h.PayPayGetPayment)
+	r.POST("/admin/paypay/topups/:merchantPaymentId/refunds", admin.Refund)
+	r.POST("/admin/paypay/topups/:merchantPaymentId/revoke", admin.Revoke)
+	return r
+}
+
+func checkout(t *testing.T, r *gin.Engine, programID int64) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(fmt.Sprintf(`{"program_id": %d}`, programID)))
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var res struct {
+		MerchantPaymentID string `json:"merchant_payment_id"`
+		URL               string `json:"url"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Contains(t, res.URL, "/sim/pay/")
+	return res.MerchantPaymentID
+}
+
+type confirmResponse struct {
+	Status  string `json:"status"`
+	Granted bool   `json:"granted"`
+}
+
+func confirm(t *testing.T, r *gin.Engine, merchantPaymentID string) confirmResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/me/paypay/payments/"+merchantPaymentID, nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res confirmResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return res
+}
+
+func isPermitted(t *testing.T, dbConn *sql.DB, userID string, programID int64) bool {
+	t.Helper()
+	var permitted bool
+	err := dbConn.QueryRow(`SELECT EXISTS(SELECT 1 FROM permitted_program_users WHERE user_id = $1 AND program_id = $2)`, userID, programID).Scan(&permitted)
+	require.NoError(t, err)
+	return permitted
+}
+
+func topupEvents(t *testing.T, q *db.Queries, merchantPaymentID string) []string {
+	t.Helper()
+	topup, err := q.GetPaymentByMerchantPaymentID(context.Background(), merchantPaymentID)
+	require.NoError(t, err)
+	events, err := q.ListPaymentEvents(context.Background(), topup.ID)
+	require.NoError(t, err)
+	var statuses []string
+	for _, e := range events {
+		statuses = append(statuses, e.ToStatus)
+	}
+	return statuses
+}
+
+// =============================================================================
+// Checkout → 支払い → 確認API
+// =============================================================================
+
+func TestPayPayCheckoutAndConfirm_WithSimulator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sim := setupPayPaySim(t)
+	dbConn, q := setupTestDB(t)
+
+	programID := seedPurchasableProgram(t, dbConn, "sim-buyer", 300)
+	r := newPayPayTestRouter(usecase.NewPaymentsUsecase(dbConn, q, nil), "sim-buyer")
+
+	mpid := checkout(t, r, programID)
+
+	topup, err := q.GetPaymentByMerchantPaymentID(context.Background(), mpid)
+	require.NoError(t, err)
+	assert.Equal(t, "AWAITING", topup.Status)
+	assert.Equal(t, int32(300), topup.AmountYen)
+	assert.True(t, topup.CheckoutExpiresAt.Valid)
+
+	// 支払い前は支払い待ちのまま
+	res := confirm(t, r, mpid)
+	assert.Equal(t, "AWAITING", res.Status)
+	assert.False(t, res.Granted)
+	assert.False(t, isPermitted(t, dbConn, "sim-buyer", programID))
+
+	require.NoError(t, sim.Complete(mpid))
+
+	res = confirm(t, r, mpid)
+	assert.Equal(t, "COMPLETED", res.Status)
+	assert.True(t, res.Granted)
+	assert.True(t, isPermitted(t, dbConn, "sim-buyer", programID))
+
+	// 二重付与しない
+	res = confirm(t, r, mpid)
+	assert.Equal(t, "COMPLETED", res.Status)
+	assert.False(t, res.Granted)
+
+	assert.Equal(t, []string{"CREATED", "AWAITING", "COMPLETED"}, topupEvents(t, q, mpid))
+
+	// 購入済みの番組は再度購入できない
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(fmt.Sprintf(`{"program_id": %d}`, programID)))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestPayPayCheckout_WebhookGrantsAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sim := setupPayPaySim(t)
+	dbConn, q := setupTestDB(t)
+	os.Setenv("PAYPAY_WEBHOOK_SECRET", "testsecret")
+
+	webhook := gin.New()
+	webhook.POST("/paypay/webhook", NewPayPayWebhookHandler(dbConn, q).Handle)
+	webhookSrv := httptest.NewServer(webhook)
+	defer webhookSrv.Close()
+	sim.WebhookURL = webhookSrv.URL + "/paypay/webhook"
+	sim.WebhookSecret = "testsecret"
+
+	programID := seedPurchasableProgram(t, dbConn, "sim-webhook-buyer", 500)
+	r := newPayPayTestRouter(usecase.NewPaymentsUsecase(dbConn, q, nil), "sim-webhook-buyer")
+
+	mpid := checkout(t, r, programID)
+	require.NoError(t, sim.Complete(mpid))
+	processWebhookDeliveries(t, dbConn, q)
+
+	// Webhookで付与済み
+	assert.True(t, isPermitted(t, dbConn, "sim-webhook-buyer", programID))
+	res := confirm(t, r, mpid)
+	assert.Equal(t, "COMPLETED", res.Status)
+	assert.False(t, res.Granted)
+}
+
+func TestPaymentCheckout_OtherProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	fake := payments.NewFakeProvider()
+	uc := usecase.NewPaymentsUsecaseWithProviders(dbConn, q, nil, payments.NewRegistry(fake))
+	programID := seedPurchasableProgram(t, dbConn, "fake-buyer", 400)
+	r := newPayPayTestRouter(uc, "fake-buyer")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(fmt.Sprintf(`{"program_id": %d, "provider": "fake"}`, programID)))
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var created struct {
+		MerchantPaymentID string `json:"merchant_payment_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	payment, err := q.GetPaymentByMerchantPaymentID(context.Background(), created.MerchantPaymentID)
+	require.NoError(t, err)
+	assert.Equal(t, payments.ProviderFake, payment.Provider)
+	assert.Equal(t, "AWAITING", payment.Status)
+
+	require.NoError(t, fake.SetStatus(created.MerchantPaymentID, payments.StatusCompleted))
+	res := confirm(t, r, created.MerchantPaymentID)
+	assert.Equal(t, "COMPLETED", res.Status)
+	assert.True(t, res.Granted)
+	assert.True(t, isPermitted(t, dbConn, "fake-buyer", programID))
+
+	// 登録されていない決済サービスは指定できない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(fmt.Sprintf(`{"program_id": %d, "provider": "credit_card"}`, programID)))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPayPayRefund_WithSimulator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sim := setupPayPaySim(t)
+	dbConn, q := setupTestDB(t)
+
+	programID := seedPurchasableProgram(t, dbConn, "sim-refund-buyer", 800)
+	r := newPayPayTestRouter(usecase.NewPaymentsUsecase(dbConn, q, nil), "sim-refund-buyer")
+
+	mpid := checkout(t, r, programID)
+	require.NoError(t, sim.Complete(mpid))
+	confirm(t, r, mpid)
+	require.True(t, isPermitted(t, dbConn, "sim-refund-buyer", programID))
+
+	refund := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/paypay/topups/"+mpid+"/refunds", strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 一部返金では権限は残る
+	w := refund(`{"amount_yen": 300, "reason": "partial"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"REFUNDED"`)
+	assert.True(t, isPermitted(t, dbConn, "sim-refund-buyer", programID))
+
+	// 残額の全額返金で権限が取り消される
+	w = refund(`{}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"amount_yen":500`)
+	assert.False(t, isPermitted(t, dbConn, "sim-refund-buyer", programID))
+
+	topup, err := q.GetPaymentByMerchantPaymentID(context.Background(), mpid)
+	require.NoError(t, err)
+	assert.Equal(t, "REFUNDED", topup.Status)
+	assert.Equal(t, int32(800), topup.RefundedAmountYen)
+
+	payment, _ := sim.Payment(mpid)
+	assert.Equal(t, int32(800), payment.RefundedAmount)
+
+	// これ以上は返金できない
+	assert.Equal(t, http.StatusConflict, refund(`{}`).Code)
+}
+
+func TestPayPayReconcile_WithSimulator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sim := setupPayPaySim(t)
+	dbConn, q := setupTestDB(t)
+
+	programID := seedPurchasableProgram(t, dbConn, "sim-reconcile-buyer", 200)
+	uc := usecase.NewPaymentsUsecase(dbConn, q, nil)
+	r := newPayPayTestRouter(uc, "sim-reconcile-buyer")
+
+	// Webhookも確認APIも届かなかった支払いを照合で回収する
+	paid := checkout(t, r, programID)
+	require.NoError(t, sim.Complete(paid))
+	res, err := uc.ReconcilePayment(context.Background(), paid)
+	require.NoError(t, err)
+	assert.Equal(t, "COMPLETED", res.Status)
+	assert.True(t, res.Granted)
+	assert.True(t, isPermitted(t, dbConn, "sim-reconcile-buyer", programID))
+
+	// 期限切れになった決済はEXPIREDにする
+	_, err = dbConn.Exec(`DELETE FROM permitted_program_users`)
+	require.NoError(t, err)
+	expired := checkout(t, r, programID)
+	require.NoError(t, sim.Expire(expired))
+	res, err = uc.ReconcilePayment(context.Background(), expired)
+	require.NoError(t, err)
+	assert.Equal(t, "EXPIRED", res.Status)
+	assert.False(t, res.Granted)
+	assert.Equal(t, []string{"CREATED", "AWAITING", "EXPIRED"}, topupEvents(t, q, expired))
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// seedRefundTopup は購入済み（COMPLETED）のtopupと閲覧権限を作り、topupのIDと番組IDを返す
+func seedRefundTopup(t *testing.T, dbConn *sql.DB, userID, merchantPaymentID string, amount int32) (int64, int64) {
+	t.Helper()
+	_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified", "createdAt", "updatedAt") VALUES ($1, $2, $3, $4, now(), now()) ON CONFLICT (id) DO NOTHING`, userID, userID, userID+"@example.com", true)
+	if err != nil {
+		t.Fatalf("failed to insert test user: %v", err)
+	}
+	var programID int64
+	err = dbConn.QueryRow(`INSERT INTO programs (title, video_path, is_limited_release, price) VALUES ($1, $2, $3, $4) RETURNING id`,
+		"refund-"+merchantPaymentID, "/video/refund.mp4", true, amount).Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+	var paymentID int64
+	err = dbConn.QueryRow(`INSERT INTO payments (provider, user_id, merchant_payment_id, amount_yen, status, program_id, provider_payment_id, credited_at) VALUES ('paypay', $1, $2, $3, 'COMPLETED', $4, $5, now()) RETURNING id`,
+		userID, merchantPaymentID, amount, programID, "paypay-"+merchantPaymentID).Scan(&paymentID)
+	if err != nil {
+		t.Fatalf("failed to insert test topup: %v", err)
+	}
+	if _, err := dbConn.Exec(`INSERT INTO permitted_program_users (user_id, program_id, payment_id) VALUES ($1, $2, $3)`, userID, programID, paymentID); err != nil {
+		t.Fatalf("failed to insert permitted_program_users: %v", err)
+	}
+	return paymentID, programID
+}
+
+// sendPayPayWebhook は署名付きのWebhookを送る（保存のみで、処理はprocessWebhookDeliveriesで行う）
+func sendPayPayWebhook(t *testing.T, r *gin.Engine, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newSignedPayPayWebhookRequest(body, "13.112.237.64:12345"))
+	return w
+}
+
+// =============================================================================
+// Refund webhook
+// =============================================================================
+
+func TestPayPayWebhook_FullRefundRevokesAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("PAYPAY_WEBHOOK_SECRET", "testsecret")
+	dbConn, q := setupTestDB(t)
+
+	paymentID, programID := seedRefundTopup(t, dbConn, "refund-user", "refund-full", 500)
+	_, err := dbConn.Exec(`INSERT INTO payment_refunds (payment_id, merchant_refund_id, amount_yen) VALUES ($1, $2, $3)`, paymentID, "refund-full-1", 500)
+	if err != nil {
+		t.Fatalf("failed to insert refund: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/api/paypay/webhook", NewPayPayWebhookHandler(dbConn, q).Handle)
+
+	body := `{"notification_type":"Refund","merchant_id":"test-merchant","merchant_refund_id":"refund-full-1","merchant_order_id":"refund-full","state":"REFUNDED"}`
+	w := sendPayPayWebhook(t, r, body)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	processWebhookDeliveries(t, dbConn, q)
+
+	var status string
+	var refunded int32
+	err = dbConn.QueryRow(`SELECT status, refunded_amount_yen FROM payments WHERE id = $1`, paymentID).Scan(&status, &refunded)
+	if err != nil {
+		t.Fatalf("failed to query topup: %v", err)
+	}
+	assert.Equal(t, "REFUNDED", status)
+	assert.Equal(t, int32(500), refunded)
+
+	var refundStatus string
+	if err := dbConn.QueryRow(`SELECT status FROM payment_refunds WHERE merchant_refund_id = 'refund-full-1'`).Scan(&refundStatus); err != nil {
+		t.Fatalf("failed to query refund: %v", err)
+	}
+	assert.Equal(t, "REFUNDED", refundStatus)
+
+	// 全額返金で閲覧権限が取り消される
+	var permitted bool
+	err = dbConn.QueryRow(`SELECT EXISTS(SELECT 1 FROM permitted_program_users WHERE user_id = $1 AND program_id = $2)`, "refund-user", programID).Scan(&permitted)
+	if err != nil {
+		t.Fatalf("failed to query permitted_program_users: %v", err)
+	}
+	assert.False(t, permitted)
+
+	// 再送では返金額を二重計上しない
+	w = sendPayPayWebhook(t, r, body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	processWebhookDeliveries(t, dbConn, q)
+	if err := dbConn.QueryRow(`SELECT refunded_amount_yen FROM payments WHERE id = $1`, paymentID).Scan(&refunded); err != nil {
+		t.Fatalf("failed to query topup: %v", err)
+	}
+	assert.Equal(t, int32(500), refunded)
+
+	// 返金履歴が監査ログに残る
+	var events int
+	if err := dbConn.QueryRow(`SELECT COUNT(*) FROM payment_events WHERE payment_id = $1`, paymentID).Scan(&events); err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	assert.Equal(t, 2, events) // 返金完了 + COMPLETED→REFUNDED
+}
+
+func TestPayPayWebhook_PartialRefundKeepsAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("PAYPAY_WEBHOOK_SECRET", "testsecret")
+	dbConn, q := setupTestDB(t)
+
+	paymentID, programID := seedRefundTopup(t, dbConn, "partial-user", "refund-partial", 500)
+	_, err := dbConn.Exec(`INSERT INTO payment_refunds (payment_id, merchant_refund_id, amount_yen) VALUES ($1, $2, $3)`, paymentID, "refund-partial-1", 200)
+	if err != nil {
+		t.Fatalf("failed to insert refund: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/api/paypay/webhook", NewPayPayWebhookHandler(dbConn, q).Handle)
+
+	w := sendPayPayWebhook(t, r, `{"notification_type":"Refund","merchant_id":"test-merchant","merchant_refund_id":"refund-partial-1","merchant_order_id":"refund-partial","state":"REFUNDED"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	processWebhookDeliveries(t, dbConn, q)
+
+	var status string
+	var refunded int32
+	if err := dbConn.QueryRow(`SELECT status, refunded_amount_yen FROM payments WHERE id = $1`, paymentID).Scan(&status, &refunded); err != nil {
+		t.Fatalf("failed to query topup: %v", err)
+	}
+	assert.Equal(t, "COMPLETED", status)
+	assert.Equal(t, int32(200), refunded)
+
+	var permitted bool
+	err = dbConn.QueryRow(`SELECT EXISTS(SELECT 1 FROM permitted_program_users WHERE user_id = $1 AND program_id = $2)`, "partial-user", programID).Scan(&permitted)
+	if err != nil {
+		t.Fatalf("failed to query permitted_program_users: %v", err)
+	}
+	assert.True(t, permitted)
+
+	// 記録の無い返金通知は無視する
+	w = sendPayPayWebhook(t, r, `{"notification_type":"Refund","merchant_id":"test-merchant","merchant_refund_id":"unknown-refund","state":"REFUNDED"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	processWebhookDeliveries(t, dbConn, q)
+	var deliveryStatus string
+	if err := dbConn.QueryRow(`SELECT status FROM webhook_deliveries WHERE dedupe_key = 'Refund:unknown-refund:REFUNDED'`).Scan(&deliveryStatus); err != nil {
+		t.Fatalf("failed to query webhook delivery: %v", err)
+	}
+	assert.Equal(t, "succeeded", deliveryStatus)
+}
+
+// =============================================================================
+// POST /admin/paypay/topups/:merchantPaymentId/refunds
+// =============================================================================
+
+func TestAdminRefund_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// PayPay APIは呼ばれない（呼ぶ前に検証で弾かれる）ケースのみ
+	os.Setenv("PAYPAY_API_KEY", "test-key")
+	os.Setenv("PAYPAY_API_SECRET", "test-secret")
+	defer os.Unsetenv("PAYPAY_API_KEY")
+	defer os.Unsetenv("PAYPAY_API_SECRET")
+	dbConn, q := setupTestDB(t)
+
+	paymentID, _ := seedRefundTopup(t, dbConn, "admin-refund-user", "refund-validate", 500)
+	// 処理中の返金で残額は100円
+	_, err := dbConn.Exec(`INSERT INTO payment_refunds (payment_id, merchant_refund_id, amount_yen) VALUES ($1, $2, $3)`, paymentID, "refund-validate-1", 400)
+	if err != nil {
+		t.Fatalf("failed to insert refund: %v", err)
+	}
+	_, err = dbConn.Exec(`INSERT INTO payments (provider, user_id, merchant_payment_id, amount_yen, status) VALUES ('paypay', $1, $2, $3, 'AWAITING')`, "admin-refund-user", "refund-awaiting", 500)
+	if err != nil {
+		t.Fatalf("failed to insert awaiting topup: %v", err)
+	}
+
+	h := NewAdminPayPayHandler(usecase.NewPaymentsUsecase(dbConn, q, nil))
+	r := gin.New()
+	r.Use(MockOptionalAuth("admin-refund-user"))
+	r.GET("/admin/paypay/topups/:merchantPaymentId/refunds", h.ListRefunds)
+	r.POST("/admin/paypay/topups/:merchantPaymentId/refunds", h.Refund)
+
+	post := func(merchantPaymentID, body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/paypay/topups/"+merchantPaymentID+"/refunds", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, post("no-such-topup", `{}`))
+	assert.Equal(t, http.StatusConflict, post("refund-awaiting", `{}`))
+	assert.Equal(t, http.StatusBadRequest, post("refund-validate", `{"amount_yen": 200}`))
+	assert.Equal(t, http.StatusBadRequest, post("refund-validate", `{"amount_yen": -1}`))
+	assert.Equal(t, http.StatusBadRequest, post("refund-validate", `{"unknown": 1}`))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/paypay/topups/refund-validate/refunds", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"merchant_refund_id":"refund-validate-1"`)
+	assert.Contains(t, w.Body.String(), `"refunded_amount_yen":0`)
+}
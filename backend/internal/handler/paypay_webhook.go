@@ -2,11 +2,10 @@ package handler
 
 import (
 	"database/sql"
+	"errors"
 	"io"
 	"log"
 	"net/http"
-	"os"
-	"strings"
 
 	"github.com/chan-shizu/SZer/db"
 	"github.com/chan-shizu/SZer/internal/paypay"
@@ -14,76 +13,86 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// 受信するWebhookボディの上限（PayPayの通知は数KB）
+const maxPayPayWebhookBodyBytes = 64 << 10
+
 // PayPayWebhookHandler はPayPay Webhook受信用のハンドラだよ！
 type PayPayWebhookHandler struct {
-	Q  *db.Queries
-	DB *sql.DB
+	Q          *db.Queries
+	DB         *sql.DB
+	deliveries *usecase.WebhookDeliveriesUsecase
+	auth       *paypay.WebhookAuthenticator
+	authErr    error
 }
 
 func NewPayPayWebhookHandler(db *sql.DB, q *db.Queries) *PayPayWebhookHandler {
-	return &PayPayWebhookHandler{Q: q, DB: db}
+	h := &PayPayWebhookHandler{Q: q, DB: db, deliveries: usecase.NewWebhookDeliveriesUsecase(db, q)}
+	cfg, err := paypay.LoadWebhookAuthConfigFromEnv()
+	if err != nil {
+		// 設定が壊れている・シークレットが無い場合は全て拒否する
+		log.Printf("[PayPayWebhook] invalid webhook auth config: %v", err)
+		h.authErr = err
+		return h
+	}
+	h.auth = paypay.NewWebhookAuthenticator(cfg)
+	return h
 }
 
 func (h *PayPayWebhookHandler) Handle(c *gin.Context) {
-	// IPホワイトリストチェック（PayPay推奨のセキュリティ方式）
-	ipWhiteList := os.Getenv("PAYPAY_WEBHOOK_IP_WHITE_LIST")
-	if ipWhiteList != "" {
-		allowed := false
-		remoteIP := c.ClientIP()
-		for _, ip := range splitAndTrim(ipWhiteList, ",") {
-			if remoteIP == ip {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			log.Printf("[PayPayWebhook] forbidden IP: %s", remoteIP)
-			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden IP"})
-			return
-		}
+	if h.authErr != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "webhook auth not configured"})
+		return
 	}
 
-	// リクエストBody取得
-	bodyBytes, err := io.ReadAll(c.Request.Body)
+	// リクエストBody取得（上限を超えるボディは読み込まない）
+	bodyBytes, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPayPayWebhookBodyBytes))
 	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			log.Printf("[PayPayWebhook] body too large: limit=%d", maxErr.Limit)
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "body too large"})
+			return
+		}
 		log.Printf("[PayPayWebhook] failed to read body: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
 		return
 	}
-	bodyStr := string(bodyBytes)
 
-	// 署名検証（ヘッダーがある場合のみ。PayPayの公式ドキュメントでは署名仕様が未記載のため任意）
-	signature := c.GetHeader("X-PayPay-Signature")
-	if signature != "" {
-		secret := os.Getenv("PAYPAY_WEBHOOK_SECRET")
-		if secret != "" {
-			if !paypay.VerifyWebhookSignature(secret, bodyStr, signature) {
-				log.Printf("[PayPayWebhook] invalid signature")
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
-				return
-			}
+	// 送信元IP（CIDR許可リスト）・署名・署名時刻を検証
+	remoteIP := h.auth.ClientIP(c.Request).String()
+	if err := h.auth.Verify(c.Request, bodyBytes); err != nil {
+		switch {
+		case errors.Is(err, paypay.ErrWebhookForbiddenIP):
+			log.Printf("[PayPayWebhook] forbidden IP: %s", remoteIP)
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden IP"})
+		case errors.Is(err, paypay.ErrWebhookSecretNotConfigured):
+			log.Printf("[PayPayWebhook] PAYPAY_WEBHOOK_SECRET is not set")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "webhook auth not configured"})
+		default:
+			log.Printf("[PayPayWebhook] unauthorized: ip=%s, err=%v", remoteIP, err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
 		}
+		return
 	}
 
-	// ビジネスロジック呼び出し（イベントタイプはbody内のnotification_typeで判定）
-	err = usecase.PayPayWebhookEventHandler(c.Request.Context(), h.DB, h.Q, bodyBytes)
+	// 受信内容を保存してすぐに応答し、処理はジョブで非同期に行う（イベントタイプはbody内のnotification_typeで判定）
+	id, duplicate, err := h.deliveries.ReceivePayPay(c.Request.Context(), bodyBytes, remoteIP)
 	if err != nil {
-		log.Printf("[PayPayWebhook] event handling failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "event handling failed"})
+		if errors.Is(err, usecase.ErrInvalidWebhookBody) {
+			log.Printf("[PayPayWebhook] invalid body: ip=%s", remoteIP)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		log.Printf("[PayPayWebhook] failed to store delivery: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store delivery"})
 		return
 	}
+	if duplicate {
+		// 再送された同じイベントは保存済みなので処理しない
+		log.Printf("[PayPayWebhook] duplicate delivery ignored")
+	} else {
+		log.Printf("[PayPayWebhook] delivery stored: id=%d", id)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "OK"})
 }
-
-// --- 末尾にユーティリティ関数 ---
-func splitAndTrim(s, sep string) []string {
-	var out []string
-	for _, v := range strings.Split(s, sep) {
-		trimmed := strings.TrimSpace(v)
-		if trimmed != "" {
-			out = append(out, trimmed)
-		}
-	}
-	return out
-}
@@ -2,19 +2,29 @@ package handler
 
 import (
 	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/paypay"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
 
+// newSignedPayPayWebhookRequest は時刻付きの署名を付けたWebhookリクエストを作る
+func newSignedPayPayWebhookRequest(body, remoteAddr string) *http.Request {
+	timestamp, signature := paypay.SignWebhook("testsecret", time.Now(), []byte(body))
+	req, _ := http.NewRequest("POST", "/api/paypay/webhook", bytes.NewBuffer([]byte(body)))
+	req.Header.Set(paypay.WebhookTimestampHeader, timestamp)
+	req.Header.Set(paypay.WebhookSignatureHeader, signature)
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
 func TestPayPayWebhookHandler_Integration(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
@@ -37,7 +47,7 @@ func TestPayPayWebhookHandler_Integration(t *testing.T) {
 	}
 
 	// テスト用topupをinsert
-	_, err = dbConn.Exec(`INSERT INTO paypay_topups (user_id, merchant_payment_id, amount_yen, status, program_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, now(), now()) ON CONFLICT (merchant_payment_id) DO NOTHING`, "integration-user-id", "integration-merchant-id", 100, "CREATED", programID)
+	_, err = dbConn.Exec(`INSERT INTO payments (provider, user_id, merchant_payment_id, amount_yen, status, program_id, created_at, updated_at) VALUES ('paypay', $1, $2, $3, $4, $5, now(), now()) ON CONFLICT (merchant_payment_id) DO NOTHING`, "integration-user-id", "integration-merchant-id", 100, "CREATED", programID)
 	if err != nil {
 		t.Fatalf("failed to insert test topup: %v", err)
 	}
@@ -48,24 +58,19 @@ func TestPayPayWebhookHandler_Integration(t *testing.T) {
 
 	// PayPayの実際のWebhookペイロード形式
 	body := `{"notification_type":"Transaction","merchant_id":"test-merchant","order_id":"integration-payment-id","merchant_order_id":"integration-merchant-id","order_amount":"100","state":"COMPLETED","paid_at":"2026-02-08T12:00:00Z"}`
-	mac := hmac.New(sha256.New, []byte("testsecret"))
-	mac.Write([]byte(body))
-	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/api/paypay/webhook", bytes.NewBuffer([]byte(body)))
-	req.Header.Set("X-PayPay-Signature", signature)
-	// テスト用IP（ホワイトリスト内）をセット
-	req.RemoteAddr = "13.112.237.64:12345"
+	// テスト用IP（ホワイトリスト内）から送る
+	req := newSignedPayPayWebhookRequest(body, "13.112.237.64:12345")
 
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("expected 200, got %d, body: %s", w.Code, w.Body.String())
 	}
+	processWebhookDeliveries(t, dbConn, q)
 
 	// DBの状態を検証: topupのstatus
-	topup, err := q.GetPayPayTopupForUpdate(req.Context(), db.GetPayPayTopupForUpdateParams{
+	topup, err := q.GetPaymentForUpdate(req.Context(), db.GetPaymentForUpdateParams{
 		UserID:            "integration-user-id",
 		MerchantPaymentID: "integration-merchant-id",
 	})
@@ -88,18 +93,113 @@ func TestPayPayWebhookHandler_Integration(t *testing.T) {
 	if !permitted {
 		t.Errorf("expected user to have viewing permission for program %d", programID)
 	}
+
+	// 監査ログに遷移が記録されていることを確認
+	events, err := q.ListPaymentEvents(req.Context(), topup.ID)
+	if err != nil {
+		t.Fatalf("failed to list topup events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 topup event, got %d", len(events))
+	}
+	if events[0].FromStatus.String != "CREATED" || events[0].ToStatus != "COMPLETED" || events[0].Source != "webhook" {
+		t.Errorf("unexpected topup event: %+v", events[0])
+	}
+
+	// 同じWebhookの再送では何も変わらない
+	w = httptest.NewRecorder()
+	req = newSignedPayPayWebhookRequest(body, "13.112.237.64:12345")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 on redelivery, got %d", w.Code)
+	}
+	processWebhookDeliveries(t, dbConn, q)
+
+	// 再送は保存済みのイベントとして記録されない
+	var deliveries int
+	if err := dbConn.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries`).Scan(&deliveries); err != nil {
+		t.Fatalf("failed to count webhook deliveries: %v", err)
+	}
+	if deliveries != 1 {
+		t.Errorf("expected 1 webhook delivery, got %d", deliveries)
+	}
+	events, err = q.ListPaymentEvents(req.Context(), topup.ID)
+	if err != nil {
+		t.Fatalf("failed to list topup events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("expected redelivery to add no events, got %d", len(events))
+	}
+}
+
+func TestPayPayWebhookHandler_RejectsInvalidTransition(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("PAYPAY_WEBHOOK_SECRET", "testsecret")
+
+	dbConn, q := setupTestDB(t)
+	_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified", "createdAt", "updatedAt") VALUES ($1, $2, $3, $4, now(), now()) ON CONFLICT (id) DO NOTHING`, "failed-user-id", "failed-user", "failed@example.com", true)
+	if err != nil {
+		t.Fatalf("failed to insert test user: %v", err)
+	}
+	var programID int64
+	err = dbConn.QueryRow(`INSERT INTO programs (title, video_path, is_limited_release, price) VALUES ($1, $2, $3, $4) RETURNING id`,
+		"webhook-failed-program", "/video/webhook.mp4", true, 100).Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+	// コード発行に失敗したtopup
+	_, err = dbConn.Exec(`INSERT INTO payments (provider, user_id, merchant_payment_id, amount_yen, status, program_id) VALUES ('paypay', $1, $2, $3, $4, $5)`, "failed-user-id", "failed-merchant-id", 100, "FAILED", programID)
+	if err != nil {
+		t.Fatalf("failed to insert test topup: %v", err)
+	}
+
+	handler := NewPayPayWebhookHandler(dbConn, q)
+	r := gin.New()
+	r.POST("/api/paypay/webhook", handler.Handle)
+
+	body := `{"notification_type":"Transaction","merchant_id":"test-merchant","order_id":"failed-payment-id","merchant_order_id":"failed-merchant-id","order_amount":"100","state":"COMPLETED","paid_at":"2026-02-08T12:00:00Z"}`
+	w := httptest.NewRecorder()
+	req := newSignedPayPayWebhookRequest(body, "13.112.237.64:12345")
+	r.ServeHTTP(w, req)
+
+	// 拒否した遷移で再送が繰り返されないよう200を返す
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d, body: %s", w.Code, w.Body.String())
+	}
+	processWebhookDeliveries(t, dbConn, q)
+
+	topup, err := q.GetPaymentByMerchantPaymentIDForUpdate(req.Context(), "failed-merchant-id")
+	if err != nil {
+		t.Fatalf("failed to get topup: %v", err)
+	}
+	if topup.Status != "FAILED" {
+		t.Errorf("expected status FAILED, got %s", topup.Status)
+	}
+
+	var permitted bool
+	err = dbConn.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM permitted_program_users WHERE user_id = $1 AND program_id = $2)`,
+		"failed-user-id", programID,
+	).Scan(&permitted)
+	if err != nil {
+		t.Fatalf("failed to query permitted_program_users: %v", err)
+	}
+	if permitted {
+		t.Errorf("expected no viewing permission for program %d", programID)
+	}
 }
 
 func TestPayPayWebhookHandler_ForbiddenIP(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	os.Setenv("PAYPAY_WEBHOOK_SECRET", "testsecret")
+	t.Setenv("PAYPAY_WEBHOOK_IP_WHITE_LIST", "13.112.237.0/24")
 
 	dbConn, q := setupTestDB(t)
 	_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified", "createdAt", "updatedAt") VALUES ($1, $2, $3, $4, now(), now()) ON CONFLICT (id) DO NOTHING`, "forbidden-user-id", "forbidden-user", "forbidden@example.com", true)
 	if err != nil {
 		t.Fatalf("failed to insert test user: %v", err)
 	}
-	_, err = dbConn.Exec(`INSERT INTO paypay_topups (user_id, merchant_payment_id, amount_yen, status, created_at, updated_at) VALUES ($1, $2, $3, $4, now(), now()) ON CONFLICT (merchant_payment_id) DO NOTHING`, "forbidden-user-id", "forbidden-merchant-id", 100, "CREATED")
+	_, err = dbConn.Exec(`INSERT INTO payments (provider, user_id, merchant_payment_id, amount_yen, status, created_at, updated_at) VALUES ('paypay', $1, $2, $3, $4, now(), now()) ON CONFLICT (merchant_payment_id) DO NOTHING`, "forbidden-user-id", "forbidden-merchant-id", 100, "CREATED")
 	if err != nil {
 		t.Fatalf("failed to insert test topup: %v", err)
 	}
@@ -110,15 +210,9 @@ func TestPayPayWebhookHandler_ForbiddenIP(t *testing.T) {
 
 	// PayPayの実際のWebhookペイロード形式
 	body := `{"notification_type":"Transaction","merchant_id":"test-merchant","order_id":"forbidden-payment-id","merchant_order_id":"forbidden-merchant-id","order_amount":"100","state":"COMPLETED","paid_at":"2026-02-08T12:00:00Z"}`
-	mac := hmac.New(sha256.New, []byte("testsecret"))
-	mac.Write([]byte(body))
-	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/api/paypay/webhook", bytes.NewBuffer([]byte(body)))
-	req.Header.Set("X-PayPay-Signature", signature)
 	// ホワイトリスト外のIP
-	req.RemoteAddr = "1.2.3.4:12345"
+	req := newSignedPayPayWebhookRequest(body, "1.2.3.4:12345")
 
 	r.ServeHTTP(w, req)
 
@@ -126,3 +220,129 @@ func TestPayPayWebhookHandler_ForbiddenIP(t *testing.T) {
 		t.Errorf("expected 403, got %d", w.Code)
 	}
 }
+
+func TestPayPayWebhookHandler_StrictSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("PAYPAY_WEBHOOK_SECRET", "testsecret")
+	t.Setenv("PAYPAY_WEBHOOK_SIGNATURE_MODE", "strict")
+	t.Setenv("PAYPAY_WEBHOOK_IP_WHITE_LIST", "13.112.237.0/24")
+	t.Setenv("PAYPAY_WEBHOOK_TRUSTED_PROXIES", "10.0.0.0/8")
+
+	dbConn, q := setupTestDB(t)
+	r := gin.New()
+	r.POST("/api/paypay/webhook", NewPayPayWebhookHandler(dbConn, q).Handle)
+
+	body := `{"notification_type":"Transaction","merchant_id":"test-merchant","order_id":"strict-payment-id","merchant_order_id":"strict-merchant-id","order_amount":"100","state":"COMPLETED"}`
+	send := func(req *http.Request) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 署名なしは拒否
+	req, _ := http.NewRequest("POST", "/api/paypay/webhook", bytes.NewBuffer([]byte(body)))
+	req.RemoteAddr = "13.112.237.64:12345"
+	if code := send(req); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without signature, got %d", code)
+	}
+
+	// 時刻の無い旧形式の署名も拒否
+	req = newSignedPayPayWebhookRequest(body, "13.112.237.64:12345")
+	req.Header.Del(paypay.WebhookTimestampHeader)
+	if code := send(req); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without timestamp, got %d", code)
+	}
+
+	// 古い時刻の署名（リプレイ）は拒否
+	req, _ = http.NewRequest("POST", "/api/paypay/webhook", bytes.NewBuffer([]byte(body)))
+	timestamp, signature := paypay.SignWebhook("testsecret", time.Now().Add(-time.Hour), []byte(body))
+	req.Header.Set(paypay.WebhookTimestampHeader, timestamp)
+	req.Header.Set(paypay.WebhookSignatureHeader, signature)
+	req.RemoteAddr = "13.112.237.64:12345"
+	if code := send(req); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with stale timestamp, got %d", code)
+	}
+
+	// 信頼しない接続元のX-Forwarded-Forは使わない
+	req = newSignedPayPayWebhookRequest(body, "1.2.3.4:12345")
+	req.Header.Set("X-Forwarded-For", "13.112.237.64")
+	if code := send(req); code != http.StatusForbidden {
+		t.Errorf("expected 403 with spoofed X-Forwarded-For, got %d", code)
+	}
+
+	// 信頼するプロキシ経由なら転送元IPで判定する
+	req = newSignedPayPayWebhookRequest(body, "10.0.0.1:12345")
+	req.Header.Set("X-Forwarded-For", "13.112.237.64")
+	if code := send(req); code != http.StatusOK {
+		t.Errorf("expected 200 via trusted proxy, got %d", code)
+	}
+	var remoteIP string
+	if err := dbConn.QueryRow(`SELECT remote_ip FROM webhook_deliveries WHERE dedupe_key = 'Transaction:strict-payment-id:COMPLETED'`).Scan(&remoteIP); err != nil {
+		t.Fatalf("failed to query webhook delivery: %v", err)
+	}
+	if remoteIP != "13.112.237.64" {
+		t.Errorf("expected remote_ip 13.112.237.64, got %s", remoteIP)
+	}
+}
+
+func TestPayPayWebhookHandler_RejectsWithoutSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("PAYPAY_WEBHOOK_SECRET", "")
+	t.Setenv("PAYPAY_WEBHOOK_SIGNATURE_MODE", "")
+
+	dbConn, q := setupTestDB(t)
+	r := gin.New()
+	r.POST("/api/paypay/webhook", NewPayPayWebhookHandler(dbConn, q).Handle)
+
+	// シークレットが無い場合は署名なしのWebhookを受け付けず、保存もしない
+	body := `{"notification_type":"Transaction","merchant_id":"test-merchant","order_id":"nosecret-payment-id","merchant_order_id":"nosecret-merchant-id","order_amount":"100","state":"COMPLETED"}`
+	req, _ := http.NewRequest("POST", "/api/paypay/webhook", bytes.NewBuffer([]byte(body)))
+	req.RemoteAddr = "13.112.237.64:12345"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 without secret, got %d", w.Code)
+	}
+
+	var count int
+	if err := dbConn.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries`).Scan(&count); err != nil {
+		t.Fatalf("failed to count webhook deliveries: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected no stored delivery, got %d", count)
+	}
+}
+
+func TestPayPayWebhookHandler_RejectsInvalidBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("PAYPAY_WEBHOOK_SECRET", "testsecret")
+
+	dbConn, q := setupTestDB(t)
+	r := gin.New()
+	r.POST("/api/paypay/webhook", NewPayPayWebhookHandler(dbConn, q).Handle)
+	send := func(body string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newSignedPayPayWebhookRequest(body, "13.112.237.64:12345"))
+		return w.Code
+	}
+
+	// 上限を超えるボディは読み込まない
+	if code := send(`{"notification_type":"Transaction","padding":"` + strings.Repeat("a", maxPayPayWebhookBodyBytes) + `"}`); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for large body, got %d", code)
+	}
+	// 署名が正しくても解析できない・対象の決済が分からないボディは保存しない
+	if code := send(`not json`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unparseable body, got %d", code)
+	}
+	if code := send(`{"notification_type":"Transaction","state":"COMPLETED"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for body without order id, got %d", code)
+	}
+
+	var count int
+	if err := dbConn.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries`).Scan(&count); err != nil {
+		t.Fatalf("failed to count webhook deliveries: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected no stored delivery, got %d", count)
+	}
+}
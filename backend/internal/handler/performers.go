@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type PerformersHandler struct {
+	performers *usecase.PerformersUsecase
+}
+
+func NewPerformersHandler(performers *usecase.PerformersUsecase) *PerformersHandler {
+	return &PerformersHandler{performers: performers}
+}
+
+// GET /performers?q=
+func (h *PerformersHandler) ListPerformers(c *gin.Context) {
+	name := c.Query("q")
+
+	page, ok := parsePageRequest(c, "ListPerformers")
+	if !ok {
+		return
+	}
+
+	result, err := h.performers.ListPerformers(c.Request.Context(), name, page)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		log.Printf("[ListPerformers] InternalServerError: q=%s, err=%v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list performers"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"performers": result.Performers, "next_cursor": result.NextCursor})
+}
+
+// GET /performers/:id
+func (h *PerformersHandler) GetPerformer(c *gin.Context) {
+	id, ok := parsePerformerIDParam(c, "GetPerformer")
+	if !ok {
+		return
+	}
+
+	performer, err := h.performers.GetPerformer(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrPerformerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "performer not found"})
+			return
+		}
+		log.Printf("[GetPerformer] InternalServerError: performerID=%d, err=%v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get performer"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"performer": performer})
+}
+
+// GET /performers/:id/programs
+func (h *PerformersHandler) ListPerformerPrograms(c *gin.Context) {
+	id, ok := parsePerformerIDParam(c, "ListPerformerPrograms")
+	if !ok {
+		return
+	}
+	page, ok := parsePageRequest(c, "ListPerformerPrograms")
+	if !ok {
+		return
+	}
+
+	result, err := h.performers.ListPerformerPrograms(c.Request.Context(), id, page)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrPerformerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "performer not found"})
+		case errors.Is(err, usecase.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		default:
+			log.Printf("[ListPerformerPrograms] InternalServerError: performerID=%d, err=%v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list programs"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"programs": result.Programs, "next_cursor": result.NextCursor})
+}
+
+func parsePerformerIDParam(c *gin.Context, name string) (int64, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		log.Printf("[%s] BadRequest: invalid id. idStr=%s, err=%v", name, idStr, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPerformersRouter(h *PerformersHandler) *gin.Engine {
+	r := gin.New()
+	r.GET("/performers", h.ListPerformers)
+	r.GET("/performers/:id", h.GetPerformer)
+	r.GET("/performers/:id/programs", h.ListPerformerPrograms)
+	return r
+}
+
+// =============================================================================
+// GET /performers
+// =============================================================================
+
+func TestListPerformers_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	performers := [][4]string{
+		{"太郎", "山田", "タロウ", "ヤマダ"},
+		{"花子", "佐藤", "ハナコ", "サトウ"},
+		{"一郎", "伊藤", "イチロウ", "イトウ"},
+	}
+	for _, p := range performers {
+		_, err := dbConn.Exec(`INSERT INTO performers (first_name, last_name, first_name_kana, last_name_kana) VALUES ($1, $2, $3, $4)`,
+			p[0], p[1], p[2], p[3])
+		if err != nil {
+			t.Fatalf("failed to insert performer: %v", err)
+		}
+	}
+
+	r := setupPerformersRouter(NewPerformersHandler(usecase.NewPerformersUsecase(q)))
+
+	type listResp struct {
+		Performers []struct {
+			ID           int64  `json:"id"`
+			FullName     string `json:"full_name"`
+			FullNameKana string `json:"full_name_kana"`
+		} `json:"performers"`
+		NextCursor *string `json:"next_cursor"`
+	}
+	fetch := func(query string) listResp {
+		req, _ := http.NewRequest("GET", "/performers"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp listResp
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return resp
+	}
+
+	// 五十音順にページングできる
+	var names []string
+	resp := fetch("?limit=2")
+	for i := 0; i < 3; i++ {
+		for _, p := range resp.Performers {
+			names = append(names, p.FullName)
+		}
+		if resp.NextCursor == nil {
+			break
+		}
+		resp = fetch("?limit=2&cursor=" + *resp.NextCursor)
+	}
+	assert.Equal(t, []string{"伊藤一郎", "佐藤花子", "山田太郎"}, names)
+
+	// 漢字・ひらがな・カタカナ・半角カナ・空白入りで検索できる
+	for _, query := range []string{"山田", "やまだ", "ヤマダ", "ﾔﾏﾀﾞ", "山田 太郎", "たろう"} {
+		resp := fetch("?q=" + url.QueryEscape(query))
+		if assert.Len(t, resp.Performers, 1, query) {
+			assert.Equal(t, "山田太郎", resp.Performers[0].FullName)
+		}
+	}
+	// LIKEのワイルドカードはエスケープされる
+	assert.Empty(t, fetch("?q=%25").Performers)
+}
+
+// =============================================================================
+// GET /performers/:id, GET /performers/:id/programs
+// =============================================================================
+
+func TestGetPerformer_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	var performerID int64
+	err := dbConn.QueryRow(`INSERT INTO performers (first_name, last_name, first_name_kana, last_name_kana, image_path) VALUES ('太郎', '山田', 'タロウ', 'ヤマダ', 'https://example.com/yamada.jpg') RETURNING id`).Scan(&performerID)
+	if err != nil {
+		t.Fatalf("failed to insert performer: %v", err)
+	}
+	insertProgram := func(title string, isPublic bool, createdAt string) int64 {
+		var id int64
+		err := dbConn.QueryRow(`INSERT INTO programs (title, video_path, is_public, created_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+			title, "/video/"+title+".mp4", isPublic, createdAt).Scan(&id)
+		if err != nil {
+			t.Fatalf("failed to insert program: %v", err)
+		}
+		if _, err := dbConn.Exec(`INSERT INTO program_performers (program_id, performer_id) VALUES ($1, $2)`, id, performerID); err != nil {
+			t.Fatalf("failed to insert program_performers: %v", err)
+		}
+		return id
+	}
+	insertProgram("perf-old", true, "2026-01-01T00:00:00Z")
+	insertProgram("perf-new", true, "2026-01-02T00:00:00Z")
+	insertProgram("perf-private", false, "2026-01-03T00:00:00Z")
+
+	r := setupPerformersRouter(NewPerformersHandler(usecase.NewPerformersUsecase(q)))
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/performers/%d", performerID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var detail struct {
+		Performer struct {
+			FullName     string  `json:"full_name"`
+			FullNameKana string  `json:"full_name_kana"`
+			ImageUrl     *string `json:"image_url"`
+			ProgramCount int64   `json:"program_count"`
+		} `json:"performer"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, "山田太郎", detail.Performer.FullName)
+	assert.Equal(t, "ヤマダタロウ", detail.Performer.FullNameKana)
+	if assert.NotNil(t, detail.Performer.ImageUrl) {
+		assert.Equal(t, "https://example.com/yamada.jpg", *detail.Performer.ImageUrl)
+	}
+	// 非公開番組は数えない
+	assert.Equal(t, int64(2), detail.Performer.ProgramCount)
+
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/performers/%d/programs", performerID), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var programs struct {
+		Programs []map[string]interface{} `json:"programs"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &programs))
+	if assert.Len(t, programs.Programs, 2) {
+		assert.Equal(t, "perf-new", programs.Programs[0]["title"])
+		assert.Equal(t, "perf-old", programs.Programs[1]["title"])
+	}
+
+	for _, path := range []string{"/performers/999999", "/performers/999999/programs"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+	req, _ = http.NewRequest("GET", "/performers/abc", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
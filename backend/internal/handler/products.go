@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/chan-shizu/SZer/internal/middleware"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type ProductsHandler struct {
+	products *usecase.ProductsUsecase
+}
+
+func NewProductsHandler(products *usecase.ProductsUsecase) *ProductsHandler {
+	return &ProductsHandler{products: products}
+}
+
+// GET /products（販売中のもののみ）
+func (h *ProductsHandler) ListProducts(c *gin.Context) {
+	products, err := h.products.ListProducts(c.Request.Context(), true)
+	if err != nil {
+		log.Printf("[ListProducts] InternalServerError: err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list products"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"products": products})
+}
+
+// GET /products/:id
+func (h *ProductsHandler) GetProduct(c *gin.Context) {
+	id, ok := parseProductIDParam(c, "GetProduct")
+	if !ok {
+		return
+	}
+
+	product, err := h.products.GetProduct(c.Request.Context(), id, true)
+	if err != nil {
+		if errors.Is(err, usecase.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+			return
+		}
+		log.Printf("[GetProduct] InternalServerError: productID=%d, err=%v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get product"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"product": product})
+}
+
+// GET /me/products（有効期限内の購入済み商品）
+func (h *ProductsHandler) ListMyProducts(c *gin.Context) {
+	userID, err := middleware.UserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	grants, err := h.products.ListMyProducts(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("[ListMyProducts] InternalServerError: userID=%s, err=%v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list products"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"products": grants})
+}
+
+func parseProductIDParam(c *gin.Context, name string) (int64, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		log.Printf("[%s] BadRequest: invalid id. idStr=%s, err=%v", name, idStr, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
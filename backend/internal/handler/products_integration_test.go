@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// IsUserPermittedForProgram（商品の閲覧権限）
+// =============================================================================
+
+func TestIsUserPermittedForProgram_ProductGrants(t *testing.T) {
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	programID := seedPurchasableProgram(t, dbConn, "grant-user", 500)
+	otherProgramID := seedPurchasableProgram(t, dbConn, "grant-user", 500)
+	var freeProgramID, performerID int64
+	require.NoError(t, dbConn.QueryRow(`INSERT INTO programs (title, video_path) VALUES ('free', '/video/free.mp4') RETURNING id`).Scan(&freeProgramID))
+	require.NoError(t, dbConn.QueryRow(`INSERT INTO performers (first_name, last_name, first_name_kana, last_name_kana) VALUES ('花子', '山田', 'はなこ', 'やまだ') RETURNING id`).Scan(&performerID))
+	_, err := dbConn.Exec(`INSERT INTO program_performers (program_id, performer_id) VALUES ($1, $3), ($2, $3)`, programID, freeProgramID, performerID)
+	require.NoError(t, err)
+
+	permitted := func(programID int64) bool {
+		t.Helper()
+		ok, err := q.IsUserPermittedForProgram(ctx, db.IsUserPermittedForProgramParams{UserID: "grant-user", ProgramID: programID})
+		require.NoError(t, err)
+		return ok
+	}
+	grant := func(productID int64, expiresAt any) {
+		t.Helper()
+		_, err := dbConn.Exec(`INSERT INTO user_product_grants (user_id, product_id, expires_at) VALUES ('grant-user', $1, $2)`, productID, expiresAt)
+		require.NoError(t, err)
+	}
+	reset := func() {
+		t.Helper()
+		_, err := dbConn.Exec(`DELETE FROM user_product_grants`)
+		require.NoError(t, err)
+	}
+
+	products := usecase.NewProductsUsecase(dbConn, q)
+	days := int32(30)
+
+	// bundleは含まれる番組だけ
+	bundle, err := products.CreateProduct(ctx, usecase.ProductInput{Kind: usecase.ProductKindBundle, Name: "bundle", Price: 800, ProgramIDs: []int64{programID}})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{programID}, bundle.ProgramIDs)
+	assert.False(t, permitted(programID))
+	grant(bundle.ID, nil)
+	assert.True(t, permitted(programID))
+	assert.False(t, permitted(otherProgramID))
+	reset()
+
+	// performer_packは出演者の限定公開番組
+	pack, err := products.CreateProduct(ctx, usecase.ProductInput{Kind: usecase.ProductKindPerformerPack, Name: "pack", Price: 800, PerformerID: &performerID})
+	require.NoError(t, err)
+	grant(pack.ID, nil)
+	assert.True(t, permitted(programID))
+	assert.False(t, permitted(otherProgramID))
+	assert.False(t, permitted(freeProgramID))
+	reset()
+
+	// passは期限内なら全ての限定公開番組
+	pass, err := products.CreateProduct(ctx, usecase.ProductInput{Kind: usecase.ProductKindPass, Name: "pass", Price: 3000, DurationDays: &days})
+	require.NoError(t, err)
+	grant(pass.ID, time.Now().Add(time.Hour))
+	assert.True(t, permitted(programID))
+	assert.True(t, permitted(otherProgramID))
+	reset()
+
+	// 期限切れ・取り消し済みは閲覧できない
+	grant(pass.ID, time.Now().Add(-time.Hour))
+	assert.False(t, permitted(programID))
+	_, err = dbConn.Exec(`INSERT INTO user_product_grants (user_id, product_id, revoked_at) VALUES ('grant-user', $1, now())`, bundle.ID)
+	require.NoError(t, err)
+	assert.False(t, permitted(programID))
+}
+
+// =============================================================================
+// 商品の管理API
+// =============================================================================
+
+func TestAdminProducts_CreateAndUpdate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	programID := seedPurchasableProgram(t, dbConn, "product-admin", 500)
+	uc := usecase.NewProductsUsecase(dbConn, q)
+	admin := NewAdminProductsHandler(uc)
+	public := NewProductsHandler(uc)
+	r := gin.New()
+	r.Use(MockOptionalAuth("product-admin"))
+	r.POST("/admin/products", admin.CreateProduct)
+	r.PUT("/admin/products/:id", admin.UpdateProduct)
+	r.GET("/products", public.ListProducts)
+	r.GET("/products/:id", public.GetProduct)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/admin/products", `{"kind":"bundle","name":"bundle","price":800}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/admin/products", `{"kind":"bundle","name":"bundle","price":800,"program_ids":[999999]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/admin/products", `{"kind":"performer_pack","name":"pack","price":800,"performer_id":999999}`).Code)
+
+	w := send("POST", "/admin/products", fmt.Sprintf(`{"kind":"bundle","name":"bundle","price":800,"program_ids":[%d]}`, programID))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Product usecase.Product `json:"product"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, created.Product.IsActive)
+
+	path := fmt.Sprintf("/products/%d", created.Product.ID)
+	w = send("GET", path, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"program_ids":[%d]`, programID))
+
+	// 種類は変更できない
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/admin"+path, `{"kind":"pass","name":"pass","price":800,"duration_days":30}`).Code)
+
+	// 販売停止すると公開APIからは見えない
+	w = send("PUT", "/admin"+path, fmt.Sprintf(`{"name":"bundle","price":900,"program_ids":[%d],"is_active":false}`, programID))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"price":900`)
+	assert.Equal(t, http.StatusNotFound, send("GET", path, "").Code)
+	assert.Equal(t, `{"products":[]}`, send("GET", "/products", "").Body.String())
+	assert.Equal(t, http.StatusNotFound, send("PUT", "/admin/products/999999", `{"name":"x","price":1}`).Code)
+}
+
+// =============================================================================
+// 商品のCheckout → 支払い → 返金
+// =============================================================================
+
+func TestPayPayProductCheckout_WithSimulator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sim := setupPayPaySim(t)
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	programID := seedPurchasableProgram(t, dbConn, "pass-buyer", 500)
+	days := int32(30)
+	pass, err := usecase.NewProductsUsecase(dbConn, q).CreateProduct(ctx, usecase.ProductInput{Kind: usecase.ProductKindPass, Name: "30日パス", Price: 3000, DurationDays: &days})
+	require.NoError(t, err)
+
+	r := newPayPayTestRouter(usecase.NewPaymentsUsecase(dbConn, q, nil), "pass-buyer")
+	r.GET("/me/products", NewProductsHandler(usecase.NewProductsUsecase(dbConn, q)).ListMyProducts)
+	checkoutProduct := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(fmt.Sprintf(`{"product_id": %d}`, pass.ID)))
+		r.ServeHTTP(w, req)
+		return w
+	}
+	permitted := func() bool {
+		ok, err := q.IsUserPermittedForProgram(ctx, db.IsUserPermittedForProgramParams{UserID: "pass-buyer", ProgramID: programID})
+		require.NoError(t, err)
+		return ok
+	}
+
+	// program_idとproduct_idの両方は指定できない
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(fmt.Sprintf(`{"program_id": %d, "product_id": %d}`, programID, pass.ID)))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = checkoutProduct()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res struct {
+		MerchantPaymentID string `json:"merchant_payment_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	mpid := res.MerchantPaymentID
+
+	payment, ok := sim.Payment(mpid)
+	require.True(t, ok)
+	assert.Equal(t, int32(3000), payment.Amount)
+
+	require.NoError(t, sim.Complete(mpid))
+	confirmed := confirm(t, r, mpid)
+	assert.Equal(t, "COMPLETED", confirmed.Status)
+	assert.True(t, confirmed.Granted)
+	assert.True(t, permitted())
+	// 番組単体の購入としては記録しない
+	assert.False(t, isPermitted(t, dbConn, "pass-buyer", programID))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/products", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var mine struct {
+		Products []usecase.UserProductGrant `json:"products"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &mine))
+	require.Len(t, mine.Products, 1)
+	require.NotNil(t, mine.Products[0].ExpiresAt)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), *mine.Products[0].ExpiresAt, time.Minute)
+
+	// 期限内は同じ商品を購入できない
+	assert.Equal(t, http.StatusConflict, checkoutProduct().Code)
+
+	// 全額返金で閲覧権限が取り消される
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/paypay/topups/"+mpid+"/refunds", strings.NewReader(`{}`))
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.False(t, permitted())
+}
@@ -5,9 +5,11 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"errors"
 
+	"github.com/chan-shizu/SZer/db"
 	"github.com/chan-shizu/SZer/internal/middleware"
 	"github.com/chan-shizu/SZer/internal/usecase"
 	"github.com/gin-gonic/gin"
@@ -22,6 +24,8 @@ type upsertWatchHistoryRequest struct {
 	ProgramID       int64 `json:"program_id"`
 	PositionSeconds int32 `json:"position_seconds"`
 	IsCompleted     bool  `json:"is_completed"`
+	// 再生開始の通知。視聴回数はこれがtrueのときだけ数える（シーク・離脱時の進捗保存では数えない）
+	Started bool `json:"started"`
 }
 
 func NewProgramsHandler(programs *usecase.ProgramsUsecase) *ProgramsHandler {
@@ -53,6 +57,7 @@ func (h *ProgramsHandler) ProgramDetails(c *gin.Context) {
 
 	// 限定公開判定
 	isPermitted := true
+	var rental *usecase.ProgramRental
 	if program.IsLimitedRelease {
 		isPermitted, err = h.programs.IsUserPermittedForProgram(c.Request.Context(), userID, id)
 		if err != nil {
@@ -60,16 +65,20 @@ func (h *ProgramsHandler) ProgramDetails(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permission"})
 			return
 		}
-	}
-
-	// 視聴回数インクリメント（許可ユーザーのみ）
-	if isPermitted {
-		err = h.programs.IncrementViewCount(c.Request.Context(), id)
+		// レンタル中なら残り時間を返す
+		rental, err = h.programs.GetProgramRental(c.Request.Context(), userID, id)
 		if err != nil {
-			log.Printf("[ProgramDetails] InternalServerError: failed to increment view count. userID=%s, id=%d, err=%v", userID, id, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to increment view count"})
+			log.Printf("[ProgramDetails] InternalServerError: failed to get rental. userID=%s, id=%d, err=%v", userID, id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permission"})
 			return
 		}
+		// 購入転換率の集計用（バックグラウンドで記録し、記録できなくても詳細は返す）
+		if !isPermitted {
+			viewerKey := usecase.ViewerKey(userID, c.ClientIP()+"\x00"+c.Request.UserAgent())
+			if !h.programs.RecordDetailView(id, viewerKey) {
+				log.Printf("[ProgramDetails] detail view dropped: too many pending records. userID=%s, id=%d", userID, id)
+			}
+		}
 	}
 
 	// VideoURLをmap格納前に編集
@@ -80,6 +89,7 @@ func (h *ProgramsHandler) ProgramDetails(c *gin.Context) {
 	resp := gin.H{
 		"program": program,
 		"is_permitted": isPermitted,
+		"rental": rental,
 	}
 	c.JSON(http.StatusOK, resp)
 }
@@ -152,11 +162,8 @@ func (h *ProgramsHandler) UnlikeProgram(c *gin.Context) {
 }
 
 func (h *ProgramsHandler) UpsertWatchHistory(c *gin.Context) {
-	userID, err := middleware.UserIDFromContext(c)
-	if err != nil || userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
+	// 未ログインでも再生開始の記録（視聴回数）のため受け付ける。視聴履歴の保存はログイン時のみ
+	userID, _ := middleware.UserIDFromContext(c)
 
 	var req upsertWatchHistoryRequest
 	dec := json.NewDecoder(c.Request.Body)
@@ -177,38 +184,78 @@ func (h *ProgramsHandler) UpsertWatchHistory(c *gin.Context) {
  		return
  	}
 
-	wh, err := h.programs.UpsertWatchHistory(c.Request.Context(), userID, req.ProgramID, req.PositionSeconds, req.IsCompleted)
-	if err != nil {
- 		log.Printf("[UpsertWatchHistory] InternalServerError: failed to upsert watch history. userID=%s, req=%+v, err=%v", userID, req, err)
- 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to upsert watch history"})
- 		return
+	var wh *db.WatchHistory
+	if userID != "" {
+		res, err := h.programs.UpsertWatchHistory(c.Request.Context(), userID, req.ProgramID, req.PositionSeconds, req.IsCompleted)
+		if err != nil {
+			log.Printf("[UpsertWatchHistory] InternalServerError: failed to upsert watch history. userID=%s, req=%+v, err=%v", userID, req, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to upsert watch history"})
+			return
+		}
+		wh = &res
 	}
+
+	// 視聴イベントの記録に失敗しても再生は妨げない
+	// ClientIPはTRUSTED_PROXIESに含まれるプロキシ経由の場合のみX-Forwarded-Forを使う（router参照）
+	if req.Started {
+		viewerKey := usecase.ViewerKey(userID, c.ClientIP()+"\x00"+c.Request.UserAgent())
+		if _, err := h.programs.RecordView(c.Request.Context(), req.ProgramID, userID, viewerKey); err != nil {
+			log.Printf("[UpsertWatchHistory] failed to record view. userID=%s, programID=%d, err=%v", userID, req.ProgramID, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"watch_history": wh})
 }
 
 func (h *ProgramsHandler) ListPrograms(c *gin.Context) {
-	title := c.Query("title")
-	tagIDsStr := c.QueryArray("tag_ids")
-	tagIDs := make([]int64, 0, len(tagIDsStr))
-	for _, v := range tagIDsStr {
-		id, err := strconv.ParseInt(v, 10, 64)
-		if err != nil {
- 			log.Printf("[ListPrograms] 不正リクエスト: tag_ids変換失敗 tag_idsStr=%v, err=%v", tagIDsStr, err)
- 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag_ids"})
- 			return
-		}
-		tagIDs = append(tagIDs, id)
+	filter := usecase.ProgramListFilter{
+		Title:     c.Query("title"),
+		TagMatch:  c.Query("tag_match"),
+		PriceType: c.Query("price_type"),
+		Sort:      c.Query("sort"),
+	}
+
+	var ok bool
+	if filter.TagIDs, ok = parseInt64QueryArray(c, "ListPrograms", "tag_ids"); !ok {
+		return
+	}
+	if filter.PerformerIDs, ok = parseInt64QueryArray(c, "ListPrograms", "performer_ids"); !ok {
+		return
+	}
+	if filter.CreatedFrom, ok = parseTimeQuery(c, "ListPrograms", "created_from", false); !ok {
+		return
+	}
+	if filter.CreatedTo, ok = parseTimeQuery(c, "ListPrograms", "created_to", true); !ok {
+		return
 	}
 
-	programs, err := h.programs.ListPrograms(c.Request.Context(), title, tagIDs)
+	page, ok := parsePageRequest(c, "ListPrograms")
+	if !ok {
+		return
+	}
+
+	result, err := h.programs.ListPrograms(c.Request.Context(), filter, page)
 	if err != nil {
- 		log.Printf("[ListPrograms] サーバーエラー: program一覧取得失敗 title=%s, tagIDs=%v, err=%v", title, tagIDs, err)
- 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list programs"})
- 		return
+		switch {
+		case errors.Is(err, usecase.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		case errors.Is(err, usecase.ErrInvalidProgramSort),
+			errors.Is(err, usecase.ErrInvalidPriceType),
+			errors.Is(err, usecase.ErrInvalidTagMatch),
+			errors.Is(err, usecase.ErrInvalidCreatedRange):
+			log.Printf("[ListPrograms] 不正リクエスト: filter=%+v, err=%v", filter, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("[ListPrograms] サーバーエラー: program一覧取得失敗 filter=%+v, err=%v", filter, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list programs"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"programs": programs,
+		"programs":    result.Programs,
+		"next_cursor": result.NextCursor,
 	})
 }
 
@@ -225,7 +272,13 @@ func (h *ProgramsHandler) Top(c *gin.Context) {
 	})
 }
 
+// GET /top/liked?window=1d|7d|30d|trending（windowなしは累計）
 func (h *ProgramsHandler) TopLiked(c *gin.Context) {
+	if window := c.Query("window"); window != "" {
+		h.topRanked(c, "TopLiked", usecase.RankingMetricLikes, window)
+		return
+	}
+
 	programs, err := h.programs.ListTopLikedPrograms(c.Request.Context())
 	if err != nil {
  		log.Printf("[TopLiked] サーバーエラー: いいね多いprogram取得失敗 err=%v", err)
@@ -238,7 +291,13 @@ func (h *ProgramsHandler) TopLiked(c *gin.Context) {
 	})
 }
 
+// GET /top/viewed?window=1d|7d|30d|trending（windowなしは累計）
 func (h *ProgramsHandler) TopViewed(c *gin.Context) {
+	if window := c.Query("window"); window != "" {
+		h.topRanked(c, "TopViewed", usecase.RankingMetricViews, window)
+		return
+	}
+
 	programs, err := h.programs.ListTopViewedPrograms(c.Request.Context())
 	if err != nil {
  		log.Printf("[TopViewed] サーバーエラー: 視聴多いprogram取得失敗 err=%v", err)
@@ -251,6 +310,24 @@ func (h *ProgramsHandler) TopViewed(c *gin.Context) {
 	})
 }
 
+func (h *ProgramsHandler) topRanked(c *gin.Context, name, metric, window string) {
+	programs, err := h.programs.ListTopRankedPrograms(c.Request.Context(), metric, window, time.Now())
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidRankingWindow) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid window"})
+			return
+		}
+		log.Printf("[%s] InternalServerError: window=%s, err=%v", name, window, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get ranking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"programs": programs,
+		"window":   window,
+	})
+}
+
 func (h *ProgramsHandler) ListWatchingPrograms(c *gin.Context) {
 	userID, err := middleware.UserIDFromContext(c)
 	if err != nil {
@@ -259,14 +336,23 @@ func (h *ProgramsHandler) ListWatchingPrograms(c *gin.Context) {
  		return
 	}
 
-	programs, err := h.programs.ListWatchingPrograms(c.Request.Context(), userID)
+	page, ok := parsePageRequest(c, "ListWatchingPrograms")
+	if !ok {
+		return
+	}
+
+	result, err := h.programs.ListWatchingPrograms(c.Request.Context(), userID, page)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
  		log.Printf("[ListWatchingPrograms] サーバーエラー: 視聴中program一覧取得失敗 userID=%s, err=%v", userID, err)
  		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list watching programs"})
  		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"programs": programs})
+	c.JSON(http.StatusOK, gin.H{"programs": result.Programs, "next_cursor": result.NextCursor})
 }
 
 func (h *ProgramsHandler) ListLikedPrograms(c *gin.Context) {
@@ -277,14 +363,23 @@ func (h *ProgramsHandler) ListLikedPrograms(c *gin.Context) {
  		return
 	}
 
-	programs, err := h.programs.ListLikedPrograms(c.Request.Context(), userID)
+	page, ok := parsePageRequest(c, "ListLikedPrograms")
+	if !ok {
+		return
+	}
+
+	result, err := h.programs.ListLikedPrograms(c.Request.Context(), userID, page)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
  		log.Printf("[ListLikedPrograms] サーバーエラー: いいねしたprogram一覧取得失敗 userID=%s, err=%v", userID, err)
  		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list liked programs"})
  		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"programs": programs})
+	c.JSON(http.StatusOK, gin.H{"programs": result.Programs, "next_cursor": result.NextCursor})
 }
 
 func (h *ProgramsHandler) ListPurchasedPrograms(c *gin.Context) {
@@ -295,12 +390,21 @@ func (h *ProgramsHandler) ListPurchasedPrograms(c *gin.Context) {
 		return
 	}
 
-	programs, err := h.programs.ListPurchasedPrograms(c.Request.Context(), userID)
+	page, ok := parsePageRequest(c, "ListPurchasedPrograms")
+	if !ok {
+		return
+	}
+
+	result, err := h.programs.ListPurchasedPrograms(c.Request.Context(), userID, page)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
 		log.Printf("[ListPurchasedPrograms] サーバーエラー: 購入済みprogram一覧取得失敗 userID=%s, err=%v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list purchased programs"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"programs": programs})
+	c.JSON(http.StatusOK, gin.H{"programs": result.Programs, "next_cursor": result.NextCursor})
 }
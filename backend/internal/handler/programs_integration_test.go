@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/chan-shizu/SZer/internal/usecase"
@@ -153,6 +155,107 @@ func TestTopViewed_Integration(t *testing.T) {
 	assert.Equal(t, "viewed-more", resp.Programs[0]["title"])
 }
 
+func TestTopViewed_Window_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	// 累計は多いが最近は見られていない番組、今週よく見られた番組、今日見られた番組
+	insertProgram := func(title string, viewCount int, daysAgo int, views int) {
+		var id int64
+		err := dbConn.QueryRow(`INSERT INTO programs (title, video_path, view_count) VALUES ($1, $2, $3) RETURNING id`,
+			title, "/video/"+title+".mp4", viewCount).Scan(&id)
+		if err != nil {
+			t.Fatalf("failed to insert program: %v", err)
+		}
+		_, err = dbConn.Exec(`INSERT INTO program_daily_stats (program_id, stat_date, views) VALUES ($1, (now() AT TIME ZONE 'Asia/Tokyo')::date - $2::int, $3)`,
+			id, daysAgo, views)
+		if err != nil {
+			t.Fatalf("failed to insert program_daily_stats: %v", err)
+		}
+	}
+	insertProgram("window-old", 1000, 20, 500)
+	insertProgram("window-week", 50, 5, 50)
+	insertProgram("window-today", 30, 0, 30)
+
+	h := NewProgramsHandler(usecase.NewProgramsUsecase(q, nil))
+	r := gin.New()
+	r.GET("/top/viewed", h.TopViewed)
+
+	titles := func(query string) []string {
+		req, _ := http.NewRequest("GET", "/top/viewed"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Programs []struct {
+				Title string `json:"title"`
+			} `json:"programs"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		var result []string
+		for _, p := range resp.Programs {
+			result = append(result, p.Title)
+		}
+		return result
+	}
+
+	// windowなしは従来どおり累計
+	assert.Equal(t, []string{"window-old", "window-week", "window-today"}, titles(""))
+	// 期間内に実績のない番組は並べない
+	assert.Equal(t, []string{"window-today"}, titles("?window=1d"))
+	assert.Equal(t, []string{"window-week", "window-today"}, titles("?window=7d"))
+	assert.Equal(t, []string{"window-old", "window-week", "window-today"}, titles("?window=30d"))
+	// トレンドは古い集計ほど減衰する
+	assert.Equal(t, []string{"window-today", "window-week", "window-old"}, titles("?window=trending"))
+
+	req, _ := http.NewRequest("GET", "/top/viewed?window=1y", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestStatsAggregator_RefreshLikes_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	userID := "stats-user"
+	_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified") VALUES ($1, $2, $3, true)`,
+		userID, "集計ユーザー", "stats-user@example.com")
+	if err != nil {
+		t.Fatalf("failed to insert test user: %v", err)
+	}
+	var programID int64
+	err = dbConn.QueryRow(`INSERT INTO programs (title, video_path) VALUES ('stats-program', '/video/stats.mp4') RETURNING id`).Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert program: %v", err)
+	}
+	if _, err := dbConn.Exec(`INSERT INTO likes (user_id, program_id) VALUES ($1, $2)`, userID, programID); err != nil {
+		t.Fatalf("failed to insert like: %v", err)
+	}
+
+	aggregator := usecase.NewStatsAggregator(dbConn, q)
+	todayLikes := func() int64 {
+		if _, err := aggregator.AggregateOnce(context.Background()); err != nil {
+			t.Fatalf("failed to aggregate stats: %v", err)
+		}
+		var likes int64
+		err := dbConn.QueryRow(`SELECT COALESCE(SUM(likes), 0) FROM program_daily_stats WHERE program_id = $1 AND stat_date = (now() AT TIME ZONE 'Asia/Tokyo')::date`, programID).Scan(&likes)
+		if err != nil {
+			t.Fatalf("failed to query program_daily_stats: %v", err)
+		}
+		return likes
+	}
+	assert.Equal(t, int64(1), todayLikes())
+
+	// いいね解除は再集計で反映される
+	if _, err := dbConn.Exec(`DELETE FROM likes WHERE program_id = $1`, programID); err != nil {
+		t.Fatalf("failed to delete like: %v", err)
+	}
+	assert.Equal(t, int64(0), todayLikes())
+}
+
 // =============================================================================
 // GET /programs/:id (ProgramDetails)
 // =============================================================================
@@ -348,7 +451,8 @@ func TestProgramDetails_LimitedReleaseAndPrice_Integration(t *testing.T) {
 	assert.Equal(t, float64(500), resp.Program["price"])
 }
 
-func TestProgramDetails_ViewCountIncrement_Integration(t *testing.T) {
+// 詳細取得（リロード・クローラー・プリフェッチ）では視聴回数を増やさない
+func TestProgramDetails_DoesNotIncrementViewCount_Integration(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	dbConn, q := setupTestDB(t)
 
@@ -382,7 +486,7 @@ func TestProgramDetails_ViewCountIncrement_Integration(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to query view_count: %v", err)
 	}
-	assert.Equal(t, 2, viewCount)
+	assert.Equal(t, 0, viewCount)
 }
 
 func TestProgramDetails_LimitedReleasePermission_Integration(t *testing.T) {
@@ -520,6 +624,204 @@ func TestListPrograms_WithTitleFilter_Integration(t *testing.T) {
 	assert.Equal(t, "unique-title-xyz", resp.Programs[0]["title"])
 }
 
+func TestListPrograms_Pagination_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	// 同一created_atの番組を含めてもID順で安定して並ぶこと
+	for i := 1; i <= 5; i++ {
+		_, err := dbConn.Exec(`INSERT INTO programs (title, video_path, created_at) VALUES ($1, $2, '2026-01-01T00:00:00Z')`,
+			fmt.Sprintf("page-program-%d", i), fmt.Sprintf("/video/page%d.mp4", i))
+		if err != nil {
+			t.Fatalf("failed to insert program: %v", err)
+		}
+	}
+
+	programsUC := usecase.NewProgramsUsecase(q, nil)
+	h := NewProgramsHandler(programsUC)
+	r := gin.New()
+	r.GET("/programs", h.ListPrograms)
+
+	type pageResp struct {
+		Programs   []map[string]interface{} `json:"programs"`
+		NextCursor *string                  `json:"next_cursor"`
+	}
+	fetch := func(query string) pageResp {
+		req, _ := http.NewRequest("GET", "/programs?title=page-program&limit=2"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp pageResp
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return resp
+	}
+
+	var titles []string
+	resp := fetch("")
+	for page := 0; ; page++ {
+		assert.LessOrEqual(t, len(resp.Programs), 2)
+		for _, p := range resp.Programs {
+			titles = append(titles, p["title"].(string))
+		}
+		if resp.NextCursor == nil || page > 5 {
+			break
+		}
+		// ページ取得の合間に新しい番組が追加されても、後続ページはずれない
+		if page == 0 {
+			_, err := dbConn.Exec(`INSERT INTO programs (title, video_path) VALUES ($1, $2)`, "page-program-new", "/video/pagenew.mp4")
+			if err != nil {
+				t.Fatalf("failed to insert program: %v", err)
+			}
+		}
+		resp = fetch("&cursor=" + *resp.NextCursor)
+	}
+	assert.Equal(t, []string{"page-program-5", "page-program-4", "page-program-3", "page-program-2", "page-program-1"}, titles)
+
+	// 不正なカーソル・limitは400
+	for _, query := range []string{"/programs?cursor=invalid", "/programs?limit=abc", "/programs?limit=0"} {
+		req, _ := http.NewRequest("GET", query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestListPrograms_SortAndFilter_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	insertProgram := func(title string, viewCount int, isLimited bool, price int, createdAt string) int64 {
+		var id int64
+		err := dbConn.QueryRow(`INSERT INTO programs (title, video_path, view_count, is_limited_release, price, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+			title, "/video/"+title+".mp4", viewCount, isLimited, price, createdAt).Scan(&id)
+		if err != nil {
+			t.Fatalf("failed to insert program: %v", err)
+		}
+		return id
+	}
+	p1 := insertProgram("sf-a", 10, false, 0, "2026-01-01T00:00:00Z")
+	p2 := insertProgram("sf-b", 30, true, 500, "2026-01-02T00:00:00Z")
+	p3 := insertProgram("sf-c", 20, true, 200, "2026-01-03T00:00:00Z")
+
+	var tagA, tagB, performerID int64
+	if err := dbConn.QueryRow(`INSERT INTO category_tags (name) VALUES ('sf-tag-a') RETURNING id`).Scan(&tagA); err != nil {
+		t.Fatalf("failed to insert tag: %v", err)
+	}
+	if err := dbConn.QueryRow(`INSERT INTO category_tags (name) VALUES ('sf-tag-b') RETURNING id`).Scan(&tagB); err != nil {
+		t.Fatalf("failed to insert tag: %v", err)
+	}
+	err := dbConn.QueryRow(`INSERT INTO performers (first_name, last_name, first_name_kana, last_name_kana) VALUES ('太郎', '山田', 'タロウ', 'ヤマダ') RETURNING id`).Scan(&performerID)
+	if err != nil {
+		t.Fatalf("failed to insert performer: %v", err)
+	}
+	for _, rel := range [][2]int64{{p1, tagA}, {p2, tagA}, {p2, tagB}, {p3, tagB}} {
+		if _, err := dbConn.Exec(`INSERT INTO program_category_tags (program_id, tag_id) VALUES ($1, $2)`, rel[0], rel[1]); err != nil {
+			t.Fatalf("failed to insert program_category_tags: %v", err)
+		}
+	}
+	if _, err := dbConn.Exec(`INSERT INTO program_performers (program_id, performer_id) VALUES ($1, $2)`, p3, performerID); err != nil {
+		t.Fatalf("failed to insert program_performers: %v", err)
+	}
+	if _, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified") VALUES ('sf-user', 'sf', 'sf@example.com', true)`); err != nil {
+		t.Fatalf("failed to insert test user: %v", err)
+	}
+	if _, err := dbConn.Exec(`INSERT INTO likes (user_id, program_id) VALUES ('sf-user', $1)`, p1); err != nil {
+		t.Fatalf("failed to insert like: %v", err)
+	}
+	// 取り消したいいねはすぐに並び順から外れる
+	if _, err := dbConn.Exec(`INSERT INTO likes (user_id, program_id) VALUES ('sf-user', $1)`, p2); err != nil {
+		t.Fatalf("failed to insert like: %v", err)
+	}
+	if _, err := dbConn.Exec(`DELETE FROM likes WHERE user_id = 'sf-user' AND program_id = $1`, p2); err != nil {
+		t.Fatalf("failed to delete like: %v", err)
+	}
+
+	programsUC := usecase.NewProgramsUsecase(q, nil)
+	h := NewProgramsHandler(programsUC)
+	r := gin.New()
+	r.GET("/programs", h.ListPrograms)
+
+	cases := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "newest (default)", query: "", want: []string{"sf-c", "sf-b", "sf-a"}},
+		{name: "most viewed", query: "&sort=most_viewed", want: []string{"sf-b", "sf-c", "sf-a"}},
+		{name: "most liked", query: "&sort=most_liked", want: []string{"sf-a", "sf-c", "sf-b"}},
+		{name: "price asc", query: "&sort=price_asc", want: []string{"sf-a", "sf-c", "sf-b"}},
+		{name: "price desc", query: "&sort=price_desc", want: []string{"sf-b", "sf-c", "sf-a"}},
+		{name: "free", query: "&price_type=free", want: []string{"sf-a"}},
+		{name: "paid", query: "&price_type=paid", want: []string{"sf-c", "sf-b"}},
+		{name: "performer", query: fmt.Sprintf("&performer_ids=%d", performerID), want: []string{"sf-c"}},
+		{name: "tags all", query: fmt.Sprintf("&tag_ids=%d&tag_ids=%d", tagA, tagB), want: []string{"sf-b"}},
+		{name: "tags any", query: fmt.Sprintf("&tag_ids=%d&tag_ids=%d&tag_match=any", tagA, tagB), want: []string{"sf-c", "sf-b", "sf-a"}},
+		{name: "created range", query: "&created_from=2026-01-02&created_to=2026-01-02T12:00:00Z", want: []string{"sf-b"}},
+		{name: "created to is exclusive", query: "&created_to=2026-01-01T09:00:00%2B09:00", want: []string{}},
+		{name: "created to date includes the day (JST)", query: "&created_to=2026-01-01", want: []string{"sf-a"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/programs?title=sf-"+tc.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var resp struct {
+				Programs []map[string]interface{} `json:"programs"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			titles := []string{}
+			for _, p := range resp.Programs {
+				titles = append(titles, p["title"].(string))
+			}
+			assert.Equal(t, tc.want, titles)
+		})
+	}
+
+	// ソート順を変えてページングしても重複・欠落しない
+	var titles []string
+	cursor := ""
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequest("GET", "/programs?title=sf-&sort=price_asc&limit=1"+cursor, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Programs   []map[string]interface{} `json:"programs"`
+			NextCursor *string                  `json:"next_cursor"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		for _, p := range resp.Programs {
+			titles = append(titles, p["title"].(string))
+		}
+		if resp.NextCursor == nil {
+			break
+		}
+		cursor = "&cursor=" + *resp.NextCursor
+
+		// 別のソート順のカーソルは400
+		req, _ = http.NewRequest("GET", "/programs?sort=newest"+cursor, nil)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	assert.Equal(t, []string{"sf-a", "sf-c", "sf-b"}, titles)
+
+	for _, query := range []string{"sort=cheapest", "price_type=maybe", "tag_match=some", "performer_ids=x", "created_from=yesterday", "created_from=2026-01-02&created_to=2026-01-01T00:00:00Z"} {
+		req, _ := http.NewRequest("GET", "/programs?"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
 // =============================================================================
 // POST /programs/:id/like (LikeProgram)
 // =============================================================================
@@ -782,9 +1084,16 @@ func TestUpsertWatchHistory_Update_Integration(t *testing.T) {
 	assert.Equal(t, float64(300), resp.WatchHistory["position_seconds"])
 }
 
-func TestUpsertWatchHistory_Unauthorized_Integration(t *testing.T) {
+func TestUpsertWatchHistory_Anonymous_Integration(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	_, q := setupTestDB(t)
+	dbConn, q := setupTestDB(t)
+
+	var programID int64
+	err := dbConn.QueryRow(`INSERT INTO programs (title, video_path) VALUES ($1, $2) RETURNING id`,
+		"anonymous-watch", "/video/anon.mp4").Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
 
 	programsUC := usecase.NewProgramsUsecase(q, nil)
 	h := NewProgramsHandler(programsUC)
@@ -792,13 +1101,146 @@ func TestUpsertWatchHistory_Unauthorized_Integration(t *testing.T) {
 	r.Use(MockOptionalAuth(""))
 	r.POST("/watch-histories", h.UpsertWatchHistory)
 
-	body := `{"program_id":1,"position_seconds":60,"is_completed":false}`
+	body := fmt.Sprintf(`{"program_id":%d,"position_seconds":0,"is_completed":false,"started":true}`, programID)
 	req, _ := http.NewRequest("POST", "/watch-histories", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	// 未ログインでは視聴履歴は保存せず、視聴イベントのみ記録する
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		WatchHistory map[string]interface{} `json:"watch_history"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	assert.Nil(t, resp.WatchHistory)
+
+	var histories, events int
+	if err := dbConn.QueryRow(`SELECT COUNT(*) FROM watch_histories WHERE program_id = $1`, programID).Scan(&histories); err != nil {
+		t.Fatalf("failed to count watch_histories: %v", err)
+	}
+	if err := dbConn.QueryRow(`SELECT COUNT(*) FROM program_view_events WHERE program_id = $1`, programID).Scan(&events); err != nil {
+		t.Fatalf("failed to count program_view_events: %v", err)
+	}
+	assert.Equal(t, 0, histories)
+	assert.Equal(t, 1, events)
+}
+
+// =============================================================================
+// 視聴イベントの記録・集計
+// =============================================================================
+
+func TestViewEvents_DedupAndAggregate_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	userID := "view-user"
+	_, err := dbConn.Exec(`INSERT INTO "user" (id, name, email, "emailVerified") VALUES ($1, $2, $3, true)`,
+		userID, "視聴ユーザー", "view-user@example.com")
+	if err != nil {
+		t.Fatalf("failed to insert test user: %v", err)
+	}
+	var publicID, limitedID int64
+	err = dbConn.QueryRow(`INSERT INTO programs (title, video_path) VALUES ('view-public', '/video/public.mp4') RETURNING id`).Scan(&publicID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+	err = dbConn.QueryRow(`INSERT INTO programs (title, video_path, is_limited_release, price) VALUES ('view-limited', '/video/limited.mp4', true, 500) RETURNING id`).Scan(&limitedID)
+	if err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+
+	programsUC := usecase.NewProgramsUsecase(q, nil)
+	post := func(userID string, programID int64, userAgent string, started bool) {
+		r := gin.New()
+		r.Use(MockOptionalAuth(userID))
+		r.POST("/watch-histories", NewProgramsHandler(programsUC).UpsertWatchHistory)
+
+		body := fmt.Sprintf(`{"program_id":%d,"position_seconds":10,"is_completed":false,"started":%t}`, programID, started)
+		req, _ := http.NewRequest("POST", "/watch-histories", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// 同一ユーザーの再生開始は直近の時間枠内で1回、未ログインはfingerprintごとに1回
+	post(userID, publicID, "browser-a", true)
+	post(userID, publicID, "browser-b", true)
+	post("", publicID, "browser-a", true)
+	post("", publicID, "browser-a", true)
+	post("", publicID, "browser-b", true)
+	// 再生開始以外の進捗更新（シーク・離脱・視聴完了）は数えない
+	post("", publicID, "browser-c", false)
+	// 権限のない限定公開番組は数えない
+	post(userID, limitedID, "browser-a", true)
+
+	aggregator := usecase.NewStatsAggregator(dbConn, q)
+	n, err := aggregator.AggregateOnce(context.Background())
+	if err != nil {
+		t.Fatalf("failed to aggregate view events: %v", err)
+	}
+	assert.Equal(t, int64(3), n)
+
+	// 集計済みイベントは二重に加算しない
+	n, err = aggregator.AggregateOnce(context.Background())
+	if err != nil {
+		t.Fatalf("failed to aggregate view events: %v", err)
+	}
+	assert.Equal(t, int64(0), n)
+
+	var publicViews, limitedViews int
+	if err := dbConn.QueryRow(`SELECT view_count FROM programs WHERE id = $1`, publicID).Scan(&publicViews); err != nil {
+		t.Fatalf("failed to query view_count: %v", err)
+	}
+	if err := dbConn.QueryRow(`SELECT view_count FROM programs WHERE id = $1`, limitedID).Scan(&limitedViews); err != nil {
+		t.Fatalf("failed to query view_count: %v", err)
+	}
+	assert.Equal(t, 3, publicViews)
+	assert.Equal(t, 0, limitedViews)
+}
+
+func TestViewEvents_ConcurrentDedup_Integration(t *testing.T) {
+	dbConn, q := setupTestDB(t)
+
+	var programID int64
+	if err := dbConn.QueryRow(`INSERT INTO programs (title, video_path) VALUES ('view-race', '/video/race.mp4') RETURNING id`).Scan(&programID); err != nil {
+		t.Fatalf("failed to insert test program: %v", err)
+	}
+
+	// 同じ視聴者の再生開始が同時に届いても1件だけ記録する
+	programsUC := usecase.NewProgramsUsecase(q, nil)
+	viewerKey := usecase.ViewerKey("", "race-browser")
+	const n = 8
+	var wg sync.WaitGroup
+	recorded := make([]bool, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			recorded[i], errs[i] = programsUC.RecordView(context.Background(), programID, "", viewerKey)
+		}(i)
+	}
+	wg.Wait()
+
+	count := 0
+	for i := 0; i < n; i++ {
+		assert.NoError(t, errs[i])
+		if recorded[i] {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+
+	var events int
+	if err := dbConn.QueryRow(`SELECT COUNT(*) FROM program_view_events WHERE program_id = $1`, programID).Scan(&events); err != nil {
+		t.Fatalf("failed to count view events: %v", err)
+	}
+	assert.Equal(t, 1, events)
 }
 
 func TestUpsertWatchHistory_InvalidBody_Integration(t *testing.T) {
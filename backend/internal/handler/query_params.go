@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// parsePageRequest はクエリの cursor / limit を読み取る（limitは未指定なら既定値、上限を超える場合は丸める）
+func parsePageRequest(c *gin.Context, name string) (usecase.PageRequest, bool) {
+	page := usecase.PageRequest{Cursor: c.Query("cursor")}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			log.Printf("[%s] BadRequest: invalid limit. limitStr=%s, err=%v", name, limitStr, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return page, false
+		}
+		page.Limit = limit
+	}
+	return page, true
+}
+
+// parseInt64QueryArray は ?key=1&key=2 形式のID配列を読み取る
+func parseInt64QueryArray(c *gin.Context, name, key string) ([]int64, bool) {
+	values := c.QueryArray(key)
+	ids := make([]int64, 0, len(values))
+	for _, v := range values {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Printf("[%s] 不正リクエスト: %s変換失敗 %s=%v, err=%v", name, key, key, values, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + key})
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// parseTimeQuery はRFC3339または日付（YYYY-MM-DD、JST）の指定を読み取る。
+// 日付のみで期間の終わり（endOfDay）として指定された場合は、その日を含むよう翌日0時を返す
+func parseTimeQuery(c *gin.Context, name, key string, endOfDay bool) (*time.Time, bool) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, true
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, true
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, usecase.JST)
+	if err != nil {
+		log.Printf("[%s] 不正リクエスト: %s変換失敗 %s=%s, err=%v", name, key, key, v, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + key})
+		return nil, false
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, true
+}
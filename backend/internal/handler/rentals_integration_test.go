@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedRentableProgram は買い切り・レンタルの両方で購入できる番組を作成する
+func seedRentableProgram(t *testing.T, dbConn *sql.DB, userID string, price, rentalPrice, hours int32) int64 {
+	t.Helper()
+	programID := seedPurchasableProgram(t, dbConn, userID, price)
+	_, err := dbConn.Exec(`UPDATE programs SET rental_price = $2, rental_duration_hours = $3 WHERE id = $1`, programID, rentalPrice, hours)
+	require.NoError(t, err)
+	return programID
+}
+
+func checkoutProgram(r *gin.Engine, programID int64, purchaseType string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"program_id": %d, "purchase_type": %q}`, programID, purchaseType)
+	req, _ := http.NewRequest("POST", "/me/paypay/checkout", strings.NewReader(body))
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPayPayRental_WithSimulator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sim := setupPayPaySim(t)
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	programID := seedRentableProgram(t, dbConn, "renter", 1500, 300, 48)
+	r := newPayPayTestRouter(usecase.NewPaymentsUsecase(dbConn, q, nil), "renter")
+	programs := NewProgramsHandler(usecase.NewProgramsUsecase(q, nil))
+	r.GET("/programs/:id", programs.ProgramDetails)
+	r.GET("/me/purchased-programs", programs.ListPurchasedPrograms)
+
+	permitted := func() bool {
+		ok, err := q.IsUserPermittedForProgram(ctx, db.IsUserPermittedForProgramParams{UserID: "renter", ProgramID: programID})
+		require.NoError(t, err)
+		return ok
+	}
+
+	assert.Equal(t, http.StatusBadRequest, checkoutProgram(r, programID, "lease").Code)
+
+	// レンタル価格で決済し、支払い完了から48時間閲覧できる
+	w := checkoutProgram(r, programID, "rental")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res struct {
+		MerchantPaymentID string `json:"merchant_payment_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	rentalID := res.MerchantPaymentID
+	payment, ok := sim.Payment(rentalID)
+	require.True(t, ok)
+	assert.Equal(t, int32(300), payment.Amount)
+
+	require.NoError(t, sim.Complete(rentalID))
+	assert.True(t, confirm(t, r, rentalID).Granted)
+	assert.True(t, permitted())
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/programs/%d", programID), nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var detail struct {
+		Program struct {
+			RentalPrice         *int32 `json:"rental_price"`
+			RentalDurationHours *int32 `json:"rental_duration_hours"`
+		} `json:"program"`
+		IsPermitted bool                   `json:"is_permitted"`
+		Rental      *usecase.ProgramRental `json:"rental"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.True(t, detail.IsPermitted)
+	require.NotNil(t, detail.Program.RentalPrice)
+	assert.Equal(t, int32(300), *detail.Program.RentalPrice)
+	require.NotNil(t, detail.Rental)
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), detail.Rental.ExpiresAt, time.Minute)
+	assert.InDelta(t, 48*3600, detail.Rental.RemainingSeconds, 60)
+
+	// レンタル中に再度レンタルはできない
+	assert.Equal(t, http.StatusConflict, checkoutProgram(r, programID, "rental").Code)
+
+	// 期限が切れたら閲覧できず、購入済み一覧にも出ない
+	_, err := dbConn.Exec(`UPDATE permitted_program_users SET expires_at = now() - interval '1 minute' WHERE user_id = 'renter'`)
+	require.NoError(t, err)
+	assert.False(t, permitted())
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/purchased-programs", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"programs":[]`)
+
+	// 期限切れ後は買い切りで購入でき、期限が外れる
+	w = checkoutProgram(r, programID, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	purchaseID := res.MerchantPaymentID
+	payment, _ = sim.Payment(purchaseID)
+	assert.Equal(t, int32(1500), payment.Amount)
+	require.NoError(t, sim.Complete(purchaseID))
+	assert.True(t, confirm(t, r, purchaseID).Granted)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/purchased-programs", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var purchased struct {
+		Programs []usecase.ProgramListItem `json:"programs"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &purchased))
+	require.Len(t, purchased.Programs, 1)
+	assert.Equal(t, usecase.PurchaseTypePurchase, purchased.Programs[0].PurchaseType)
+	assert.Nil(t, purchased.Programs[0].ExpiresAt)
+
+	// 買い切り後にレンタルはできず、レンタル分を返金しても買い切りの権限は残る
+	assert.Equal(t, http.StatusConflict, checkoutProgram(r, programID, "rental").Code)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/paypay/topups/"+rentalID+"/refunds", strings.NewReader(`{}`))
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.True(t, permitted())
+}
+
+func TestPayPayRental_UpgradeToPurchase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sim := setupPayPaySim(t)
+	dbConn, q := setupTestDB(t)
+
+	programID := seedRentableProgram(t, dbConn, "upgrader", 1500, 300, 48)
+	notRentable := seedPurchasableProgram(t, dbConn, "upgrader", 500)
+	r := newPayPayTestRouter(usecase.NewPaymentsUsecase(dbConn, q, nil), "upgrader")
+
+	// レンタル価格のない番組はレンタルできない
+	assert.Equal(t, http.StatusBadRequest, checkoutProgram(r, notRentable, "rental").Code)
+
+	w := checkoutProgram(r, programID, "rental")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res struct {
+		MerchantPaymentID string `json:"merchant_payment_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.NoError(t, sim.Complete(res.MerchantPaymentID))
+	confirm(t, r, res.MerchantPaymentID)
+
+	// レンタル中でも買い切りにできる
+	purchaseID := checkout(t, r, programID)
+	require.NoError(t, sim.Complete(purchaseID))
+	assert.True(t, confirm(t, r, purchaseID).Granted)
+
+	var purchaseType string
+	var expiresAt sql.NullTime
+	require.NoError(t, dbConn.QueryRow(`SELECT purchase_type, expires_at FROM permitted_program_users WHERE user_id = 'upgrader' AND program_id = $1`, programID).Scan(&purchaseType, &expiresAt))
+	assert.Equal(t, usecase.PurchaseTypePurchase, purchaseType)
+	assert.False(t, expiresAt.Valid)
+}
+
+func TestPayPayRental_RefundOnlyRevokesOwnGrant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sim := setupPayPaySim(t)
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	programID := seedRentableProgram(t, dbConn, "rerenter", 1500, 300, 48)
+	r := newPayPayTestRouter(usecase.NewPaymentsUsecase(dbConn, q, nil), "rerenter")
+
+	permitted := func() bool {
+		ok, err := q.IsUserPermittedForProgram(ctx, db.IsUserPermittedForProgramParams{UserID: "rerenter", ProgramID: programID})
+		require.NoError(t, err)
+		return ok
+	}
+	rent := func() string {
+		w := checkoutProgram(r, programID, "rental")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var res struct {
+			MerchantPaymentID string `json:"merchant_payment_id"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.NoError(t, sim.Complete(res.MerchantPaymentID))
+		assert.True(t, confirm(t, r, res.MerchantPaymentID).Granted)
+		return res.MerchantPaymentID
+	}
+	refund := func(merchantPaymentID string) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/paypay/topups/"+merchantPaymentID+"/refunds", strings.NewReader(`{}`))
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// 期限切れ後に再レンタルすると、閲覧権限は新しいレンタルのものになる
+	firstID := rent()
+	_, err := dbConn.Exec(`UPDATE permitted_program_users SET expires_at = now() - interval '1 minute' WHERE user_id = 'rerenter'`)
+	require.NoError(t, err)
+	secondID := rent()
+
+	// 前のレンタルを返金しても今のレンタルは残る
+	refund(firstID)
+	assert.True(t, permitted())
+
+	// 今のレンタルを返金すると取り消される
+	refund(secondID)
+	assert.False(t, permitted())
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	search *usecase.SearchUsecase
+}
+
+func NewSearchHandler(search *usecase.SearchUsecase) *SearchHandler {
+	return &SearchHandler{search: search}
+}
+
+// GET /search?q=
+func (h *SearchHandler) SearchPrograms(c *gin.Context) {
+	query := c.Query("q")
+
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		v, err := strconv.Atoi(limitStr)
+		if err != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = v
+	}
+
+	programs, err := h.search.SearchPrograms(c.Request.Context(), query, limit)
+	if err != nil {
+		if errors.Is(err, usecase.ErrSearchQueryRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+			return
+		}
+		log.Printf("[SearchPrograms] InternalServerError: q=%s, err=%v", query, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search programs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"query": query, "programs": programs})
+}
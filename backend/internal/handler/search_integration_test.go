@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// GET /search
+// =============================================================================
+
+func TestSearchPrograms_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+	ctx := context.Background()
+
+	var tagID, performerID int64
+	if err := dbConn.QueryRow(`INSERT INTO category_tags (name) VALUES ('漫才') RETURNING id`).Scan(&tagID); err != nil {
+		t.Fatalf("failed to insert tag: %v", err)
+	}
+	err := dbConn.QueryRow(`INSERT INTO performers (first_name, last_name, first_name_kana, last_name_kana) VALUES ('太郎', '山田', 'タロウ', 'ヤマダ') RETURNING id`).Scan(&performerID)
+	if err != nil {
+		t.Fatalf("failed to insert performer: %v", err)
+	}
+
+	// 管理APIで作成すると検索索引も作られる
+	isPublic := true
+	description := "年末恒例のスペシャルLIVE"
+	admin := usecase.NewAdminProgramsUsecase(dbConn, q)
+	p1, err := admin.CreateProgram(ctx, usecase.AdminProgramInput{
+		Title: "お笑いライブ2026", VideoPath: "/video/s1.mp4", Description: &description, IsPublic: &isPublic,
+		CategoryTagIDs: []int64{tagID}, PerformerIDs: []int64{performerID},
+	})
+	if err != nil {
+		t.Fatalf("failed to create program: %v", err)
+	}
+	backstage := "ライブの舞台裏に密着"
+	p2, err := admin.CreateProgram(ctx, usecase.AdminProgramInput{
+		Title: "ドキュメンタリー", VideoPath: "/video/s2.mp4", Description: &backstage, IsPublic: &isPublic,
+	})
+	if err != nil {
+		t.Fatalf("failed to create program: %v", err)
+	}
+	// 非公開番組は検索に出ない
+	if _, err := admin.CreateProgram(ctx, usecase.AdminProgramInput{Title: "お笑い非公開", VideoPath: "/video/s3.mp4"}); err != nil {
+		t.Fatalf("failed to create program: %v", err)
+	}
+
+	// 管理API以外で作られた番組はReindexMissingで索引される
+	var p4 int64
+	if err := dbConn.QueryRow(`INSERT INTO programs (title, video_path) VALUES ('コント傑作選', '/video/s4.mp4') RETURNING id`).Scan(&p4); err != nil {
+		t.Fatalf("failed to insert program: %v", err)
+	}
+	n, err := usecase.NewSearchUsecase(q).ReindexMissing(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	h := NewSearchHandler(usecase.NewSearchUsecase(q))
+	r := gin.New()
+	r.GET("/search", h.SearchPrograms)
+
+	cases := []struct {
+		name string
+		q    string
+		want []int64
+	}{
+		{name: "title kanji/katakana", q: "お笑い", want: []int64{p1.ProgramID}},
+		{name: "katakana folds to hiragana", q: "らいぶ", want: []int64{p1.ProgramID, p2.ProgramID}},
+		{name: "full-width latin prefix", q: "ＬＩＶ", want: []int64{p1.ProgramID}},
+		{name: "performer name", q: "山田太郎", want: []int64{p1.ProgramID}},
+		{name: "performer kana", q: "ヤマダ", want: []int64{p1.ProgramID}},
+		{name: "tag name", q: "漫才", want: []int64{p1.ProgramID}},
+		{name: "multiple terms are AND", q: "ライブ ドキュメンタリー", want: []int64{p2.ProgramID}},
+		{name: "reindexed program", q: "コント", want: []int64{p4}},
+		{name: "no hit", q: "存在しない番組", want: []int64{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/search?q="+url.QueryEscape(tc.q), nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var resp struct {
+				Programs []struct {
+					ProgramID int64   `json:"program_id"`
+					Rank      float64 `json:"rank"`
+				} `json:"programs"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			ids := []int64{}
+			for _, p := range resp.Programs {
+				ids = append(ids, p.ProgramID)
+			}
+			assert.ElementsMatch(t, tc.want, ids)
+		})
+	}
+
+	// タイトル一致は説明文の一致より上位
+	req, _ := http.NewRequest("GET", "/search?q="+url.QueryEscape("ライブ"), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var ranked struct {
+		Programs []struct {
+			ProgramID int64 `json:"program_id"`
+		} `json:"programs"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &ranked))
+	if assert.Len(t, ranked.Programs, 2) {
+		assert.Equal(t, p1.ProgramID, ranked.Programs[0].ProgramID)
+	}
+
+	// 検索語が空なら400
+	for _, query := range []string{"/search", "/search?q=+++", "/search?q=a&limit=x"} {
+		req, _ := http.NewRequest("GET", query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type TagsHandler struct {
+	tags *usecase.TagsUsecase
+}
+
+func NewTagsHandler(tags *usecase.TagsUsecase) *TagsHandler {
+	return &TagsHandler{tags: tags}
+}
+
+// GET /tags
+func (h *TagsHandler) ListTags(c *gin.Context) {
+	tags, err := h.tags.ListTags(c.Request.Context())
+	if err != nil {
+		log.Printf("[ListTags] InternalServerError: err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// GET /tags/:id/programs
+func (h *TagsHandler) ListTagPrograms(c *gin.Context) {
+	id, ok := parseTagIDParam(c, "ListTagPrograms")
+	if !ok {
+		return
+	}
+	page, ok := parsePageRequest(c, "ListTagPrograms")
+	if !ok {
+		return
+	}
+
+	result, err := h.tags.ListTagPrograms(c.Request.Context(), id, page)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrCategoryTagNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		case errors.Is(err, usecase.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		default:
+			log.Printf("[ListTagPrograms] InternalServerError: tagID=%d, err=%v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list programs"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"programs": result.Programs, "next_cursor": result.NextCursor})
+}
+
+func parseTagIDParam(c *gin.Context, name string) (int64, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		log.Printf("[%s] BadRequest: invalid id. idStr=%s, err=%v", name, idStr, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
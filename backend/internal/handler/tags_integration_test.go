@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chan-shizu/SZer/internal/usecase"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// GET /tags, GET /tags/:id/programs
+// =============================================================================
+
+func TestListTags_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	insertTag := func(name string) int64 {
+		var id int64
+		if err := dbConn.QueryRow(`INSERT INTO category_tags (name) VALUES ($1) RETURNING id`, name).Scan(&id); err != nil {
+			t.Fatalf("failed to insert tag: %v", err)
+		}
+		return id
+	}
+	musicID := insertTag("音楽")
+	insertTag("お笑い")
+
+	insertProgram := func(title string, isPublic bool, createdAt string) {
+		var id int64
+		err := dbConn.QueryRow(`INSERT INTO programs (title, video_path, is_public, created_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+			title, "/video/"+title+".mp4", isPublic, createdAt).Scan(&id)
+		if err != nil {
+			t.Fatalf("failed to insert program: %v", err)
+		}
+		if _, err := dbConn.Exec(`INSERT INTO program_category_tags (program_id, tag_id) VALUES ($1, $2)`, id, musicID); err != nil {
+			t.Fatalf("failed to insert program_category_tags: %v", err)
+		}
+	}
+	insertProgram("tag-old", true, "2026-01-01T00:00:00Z")
+	insertProgram("tag-new", true, "2026-01-02T00:00:00Z")
+	insertProgram("tag-private", false, "2026-01-03T00:00:00Z")
+
+	h := NewTagsHandler(usecase.NewTagsUsecase(dbConn, q))
+	r := gin.New()
+	r.GET("/tags", h.ListTags)
+	r.GET("/tags/:id/programs", h.ListTagPrograms)
+
+	// 非公開番組は件数に含めない
+	req, _ := http.NewRequest("GET", "/tags", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tagsResp struct {
+		Tags []struct {
+			ID           int64  `json:"id"`
+			Name         string `json:"name"`
+			ProgramCount int64  `json:"program_count"`
+		} `json:"tags"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &tagsResp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	counts := map[string]int64{}
+	for _, tag := range tagsResp.Tags {
+		counts[tag.Name] = tag.ProgramCount
+	}
+	assert.Equal(t, map[string]int64{"音楽": 2, "お笑い": 0}, counts)
+
+	// 新しい順にページングでき、非公開番組は含まれない
+	type programsResp struct {
+		Programs []struct {
+			Title string `json:"title"`
+		} `json:"programs"`
+		NextCursor *string `json:"next_cursor"`
+	}
+	fetch := func(query string) programsResp {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/tags/%d/programs%s", musicID, query), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp programsResp
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return resp
+	}
+	first := fetch("?limit=1")
+	if assert.Len(t, first.Programs, 1) && assert.NotNil(t, first.NextCursor) {
+		assert.Equal(t, "tag-new", first.Programs[0].Title)
+		second := fetch("?limit=1&cursor=" + *first.NextCursor)
+		if assert.Len(t, second.Programs, 1) {
+			assert.Equal(t, "tag-old", second.Programs[0].Title)
+		}
+		assert.Nil(t, second.NextCursor)
+	}
+
+	// 存在しないタグは404
+	req, _ = http.NewRequest("GET", "/tags/999999/programs", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// =============================================================================
+// POST/PUT/DELETE /admin/tags
+// =============================================================================
+
+func TestAdminTags_Integration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbConn, q := setupTestDB(t)
+
+	h := NewAdminTagsHandler(usecase.NewTagsUsecase(dbConn, q))
+	r := gin.New()
+	r.Use(MockOptionalAuth("admin-user"))
+	r.POST("/admin/tags", h.CreateTag)
+	r.PUT("/admin/tags/:id", h.UpdateTag)
+	r.DELETE("/admin/tags/:id", h.DeleteTag)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/admin/tags", `{"name":" 旅行 "}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Tag struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"tag"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	assert.Equal(t, "旅行", created.Tag.Name)
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/admin/tags", `{"name":"  "}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/admin/tags", `{"name":"x","extra":1}`).Code)
+	assert.Equal(t, http.StatusConflict, do("POST", "/admin/tags", `{"name":"旅行"}`).Code)
+
+	// 名前変更は検索索引にも反映される
+	var programID int64
+	err := dbConn.QueryRow(`INSERT INTO programs (title, video_path, is_public) VALUES ('温泉めぐり', '/video/onsen.mp4', true) RETURNING id`).Scan(&programID)
+	if err != nil {
+		t.Fatalf("failed to insert program: %v", err)
+	}
+	if _, err := dbConn.Exec(`INSERT INTO program_category_tags (program_id, tag_id) VALUES ($1, $2)`, programID, created.Tag.ID); err != nil {
+		t.Fatalf("failed to insert program_category_tags: %v", err)
+	}
+
+	tagPath := fmt.Sprintf("/admin/tags/%d", created.Tag.ID)
+	w = do("PUT", tagPath, `{"name":"国内旅行"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var indexed bool
+	err = dbConn.QueryRow(`SELECT EXISTS (SELECT 1 FROM program_search_documents WHERE program_id = $1)`, programID).Scan(&indexed)
+	if err != nil {
+		t.Fatalf("failed to query search document: %v", err)
+	}
+	assert.True(t, indexed)
+
+	assert.Equal(t, http.StatusNotFound, do("PUT", "/admin/tags/999999", `{"name":"なし"}`).Code)
+
+	// 削除すると番組との紐づけも消える
+	assert.Equal(t, http.StatusNoContent, do("DELETE", tagPath, "").Code)
+	var links int
+	if err := dbConn.QueryRow(`SELECT COUNT(*) FROM program_category_tags WHERE program_id = $1`, programID).Scan(&links); err != nil {
+		t.Fatalf("failed to count program_category_tags: %v", err)
+	}
+	assert.Equal(t, 0, links)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", tagPath, "").Code)
+}
@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 
 	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/usecase"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
@@ -18,10 +20,27 @@ func cleanupProgramDetailsTestData(t *testing.T, dbConn *sql.DB) {
 		"likes",
 		"comments",
 		"watch_histories",
-		"paypay_topups",
+		"webhook_deliveries",
+		"gifts",
+		"user_product_grants",
+		"product_programs",
+		"payment_refunds",
+		"payment_events",
+		"payments",
+		"coupon_programs",
+		"coupon_tags",
+		"coupons",
+		"products",
+		"program_search_documents",
+		"program_view_events",
+		"program_daily_stats",
 		"programs",
 		"category_tags",
 		"performers",
+		"user_roles",
+		"api_tokens",
+		"jobs",
+		"job_schedules",
 		"user",
 	}
 	for _, table := range tables {
@@ -57,4 +76,28 @@ func MockOptionalAuth(userID string) gin.HandlerFunc {
 		c.Set("user_id", userID)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+// processWebhookDeliveries は受信済みのWebhookを処理する（本番ではジョブで非同期に処理される）
+func processWebhookDeliveries(t *testing.T, dbConn *sql.DB, q *db.Queries) {
+	t.Helper()
+	rows, err := dbConn.Query(`SELECT id FROM webhook_deliveries WHERE status = 'received' ORDER BY id`)
+	if err != nil {
+		t.Fatalf("failed to query webhook_deliveries: %v", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("failed to scan webhook_deliveries: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	uc := usecase.NewWebhookDeliveriesUsecase(dbConn, q)
+	for _, id := range ids {
+		if err := uc.ProcessDelivery(context.Background(), id); err != nil {
+			t.Logf("webhook delivery %d failed: %v", id, err)
+		}
+	}
+}
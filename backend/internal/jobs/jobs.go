@@ -0,0 +1,88 @@
+// Package jobs はPostgresをキューにしたバックグラウンドジョブの実行基盤。
+// ワーカーは FOR UPDATE SKIP LOCKED でジョブを取り出すため、複数プロセスで動かしても同じジョブを二重に実行しない。
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+const (
+	DefaultMaxAttempts = 5
+
+	backoffBase = 10 * time.Second
+	backoffMax  = time.Hour
+)
+
+// Job はハンドラーに渡される実行中のジョブ
+type Job struct {
+	ID          int64
+	Kind        string
+	Payload     json.RawMessage
+	Attempts    int32
+	MaxAttempts int32
+}
+
+// Decode はpayloadをvにデコードする
+func (j Job) Decode(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Handler はジョブを実行する。エラーを返すとバックオフ後に再試行される
+type Handler func(ctx context.Context, job Job) error
+
+// Store はジョブの永続化先（db.Queriesが満たす）
+type Store interface {
+	EnqueueStore
+	ClaimJobs(ctx context.Context, arg db.ClaimJobsParams) ([]db.ClaimJobsRow, error)
+	CompleteJob(ctx context.Context, id int64) error
+	RetryJob(ctx context.Context, arg db.RetryJobParams) error
+	FailJob(ctx context.Context, arg db.FailJobParams) error
+	RequeueStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error)
+	DeleteFinishedJobs(ctx context.Context, finishedBefore time.Time) (int64, error)
+	EnsureJobSchedule(ctx context.Context, arg db.EnsureJobScheduleParams) error
+	ClaimDueJobSchedule(ctx context.Context, arg db.ClaimDueJobScheduleParams) (string, error)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent は再試行しても成功しないエラーを表す（ハンドラーが返すと即座に失敗扱いになる）
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent はerrがPermanentで包まれているかを返す
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// backoff はattempt回目の失敗後に次の実行まで待つ時間（10秒から倍々、最大1時間）
+func backoff(attempt int32) time.Duration {
+	d := backoffBase
+	for i := int32(1); i < attempt && d < backoffMax; i++ {
+		d *= 2
+	}
+	if d > backoffMax {
+		d = backoffMax
+	}
+	return d
+}
+
+// withJitter は同時に失敗したジョブが一斉に再実行されないよう±20%ずらす
+func withJitter(d time.Duration) time.Duration {
+	return d + time.Duration((rand.Float64()*0.4-0.2)*float64(d))
+}
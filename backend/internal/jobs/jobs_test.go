@@ -0,0 +1,323 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeJob struct {
+	row       db.ClaimJobsRow
+	status    string
+	runAt     time.Time
+	dedupeKey string
+	lastError string
+}
+
+type fakeStore struct {
+	mu        sync.Mutex
+	nextID    int64
+	jobs      map[int64]*fakeJob
+	schedules map[string]time.Time
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{jobs: map[int64]*fakeJob{}, schedules: map[string]time.Time{}}
+}
+
+func (s *fakeStore) EnqueueJob(ctx context.Context, arg db.EnqueueJobParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if arg.DedupeKey.Valid {
+		for _, j := range s.jobs {
+			if j.dedupeKey == arg.DedupeKey.String && (j.status == "queued" || j.status == "running") {
+				return 0, sql.ErrNoRows
+			}
+		}
+	}
+	s.nextID++
+	s.jobs[s.nextID] = &fakeJob{
+		row:       db.ClaimJobsRow{ID: s.nextID, Kind: arg.Kind, Payload: arg.Payload, MaxAttempts: arg.MaxAttempts},
+		status:    "queued",
+		runAt:     arg.RunAt,
+		dedupeKey: arg.DedupeKey.String,
+	}
+	return s.nextID, nil
+}
+
+func (s *fakeStore) ClaimJobs(ctx context.Context, arg db.ClaimJobsParams) ([]db.ClaimJobsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]int64, 0, len(s.jobs))
+	for id := range s.jobs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var rows []db.ClaimJobsRow
+	for _, id := range ids {
+		j := s.jobs[id]
+		if len(rows) >= int(arg.BatchSize) {
+			break
+		}
+		if j.status != "queued" || j.runAt.After(time.Now()) || !contains(arg.Kinds, j.row.Kind) {
+			continue
+		}
+		j.status = "running"
+		j.row.Attempts++
+		rows = append(rows, j.row)
+	}
+	return rows, nil
+}
+
+func (s *fakeStore) CompleteJob(ctx context.Context, id int64) error {
+	return s.set(id, func(j *fakeJob) { j.status = "succeeded" })
+}
+
+func (s *fakeStore) RetryJob(ctx context.Context, arg db.RetryJobParams) error {
+	return s.set(arg.ID, func(j *fakeJob) { j.status, j.runAt, j.lastError = "queued", arg.RunAt, arg.LastError })
+}
+
+func (s *fakeStore) FailJob(ctx context.Context, arg db.FailJobParams) error {
+	return s.set(arg.ID, func(j *fakeJob) { j.status, j.lastError = "failed", arg.LastError })
+}
+
+func (s *fakeStore) RequeueStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *fakeStore) DeleteFinishedJobs(ctx context.Context, finishedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *fakeStore) EnsureJobSchedule(ctx context.Context, arg db.EnsureJobScheduleParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schedules[arg.Name]; !ok {
+		s.schedules[arg.Name] = arg.NextRunAt
+	}
+	return nil
+}
+
+func (s *fakeStore) ClaimDueJobSchedule(ctx context.Context, arg db.ClaimDueJobScheduleParams) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next, ok := s.schedules[arg.Name]
+	if !ok || next.After(time.Now()) {
+		return "", sql.ErrNoRows
+	}
+	s.schedules[arg.Name] = arg.NextRunAt
+	return arg.Name, nil
+}
+
+func (s *fakeStore) set(id int64, fn func(j *fakeJob)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.jobs[id])
+	return nil
+}
+
+func (s *fakeStore) job(id int64) fakeJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.jobs[id]
+}
+
+func (s *fakeStore) countByKind(kind string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, j := range s.jobs {
+		if j.row.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestRunner(store Store) *Runner {
+	r := NewRunner(store)
+	r.pollInterval = 10 * time.Millisecond
+	return r
+}
+
+func startRunner(t *testing.T, r *Runner) {
+	t.Helper()
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start runner: %v", err)
+	}
+	t.Cleanup(func() { _ = r.Stop(context.Background()) })
+}
+
+func TestRunner_CompletesJob(t *testing.T) {
+	store := newFakeStore()
+	r := newTestRunner(store)
+
+	got := make(chan string, 1)
+	r.Register("greet", func(ctx context.Context, job Job) error {
+		var p struct {
+			Name string `json:"name"`
+		}
+		if err := job.Decode(&p); err != nil {
+			return err
+		}
+		got <- p.Name
+		return nil
+	})
+	startRunner(t, r)
+
+	id, err := NewQueue(store).Enqueue(context.Background(), "greet", map[string]string{"name": "szer"})
+	if err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	select {
+	case name := <-got:
+		assert.Equal(t, "szer", name)
+	case <-time.After(2 * time.Second):
+		t.Fatal("job was not executed")
+	}
+	assert.Eventually(t, func() bool { return store.job(id).status == "succeeded" }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestRunner_RetryAndFail(t *testing.T) {
+	store := newFakeStore()
+	r := newTestRunner(store)
+	r.Register("flaky", func(ctx context.Context, job Job) error { return errors.New("temporary") })
+	r.Register("broken", func(ctx context.Context, job Job) error { return Permanent(errors.New("bad payload")) })
+	r.Register("panics", func(ctx context.Context, job Job) error { panic("boom") })
+	startRunner(t, r)
+
+	queue := NewQueue(store)
+	flakyID, _ := queue.Enqueue(context.Background(), "flaky", nil, MaxAttempts(3))
+	lastID, _ := queue.Enqueue(context.Background(), "flaky", nil, MaxAttempts(1))
+	brokenID, _ := queue.Enqueue(context.Background(), "broken", nil)
+	panicID, _ := queue.Enqueue(context.Background(), "panics", nil)
+
+	// 失敗したジョブはバックオフ後に再試行される
+	assert.Eventually(t, func() bool {
+		j := store.job(flakyID)
+		return j.status == "queued" && j.row.Attempts == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, store.job(flakyID).runAt.After(time.Now().Add(5*time.Second)))
+	assert.Equal(t, "temporary", store.job(flakyID).lastError)
+
+	// 最大回数に達したジョブ・Permanentは再試行しない
+	assert.Eventually(t, func() bool { return store.job(lastID).status == "failed" }, 2*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return store.job(brokenID).status == "failed" }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "bad payload", store.job(brokenID).lastError)
+
+	// panicはエラーとして再試行される
+	assert.Eventually(t, func() bool { return store.job(panicID).status == "queued" }, 2*time.Second, 10*time.Millisecond)
+	assert.Contains(t, store.job(panicID).lastError, "boom")
+}
+
+func TestRunner_StopWaitsForRunningJobs(t *testing.T) {
+	store := newFakeStore()
+	r := newTestRunner(store)
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	r.Register("slow", func(ctx context.Context, job Job) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	})
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start runner: %v", err)
+	}
+	id, _ := NewQueue(store).Enqueue(context.Background(), "slow", nil)
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job was not executed")
+	}
+	assert.NoError(t, r.Stop(context.Background()))
+	assert.True(t, finished.Load())
+	assert.Equal(t, "succeeded", store.job(id).status)
+}
+
+func TestRunner_Schedule(t *testing.T) {
+	store := newFakeStore()
+	r := newTestRunner(store)
+
+	var runs atomic.Int32
+	r.Register("tick", func(ctx context.Context, job Job) error {
+		runs.Add(1)
+		return nil
+	})
+	if err := r.Schedule("tick-every", Every(30*time.Millisecond), "tick", nil); err != nil {
+		t.Fatalf("failed to add schedule: %v", err)
+	}
+	startRunner(t, r)
+
+	assert.Eventually(t, func() bool { return runs.Load() >= 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestQueue_DedupeKey(t *testing.T) {
+	store := newFakeStore()
+	queue := NewQueue(store)
+
+	id, err := queue.Enqueue(context.Background(), "once", nil, DedupeKey("k"))
+	assert.NoError(t, err)
+	assert.NotZero(t, id)
+
+	// 未完了の間は登録されない
+	id, err = queue.Enqueue(context.Background(), "once", nil, DedupeKey("k"))
+	assert.NoError(t, err)
+	assert.Zero(t, id)
+	assert.Equal(t, 1, store.countByKind("once"))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, backoff(1))
+	assert.Equal(t, 20*time.Second, backoff(2))
+	assert.Equal(t, 80*time.Second, backoff(4))
+	assert.Equal(t, time.Hour, backoff(20))
+
+	for i := 0; i < 100; i++ {
+		d := withJitter(time.Minute)
+		assert.GreaterOrEqual(t, d, 48*time.Second)
+		assert.LessOrEqual(t, d, 72*time.Second)
+	}
+}
+
+func TestDailySchedule(t *testing.T) {
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+	s := Daily(4, 0, jst)
+
+	next := s.Next(time.Date(2026, 10, 17, 3, 59, 0, 0, jst))
+	assert.Equal(t, time.Date(2026, 10, 17, 4, 0, 0, 0, jst), next)
+
+	// ちょうどの時刻・過ぎた後は翌日
+	next = s.Next(time.Date(2026, 10, 17, 4, 0, 0, 0, jst))
+	assert.Equal(t, time.Date(2026, 10, 18, 4, 0, 0, 0, jst), next)
+}
+
+func TestPermanent(t *testing.T) {
+	base := errors.New("invalid")
+	err := Permanent(base)
+	assert.True(t, IsPermanent(err))
+	assert.ErrorIs(t, err, base)
+	assert.False(t, IsPermanent(base))
+	assert.Nil(t, Permanent(nil))
+}
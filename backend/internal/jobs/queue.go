@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+// Enqueuer はusecaseからジョブを登録するためのインターフェース
+type Enqueuer interface {
+	Enqueue(ctx context.Context, kind string, payload any, opts ...EnqueueOption) (int64, error)
+}
+
+// EnqueueStore はジョブの登録先（db.Queriesが満たす。トランザクション内ならWithTxしたものを渡す）
+type EnqueueStore interface {
+	EnqueueJob(ctx context.Context, arg db.EnqueueJobParams) (int64, error)
+}
+
+type enqueueOptions struct {
+	runAt       time.Time
+	maxAttempts int32
+	dedupeKey   string
+}
+
+type EnqueueOption func(*enqueueOptions)
+
+// RunAt は指定時刻以降に実行する
+func RunAt(t time.Time) EnqueueOption {
+	return func(o *enqueueOptions) { o.runAt = t }
+}
+
+// Delay は指定時間後に実行する
+func Delay(d time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) { o.runAt = time.Now().Add(d) }
+}
+
+// MaxAttempts は再試行を含めた最大実行回数を指定する
+func MaxAttempts(n int32) EnqueueOption {
+	return func(o *enqueueOptions) { o.maxAttempts = n }
+}
+
+// DedupeKey は同じキーの未完了ジョブがあれば登録しない
+func DedupeKey(key string) EnqueueOption {
+	return func(o *enqueueOptions) { o.dedupeKey = key }
+}
+
+type Queue struct {
+	store EnqueueStore
+}
+
+func NewQueue(store EnqueueStore) *Queue {
+	return &Queue{store: store}
+}
+
+// Enqueue はジョブを登録してIDを返す。DedupeKeyで重複した場合は0を返す
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, opts ...EnqueueOption) (int64, error) {
+	o := enqueueOptions{runAt: time.Now(), maxAttempts: DefaultMaxAttempts}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxAttempts <= 0 {
+		o.maxAttempts = 1
+	}
+
+	raw := json.RawMessage("{}")
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return 0, err
+		}
+		raw = b
+	}
+
+	id, err := q.store.EnqueueJob(ctx, db.EnqueueJobParams{
+		Kind:        kind,
+		Payload:     raw,
+		MaxAttempts: o.maxAttempts,
+		RunAt:       o.runAt,
+		DedupeKey:   sql.NullString{String: o.dedupeKey, Valid: o.dedupeKey != ""},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return id, nil
+}
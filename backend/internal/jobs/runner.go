@@ -0,0 +1,260 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+const (
+	defaultConcurrency  = 4
+	defaultPollInterval = time.Second
+	// ジョブ1件の実行時間の上限。これを超えて実行中のままのジョブは落ちたものとみなして戻す
+	defaultJobTimeout  = 5 * time.Minute
+	staleJobGrace      = time.Minute
+	maintenanceEvery   = time.Minute
+	finishedRetention  = 7 * 24 * time.Hour
+	resultWriteTimeout = 10 * time.Second
+)
+
+type scheduledJob struct {
+	name     string
+	schedule Schedule
+	kind     string
+	payload  json.RawMessage
+}
+
+// Runner は登録されたハンドラーでジョブを実行し、定期ジョブを登録する
+type Runner struct {
+	store    Store
+	workerID string
+
+	handlers  map[string]Handler
+	schedules []scheduledJob
+
+	concurrency  int
+	pollInterval time.Duration
+	jobTimeout   time.Duration
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	loops   sync.WaitGroup
+	running sync.WaitGroup
+}
+
+func NewRunner(store Store) *Runner {
+	host, _ := os.Hostname()
+	return &Runner{
+		store:        store,
+		workerID:     fmt.Sprintf("%s:%d", host, os.Getpid()),
+		handlers:     map[string]Handler{},
+		concurrency:  defaultConcurrency,
+		pollInterval: defaultPollInterval,
+		jobTimeout:   defaultJobTimeout,
+	}
+}
+
+// Register はkindのジョブを実行するハンドラーを登録する（Start前に呼ぶ）
+func (r *Runner) Register(kind string, h Handler) {
+	r.handlers[kind] = h
+}
+
+// Schedule はscheduleに従ってkindのジョブを登録する定期ジョブを追加する（Start前に呼ぶ）。
+// 前回分が未完了の間は重複して登録しない
+func (r *Runner) Schedule(name string, schedule Schedule, kind string, payload any) error {
+	raw := json.RawMessage("{}")
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+	r.schedules = append(r.schedules, scheduledJob{name: name, schedule: schedule, kind: kind, payload: raw})
+	return nil
+}
+
+// Start はワーカー・スケジューラー・メンテナンスのループを開始する
+func (r *Runner) Start(ctx context.Context) error {
+	now := time.Now()
+	for _, s := range r.schedules {
+		if err := r.store.EnsureJobSchedule(ctx, db.EnsureJobScheduleParams{Name: s.name, NextRunAt: s.schedule.Next(now)}); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r.mu.Lock()
+	r.cancel = cancel
+	r.mu.Unlock()
+
+	sem := make(chan struct{}, r.concurrency)
+	r.loop(ctx, r.pollInterval, func(ctx context.Context) bool { return r.poll(ctx, sem) })
+	if len(r.schedules) > 0 {
+		r.loop(ctx, r.pollInterval, func(ctx context.Context) bool { r.enqueueDueSchedules(ctx); return false })
+	}
+	r.loop(ctx, maintenanceEvery, func(ctx context.Context) bool { r.maintain(ctx); return false })
+	return nil
+}
+
+// Stop は新しいジョブの取り出しを止め、実行中のジョブの完了をctxの期限まで待つ。
+// 期限までに終わらなかったジョブは実行中のまま残り、次回起動時に実行待ちへ戻される
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	cancel := r.cancel
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	r.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		r.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// private functions
+
+// loop はfnをinterval毎に実行する。fnがtrueを返した場合は待たずに続けて実行する
+func (r *Runner) loop(ctx context.Context, interval time.Duration, fn func(ctx context.Context) bool) {
+	r.loops.Add(1)
+	go func() {
+		defer r.loops.Done()
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			if fn(ctx) {
+				timer.Reset(0)
+			} else {
+				timer.Reset(interval)
+			}
+		}
+	}()
+}
+
+// poll は空いている実行枠の分だけジョブを取り出して実行し、枠を使い切ったかを返す
+func (r *Runner) poll(ctx context.Context, sem chan struct{}) bool {
+	free := cap(sem) - len(sem)
+	if free == 0 || len(r.handlers) == 0 {
+		return false
+	}
+
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+	rows, err := r.store.ClaimJobs(ctx, db.ClaimJobsParams{WorkerID: r.workerID, Kinds: kinds, BatchSize: int32(free)})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[jobs] failed to claim jobs: %v", err)
+		}
+		return false
+	}
+
+	for _, row := range rows {
+		sem <- struct{}{}
+		r.running.Add(1)
+		job := Job{ID: row.ID, Kind: row.Kind, Payload: row.Payload, Attempts: row.Attempts, MaxAttempts: row.MaxAttempts}
+		go func() {
+			defer func() {
+				<-sem
+				r.running.Done()
+			}()
+			r.execute(job)
+		}()
+	}
+	return len(rows) == free
+}
+
+func (r *Runner) execute(job Job) {
+	// シャットダウン中も実行中のジョブは最後まで走らせるため、Startのctxとは切り離す
+	ctx, cancel := context.WithTimeout(context.Background(), r.jobTimeout)
+	defer cancel()
+
+	err := r.runHandler(ctx, job)
+
+	resultCtx, cancelResult := context.WithTimeout(context.Background(), resultWriteTimeout)
+	defer cancelResult()
+	switch {
+	case err == nil:
+		if err := r.store.CompleteJob(resultCtx, job.ID); err != nil {
+			log.Printf("[jobs] failed to complete job. id=%d, kind=%s, err=%v", job.ID, job.Kind, err)
+		}
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		log.Printf("[jobs] job failed. id=%d, kind=%s, attempts=%d, err=%v", job.ID, job.Kind, job.Attempts, err)
+		if err := r.store.FailJob(resultCtx, db.FailJobParams{ID: job.ID, LastError: err.Error()}); err != nil {
+			log.Printf("[jobs] failed to mark job failed. id=%d, kind=%s, err=%v", job.ID, job.Kind, err)
+		}
+	default:
+		runAt := time.Now().Add(withJitter(backoff(job.Attempts)))
+		log.Printf("[jobs] job will be retried. id=%d, kind=%s, attempts=%d, runAt=%s, err=%v", job.ID, job.Kind, job.Attempts, runAt.Format(time.RFC3339), err)
+		if err := r.store.RetryJob(resultCtx, db.RetryJobParams{ID: job.ID, RunAt: runAt, LastError: err.Error()}); err != nil {
+			log.Printf("[jobs] failed to reschedule job. id=%d, kind=%s, err=%v", job.ID, job.Kind, err)
+		}
+	}
+}
+
+func (r *Runner) runHandler(ctx context.Context, job Job) (err error) {
+	h, ok := r.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for job kind %q", job.Kind))
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return h(ctx, job)
+}
+
+func (r *Runner) enqueueDueSchedules(ctx context.Context) {
+	queue := NewQueue(r.store)
+	now := time.Now()
+	for _, s := range r.schedules {
+		_, err := r.store.ClaimDueJobSchedule(ctx, db.ClaimDueJobScheduleParams{Name: s.name, NextRunAt: s.schedule.Next(now)})
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) && ctx.Err() == nil {
+				log.Printf("[jobs] failed to claim schedule. name=%s, err=%v", s.name, err)
+			}
+			continue
+		}
+		if _, err := queue.Enqueue(ctx, s.kind, s.payload, DedupeKey("schedule:"+s.name), MaxAttempts(1)); err != nil {
+			log.Printf("[jobs] failed to enqueue scheduled job. name=%s, err=%v", s.name, err)
+		}
+	}
+}
+
+func (r *Runner) maintain(ctx context.Context) {
+	now := time.Now()
+	if n, err := r.store.RequeueStaleJobs(ctx, now.Add(-(r.jobTimeout + staleJobGrace))); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[jobs] failed to requeue stale jobs: %v", err)
+		}
+	} else if n > 0 {
+		log.Printf("[jobs] recovered %d stale jobs (requeued or failed)", n)
+	}
+	if _, err := r.store.DeleteFinishedJobs(ctx, now.Add(-finishedRetention)); err != nil && ctx.Err() == nil {
+		log.Printf("[jobs] failed to delete finished jobs: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import "time"
+
+// Schedule は定期ジョブの次回実行時刻を決める
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+type every time.Duration
+
+// Every は一定間隔で実行する
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+func (e every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+type daily struct {
+	hour, minute int
+	loc          *time.Location
+}
+
+// Daily は毎日loc基準のhour:minuteに実行する
+func Daily(hour, minute int, loc *time.Location) Schedule {
+	return daily{hour: hour, minute: minute, loc: loc}
+}
+
+func (d daily) Next(after time.Time) time.Time {
+	t := after.In(d.loc)
+	next := time.Date(t.Year(), t.Month(), t.Day(), d.hour, d.minute, 0, 0, d.loc)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/authz"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAPITokenStore struct {
+	tokens  map[string]db.GetActiveAPITokenByHashRow
+	touched []int64
+}
+
+func (s *fakeAPITokenStore) GetActiveAPITokenByHash(ctx context.Context, tokenHash string) (db.GetActiveAPITokenByHashRow, error) {
+	row, ok := s.tokens[tokenHash]
+	if !ok {
+		return db.GetActiveAPITokenByHashRow{}, sql.ErrNoRows
+	}
+	return row, nil
+}
+
+func (s *fakeAPITokenStore) TouchAPIToken(ctx context.Context, id int64) error {
+	s.touched = append(s.touched, id)
+	return nil
+}
+
+type staticSessionVerifier struct{ userID string }
+
+func (v staticSessionVerifier) VerifySession(ctx context.Context, cookieHeader string) (string, error) {
+	if v.userID == "" {
+		return "", ErrNoSession
+	}
+	return v.userID, nil
+}
+
+func TestAuthenticator_Bearer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	readToken, readHash, _, _ := authz.GenerateAPIToken()
+	adminToken, adminHash, _, _ := authz.GenerateAPIToken()
+	store := &fakeAPITokenStore{tokens: map[string]db.GetActiveAPITokenByHashRow{
+		readHash:  {ID: 1, UserID: "user-read", Scopes: []string{authz.ScopeRead}},
+		adminHash: {ID: 2, UserID: "user-admin", Scopes: []string{authz.ScopeRead, authz.ScopeWrite, authz.ScopeAdmin}},
+	}}
+	auth := NewAuthenticator(staticSessionVerifier{userID: "cookie-user"}, store)
+
+	r := gin.New()
+	ok := func(c *gin.Context) {
+		userID, _ := UserIDFromContext(c)
+		c.String(http.StatusOK, userID)
+	}
+	r.GET("/me", auth.RequireAuth(), ok)
+	r.POST("/me", auth.RequireAuth(), ok)
+	r.GET("/optional", auth.OptionalAuth(), ok)
+	r.GET("/admin", auth.RequireAuth(), RequireScope(authz.ScopeAdmin), ok)
+	r.GET("/tokens", auth.RequireAuth(), RequireSession(), ok)
+
+	cases := []struct {
+		name     string
+		method   string
+		path     string
+		header   string
+		cookie   string
+		wantCode int
+		wantUser string
+	}{
+		{name: "read token GET", method: "GET", path: "/me", header: "Bearer " + readToken, wantCode: http.StatusOK, wantUser: "user-read"},
+		{name: "lowercase scheme", method: "GET", path: "/me", header: "bearer " + readToken, wantCode: http.StatusOK, wantUser: "user-read"},
+		{name: "read token POST", method: "POST", path: "/me", header: "Bearer " + readToken, wantCode: http.StatusForbidden},
+		{name: "admin token POST", method: "POST", path: "/me", header: "Bearer " + adminToken, wantCode: http.StatusOK, wantUser: "user-admin"},
+		{name: "unknown token", method: "GET", path: "/me", header: "Bearer " + authz.APITokenPrefix + "unknown", wantCode: http.StatusUnauthorized},
+		{name: "unknown token optional", method: "GET", path: "/optional", header: "Bearer nope", wantCode: http.StatusUnauthorized},
+		{name: "bearer takes precedence over cookie", method: "GET", path: "/me", header: "Bearer " + readToken, cookie: "better-auth.session_token=x", wantCode: http.StatusOK, wantUser: "user-read"},
+		{name: "cookie session", method: "GET", path: "/me", cookie: "better-auth.session_token=x", wantCode: http.StatusOK, wantUser: "cookie-user"},
+		{name: "no credentials", method: "GET", path: "/me", wantCode: http.StatusUnauthorized},
+		{name: "no credentials optional", method: "GET", path: "/optional", wantCode: http.StatusOK},
+		{name: "read token admin scope", method: "GET", path: "/admin", header: "Bearer " + readToken, wantCode: http.StatusForbidden},
+		{name: "admin token admin scope", method: "GET", path: "/admin", header: "Bearer " + adminToken, wantCode: http.StatusOK, wantUser: "user-admin"},
+		{name: "cookie admin scope", method: "GET", path: "/admin", cookie: "better-auth.session_token=x", wantCode: http.StatusOK, wantUser: "cookie-user"},
+		{name: "token on session-only route", method: "GET", path: "/tokens", header: "Bearer " + adminToken, wantCode: http.StatusForbidden},
+		{name: "cookie on session-only route", method: "GET", path: "/tokens", cookie: "better-auth.session_token=x", wantCode: http.StatusOK, wantUser: "cookie-user"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			if tc.cookie != "" {
+				req.Header.Set("Cookie", tc.cookie)
+			}
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.wantCode, w.Code)
+			if tc.wantUser != "" {
+				assert.Equal(t, tc.wantUser, w.Body.String())
+			}
+		})
+	}
+	assert.NotEmpty(t, store.touched)
+}
@@ -1,27 +1,18 @@
 package middleware
 
 import (
-	"encoding/json"
 	"errors"
-	"io"
 	"log"
 	"net/http"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 const userIDContextKey = "user_id"
 
-type getSessionResponse struct {
-	User *struct {
-		ID string `json:"id"`
-		} `json:"user"`
-	}
-	
-	func frontendBaseURL() string {
+func frontendBaseURL() string {
 	// In docker-compose, backend can reach frontend via http://frontend:3000
 	if v := os.Getenv("BETTER_AUTH_URL"); v != "" {
 		return strings.TrimRight(v, "/")
@@ -37,57 +28,72 @@ func FrontendBaseURL() string {
 	return frontendBaseURL()
 }
 
-func RequireAuth() gin.HandlerFunc {
-	client := &http.Client{Timeout: 5 * time.Second}
+// Authenticator はCookieセッションまたはBearerトークンでリクエストを認証する
+type Authenticator struct {
+	sessions SessionVerifier
+	tokens   APITokenStore
+}
+
+func NewAuthenticator(sessions SessionVerifier, tokens APITokenStore) *Authenticator {
+	return &Authenticator{sessions: sessions, tokens: tokens}
+}
+
+// RequireAuth は認証必須。Authorization: Bearer があればトークン、なければCookieで認証する
+func (a *Authenticator) RequireAuth() gin.HandlerFunc {
 	log.Printf("[auth] RequireAuth middleware initialized")
 
 	return func(c *gin.Context) {
-		cookie := c.GetHeader("Cookie")
-		if strings.TrimSpace(cookie) == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		if token, ok := bearerToken(c.GetHeader("Authorization")); ok {
+			a.authenticateToken(c, token)
 			return
 		}
 
-		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, frontendBaseURL()+"/api/auth/get-session", nil)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to build auth request"})
+		cookie := c.GetHeader("Cookie")
+		if strings.TrimSpace(cookie) == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			return
 		}
-		req.Header.Set("Cookie", cookie)
-		req.Header.Set("Accept", "application/json")
 
-		res, err := client.Do(req)
+		userID, err := a.sessions.VerifySession(c.Request.Context(), cookie)
 		if err != nil {
-			// Fail closed: treat verification failure as unauthenticated.
-			log.Printf("[auth] failed to verify session: %v", err)
+			if !errors.Is(err, ErrNoSession) {
+				// Fail closed: treat verification failure as unauthenticated.
+				log.Printf("[auth] failed to verify session: %v", err)
+			}
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			return
 		}
-		defer res.Body.Close()
 
-		body, _ := io.ReadAll(res.Body)
-		if res.StatusCode != http.StatusOK {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-			return
-		}
+		c.Set(userIDContextKey, userID)
+		c.Next()
+	}
+}
 
-		// better-auth returns `null` when not authenticated.
-		if strings.TrimSpace(string(body)) == "" || strings.TrimSpace(string(body)) == "null" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+// 任意認証: クッキーがあればuserIDをContextにセット、なければ何もしない
+// Bearerトークンが付いている場合は明示的な認証の意図があるため、無効なら401を返す
+func (a *Authenticator) OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token, ok := bearerToken(c.GetHeader("Authorization")); ok {
+			a.authenticateToken(c, token)
 			return
 		}
 
-		var parsed getSessionResponse
-		if err := json.Unmarshal(body, &parsed); err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		cookie := c.GetHeader("Cookie")
+		if strings.TrimSpace(cookie) == "" {
+			c.Next()
 			return
 		}
-		if parsed.User == nil || strings.TrimSpace(parsed.User.ID) == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+
+		userID, err := a.sessions.VerifySession(c.Request.Context(), cookie)
+		if err != nil {
+			if !errors.Is(err, ErrNoSession) {
+				log.Printf("[auth] failed to verify session (optional): %v", err)
+			}
+			c.Next()
 			return
 		}
 
-		c.Set(userIDContextKey, parsed.User.ID)
+		c.Set(userIDContextKey, userID)
 		c.Next()
 	}
 }
@@ -104,30 +110,25 @@ func UserIDFromContext(c *gin.Context) (string, error) {
 	return s, nil
 }
 
-// 任意認証: クッキーがあればuserIDをContextにセット、なければ何もしない
-func OptionalAuth() gin.HandlerFunc {
-	client := &http.Client{Timeout: 5 * time.Second}
-	return func(c *gin.Context) {
-		cookie := c.GetHeader("Cookie")
-		if strings.TrimSpace(cookie) == "" {
-			c.Next()
-			return
-		}
-		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, frontendBaseURL()+"/api/auth/get-session", nil)
-		req.Header.Set("Cookie", cookie)
-		req.Header.Set("Accept", "application/json")
-		res, err := client.Do(req)
-		if err != nil || res.StatusCode != http.StatusOK {
-			c.Next()
-			return
-		}
+// private functions
 
-		defer res.Body.Close()
-		body, _ := io.ReadAll(res.Body)
-		var parsed getSessionResponse
-		if err := json.Unmarshal(body, &parsed); err == nil && parsed.User != nil && parsed.User.ID != "" {
-			c.Set(userIDContextKey, parsed.User.ID)
+func (a *Authenticator) authenticateToken(c *gin.Context, token string) {
+	row, err := verifyAPIToken(c.Request.Context(), a.tokens, token)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidAPIToken) {
+			log.Printf("[auth] failed to verify api token: %v", err)
 		}
-		c.Next()
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	scope := scopeForMethod(c.Request.Method)
+	if !containsString(row.Scopes, scope) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope", "required_scope": scope})
+		return
 	}
-}
\ No newline at end of file
+
+	c.Set(userIDContextKey, row.UserID)
+	c.Set(tokenScopesContextKey, row.Scopes)
+	c.Next()
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/chan-shizu/SZer/internal/authz"
+	"github.com/gin-gonic/gin"
+)
+
+const userRolesContextKey = "user_roles"
+
+// RoleLoader はユーザーのロールを取得する（db.Queriesが満たす）
+type RoleLoader interface {
+	ListUserRoles(ctx context.Context, userID string) ([]string, error)
+}
+
+// RequireRole は指定ロールのいずれかを持つユーザーのみ通過させる。RequireAuthの後に使うこと。
+// adminは全てのロールチェックを通過する。
+func RequireRole(loader RoleLoader, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := UserIDFromContext(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		userRoles, err := loadRoles(c, loader, userID)
+		if err != nil {
+			log.Printf("[auth] failed to load roles. userID=%s, err=%v", userID, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load roles"})
+			return
+		}
+
+		if !hasAnyRole(userRoles, roles) {
+			log.Printf("[auth] forbidden: required=%v, roles=%v, userID=%s", roles, userRoles, userID)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RolesFromContext はRequireRoleで読み込んだロールを返す（未読み込みならnil）
+func RolesFromContext(c *gin.Context) []string {
+	v, ok := c.Get(userRolesContextKey)
+	if !ok {
+		return nil
+	}
+	roles, _ := v.([]string)
+	return roles
+}
+
+// private functions
+
+// loadRoles はリクエスト内で一度だけロールをDBから読み込む
+func loadRoles(c *gin.Context, loader RoleLoader, userID string) ([]string, error) {
+	if v, ok := c.Get(userRolesContextKey); ok {
+		if roles, ok := v.([]string); ok {
+			return roles, nil
+		}
+	}
+
+	roles, err := loader.ListUserRoles(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	// ロール未設定のログインユーザーもviewerとして扱う
+	roles = append(roles, authz.RoleViewer)
+	c.Set(userRolesContextKey, roles)
+	return roles, nil
+}
+
+func hasAnyRole(userRoles, required []string) bool {
+	for _, r := range userRoles {
+		if r == authz.RoleAdmin {
+			return true
+		}
+		for _, req := range required {
+			if r == req {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+// ErrNoSession は有効なセッションが見つからない場合のエラー
+var ErrNoSession = errors.New("no valid session")
+
+// better-authのセッションCookie名（HTTPS環境では__Secure-プレフィックス付き）
+var sessionCookieNames = []string{
+	"__Secure-better-auth.session_token",
+	"better-auth.session_token",
+}
+
+// SessionVerifier はCookieヘッダーからbetter-authのセッションを検証しuserIDを返す
+type SessionVerifier interface {
+	VerifySession(ctx context.Context, cookieHeader string) (string, error)
+}
+
+// SessionStore はセッショントークンからセッションを取得する（db.Queriesが満たす）
+type SessionStore interface {
+	GetSessionByToken(ctx context.Context, token string) (db.GetSessionByTokenRow, error)
+}
+
+// NewSessionVerifierFromEnv は環境変数から検証方式を選ぶ。
+// AUTH_SESSION_VERIFIER=db|http（未指定時はBETTER_AUTH_SECRETがあればdb、なければhttp）
+func NewSessionVerifierFromEnv(store SessionStore) SessionVerifier {
+	secret := os.Getenv("BETTER_AUTH_SECRET")
+	if secret == "" {
+		secret = os.Getenv("AUTH_SECRET")
+	}
+
+	mode := strings.TrimSpace(os.Getenv("AUTH_SESSION_VERIFIER"))
+	if mode == "" {
+		mode = "http"
+		if secret != "" {
+			mode = "db"
+		}
+	}
+
+	if mode == "db" {
+		if secret == "" {
+			log.Printf("[auth] BETTER_AUTH_SECRET is not set; falling back to http session verifier")
+		} else {
+			ttl := 30 * time.Second
+			if v := os.Getenv("AUTH_SESSION_CACHE_TTL"); v != "" {
+				if d, err := time.ParseDuration(v); err == nil {
+					ttl = d
+				}
+			}
+			log.Printf("[auth] session verifier: db (cacheTTL=%s)", ttl)
+			return NewDBSessionVerifier(store, secret, ttl)
+		}
+	}
+
+	log.Printf("[auth] session verifier: http (%s)", frontendBaseURL())
+	return NewHTTPSessionVerifier(frontendBaseURL())
+}
+
+// =============================================================================
+// DBSessionVerifier: sessionテーブルを直接参照して検証する
+// =============================================================================
+
+type DBSessionVerifier struct {
+	store    SessionStore
+	secret   []byte
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedSession
+}
+
+type cachedSession struct {
+	userID     string
+	validUntil time.Time
+}
+
+const maxCachedSessions = 10000
+
+func NewDBSessionVerifier(store SessionStore, secret string, cacheTTL time.Duration) *DBSessionVerifier {
+	return &DBSessionVerifier{
+		store:    store,
+		secret:   []byte(secret),
+		cacheTTL: cacheTTL,
+		now:      time.Now,
+		cache:    map[string]cachedSession{},
+	}
+}
+
+func (v *DBSessionVerifier) VerifySession(ctx context.Context, cookieHeader string) (string, error) {
+	signed := sessionCookieValue(cookieHeader)
+	if signed == "" {
+		return "", ErrNoSession
+	}
+	token, ok := verifySignedCookieValue(signed, v.secret)
+	if !ok {
+		return "", ErrNoSession
+	}
+
+	now := v.now()
+	if userID, ok := v.cached(token, now); ok {
+		return userID, nil
+	}
+
+	session, err := v.store.GetSessionByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNoSession
+		}
+		return "", err
+	}
+	if !session.ExpiresAt.After(now) {
+		return "", ErrNoSession
+	}
+
+	validUntil := now.Add(v.cacheTTL)
+	if session.ExpiresAt.Before(validUntil) {
+		validUntil = session.ExpiresAt
+	}
+	v.remember(token, cachedSession{userID: session.UserId, validUntil: validUntil}, now)
+	return session.UserId, nil
+}
+
+func (v *DBSessionVerifier) cached(token string, now time.Time) (string, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.cache[token]
+	if !ok {
+		return "", false
+	}
+	if !now.Before(s.validUntil) {
+		delete(v.cache, token)
+		return "", false
+	}
+	return s.userID, true
+}
+
+func (v *DBSessionVerifier) remember(token string, s cachedSession, now time.Time) {
+	if v.cacheTTL <= 0 {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.cache) >= maxCachedSessions {
+		for k, c := range v.cache {
+			if !now.Before(c.validUntil) {
+				delete(v.cache, k)
+			}
+		}
+		if len(v.cache) >= maxCachedSessions {
+			v.cache = map[string]cachedSession{}
+		}
+	}
+	v.cache[token] = s
+}
+
+// =============================================================================
+// HTTPSessionVerifier: フロントエンド（Next.js）のget-sessionに問い合わせる
+// =============================================================================
+
+type HTTPSessionVerifier struct {
+	baseURL string
+	client  *http.Client
+}
+
+type getSessionResponse struct {
+	User *struct {
+		ID string `json:"id"`
+	} `json:"user"`
+}
+
+func NewHTTPSessionVerifier(baseURL string) *HTTPSessionVerifier {
+	return &HTTPSessionVerifier{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (v *HTTPSessionVerifier) VerifySession(ctx context.Context, cookieHeader string) (string, error) {
+	if strings.TrimSpace(cookieHeader) == "" {
+		return "", ErrNoSession
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.baseURL+"/api/auth/get-session", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Cookie", cookieHeader)
+	req.Header.Set("Accept", "application/json")
+
+	res, err := v.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return "", ErrNoSession
+	}
+
+	// better-auth returns `null` when not authenticated.
+	if strings.TrimSpace(string(body)) == "" || strings.TrimSpace(string(body)) == "null" {
+		return "", ErrNoSession
+	}
+
+	var parsed getSessionResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", ErrNoSession
+	}
+	if parsed.User == nil || strings.TrimSpace(parsed.User.ID) == "" {
+		return "", ErrNoSession
+	}
+	return parsed.User.ID, nil
+}
+
+// private functions
+
+// sessionCookieValue はCookieヘッダーからセッションCookieの値（URLデコード済み）を取り出す
+func sessionCookieValue(cookieHeader string) string {
+	if strings.TrimSpace(cookieHeader) == "" {
+		return ""
+	}
+	req := &http.Request{Header: http.Header{"Cookie": []string{cookieHeader}}}
+	for _, name := range sessionCookieNames {
+		cookie, err := req.Cookie(name)
+		if err != nil || cookie.Value == "" {
+			continue
+		}
+		value, err := url.PathUnescape(cookie.Value)
+		if err != nil {
+			return ""
+		}
+		return value
+	}
+	return ""
+}
+
+// verifySignedCookieValue はbetter-call形式の署名付きCookie（"{value}.{base64(HMAC-SHA256)}"）を検証する
+func verifySignedCookieValue(signed string, secret []byte) (string, bool) {
+	pos := strings.LastIndex(signed, ".")
+	if pos < 1 {
+		return "", false
+	}
+	value, signature := signed[:pos], signed[pos+1:]
+	if len(signature) != 44 || !strings.HasSuffix(signature, "=") {
+		return "", false
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(value))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", false
+	}
+	return value, true
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSessionStore struct {
+	sessions map[string]db.GetSessionByTokenRow
+	calls    int
+}
+
+func (s *fakeSessionStore) GetSessionByToken(ctx context.Context, token string) (db.GetSessionByTokenRow, error) {
+	s.calls++
+	row, ok := s.sessions[token]
+	if !ok {
+		return db.GetSessionByTokenRow{}, sql.ErrNoRows
+	}
+	return row, nil
+}
+
+// signedSessionCookie はbetter-authと同じ形式のCookieヘッダーを作る
+func signedSessionCookie(name, token, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(token))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return name + "=" + url.QueryEscape(token+"."+signature)
+}
+
+func TestDBSessionVerifier_VerifySession(t *testing.T) {
+	now := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeSessionStore{sessions: map[string]db.GetSessionByTokenRow{
+		"valid-token":   {UserId: "user-1", ExpiresAt: now.Add(time.Hour)},
+		"expired-token": {UserId: "user-2", ExpiresAt: now.Add(-time.Minute)},
+	}}
+	v := NewDBSessionVerifier(store, "test-secret", 0)
+	v.now = func() time.Time { return now }
+
+	cases := []struct {
+		name   string
+		cookie string
+		want   string
+	}{
+		{name: "valid", cookie: signedSessionCookie("better-auth.session_token", "valid-token", "test-secret"), want: "user-1"},
+		{name: "secure prefix", cookie: "foo=bar; " + signedSessionCookie("__Secure-better-auth.session_token", "valid-token", "test-secret"), want: "user-1"},
+		{name: "wrong secret", cookie: signedSessionCookie("better-auth.session_token", "valid-token", "other-secret")},
+		{name: "unsigned", cookie: "better-auth.session_token=valid-token"},
+		{name: "expired", cookie: signedSessionCookie("better-auth.session_token", "expired-token", "test-secret")},
+		{name: "unknown token", cookie: signedSessionCookie("better-auth.session_token", "unknown-token", "test-secret")},
+		{name: "no session cookie", cookie: "foo=bar"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			userID, err := v.VerifySession(context.Background(), tc.cookie)
+			if tc.want == "" {
+				assert.ErrorIs(t, err, ErrNoSession)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, userID)
+		})
+	}
+}
+
+func TestDBSessionVerifier_Cache(t *testing.T) {
+	now := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeSessionStore{sessions: map[string]db.GetSessionByTokenRow{
+		"valid-token": {UserId: "user-1", ExpiresAt: now.Add(time.Hour)},
+	}}
+	v := NewDBSessionVerifier(store, "test-secret", 30*time.Second)
+	v.now = func() time.Time { return now }
+	cookie := signedSessionCookie("better-auth.session_token", "valid-token", "test-secret")
+
+	for i := 0; i < 3; i++ {
+		userID, err := v.VerifySession(context.Background(), cookie)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+	}
+	assert.Equal(t, 1, store.calls)
+
+	// TTL経過後はDBを再確認する（サインアウト済みなら弾かれる）
+	delete(store.sessions, "valid-token")
+	now = now.Add(31 * time.Second)
+	_, err := v.VerifySession(context.Background(), cookie)
+	assert.ErrorIs(t, err, ErrNoSession)
+	assert.Equal(t, 2, store.calls)
+}
@@ -0,0 +1,129 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const ProviderFake = "fake"
+
+// FakeProvider は外部サービスを呼ばない決済サービス（テスト・ローカル開発用）。
+// 決済はSetStatusを呼ぶまで支払い待ちのまま
+type FakeProvider struct {
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+	refunds  map[string]string // merchant_refund_id -> 状態
+	seq      int
+}
+
+type fakePayment struct {
+	status            string
+	amountYen         int32
+	providerPaymentID string
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		payments: make(map[string]*fakePayment),
+		refunds:  make(map[string]string),
+	}
+}
+
+func (f *FakeProvider) Name() string { return ProviderFake }
+
+func (f *FakeProvider) Configured() bool { return true }
+
+// SetStatus は決済の状態を変える（支払い完了などを再現する）
+func (f *FakeProvider) SetStatus(merchantPaymentID, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[merchantPaymentID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, merchantPaymentID)
+	}
+	p.status = status
+	if status == StatusCompleted && p.providerPaymentID == "" {
+		f.seq++
+		p.providerPaymentID = fmt.Sprintf("fake-%d", f.seq)
+	}
+	return nil
+}
+
+func (f *FakeProvider) CreateCheckout(ctx context.Context, req CheckoutRequest) (Checkout, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.payments[req.MerchantPaymentID]; ok {
+		return Checkout{}, fmt.Errorf("%w: duplicate merchant_payment_id %s", ErrRejected, req.MerchantPaymentID)
+	}
+	f.payments[req.MerchantPaymentID] = &fakePayment{status: StatusAwaiting, amountYen: req.AmountYen}
+	return Checkout{
+		CheckoutID: "fake-checkout-" + req.MerchantPaymentID,
+		URL:        req.RedirectURL,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}, nil
+}
+
+func (f *FakeProvider) GetStatus(ctx context.Context, merchantPaymentID string) (PaymentStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[merchantPaymentID]
+	if !ok {
+		return PaymentStatus{}, fmt.Errorf("%w: %s", ErrNotFound, merchantPaymentID)
+	}
+	return PaymentStatus{
+		Status:            p.status,
+		RawStatus:         p.status,
+		ProviderPaymentID: p.providerPaymentID,
+		AmountYen:         p.amountYen,
+	}, nil
+}
+
+func (f *FakeProvider) Cancel(ctx context.Context, merchantPaymentID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[merchantPaymentID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, merchantPaymentID)
+	}
+	if p.status != StatusAwaiting {
+		return fmt.Errorf("%w: payment is %s", ErrRejected, p.status)
+	}
+	p.status = StatusCanceled
+	return nil
+}
+
+func (f *FakeProvider) Refund(ctx context.Context, req RefundRequest) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[req.MerchantPaymentID]
+	if !ok || p.providerPaymentID != req.ProviderPaymentID {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, req.MerchantPaymentID)
+	}
+	if p.status != StatusCompleted {
+		return "", fmt.Errorf("%w: payment is %s", ErrRejected, p.status)
+	}
+	f.refunds[req.MerchantRefundID] = RefundRefunded
+	return RefundRefunded, nil
+}
+
+func (f *FakeProvider) GetRefundStatus(ctx context.Context, merchantRefundID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status, ok := f.refunds[merchantRefundID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, merchantRefundID)
+	}
+	return status, nil
+}
+
+// ParseWebhook はWebhookEventをそのままJSONにしたものを解析する
+func (f *FakeProvider) ParseWebhook(body []byte) (WebhookEvent, error) {
+	var ev WebhookEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return WebhookEvent{}, fmt.Errorf("invalid webhook body: %w", err)
+	}
+	return ev, nil
+}
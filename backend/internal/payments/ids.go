@@ -1,4 +1,4 @@
-package paypay
+package payments
 
 import (
 	"crypto/rand"
@@ -13,3 +13,7 @@ func RandomMerchantPaymentID() (string, error) {
 	// 32 chars hex
 	return hex.EncodeToString(b), nil
 }
+
+func RandomMerchantRefundID() (string, error) {
+	return RandomMerchantPaymentID()
+}
@@ -0,0 +1,200 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/paypay"
+)
+
+const ProviderPayPay = "paypay"
+
+// PayPayProvider はPayPayの動的QRコード（ORDER_QR）による決済
+type PayPayProvider struct {
+	client *paypay.Client
+	cfgErr error
+}
+
+func NewPayPayProvider(client *paypay.Client) *PayPayProvider {
+	return &PayPayProvider{client: client}
+}
+
+// NewPayPayProviderFromEnv は環境変数の設定でPayPayProviderを作る。
+// 設定が無くてもWebhookの解析はできるよう、エラーは各API呼び出しで返す
+func NewPayPayProviderFromEnv() *PayPayProvider {
+	cfg, err := paypay.LoadConfigFromEnv()
+	if err != nil {
+		return &PayPayProvider{cfgErr: err}
+	}
+	return NewPayPayProvider(paypay.NewClient(cfg))
+}
+
+func (p *PayPayProvider) Name() string { return ProviderPayPay }
+
+func (p *PayPayProvider) Configured() bool { return p.client != nil }
+
+func (p *PayPayProvider) ready() error {
+	if p.client == nil {
+		return fmt.Errorf("%w: %s: %v", ErrNotConfigured, ProviderPayPay, p.cfgErr)
+	}
+	return nil
+}
+
+// payPayError はPayPay APIのエラーのうち、結果が確定しているものを共通のエラーで包む
+func payPayError(err error) error {
+	switch {
+	case paypay.IsNotFound(err):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case paypay.IsRejected(err):
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	return err
+}
+
+// payPayStatus はPayPay API・Webhookの決済の状態を共通の状態に変換する
+func payPayStatus(state string) string {
+	switch state {
+	case "CREATED", "AUTHORIZED":
+		// PayPay側にコードがある＝支払い待ち
+		return StatusAwaiting
+	case StatusCompleted, StatusFailed, StatusCanceled, StatusExpired, StatusRefunded:
+		return state
+	}
+	return ""
+}
+
+// payPayRefundStatus はPayPayの返金の状態を共通の状態に変換する
+func payPayRefundStatus(state string) string {
+	switch state {
+	case RefundCreated, RefundRefunded, RefundFailed:
+		return state
+	}
+	return ""
+}
+
+func (p *PayPayProvider) CreateCheckout(ctx context.Context, req CheckoutRequest) (Checkout, error) {
+	if err := p.ready(); err != nil {
+		return Checkout{}, err
+	}
+
+	var codeReq paypay.CreateCodeRequest
+	codeReq.MerchantPaymentID = req.MerchantPaymentID
+	codeReq.Amount.Amount = req.AmountYen
+	codeReq.Amount.Currency = "JPY"
+	codeReq.OrderDescription = req.Description
+	codeReq.CodeType = "ORDER_QR"
+	codeReq.RedirectURL = req.RedirectURL
+	codeReq.RedirectType = "WEB_LINK"
+
+	resp, err := p.client.CreateCode(ctx, codeReq)
+	if err != nil {
+		return Checkout{}, payPayError(err)
+	}
+
+	checkout := Checkout{
+		CheckoutID: resp.Data.CodeID,
+		URL:        resp.Data.URL,
+		Deeplink:   resp.Data.Deeplink,
+	}
+	// expiryDateはエポック秒
+	if resp.Data.ExpiryDate > 0 {
+		checkout.ExpiresAt = time.Unix(resp.Data.ExpiryDate, 0)
+	}
+	return checkout, nil
+}
+
+func (p *PayPayProvider) GetStatus(ctx context.Context, merchantPaymentID string) (PaymentStatus, error) {
+	if err := p.ready(); err != nil {
+		return PaymentStatus{}, err
+	}
+	resp, err := p.client.GetPaymentDetails(ctx, merchantPaymentID)
+	if err != nil {
+		return PaymentStatus{}, payPayError(err)
+	}
+	return PaymentStatus{
+		Status:            payPayStatus(resp.Data.Status),
+		RawStatus:         resp.Data.Status,
+		ProviderPaymentID: resp.Data.PaymentID,
+		AmountYen:         resp.Data.Amount.Amount,
+	}, nil
+}
+
+func (p *PayPayProvider) Cancel(ctx context.Context, merchantPaymentID string) error {
+	if err := p.ready(); err != nil {
+		return err
+	}
+	return payPayError(p.client.CancelPayment(ctx, merchantPaymentID))
+}
+
+func (p *PayPayProvider) Refund(ctx context.Context, req RefundRequest) (string, error) {
+	if err := p.ready(); err != nil {
+		return "", err
+	}
+
+	var refundReq paypay.RefundRequest
+	refundReq.MerchantRefundID = req.MerchantRefundID
+	refundReq.PaymentID = req.ProviderPaymentID
+	refundReq.Amount.Amount = req.AmountYen
+	refundReq.Amount.Currency = "JPY"
+	refundReq.RequestedAt = time.Now().Unix()
+	refundReq.Reason = req.Reason
+
+	resp, err := p.client.RefundPayment(ctx, refundReq)
+	if err != nil {
+		return "", payPayError(err)
+	}
+	return payPayRefundStatus(resp.Data.Status), nil
+}
+
+func (p *PayPayProvider) GetRefundStatus(ctx context.Context, merchantRefundID string) (string, error) {
+	if err := p.ready(); err != nil {
+		return "", err
+	}
+	resp, err := p.client.GetRefundDetails(ctx, merchantRefundID)
+	if err != nil {
+		return "", payPayError(err)
+	}
+	return payPayRefundStatus(resp.Data.Status), nil
+}
+
+// ParseWebhook はPayPayのWebhook（決済はTransaction、返金はRefund通知）を解析する
+func (p *PayPayProvider) ParseWebhook(body []byte) (WebhookEvent, error) {
+	var payload struct {
+		NotificationType string `json:"notification_type"`
+		OrderID          string `json:"order_id"`
+		MerchantOrderID  string `json:"merchant_order_id"`
+		MerchantRefundID string `json:"merchant_refund_id"`
+		State            string `json:"state"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return WebhookEvent{}, fmt.Errorf("invalid webhook body: %w", err)
+	}
+
+	switch payload.NotificationType {
+	case "Transaction":
+		if payload.MerchantOrderID == "" {
+			return WebhookEvent{}, fmt.Errorf("missing merchant_order_id in webhook payload")
+		}
+		return WebhookEvent{
+			Type:              WebhookPayment,
+			MerchantPaymentID: payload.MerchantOrderID,
+			ProviderPaymentID: payload.OrderID,
+			Status:            payPayStatus(payload.State),
+			RawStatus:         payload.State,
+		}, nil
+	case "Refund":
+		if payload.MerchantRefundID == "" {
+			return WebhookEvent{}, fmt.Errorf("missing merchant_refund_id in webhook payload")
+		}
+		return WebhookEvent{
+			Type:              WebhookRefund,
+			MerchantPaymentID: payload.MerchantOrderID,
+			MerchantRefundID:  payload.MerchantRefundID,
+			Status:            payPayRefundStatus(payload.State),
+			RawStatus:         payload.State,
+		}, nil
+	}
+	return WebhookEvent{RawStatus: payload.State}, nil
+}
@@ -0,0 +1,56 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayPayStatus(t *testing.T) {
+	for state, want := range map[string]string{
+		"CREATED":    StatusAwaiting,
+		"AUTHORIZED": StatusAwaiting,
+		"COMPLETED":  StatusCompleted,
+		"FAILED":     StatusFailed,
+		"CANCELED":   StatusCanceled,
+		"EXPIRED":    StatusExpired,
+		"REFUNDED":   StatusRefunded,
+	} {
+		assert.Equal(t, want, payPayStatus(state), state)
+	}
+	assert.Empty(t, payPayStatus("SOMETHING_NEW"))
+}
+
+func TestPayPayParseWebhook(t *testing.T) {
+	p := NewPayPayProvider(nil)
+
+	ev, err := p.ParseWebhook([]byte(`{"notification_type":"Transaction","merchant_order_id":"m1","order_id":"o1","state":"COMPLETED"}`))
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEvent{Type: WebhookPayment, MerchantPaymentID: "m1", ProviderPaymentID: "o1", Status: StatusCompleted, RawStatus: "COMPLETED"}, ev)
+
+	ev, err = p.ParseWebhook([]byte(`{"notification_type":"Refund","merchant_order_id":"m1","merchant_refund_id":"r1","state":"REFUNDED"}`))
+	require.NoError(t, err)
+	assert.Equal(t, WebhookRefund, ev.Type)
+	assert.Equal(t, "r1", ev.MerchantRefundID)
+	assert.Equal(t, RefundRefunded, ev.Status)
+
+	// 処理しない通知
+	ev, err = p.ParseWebhook([]byte(`{"notification_type":"Other"}`))
+	require.NoError(t, err)
+	assert.Empty(t, ev.Type)
+
+	_, err = p.ParseWebhook([]byte(`{"notification_type":"Transaction","state":"COMPLETED"}`))
+	assert.Error(t, err)
+	_, err = p.ParseWebhook([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestPayPayProviderNotConfigured(t *testing.T) {
+	p := &PayPayProvider{cfgErr: errors.New("missing key")}
+	assert.False(t, p.Configured())
+	_, err := p.CreateCheckout(context.Background(), CheckoutRequest{MerchantPaymentID: "m1", AmountYen: 500})
+	assert.True(t, errors.Is(err, ErrNotConfigured))
+}
@@ -0,0 +1,102 @@
+// Package payments は決済サービス（PayPay・クレジットカード・コンビニ払いなど）ごとの差異を吸収する。
+// 決済サービスはProviderを実装してRegistryに登録する
+package payments
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 決済サービスから得た決済の状態（paymentsテーブルのstatusと同じ値）
+const (
+	StatusAwaiting  = "AWAITING" // 支払い待ち
+	StatusCompleted = "COMPLETED"
+	StatusFailed    = "FAILED"
+	StatusCanceled  = "CANCELED"
+	StatusExpired   = "EXPIRED"
+	StatusRefunded  = "REFUNDED"
+)
+
+// 返金の状態（payment_refundsテーブルのstatusと同じ値）
+const (
+	RefundCreated  = "CREATED" // 決済サービスで処理中
+	RefundRefunded = "REFUNDED"
+	RefundFailed   = "FAILED"
+)
+
+// Webhookで通知されるイベントの種類
+const (
+	WebhookPayment = "payment"
+	WebhookRefund  = "refund"
+)
+
+var ErrNotConfigured = errors.New("payment provider not configured")
+var ErrUnknownProvider = errors.New("unknown payment provider")
+
+// ErrRejected は決済サービスがリクエストを受け付けなかったことが確定している場合のエラー
+var ErrRejected = errors.New("rejected by payment provider")
+
+// ErrNotFound は決済サービス側に対象の決済・返金が存在しない場合のエラー
+var ErrNotFound = errors.New("not found at payment provider")
+
+// IsRejected は決済サービスがリクエストを処理していないことが確定しているエラーかを返す。
+// 通信エラー等、処理されたか分からない場合はfalse
+func IsRejected(err error) bool {
+	return errors.Is(err, ErrRejected) || errors.Is(err, ErrNotFound)
+}
+
+type CheckoutRequest struct {
+	MerchantPaymentID string
+	AmountYen         int32
+	Description       string
+	RedirectURL       string // 支払い後に戻るURL
+}
+
+type Checkout struct {
+	CheckoutID string // 決済サービス側の決済ページ・コードのID
+	URL        string
+	Deeplink   string
+	ExpiresAt  time.Time // 分からなければゼロ値
+}
+
+type PaymentStatus struct {
+	Status            string // Status*。変換できない状態は空
+	RawStatus         string // 決済サービスの状態そのまま（ログ用）
+	ProviderPaymentID string
+	AmountYen         int32 // 分からなければ0
+}
+
+type RefundRequest struct {
+	MerchantRefundID  string
+	MerchantPaymentID string
+	ProviderPaymentID string
+	AmountYen         int32
+	Reason            string
+}
+
+type WebhookEvent struct {
+	Type              string // WebhookPayment / WebhookRefund。処理しない通知は空
+	MerchantPaymentID string
+	ProviderPaymentID string
+	MerchantRefundID  string
+	Status            string // 決済はStatus*、返金はRefund*。変換できない状態は空
+	RawStatus         string
+}
+
+// Provider は決済サービスの実装
+type Provider interface {
+	// Name はpaymentsテーブルのproviderに保存する名前
+	Name() string
+	// Configured は決済サービスのAPIを呼べる設定があるかを返す（Webhookの解析は設定が無くてもできる）
+	Configured() bool
+
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (Checkout, error)
+	GetStatus(ctx context.Context, merchantPaymentID string) (PaymentStatus, error)
+	// Cancel は支払い待ちの決済を取り消す
+	Cancel(ctx context.Context, merchantPaymentID string) error
+	// Refund は完了済みの決済を返金し、返金の状態（Refund*）を返す
+	Refund(ctx context.Context, req RefundRequest) (string, error)
+	GetRefundStatus(ctx context.Context, merchantRefundID string) (string, error)
+	ParseWebhook(body []byte) (WebhookEvent, error)
+}
@@ -0,0 +1,52 @@
+package payments
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Registry は利用できる決済サービスを名前で引けるようにする
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+	names     []string // 登録順
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register は決済サービスを登録する。同じ名前で登録済みなら置き換える
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.providers[p.Name()]; !ok {
+		r.names = append(r.names, p.Name())
+	}
+	r.providers[p.Name()] = p
+}
+
+func (r *Registry) Get(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// Providers は登録済みの決済サービスを登録順に返す
+func (r *Registry) Providers() []Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	providers := make([]Provider, 0, len(r.names))
+	for _, name := range r.names {
+		providers = append(providers, r.providers[name])
+	}
+	return providers
+}
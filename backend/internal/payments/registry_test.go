@@ -0,0 +1,59 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	fake := NewFakeProvider()
+	r := NewRegistry(NewPayPayProvider(nil))
+	r.Register(fake)
+
+	p, err := r.Get(ProviderFake)
+	require.NoError(t, err)
+	assert.Same(t, fake, p)
+
+	_, err = r.Get("credit_card")
+	assert.True(t, errors.Is(err, ErrUnknownProvider))
+
+	// 登録順に返し、同じ名前は置き換える
+	replaced := NewFakeProvider()
+	r.Register(replaced)
+	providers := r.Providers()
+	require.Len(t, providers, 2)
+	assert.Equal(t, ProviderPayPay, providers[0].Name())
+	assert.Same(t, replaced, providers[1])
+}
+
+func TestFakeProvider(t *testing.T) {
+	ctx := context.Background()
+	f := NewFakeProvider()
+
+	_, err := f.CreateCheckout(ctx, CheckoutRequest{MerchantPaymentID: "m1", AmountYen: 500})
+	require.NoError(t, err)
+	st, err := f.GetStatus(ctx, "m1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusAwaiting, st.Status)
+	assert.Equal(t, int32(500), st.AmountYen)
+
+	// 未完了の決済は返金できない
+	_, err = f.Refund(ctx, RefundRequest{MerchantRefundID: "r1", MerchantPaymentID: "m1"})
+	assert.True(t, IsRejected(err))
+
+	require.NoError(t, f.SetStatus("m1", StatusCompleted))
+	st, err = f.GetStatus(ctx, "m1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, st.ProviderPaymentID)
+
+	status, err := f.Refund(ctx, RefundRequest{MerchantRefundID: "r1", MerchantPaymentID: "m1", ProviderPaymentID: st.ProviderPaymentID})
+	require.NoError(t, err)
+	assert.Equal(t, RefundRefunded, status)
+
+	_, err = f.GetRefundStatus(ctx, "unknown")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
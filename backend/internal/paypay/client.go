@@ -74,6 +74,36 @@ type CreateCodeRequest struct {
 	RedirectType     string `json:"redirectType,omitempty"`
 }
 
+// APIError はPayPay APIが2xx以外を返したときのエラー
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("paypay api error: status=%d body=%s", e.StatusCode, e.Body)
+}
+
+// IsRejected はPayPayがリクエストを受け付けなかったことが確定しているエラーかを返す。
+// 通信エラーや5xx・タイムアウト等、PayPay側で処理されたか分からない場合はfalse
+func IsRejected(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return false
+	}
+	return apiErr.StatusCode >= 400 && apiErr.StatusCode < 500
+}
+
+// IsNotFound はPayPay側に対象のリソースが存在しないことを示すエラーかを返す
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
 type ResultInfo struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
@@ -87,6 +117,8 @@ type CreateCodeResponse struct {
 		URL              string `json:"url"`
 		Deeplink         string `json:"deeplink"`
 		MerchantPaymentID string `json:"merchantPaymentId"`
+		// コードの有効期限（epoch秒）
+		ExpiryDate int64 `json:"expiryDate"`
 	} `json:"data"`
 }
 
@@ -96,6 +128,10 @@ type GetPaymentDetailsResponse struct {
 		Status            string `json:"status"`
 		PaymentID         string `json:"paymentId"`
 		MerchantPaymentID string `json:"merchantPaymentId"`
+		Amount            struct {
+			Amount   int32  `json:"amount"`
+			Currency string `json:"currency"`
+		} `json:"amount"`
 	} `json:"data"`
 }
 
@@ -117,6 +153,61 @@ func (c *Client) GetPaymentDetails(ctx context.Context, merchantPaymentID string
 	return res, err
 }
 
+type RefundRequest struct {
+	MerchantRefundID string `json:"merchantRefundId"`
+	PaymentID        string `json:"paymentId"`
+	Amount           struct {
+		Amount   int32  `json:"amount"`
+		Currency string `json:"currency"`
+	} `json:"amount"`
+	RequestedAt int64  `json:"requestedAt"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+type RefundResponse struct {
+	ResultInfo ResultInfo `json:"resultInfo"`
+	Data       struct {
+		// CREATED（処理中）/ REFUNDED / FAILED
+		Status           string `json:"status"`
+		AcceptedAt       int64  `json:"acceptedAt"`
+		MerchantRefundID string `json:"merchantRefundId"`
+		PaymentID        string `json:"paymentId"`
+		Amount           struct {
+			Amount   int32  `json:"amount"`
+			Currency string `json:"currency"`
+		} `json:"amount"`
+		Reason string `json:"reason"`
+	} `json:"data"`
+}
+
+// RefundPayment は完了済みの決済を返金する（一部返金も可）
+func (c *Client) RefundPayment(ctx context.Context, req RefundRequest) (RefundResponse, error) {
+	var res RefundResponse
+	b, err := json.Marshal(req)
+	if err != nil {
+		return res, err
+	}
+
+	err = c.doJSON(ctx, http.MethodPost, "/v2/refunds", url.Values{}, "application/json", b, &res)
+	return res, err
+}
+
+func (c *Client) GetRefundDetails(ctx context.Context, merchantRefundID string) (RefundResponse, error) {
+	var res RefundResponse
+	path := "/v2/refunds/" + url.PathEscape(merchantRefundID)
+	err := c.doJSON(ctx, http.MethodGet, path, url.Values{}, "application/json", nil, &res)
+	return res, err
+}
+
+// CancelPayment は決済をキャンセルする（結果不明の決済の取り消しや、未払いの決済の中止に使う）
+func (c *Client) CancelPayment(ctx context.Context, merchantPaymentID string) error {
+	var res struct {
+		ResultInfo ResultInfo `json:"resultInfo"`
+	}
+	path := "/v2/payments/" + url.PathEscape(merchantPaymentID)
+	return c.doJSON(ctx, http.MethodDelete, path, url.Values{}, "application/json", nil, &res)
+}
+
 func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, contentType string, body []byte, out any) error {
 	fullURL := c.cfg.BaseURL + path
 	if len(query) > 0 {
@@ -167,7 +258,7 @@ func (c *Client) doJSON(ctx context.Context, method, path string, query url.Valu
 	respBody, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		// PayPay often returns JSON, but keep raw body for debugging.
-		return fmt.Errorf("paypay api error: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		return &APIError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
 	}
 
 	if out == nil {
@@ -0,0 +1,40 @@
+package paypay
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestIsRejected(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest}, true},
+		{"not found", &APIError{StatusCode: http.StatusNotFound}, true},
+		{"wrapped", fmt.Errorf("refund: %w", &APIError{StatusCode: http.StatusBadRequest}), true},
+		// PayPay側で処理されたか分からない
+		{"timeout", &APIError{StatusCode: http.StatusRequestTimeout}, false},
+		{"conflict", &APIError{StatusCode: http.StatusConflict}, false},
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, false},
+		{"server error", &APIError{StatusCode: http.StatusInternalServerError}, false},
+		{"network error", errors.New("connection reset"), false},
+	}
+	for _, tc := range cases {
+		if got := IsRejected(tc.err); got != tc.want {
+			t.Errorf("%s: IsRejected() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	if !IsNotFound(&APIError{StatusCode: http.StatusNotFound}) {
+		t.Error("404 should be not found")
+	}
+	if IsNotFound(&APIError{StatusCode: http.StatusBadRequest}) {
+		t.Error("400 should not be not found")
+	}
+}
@@ -0,0 +1,716 @@
+// Package paypaysim はローカル開発・テスト用のPayPay API模擬サーバー。
+// paypay.Clientが使うエンドポイント（決済コード作成・決済照会・キャンセル・返金）を実装し、
+// OPA-Auth署名を検証する。決済状態はテストから操作でき、変化のたびにWebhookを送る。
+package paypaysim
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/paypay"
+)
+
+// 決済の状態（PayPayの決済照会APIのstatus）
+const (
+	StatusCreated   = "CREATED"
+	StatusCompleted = "COMPLETED"
+	StatusFailed    = "FAILED"
+	StatusCanceled  = "CANCELED"
+	StatusExpired   = "EXPIRED"
+)
+
+// 返金の状態
+const (
+	RefundCreated  = "CREATED"
+	RefundRefunded = "REFUNDED"
+	RefundFailed   = "FAILED"
+)
+
+var ErrPaymentNotFound = errors.New("payment not found")
+var ErrRefundNotFound = errors.New("refund not found")
+var ErrInvalidState = errors.New("invalid payment state")
+
+// 署名のepochとの許容誤差
+const authClockSkew = 2 * time.Minute
+
+type Payment struct {
+	MerchantPaymentID string
+	CodeID            string
+	PaymentID         string // 支払い完了時に採番される
+	Amount            int32
+	OrderDescription  string
+	RedirectURL       string
+	Status            string
+	ExpiresAt         time.Time
+	RefundedAmount    int32
+	CreatedAt         time.Time
+	PaidAt            time.Time
+}
+
+type Refund struct {
+	MerchantRefundID  string
+	MerchantPaymentID string
+	PaymentID         string
+	Amount            int32
+	Reason            string
+	Status            string
+	RequestedAt       int64
+	AcceptedAt        int64
+}
+
+type Server struct {
+	APIKey    string
+	APISecret string
+
+	// WebhookURL が空でなければ状態が変わるたびにWebhookを送る
+	WebhookURL string
+	// WebhookSecret が空でなければX-PayPay-SignatureとX-PayPay-Timestampを付ける
+	WebhookSecret string
+	MerchantID    string
+
+	// PublicURL は決済画面（/sim/pay/...）のURLに使う。空ならリクエストのHostから組み立てる
+	PublicURL string
+	// CodeTTL は決済コードの有効期限
+	CodeTTL time.Duration
+	// AsyncRefunds がtrueなら返金はCREATEDで受け付け、CompleteRefundで完了させる
+	AsyncRefunds bool
+
+	// Now はテストで時刻を差し替えるためのフック
+	Now func() time.Time
+
+	httpClient *http.Client
+	mux        *http.ServeMux
+
+	mu       sync.Mutex
+	payments map[string]*Payment // merchantPaymentId → 決済
+	codes    map[string]string   // codeId → merchantPaymentId
+	refunds  map[string]*Refund  // merchantRefundId → 返金
+}
+
+func New(apiKey, apiSecret string) *Server {
+	s := &Server{
+		APIKey:     apiKey,
+		APISecret:  apiSecret,
+		MerchantID: "paypaysim-merchant",
+		CodeTTL:    5 * time.Minute,
+		Now:        time.Now,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		payments:   map[string]*Payment{},
+		codes:      map[string]string{},
+		refunds:    map[string]*Refund{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v2/codes", s.authed(s.handleCreateCode))
+	mux.HandleFunc("DELETE /v2/codes/{codeId}", s.authed(s.handleDeleteCode))
+	mux.HandleFunc("GET /v2/codes/payments/{merchantPaymentId}", s.authed(s.handleGetPayment))
+	mux.HandleFunc("DELETE /v2/payments/{merchantPaymentId}", s.authed(s.handleCancelPayment))
+	mux.HandleFunc("POST /v2/refunds", s.authed(s.handleRefund))
+	mux.HandleFunc("GET /v2/refunds/{merchantRefundId}", s.authed(s.handleGetRefund))
+
+	// 開発用の決済画面（ブラウザで開いて支払い・キャンセルを選ぶ）
+	mux.HandleFunc("GET /sim/pay/{codeId}", s.handlePayPage)
+	mux.HandleFunc("POST /sim/pay/{codeId}", s.handlePaySubmit)
+	s.mux = mux
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ---------------------------------------------------------------------------
+// テストから状態を操作するAPI
+// ---------------------------------------------------------------------------
+
+// Payment は決済の現在の状態を返す
+func (s *Server) Payment(merchantPaymentID string) (Payment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.payments[merchantPaymentID]
+	if !ok {
+		return Payment{}, false
+	}
+	s.expireIfNeeded(p)
+	return *p, true
+}
+
+// Refund は返金の現在の状態を返す
+func (s *Server) Refund(merchantRefundID string) (Refund, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.refunds[merchantRefundID]
+	if !ok {
+		return Refund{}, false
+	}
+	return *r, true
+}
+
+// Complete はユーザーが支払いを完了した状態にし、Webhookを送る。
+// Webhookの送信に失敗しても状態は変わったまま、送信エラーを返す
+func (s *Server) Complete(merchantPaymentID string) error {
+	return s.transition(merchantPaymentID, StatusCompleted)
+}
+
+// Fail は支払いを失敗させ、Webhookを送る
+func (s *Server) Fail(merchantPaymentID string) error {
+	return s.transition(merchantPaymentID, StatusFailed)
+}
+
+// Expire は決済コードを期限切れにし、Webhookを送る
+func (s *Server) Expire(merchantPaymentID string) error {
+	return s.transition(merchantPaymentID, StatusExpired)
+}
+
+// CompleteRefund は処理中（AsyncRefunds）の返金を完了させ、Webhookを送る
+func (s *Server) CompleteRefund(merchantRefundID string) error {
+	return s.finishRefund(merchantRefundID, RefundRefunded)
+}
+
+// FailRefund は処理中の返金を失敗させ、Webhookを送る
+func (s *Server) FailRefund(merchantRefundID string) error {
+	return s.finishRefund(merchantRefundID, RefundFailed)
+}
+
+func (s *Server) transition(merchantPaymentID, status string) error {
+	s.mu.Lock()
+	p, ok := s.payments[merchantPaymentID]
+	if !ok {
+		s.mu.Unlock()
+		return ErrPaymentNotFound
+	}
+	s.expireIfNeeded(p)
+	if p.Status != StatusCreated {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidState, p.Status, status)
+	}
+	p.Status = status
+	if status == StatusCompleted {
+		p.PaymentID = randomDigits(19)
+		p.PaidAt = s.Now()
+	}
+	event := s.transactionEvent(p)
+	s.mu.Unlock()
+
+	return s.sendWebhook(event)
+}
+
+func (s *Server) finishRefund(merchantRefundID, status string) error {
+	s.mu.Lock()
+	r, ok := s.refunds[merchantRefundID]
+	if !ok {
+		s.mu.Unlock()
+		return ErrRefundNotFound
+	}
+	if r.Status != RefundCreated {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: refund %s -> %s", ErrInvalidState, r.Status, status)
+	}
+	r.Status = status
+	if status == RefundFailed {
+		// 確保していた返金額を戻す
+		if p, ok := s.payments[r.MerchantPaymentID]; ok {
+			p.RefundedAmount -= r.Amount
+		}
+	}
+	event := s.refundEvent(r)
+	s.mu.Unlock()
+
+	return s.sendWebhook(event)
+}
+
+// expireIfNeeded は有効期限を過ぎた未払いの決済をEXPIREDにする（mu保持中に呼ぶ）
+func (s *Server) expireIfNeeded(p *Payment) {
+	if p.Status == StatusCreated && !s.Now().Before(p.ExpiresAt) {
+		p.Status = StatusExpired
+	}
+}
+
+// ---------------------------------------------------------------------------
+// 認証（OPA-Auth）
+// ---------------------------------------------------------------------------
+
+// authed はpaypay.Clientが付けるAuthorizationヘッダーを検証してからhを呼ぶ
+func (s *Server) authed(h func(w http.ResponseWriter, r *http.Request, body []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST_PARAMS", "failed to read body")
+			return
+		}
+		if err := s.verifyAuth(r, body); err != nil {
+			log.Printf("[paypaysim] unauthorized: %s %s: %v", r.Method, r.URL.Path, err)
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", err.Error())
+			return
+		}
+		h(w, r, body)
+	}
+}
+
+func (s *Server) verifyAuth(r *http.Request, body []byte) error {
+	header := r.Header.Get("Authorization")
+	const prefix = "hmac OPA-Auth:"
+	if !strings.HasPrefix(header, prefix) {
+		return errors.New("missing OPA-Auth header")
+	}
+	// {APIKey}:{macData}:{Nonce}:{Epoch}:{Hash}
+	parts := strings.Split(strings.TrimPrefix(header, prefix), ":")
+	if len(parts) != 5 {
+		return errors.New("malformed OPA-Auth header")
+	}
+	apiKey, macData, nonce, epochStr, hash := parts[0], parts[1], parts[2], parts[3], parts[4]
+
+	if apiKey != s.APIKey {
+		return errors.New("unknown api key")
+	}
+	epoch, err := strconv.ParseInt(epochStr, 10, 64)
+	if err != nil {
+		return errors.New("invalid epoch")
+	}
+	if d := s.Now().Sub(time.Unix(epoch, 0)); d > authClockSkew || d < -authClockSkew {
+		return errors.New("epoch out of range")
+	}
+
+	ct := "empty"
+	expectedHash := "empty"
+	if len(body) > 0 {
+		ct = r.Header.Get("Content-Type")
+		md := md5.New()
+		md.Write([]byte(ct))
+		md.Write(body)
+		expectedHash = base64.StdEncoding.EncodeToString(md.Sum(nil))
+	}
+	if hash != expectedHash {
+		return errors.New("body hash mismatch")
+	}
+
+	pathWithQuery := r.URL.EscapedPath()
+	if r.URL.RawQuery != "" {
+		pathWithQuery += "?" + r.URL.RawQuery
+	}
+	signatureString := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s", pathWithQuery, r.Method, nonce, epochStr, ct, hash)
+	mac := hmac.New(sha256.New, []byte(s.APISecret))
+	mac.Write([]byte(signatureString))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(macData)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// PayPay API
+// ---------------------------------------------------------------------------
+
+type amount struct {
+	Amount   int32  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func (s *Server) handleCreateCode(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req struct {
+		MerchantPaymentID string `json:"merchantPaymentId"`
+		Amount            amount `json:"amount"`
+		OrderDescription  string `json:"orderDescription"`
+		CodeType          string `json:"codeType"`
+		RedirectURL       string `json:"redirectUrl"`
+		RedirectType      string `json:"redirectType"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST_PARAMS", "invalid body")
+		return
+	}
+	if req.MerchantPaymentID == "" || req.Amount.Amount <= 0 || req.CodeType != "ORDER_QR" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST_PARAMS", "merchantPaymentId, amount and codeType=ORDER_QR are required")
+		return
+	}
+
+	s.mu.Lock()
+	if _, ok := s.payments[req.MerchantPaymentID]; ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "DUPLICATE_DYNAMIC_QR_REQUEST", "duplicate merchantPaymentId")
+		return
+	}
+	now := s.Now()
+	p := &Payment{
+		MerchantPaymentID: req.MerchantPaymentID,
+		CodeID:            "04-" + randomHex(8),
+		Amount:            req.Amount.Amount,
+		OrderDescription:  req.OrderDescription,
+		RedirectURL:       req.RedirectURL,
+		Status:            StatusCreated,
+		ExpiresAt:         now.Add(s.CodeTTL),
+		CreatedAt:         now,
+	}
+	s.payments[p.MerchantPaymentID] = p
+	s.codes[p.CodeID] = p.MerchantPaymentID
+	s.mu.Unlock()
+
+	url := s.publicURL(r) + "/sim/pay/" + p.CodeID
+	writeSuccess(w, http.StatusCreated, map[string]any{
+		"codeId":            p.CodeID,
+		"url":               url,
+		"deeplink":          "paypay://payment?link_key=" + p.CodeID,
+		"merchantPaymentId": p.MerchantPaymentID,
+		"amount":            amount{Amount: p.Amount, Currency: "JPY"},
+		"expiryDate":        p.ExpiresAt.Unix(),
+		"codeType":          "ORDER_QR",
+		"redirectUrl":       p.RedirectURL,
+		"redirectType":      req.RedirectType,
+	})
+}
+
+func (s *Server) handleDeleteCode(w http.ResponseWriter, r *http.Request, _ []byte) {
+	s.mu.Lock()
+	mpid, ok := s.codes[r.PathValue("codeId")]
+	if ok {
+		delete(s.codes, r.PathValue("codeId"))
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusBadRequest, "DYNAMIC_QR_NOT_FOUND", "code not found")
+		return
+	}
+	log.Printf("[paypaysim] code deleted: merchantPaymentId=%s", mpid)
+	writeSuccess(w, http.StatusOK, nil)
+}
+
+func (s *Server) handleGetPayment(w http.ResponseWriter, r *http.Request, _ []byte) {
+	s.mu.Lock()
+	p, ok := s.payments[r.PathValue("merchantPaymentId")]
+	if ok {
+		s.expireIfNeeded(p)
+	}
+	var data map[string]any
+	if ok {
+		data = paymentData(p)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "DYNAMIC_QR_PAYMENT_NOT_FOUND", "payment not found")
+		return
+	}
+	writeSuccess(w, http.StatusOK, data)
+}
+
+func (s *Server) handleCancelPayment(w http.ResponseWriter, r *http.Request, _ []byte) {
+	s.mu.Lock()
+	p, ok := s.payments[r.PathValue("merchantPaymentId")]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "ORDER_NOT_AVAILABLE", "payment not found")
+		return
+	}
+	s.expireIfNeeded(p)
+	if p.Status != StatusCreated && p.Status != StatusCompleted {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "ORDER_NOT_REVERSIBLE", "payment is not cancelable")
+		return
+	}
+	p.Status = StatusCanceled
+	event := s.transactionEvent(p)
+	s.mu.Unlock()
+
+	if err := s.sendWebhook(event); err != nil {
+		log.Printf("[paypaysim] webhook failed: %v", err)
+	}
+	writeSuccess(w, http.StatusAccepted, nil)
+}
+
+func (s *Server) handleRefund(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req struct {
+		MerchantRefundID string `json:"merchantRefundId"`
+		PaymentID        string `json:"paymentId"`
+		Amount           amount `json:"amount"`
+		RequestedAt      int64  `json:"requestedAt"`
+		Reason           string `json:"reason"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST_PARAMS", "invalid body")
+		return
+	}
+	if req.MerchantRefundID == "" || req.PaymentID == "" || req.Amount.Amount <= 0 {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST_PARAMS", "merchantRefundId, paymentId and amount are required")
+		return
+	}
+
+	s.mu.Lock()
+	if _, ok := s.refunds[req.MerchantRefundID]; ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "DUPLICATE_REFUND_REQUEST", "duplicate merchantRefundId")
+		return
+	}
+	var p *Payment
+	for _, candidate := range s.payments {
+		if candidate.PaymentID == req.PaymentID {
+			p = candidate
+			break
+		}
+	}
+	if p == nil || p.Status != StatusCompleted {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "INVALID_PAYMENT_STATUS", "payment is not refundable")
+		return
+	}
+	if p.RefundedAmount+req.Amount.Amount > p.Amount {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "REFUND_AMOUNT_EXCEEDED", "refund amount exceeds payment amount")
+		return
+	}
+
+	p.RefundedAmount += req.Amount.Amount
+	refund := &Refund{
+		MerchantRefundID:  req.MerchantRefundID,
+		MerchantPaymentID: p.MerchantPaymentID,
+		PaymentID:         p.PaymentID,
+		Amount:            req.Amount.Amount,
+		Reason:            req.Reason,
+		Status:            RefundRefunded,
+		RequestedAt:       req.RequestedAt,
+		AcceptedAt:        s.Now().Unix(),
+	}
+	if s.AsyncRefunds {
+		refund.Status = RefundCreated
+	}
+	s.refunds[refund.MerchantRefundID] = refund
+	data := refundData(refund)
+	s.mu.Unlock()
+
+	writeSuccess(w, http.StatusCreated, data)
+}
+
+func (s *Server) handleGetRefund(w http.ResponseWriter, r *http.Request, _ []byte) {
+	s.mu.Lock()
+	refund, ok := s.refunds[r.PathValue("merchantRefundId")]
+	var data map[string]any
+	if ok {
+		data = refundData(refund)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "NO_SUCH_REFUND_ORDER", "refund not found")
+		return
+	}
+	writeSuccess(w, http.StatusOK, data)
+}
+
+func paymentData(p *Payment) map[string]any {
+	data := map[string]any{
+		"status":            p.Status,
+		"merchantPaymentId": p.MerchantPaymentID,
+		"amount":            amount{Amount: p.Amount, Currency: "JPY"},
+		"requestedAt":       p.CreatedAt.Unix(),
+		"orderDescription":  p.OrderDescription,
+	}
+	if p.PaymentID != "" {
+		data["paymentId"] = p.PaymentID
+		data["acceptedAt"] = p.PaidAt.Unix()
+	}
+	return data
+}
+
+func refundData(r *Refund) map[string]any {
+	return map[string]any{
+		"status":           r.Status,
+		"acceptedAt":       r.AcceptedAt,
+		"merchantRefundId": r.MerchantRefundID,
+		"paymentId":        r.PaymentID,
+		"amount":           amount{Amount: r.Amount, Currency: "JPY"},
+		"requestedAt":      r.RequestedAt,
+		"reason":           r.Reason,
+	}
+}
+
+func writeSuccess(w http.ResponseWriter, status int, data any) {
+	writeJSON(w, status, map[string]any{
+		"resultInfo": map[string]string{"code": "SUCCESS", "message": "Success", "codeId": "08100001"},
+		"data":       data,
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]any{
+		"resultInfo": map[string]string{"code": code, "message": message, "codeId": "08100999"},
+		"data":       nil,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// ---------------------------------------------------------------------------
+// Webhook
+// ---------------------------------------------------------------------------
+
+// transactionEvent は決済状態の通知ボディを作る（mu保持中に呼ぶ）
+func (s *Server) transactionEvent(p *Payment) map[string]any {
+	event := map[string]any{
+		"notification_type": "Transaction",
+		"merchant_id":       s.MerchantID,
+		"store_id":          "",
+		"order_id":          p.PaymentID,
+		"merchant_order_id": p.MerchantPaymentID,
+		"order_amount":      strconv.Itoa(int(p.Amount)),
+		"state":             p.Status,
+		"expires_at":        p.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+	if p.Status == StatusCompleted {
+		event["paid_at"] = p.PaidAt.UTC().Format(time.RFC3339)
+	}
+	return event
+}
+
+// refundEvent は返金の通知ボディを作る（mu保持中に呼ぶ）
+func (s *Server) refundEvent(r *Refund) map[string]any {
+	return map[string]any{
+		"notification_type":  "Refund",
+		"merchant_id":        s.MerchantID,
+		"order_id":           r.PaymentID,
+		"merchant_order_id":  r.MerchantPaymentID,
+		"merchant_refund_id": r.MerchantRefundID,
+		"refund_amount":      strconv.Itoa(int(r.Amount)),
+		"state":              r.Status,
+	}
+}
+
+func (s *Server) sendWebhook(event map[string]any) error {
+	if s.WebhookURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.WebhookSecret != "" {
+		timestamp, signature := paypay.SignWebhook(s.WebhookSecret, s.Now(), body)
+		req.Header.Set(paypay.WebhookTimestampHeader, timestamp)
+		req.Header.Set(paypay.WebhookSignatureHeader, signature)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook delivery failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("webhook delivery failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// 開発用の決済画面
+// ---------------------------------------------------------------------------
+
+func (s *Server) publicURL(r *http.Request) string {
+	if s.PublicURL != "" {
+		return strings.TrimRight(s.PublicURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func (s *Server) paymentByCode(codeID string) (Payment, bool) {
+	s.mu.Lock()
+	mpid, ok := s.codes[codeID]
+	s.mu.Unlock()
+	if !ok {
+		return Payment{}, false
+	}
+	return s.Payment(mpid)
+}
+
+func (s *Server) handlePayPage(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.paymentByCode(r.PathValue("codeId"))
+	if !ok {
+		http.Error(w, "code not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!doctype html>
+<html lang="ja"><head><meta charset="utf-8"><title>PayPay Simulator</title></head>
+<body>
+<h1>PayPay Simulator</h1>
+<p>%s</p>
+<p>金額: %d円 / 状態: %s</p>
+<form method="post">
+<button name="action" value="pay">支払う</button>
+<button name="action" value="fail">失敗させる</button>
+</form>
+</body></html>`, html.EscapeString(p.OrderDescription), p.Amount, p.Status)
+}
+
+func (s *Server) handlePaySubmit(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.paymentByCode(r.PathValue("codeId"))
+	if !ok {
+		http.Error(w, "code not found", http.StatusNotFound)
+		return
+	}
+
+	var err error
+	switch r.FormValue("action") {
+	case "pay":
+		err = s.Complete(p.MerchantPaymentID)
+	case "fail":
+		err = s.Fail(p.MerchantPaymentID)
+	default:
+		http.Error(w, "unknown action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		// Webhookが届かなくても決済照会APIで確認できるので画面は進める
+		log.Printf("[paypaysim] %s: %v", p.MerchantPaymentID, err)
+	}
+
+	if p.RedirectURL != "" {
+		http.Redirect(w, r, p.RedirectURL, http.StatusSeeOther)
+		return
+	}
+	fmt.Fprintln(w, "OK")
+}
+
+func randomHex(nBytes int) string {
+	b := make([]byte, nBytes)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func randomDigits(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = '0' + b[i]%10
+	}
+	return string(b)
+}
@@ -0,0 +1,187 @@
+package paypaysim
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chan-shizu/SZer/internal/paypay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, sim *Server, apiSecret string) *paypay.Client {
+	t.Helper()
+	srv := httptest.NewServer(sim)
+	t.Cleanup(srv.Close)
+	return paypay.NewClient(paypay.Config{BaseURL: srv.URL, APIKey: sim.APIKey, APISecret: apiSecret})
+}
+
+func createCode(t *testing.T, client *paypay.Client, merchantPaymentID string, amount int32) paypay.CreateCodeResponse {
+	t.Helper()
+	var req paypay.CreateCodeRequest
+	req.MerchantPaymentID = merchantPaymentID
+	req.Amount.Amount = amount
+	req.Amount.Currency = "JPY"
+	req.CodeType = "ORDER_QR"
+	req.RedirectURL = "http://localhost:3000/return"
+	req.RedirectType = "WEB_LINK"
+	resp, err := client.CreateCode(context.Background(), req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestPaymentLifecycle(t *testing.T) {
+	sim := New("key", "secret")
+
+	var mu sync.Mutex
+	var webhooks []map[string]any
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// Webhookには時刻付きの署名が付く
+		auth := paypay.NewWebhookAuthenticator(paypay.WebhookAuthConfig{
+			Secret:             "whsecret",
+			SignatureMode:      paypay.WebhookSignatureStrict,
+			TimestampTolerance: time.Minute,
+		})
+		assert.NoError(t, auth.Verify(r, body))
+		var event map[string]any
+		_ = json.Unmarshal(body, &event)
+		mu.Lock()
+		webhooks = append(webhooks, event)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+	sim.WebhookURL = hook.URL
+	sim.WebhookSecret = "whsecret"
+
+	client := newTestClient(t, sim, "secret")
+	ctx := context.Background()
+
+	code := createCode(t, client, "mpid-1", 500)
+	assert.NotEmpty(t, code.Data.CodeID)
+	assert.Contains(t, code.Data.URL, "/sim/pay/"+code.Data.CodeID)
+	assert.Greater(t, code.Data.ExpiryDate, time.Now().Unix())
+
+	// 同じmerchantPaymentIdは作れない
+	var dup paypay.CreateCodeRequest
+	dup.MerchantPaymentID = "mpid-1"
+	dup.Amount.Amount = 500
+	dup.CodeType = "ORDER_QR"
+	_, err := client.CreateCode(ctx, dup)
+	assert.Error(t, err)
+
+	details, err := client.GetPaymentDetails(ctx, "mpid-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCreated, details.Data.Status)
+	assert.Empty(t, details.Data.PaymentID)
+
+	require.NoError(t, sim.Complete("mpid-1"))
+	details, err = client.GetPaymentDetails(ctx, "mpid-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, details.Data.Status)
+	assert.NotEmpty(t, details.Data.PaymentID)
+	assert.Equal(t, int32(500), details.Data.Amount.Amount)
+
+	// 完了済みの決済は失敗にできない
+	assert.ErrorIs(t, sim.Fail("mpid-1"), ErrInvalidState)
+
+	mu.Lock()
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, "Transaction", webhooks[0]["notification_type"])
+	assert.Equal(t, "COMPLETED", webhooks[0]["state"])
+	assert.Equal(t, "mpid-1", webhooks[0]["merchant_order_id"])
+	assert.Equal(t, details.Data.PaymentID, webhooks[0]["order_id"])
+	mu.Unlock()
+}
+
+func TestRefunds(t *testing.T) {
+	sim := New("key", "secret")
+	client := newTestClient(t, sim, "secret")
+	ctx := context.Background()
+
+	createCode(t, client, "mpid-refund", 1000)
+	require.NoError(t, sim.Complete("mpid-refund"))
+	payment, _ := sim.Payment("mpid-refund")
+
+	refund := func(merchantRefundID string, amount int32) (paypay.RefundResponse, error) {
+		var req paypay.RefundRequest
+		req.MerchantRefundID = merchantRefundID
+		req.PaymentID = payment.PaymentID
+		req.Amount.Amount = amount
+		req.Amount.Currency = "JPY"
+		req.RequestedAt = time.Now().Unix()
+		return client.RefundPayment(ctx, req)
+	}
+
+	res, err := refund("r-1", 400)
+	require.NoError(t, err)
+	assert.Equal(t, RefundRefunded, res.Data.Status)
+
+	// 残額を超える返金はできない
+	_, err = refund("r-2", 700)
+	assert.Error(t, err)
+
+	// 非同期モードでは処理中で受け付け、テストから完了させる
+	sim.AsyncRefunds = true
+	res, err = refund("r-3", 600)
+	require.NoError(t, err)
+	assert.Equal(t, RefundCreated, res.Data.Status)
+	require.NoError(t, sim.CompleteRefund("r-3"))
+
+	details, err := client.GetRefundDetails(ctx, "r-3")
+	require.NoError(t, err)
+	assert.Equal(t, RefundRefunded, details.Data.Status)
+	assert.Equal(t, int32(600), details.Data.Amount.Amount)
+}
+
+func TestExpiryAndCancel(t *testing.T) {
+	sim := New("key", "secret")
+	sim.CodeTTL = time.Minute
+	client := newTestClient(t, sim, "secret")
+
+	createCode(t, client, "mpid-expire", 100)
+	createCode(t, client, "mpid-cancel", 100)
+
+	// 有効期限を過ぎた未払いの決済はEXPIREDになる（署名のepoch検証があるのでAPIは呼ばない）
+	sim.Now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	p, ok := sim.Payment("mpid-expire")
+	require.True(t, ok)
+	assert.Equal(t, StatusExpired, p.Status)
+	assert.ErrorIs(t, sim.Complete("mpid-expire"), ErrInvalidState)
+	sim.Now = time.Now
+
+	require.NoError(t, client.CancelPayment(context.Background(), "mpid-cancel"))
+	p, _ = sim.Payment("mpid-cancel")
+	assert.Equal(t, StatusCanceled, p.Status)
+}
+
+func TestRejectsInvalidAuth(t *testing.T) {
+	sim := New("key", "secret")
+	// 別のシークレットで署名したリクエストは拒否される
+	client := newTestClient(t, sim, "wrong-secret")
+
+	var req paypay.CreateCodeRequest
+	req.MerchantPaymentID = "mpid-auth"
+	req.Amount.Amount = 100
+	req.CodeType = "ORDER_QR"
+	_, err := client.CreateCode(context.Background(), req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status=401")
+
+	_, ok := sim.Payment("mpid-auth")
+	assert.False(t, ok)
+
+	// ヘッダー無しも拒否される
+	srv := httptest.NewServer(sim)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/v2/codes/payments/mpid-auth")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
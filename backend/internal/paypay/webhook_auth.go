@@ -0,0 +1,234 @@
+package paypay
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	WebhookSignatureHeader = "X-PayPay-Signature"
+	// 署名時刻（UNIX秒）。付いている場合は「時刻.ボディ」に対して署名する
+	WebhookTimestampHeader = "X-PayPay-Timestamp"
+)
+
+// 署名の検証方式
+const (
+	// 署名と時刻が必須
+	WebhookSignatureStrict = "strict"
+	// 署名が付いている場合のみ検証する（移行用。送信元IPの許可リストが必須）
+	WebhookSignatureOptional = "optional"
+)
+
+const defaultWebhookTimestampTolerance = 5 * time.Minute
+
+var (
+	ErrWebhookForbiddenIP         = errors.New("webhook: forbidden ip")
+	ErrWebhookMissingSignature    = errors.New("webhook: missing signature")
+	ErrWebhookInvalidSignature    = errors.New("webhook: invalid signature")
+	ErrWebhookMissingTimestamp    = errors.New("webhook: missing timestamp")
+	ErrWebhookInvalidTimestamp    = errors.New("webhook: timestamp out of tolerance")
+	ErrWebhookSecretNotConfigured = errors.New("webhook: secret not configured")
+)
+
+type WebhookAuthConfig struct {
+	// 空なら送信元IPを制限しない
+	AllowedNets []*net.IPNet
+	// X-Forwarded-Forを信頼するプロキシ。空ならX-Forwarded-Forは見ない
+	TrustedProxies     []*net.IPNet
+	Secret             string
+	SignatureMode      string
+	TimestampTolerance time.Duration
+}
+
+// LoadWebhookAuthConfigFromEnv は環境変数からWebhook認証の設定を読み込む。
+// 署名なしのWebhookで決済状態が変えられないよう、PAYPAY_WEBHOOK_SECRETが無い場合はエラーにする。
+//   - PAYPAY_WEBHOOK_SECRET: 署名の検証に使うシークレット（必須）
+//   - PAYPAY_WEBHOOK_IP_WHITE_LIST: 許可する送信元（IPまたはCIDRのカンマ区切り）
+//   - PAYPAY_WEBHOOK_TRUSTED_PROXIES: X-Forwarded-Forを信頼するプロキシ（IPまたはCIDRのカンマ区切り）
+//   - PAYPAY_WEBHOOK_SIGNATURE_MODE: strict|optional（デフォルトstrict。optionalはIP許可リストの指定が必要）
+//   - PAYPAY_WEBHOOK_TIMESTAMP_TOLERANCE_SECONDS: 署名時刻の許容ずれ（デフォルト300秒）
+func LoadWebhookAuthConfigFromEnv() (WebhookAuthConfig, error) {
+	cfg := WebhookAuthConfig{
+		Secret:             strings.TrimSpace(os.Getenv("PAYPAY_WEBHOOK_SECRET")),
+		TimestampTolerance: defaultWebhookTimestampTolerance,
+	}
+
+	if cfg.Secret == "" {
+		return WebhookAuthConfig{}, fmt.Errorf("PAYPAY_WEBHOOK_SECRET: %w", ErrWebhookSecretNotConfigured)
+	}
+
+	var err error
+	if cfg.AllowedNets, err = ParseIPNets(os.Getenv("PAYPAY_WEBHOOK_IP_WHITE_LIST")); err != nil {
+		return WebhookAuthConfig{}, fmt.Errorf("PAYPAY_WEBHOOK_IP_WHITE_LIST: %w", err)
+	}
+	if cfg.TrustedProxies, err = ParseIPNets(os.Getenv("PAYPAY_WEBHOOK_TRUSTED_PROXIES")); err != nil {
+		return WebhookAuthConfig{}, fmt.Errorf("PAYPAY_WEBHOOK_TRUSTED_PROXIES: %w", err)
+	}
+
+	cfg.SignatureMode = strings.TrimSpace(os.Getenv("PAYPAY_WEBHOOK_SIGNATURE_MODE"))
+	switch cfg.SignatureMode {
+	case "":
+		cfg.SignatureMode = WebhookSignatureStrict
+	case WebhookSignatureStrict:
+	case WebhookSignatureOptional:
+		// 署名なしを受け付けるため、送信元を限定していなければ起動時に拒否する
+		if len(cfg.AllowedNets) == 0 {
+			return WebhookAuthConfig{}, errors.New("PAYPAY_WEBHOOK_SIGNATURE_MODE: optional mode requires PAYPAY_WEBHOOK_IP_WHITE_LIST")
+		}
+	default:
+		return WebhookAuthConfig{}, fmt.Errorf("PAYPAY_WEBHOOK_SIGNATURE_MODE: unknown mode %q", cfg.SignatureMode)
+	}
+
+	if v := strings.TrimSpace(os.Getenv("PAYPAY_WEBHOOK_TIMESTAMP_TOLERANCE_SECONDS")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return WebhookAuthConfig{}, fmt.Errorf("PAYPAY_WEBHOOK_TIMESTAMP_TOLERANCE_SECONDS: invalid value %q", v)
+		}
+		cfg.TimestampTolerance = time.Duration(n) * time.Second
+	}
+
+	if cfg.SignatureMode == WebhookSignatureOptional {
+		log.Printf("[PayPayWebhook] signature mode: optional (unsigned webhooks are accepted from allowed IPs)")
+	}
+	return cfg, nil
+}
+
+// ParseIPNets はIPまたはCIDRのカンマ区切りを読み取る（単一IPは/32・/128として扱う）
+func ParseIPNets(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if strings.Contains(v, "/") {
+			_, n, err := net.ParseCIDR(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr %q", v)
+			}
+			nets = append(nets, n)
+			continue
+		}
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip %q", v)
+		}
+		bits := 128
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookAuthenticator はWebhookの送信元と署名を検証する
+type WebhookAuthenticator struct {
+	cfg WebhookAuthConfig
+	// テスト用に差し替え可能な現在時刻
+	Now func() time.Time
+}
+
+func NewWebhookAuthenticator(cfg WebhookAuthConfig) *WebhookAuthenticator {
+	return &WebhookAuthenticator{cfg: cfg, Now: time.Now}
+}
+
+// ClientIP は送信元IPを返す。直接の接続元が信頼するプロキシの場合のみX-Forwarded-Forを右から辿り、
+// 信頼するプロキシ以外で最初に現れたアドレスを送信元とする
+func (a *WebhookAuthenticator) ClientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if !containsIP(a.cfg.TrustedProxies, ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// 壊れたヘッダーはそれ以上辿らない
+			return ip
+		}
+		ip = hop
+		if !containsIP(a.cfg.TrustedProxies, hop) {
+			return hop
+		}
+	}
+	return ip
+}
+
+// Verify は送信元IP・署名・署名時刻を検証する
+func (a *WebhookAuthenticator) Verify(r *http.Request, body []byte) error {
+	if len(a.cfg.AllowedNets) > 0 && !containsIP(a.cfg.AllowedNets, a.ClientIP(r)) {
+		return ErrWebhookForbiddenIP
+	}
+
+	signature := r.Header.Get(WebhookSignatureHeader)
+	timestamp := r.Header.Get(WebhookTimestampHeader)
+	strict := a.cfg.SignatureMode == WebhookSignatureStrict
+
+	// シークレットが無ければ署名を検証できないため、モードによらず拒否する
+	if a.cfg.Secret == "" {
+		return ErrWebhookSecretNotConfigured
+	}
+	if signature == "" {
+		// 署名なしは送信元を限定している場合のみ受け付ける
+		if strict || len(a.cfg.AllowedNets) == 0 {
+			return ErrWebhookMissingSignature
+		}
+		return nil
+	}
+	if timestamp == "" {
+		if strict {
+			return ErrWebhookMissingTimestamp
+		}
+		// 時刻なしの旧形式（ボディのみに署名）
+		if !VerifyWebhookSignature(a.cfg.Secret, string(body), signature) {
+			return ErrWebhookInvalidSignature
+		}
+		return nil
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrWebhookInvalidTimestamp
+	}
+	if d := a.Now().Sub(time.Unix(sec, 0)); d > a.cfg.TimestampTolerance || d < -a.cfg.TimestampTolerance {
+		return ErrWebhookInvalidTimestamp
+	}
+	if !VerifyWebhookSignature(a.cfg.Secret, timestamp+"."+string(body), signature) {
+		return ErrWebhookInvalidSignature
+	}
+	return nil
+}
+
+// SignWebhook は時刻付きの署名を返す（送信側・テスト用）
+func SignWebhook(secret string, timestamp time.Time, body []byte) (string, string) {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return ts, signWebhookPayload(secret, ts+"."+string(body))
+}
@@ -0,0 +1,197 @@
+package paypay
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustParseIPNets(t *testing.T, s string) []*net.IPNet {
+	t.Helper()
+	nets, err := ParseIPNets(s)
+	if err != nil {
+		t.Fatalf("ParseIPNets(%q): %v", s, err)
+	}
+	return nets
+}
+
+func newWebhookRequest(remoteAddr string, headers map[string]string) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, "/paypay/webhook", strings.NewReader(""))
+	r.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return r
+}
+
+func TestParseIPNets(t *testing.T) {
+	nets := mustParseIPNets(t, " 13.112.237.64, 10.0.0.0/8 ,2001:db8::/32,")
+	if len(nets) != 3 {
+		t.Fatalf("expected 3 nets, got %d", len(nets))
+	}
+	for _, tt := range []struct {
+		ip   string
+		want bool
+	}{
+		{"13.112.237.64", true},
+		{"13.112.237.65", false},
+		{"10.1.2.3", true},
+		{"2001:db8::1", true},
+		{"::ffff:10.1.2.3", true},
+	} {
+		if got := containsIP(nets, net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("containsIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"not-an-ip", "10.0.0.0/33"} {
+		if _, err := ParseIPNets(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestWebhookAuthenticator_ClientIP(t *testing.T) {
+	a := NewWebhookAuthenticator(WebhookAuthConfig{TrustedProxies: mustParseIPNets(t, "10.0.0.0/8")})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{"direct", "13.112.237.64:1234", "", "13.112.237.64"},
+		// 信頼しない接続元のX-Forwarded-Forは無視する
+		{"untrusted proxy", "1.2.3.4:1234", "13.112.237.64", "1.2.3.4"},
+		{"trusted proxy", "10.0.0.1:1234", "13.112.237.64", "13.112.237.64"},
+		// 偽装された左側の値ではなく、信頼するプロキシが付けた値を使う
+		{"spoofed header", "10.0.0.1:1234", "13.112.237.64, 1.2.3.4", "1.2.3.4"},
+		{"proxy chain", "10.0.0.1:1234", "13.112.237.64, 10.0.0.2", "13.112.237.64"},
+		{"broken header", "10.0.0.1:1234", "garbage", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.xff != "" {
+				headers["X-Forwarded-For"] = tt.xff
+			}
+			got := a.ClientIP(newWebhookRequest(tt.remoteAddr, headers))
+			if got.String() != tt.want {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookAuthenticator_Verify(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	body := []byte(`{"notification_type":"Transaction"}`)
+	ts, sig := SignWebhook("secret", now, body)
+	legacySig := signWebhookPayload("secret", string(body))
+	staleTS, staleSig := SignWebhook("secret", now.Add(-10*time.Minute), body)
+
+	strict := WebhookAuthConfig{
+		AllowedNets:        mustParseIPNets(t, "13.112.237.0/24"),
+		Secret:             "secret",
+		SignatureMode:      WebhookSignatureStrict,
+		TimestampTolerance: 5 * time.Minute,
+	}
+	optional := strict
+	optional.SignatureMode = WebhookSignatureOptional
+	noSecret := strict
+	noSecret.Secret = ""
+	optionalNoSecret := optional
+	optionalNoSecret.Secret = ""
+	optionalAnyIP := optional
+	optionalAnyIP.AllowedNets = nil
+
+	tests := []struct {
+		name       string
+		cfg        WebhookAuthConfig
+		remoteAddr string
+		headers    map[string]string
+		want       error
+	}{
+		{"valid", strict, "13.112.237.64:1", map[string]string{WebhookTimestampHeader: ts, WebhookSignatureHeader: sig}, nil},
+		{"forbidden ip", strict, "1.2.3.4:1", map[string]string{WebhookTimestampHeader: ts, WebhookSignatureHeader: sig}, ErrWebhookForbiddenIP},
+		{"missing signature", strict, "13.112.237.64:1", nil, ErrWebhookMissingSignature},
+		{"missing timestamp", strict, "13.112.237.64:1", map[string]string{WebhookSignatureHeader: legacySig}, ErrWebhookMissingTimestamp},
+		{"stale timestamp", strict, "13.112.237.64:1", map[string]string{WebhookTimestampHeader: staleTS, WebhookSignatureHeader: staleSig}, ErrWebhookInvalidTimestamp},
+		{"bad timestamp", strict, "13.112.237.64:1", map[string]string{WebhookTimestampHeader: "abc", WebhookSignatureHeader: sig}, ErrWebhookInvalidTimestamp},
+		// 時刻を書き換えると署名が合わない
+		{"tampered timestamp", strict, "13.112.237.64:1", map[string]string{WebhookTimestampHeader: strconv.FormatInt(now.Unix()+1, 10), WebhookSignatureHeader: sig}, ErrWebhookInvalidSignature},
+		{"strict without secret", noSecret, "13.112.237.64:1", map[string]string{WebhookTimestampHeader: ts, WebhookSignatureHeader: sig}, ErrWebhookSecretNotConfigured},
+		{"optional unsigned", optional, "13.112.237.64:1", nil, nil},
+		// シークレットが無い・送信元を限定していない場合は署名なしを受け付けない
+		{"optional without secret", optionalNoSecret, "13.112.237.64:1", nil, ErrWebhookSecretNotConfigured},
+		{"optional unsigned from any ip", optionalAnyIP, "1.2.3.4:1", nil, ErrWebhookMissingSignature},
+		{"optional legacy signature", optional, "13.112.237.64:1", map[string]string{WebhookSignatureHeader: legacySig}, nil},
+		{"optional invalid signature", optional, "13.112.237.64:1", map[string]string{WebhookSignatureHeader: "invalid"}, ErrWebhookInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewWebhookAuthenticator(tt.cfg)
+			a.Now = func() time.Time { return now }
+			err := a.Verify(newWebhookRequest(tt.remoteAddr, tt.headers), body)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadWebhookAuthConfigFromEnv(t *testing.T) {
+	t.Setenv("PAYPAY_WEBHOOK_IP_WHITE_LIST", "13.112.237.0/24")
+	t.Setenv("PAYPAY_WEBHOOK_TRUSTED_PROXIES", "")
+	t.Setenv("PAYPAY_WEBHOOK_SIGNATURE_MODE", "")
+	t.Setenv("PAYPAY_WEBHOOK_TIMESTAMP_TOLERANCE_SECONDS", "")
+
+	// デフォルトはstrict
+	t.Setenv("PAYPAY_WEBHOOK_SECRET", "secret")
+	cfg, err := LoadWebhookAuthConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.SignatureMode != WebhookSignatureStrict || cfg.TimestampTolerance != defaultWebhookTimestampTolerance {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	t.Setenv("PAYPAY_WEBHOOK_SIGNATURE_MODE", WebhookSignatureOptional)
+	if cfg, err = LoadWebhookAuthConfigFromEnv(); err != nil || cfg.SignatureMode != WebhookSignatureOptional {
+		t.Errorf("expected optional mode, got %+v, err=%v", cfg, err)
+	}
+
+	// optionalは送信元を限定していないと起動時に拒否する
+	t.Setenv("PAYPAY_WEBHOOK_IP_WHITE_LIST", "")
+	if _, err := LoadWebhookAuthConfigFromEnv(); err == nil {
+		t.Error("expected error for optional mode without ip allowlist")
+	}
+	t.Setenv("PAYPAY_WEBHOOK_IP_WHITE_LIST", "13.112.237.0/24")
+	t.Setenv("PAYPAY_WEBHOOK_SIGNATURE_MODE", "")
+
+	// シークレットが無ければモードによらずエラー
+	t.Setenv("PAYPAY_WEBHOOK_SECRET", "")
+	if _, err := LoadWebhookAuthConfigFromEnv(); !errors.Is(err, ErrWebhookSecretNotConfigured) {
+		t.Errorf("expected ErrWebhookSecretNotConfigured without secret, got %v", err)
+	}
+	t.Setenv("PAYPAY_WEBHOOK_SIGNATURE_MODE", WebhookSignatureOptional)
+	if _, err := LoadWebhookAuthConfigFromEnv(); !errors.Is(err, ErrWebhookSecretNotConfigured) {
+		t.Errorf("expected ErrWebhookSecretNotConfigured in optional mode without secret, got %v", err)
+	}
+	t.Setenv("PAYPAY_WEBHOOK_SECRET", "secret")
+
+	t.Setenv("PAYPAY_WEBHOOK_SIGNATURE_MODE", "lenient")
+	if _, err := LoadWebhookAuthConfigFromEnv(); err == nil {
+		t.Error("expected error for unknown signature mode")
+	}
+	t.Setenv("PAYPAY_WEBHOOK_SIGNATURE_MODE", "")
+
+	t.Setenv("PAYPAY_WEBHOOK_IP_WHITE_LIST", "13.112.237.64/99")
+	if _, err := LoadWebhookAuthConfigFromEnv(); err == nil {
+		t.Error("expected error for invalid cidr")
+	}
+}
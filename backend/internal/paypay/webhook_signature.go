@@ -8,8 +8,12 @@ import (
 
 // VerifyWebhookSignature はPayPay Webhookの署名検証を行うギャル関数だよ！
 func VerifyWebhookSignature(secret, body, signature string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(body))
-	calculated := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	calculated := signWebhookPayload(secret, body)
 	return hmac.Equal([]byte(calculated), []byte(signature))
 }
+
+func signWebhookPayload(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
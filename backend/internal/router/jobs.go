@@ -0,0 +1,130 @@
+package router
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/jobs"
+	"github.com/chan-shizu/SZer/internal/usecase"
+)
+
+// ジョブの種類
+const (
+	JobStatsAggregate       = "stats.aggregate"
+	JobSearchReindexMissing = "search.reindex_missing"
+	JobSessionsPurgeExpired = "sessions.purge_expired"
+)
+
+// NewJobRunner はバックグラウンドジョブのハンドラーと定期実行を登録したRunnerを返す
+func NewJobRunner(conn *sql.DB, q *db.Queries) (*jobs.Runner, error) {
+	runner := jobs.NewRunner(q)
+
+	statsAggregator := usecase.NewStatsAggregator(conn, q)
+	runner.Register(JobStatsAggregate, func(ctx context.Context, job jobs.Job) error {
+		n, err := statsAggregator.AggregateOnce(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("[stats] aggregated %d view events", n)
+		}
+		return nil
+	})
+
+	searchUC := usecase.NewSearchUsecase(q)
+	runner.Register(JobSearchReindexMissing, func(ctx context.Context, job jobs.Job) error {
+		n, err := searchUC.ReindexMissing(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("[search] indexed %d programs", n)
+		}
+		return nil
+	})
+
+	maintenanceUC := usecase.NewMaintenanceUsecase(q)
+	runner.Register(JobSessionsPurgeExpired, func(ctx context.Context, job jobs.Job) error {
+		n, err := maintenanceUC.PurgeExpiredSessions(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("[sessions] purged %d expired sessions", n)
+		return nil
+	})
+
+	paymentProviders := usecase.NewPaymentProvidersFromEnv()
+	webhookDeliveriesUC := usecase.NewWebhookDeliveriesUsecaseWithProviders(conn, q, paymentProviders)
+	runner.Register(usecase.JobWebhookProcessDelivery, func(ctx context.Context, job jobs.Job) error {
+		var payload usecase.WebhookDeliveryPayload
+		if err := job.Decode(&payload); err != nil {
+			return jobs.Permanent(err)
+		}
+		if err := webhookDeliveriesUC.ProcessDelivery(ctx, payload.DeliveryID); err != nil {
+			if errors.Is(err, usecase.ErrWebhookDeliveryNotFound) {
+				return jobs.Permanent(err)
+			}
+			log.Printf("[webhook] delivery processing failed: id=%d, err=%v", payload.DeliveryID, err)
+			return err
+		}
+		return nil
+	})
+
+	paymentsUC := usecase.NewPaymentsUsecaseWithProviders(conn, q, jobs.NewQueue(q), paymentProviders)
+	if paymentsUC.Configured() {
+		runner.Register(usecase.JobPaymentReconcile, func(ctx context.Context, job jobs.Job) error {
+			var payload usecase.PaymentReconcilePayload
+			if err := job.Decode(&payload); err != nil {
+				return jobs.Permanent(err)
+			}
+			res, err := paymentsUC.ReconcilePayment(ctx, payload.MerchantPaymentID)
+			if err != nil {
+				if errors.Is(err, usecase.ErrPaymentNotFound) {
+					return jobs.Permanent(err)
+				}
+				return err
+			}
+			if res.Granted || res.Expired {
+				log.Printf("[PaymentReconcile] merchant_payment_id=%s, status=%s, granted=%v", res.MerchantPaymentID, res.Status, res.Granted)
+			}
+			return nil
+		})
+		runner.Register(usecase.JobPaymentReconcileRefund, func(ctx context.Context, job jobs.Job) error {
+			var payload usecase.PaymentRefundReconcilePayload
+			if err := job.Decode(&payload); err != nil {
+				return jobs.Permanent(err)
+			}
+			refund, err := paymentsUC.ReconcileRefund(ctx, payload.MerchantRefundID)
+			if err != nil {
+				if errors.Is(err, usecase.ErrPaymentRefundNotFound) {
+					return jobs.Permanent(err)
+				}
+				return err
+			}
+			log.Printf("[PaymentReconcile] merchant_refund_id=%s, status=%s", refund.MerchantRefundID, refund.Status)
+			return nil
+		})
+		runner.Register(usecase.JobPaymentReconcilePending, func(ctx context.Context, job jobs.Job) error {
+			if _, err := paymentsUC.ReconcilePendingPayments(ctx); err != nil {
+				return err
+			}
+			_, err := paymentsUC.ReconcilePendingRefunds(ctx)
+			return err
+		})
+		if err := runner.Schedule(usecase.JobPaymentReconcilePending, jobs.Every(5*time.Minute), usecase.JobPaymentReconcilePending, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := runner.Schedule(JobStatsAggregate, jobs.Every(30*time.Second), JobStatsAggregate, nil); err != nil {
+		return nil, err
+	}
+	if err := runner.Schedule(JobSessionsPurgeExpired, jobs.Daily(4, 0, usecase.JST), JobSessionsPurgeExpired, nil); err != nil {
+		return nil, err
+	}
+	return runner, nil
+}
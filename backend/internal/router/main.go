@@ -3,63 +3,165 @@ package router
 import (
 	"database/sql"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/authz"
 	cfutil "github.com/chan-shizu/SZer/internal/cloudfront"
 	"github.com/chan-shizu/SZer/internal/handler"
+	"github.com/chan-shizu/SZer/internal/jobs"
 	"github.com/chan-shizu/SZer/internal/middleware"
 	"github.com/chan-shizu/SZer/internal/usecase"
 	"github.com/gin-gonic/gin"
 )
 
-func NewRouter(conn *sql.DB, q *db.Queries) *gin.Engine {
+func NewRouter(conn *sql.DB, q *db.Queries, queue jobs.Enqueuer) *gin.Engine {
 	router := gin.Default()
 
+	// X-Forwarded-Forは信頼するプロキシ（フロントエンドのサーバー等）経由の場合のみ使う。
+	// 未設定なら接続元IPをそのまま使い、クライアントが送ったX-Forwarded-Forは無視する
+	if err := router.SetTrustedProxies(trustedProxiesFromEnv()); err != nil {
+		log.Fatalf("TRUSTED_PROXIES の設定が不正です: %v", err)
+	}
+
 	signer, err := cfutil.NewVideoURLSigner()
 	if err != nil {
 		log.Fatalf("CloudFront signer初期化失敗: %v", err)
 	}
 
+	auth := middleware.NewAuthenticator(middleware.NewSessionVerifierFromEnv(q), q)
+
 	programsUC := usecase.NewProgramsUsecase(q, signer)
-	paypayUC := usecase.NewPayPayUsecase(conn, q)
+	paymentProviders := usecase.NewPaymentProvidersFromEnv()
+	paymentsUC := usecase.NewPaymentsUsecaseWithProviders(conn, q, queue, paymentProviders)
 
 	requestsUC := usecase.NewRequestsUsecase(q)
+	adminProgramsUC := usecase.NewAdminProgramsUsecase(conn, q)
+	adminUsersUC := usecase.NewAdminUsersUsecase(conn, q)
+	apiTokensUC := usecase.NewAPITokensUsecase(q)
+	searchUC := usecase.NewSearchUsecase(q)
+	performersUC := usecase.NewPerformersUsecase(q)
+	tagsUC := usecase.NewTagsUsecase(conn, q)
+	webhookDeliveriesUC := usecase.NewWebhookDeliveriesUsecaseWithProviders(conn, q, paymentProviders)
+	productsUC := usecase.NewProductsUsecase(conn, q)
+	couponsUC := usecase.NewCouponsUsecase(conn, q)
+	salesUC := usecase.NewSalesUsecase(q)
+	giftsUC := usecase.NewGiftsUsecase(conn, q)
 
 	programsHandler := handler.NewProgramsHandler(programsUC)
-	paypayHandler := handler.NewPayPayHandler(paypayUC)
+	paypayHandler := handler.NewPayPayHandler(paymentsUC)
 	commentsHandler := handler.NewCommentsHandler(q)
 	paypayWebhookHandler := handler.NewPayPayWebhookHandler(conn, q)
 	requestsHandler := handler.NewRequestsHandler(requestsUC)
+	adminProgramsHandler := handler.NewAdminProgramsHandler(adminProgramsUC)
+	adminUsersHandler := handler.NewAdminUsersHandler(adminUsersUC)
+	apiTokensHandler := handler.NewAPITokensHandler(apiTokensUC)
+	searchHandler := handler.NewSearchHandler(searchUC)
+	performersHandler := handler.NewPerformersHandler(performersUC)
+	tagsHandler := handler.NewTagsHandler(tagsUC)
+	adminTagsHandler := handler.NewAdminTagsHandler(tagsUC)
+	adminPayPayHandler := handler.NewAdminPayPayHandler(paymentsUC)
+	adminWebhookDeliveriesHandler := handler.NewAdminWebhookDeliveriesHandler(webhookDeliveriesUC)
+	productsHandler := handler.NewProductsHandler(productsUC)
+	adminProductsHandler := handler.NewAdminProductsHandler(productsUC)
+	adminCouponsHandler := handler.NewAdminCouponsHandler(couponsUC)
+	adminSalesHandler := handler.NewAdminSalesHandler(salesUC)
+	giftsHandler := handler.NewGiftsHandler(giftsUC)
+	paymentHistoryHandler := handler.NewPaymentHistoryHandler(paymentsUC)
 
 	
 	// 認証不要のエンドポイント
 	router.GET("/top", programsHandler.Top)
 	router.GET("/top/liked", programsHandler.TopLiked)
 	router.GET("/top/viewed", programsHandler.TopViewed)
-	router.GET("/programs/:id", middleware.OptionalAuth(), programsHandler.ProgramDetails)
+	router.GET("/programs/:id", auth.OptionalAuth(), programsHandler.ProgramDetails)
 	router.GET("/programs", programsHandler.ListPrograms)
+	router.GET("/search", searchHandler.SearchPrograms)
+	router.GET("/performers", performersHandler.ListPerformers)
+	router.GET("/performers/:id", performersHandler.GetPerformer)
+	router.GET("/performers/:id/programs", performersHandler.ListPerformerPrograms)
+	router.GET("/tags", tagsHandler.ListTags)
+	router.GET("/tags/:id/programs", tagsHandler.ListTagPrograms)
+	router.GET("/products", productsHandler.ListProducts)
+	router.GET("/products/:id", productsHandler.GetProduct)
 
 	// PayPay Webhook（認証不要）
 	router.POST("/paypay/webhook", paypayWebhookHandler.Handle)
 
 	// コメントAPI（未ログインOK）
-	router.GET("/programs/:id/comments", middleware.OptionalAuth(), commentsHandler.ListComments)
-	router.POST("/programs/:id/comments", middleware.OptionalAuth(), commentsHandler.PostComment)
+	router.GET("/programs/:id/comments", auth.OptionalAuth(), commentsHandler.ListComments)
+	router.POST("/programs/:id/comments", auth.OptionalAuth(), commentsHandler.PostComment)
+
+	// 視聴履歴・再生開始の記録（未ログインOK。未ログイン時は視聴回数のみ記録）
+	router.POST("/watch-histories", auth.OptionalAuth(), programsHandler.UpsertWatchHistory)
 
 	// リクエストAPI（未ログインOK）
-	router.POST("/requests", middleware.OptionalAuth(), requestsHandler.CreateRequest)
+	router.POST("/requests", auth.OptionalAuth(), requestsHandler.CreateRequest)
 
 	// マイページ系APIのみ認証必須
 	authenticated := router.Group("/")
-	authenticated.Use(middleware.RequireAuth())
-	authenticated.POST("watch-histories", programsHandler.UpsertWatchHistory)
+	authenticated.Use(auth.RequireAuth())
 	authenticated.POST("programs/:id/like", programsHandler.LikeProgram)
 	authenticated.DELETE("programs/:id/like", programsHandler.UnlikeProgram)
 	authenticated.GET("me/watching-programs", programsHandler.ListWatchingPrograms)
 	authenticated.GET("me/liked-programs", programsHandler.ListLikedPrograms)
 	authenticated.GET("me/purchased-programs", programsHandler.ListPurchasedPrograms)
+	authenticated.GET("me/products", productsHandler.ListMyProducts)
 	authenticated.POST("/me/paypay/checkout", paypayHandler.PayPayCheckout)
 	authenticated.GET("/me/paypay/payments/:merchantPaymentId", paypayHandler.PayPayGetPayment)
+	authenticated.GET("/me/payments", paymentHistoryHandler.ListMyPayments)
+	authenticated.GET("/me/payments/:merchantPaymentId/receipt", paymentHistoryHandler.GetReceipt)
+	authenticated.GET("/me/gifts", giftsHandler.ListMyGifts)
+	authenticated.POST("/me/gifts/redeem", giftsHandler.RedeemGift)
+
+	// APIトークン管理（トークン自身による発行・失効を防ぐためCookieセッションのみ）
+	authenticated.POST("/me/tokens", middleware.RequireSession(), apiTokensHandler.CreateToken)
+	authenticated.GET("/me/tokens", middleware.RequireSession(), apiTokensHandler.ListTokens)
+	authenticated.DELETE("/me/tokens/:id", middleware.RequireSession(), apiTokensHandler.RevokeToken)
+
+	// 管理・モデレーション系API（認証＋ロール必須。adminは全ロールを兼ねる）
+	admin := router.Group("/admin")
+	admin.Use(auth.RequireAuth(), middleware.RequireScope(authz.ScopeAdmin))
+	admin.POST("/programs", middleware.RequireRole(q, authz.RoleUploader), adminProgramsHandler.CreateProgram)
+	admin.GET("/programs/:id", middleware.RequireRole(q, authz.RoleUploader, authz.RoleModerator), adminProgramsHandler.GetProgram)
+	admin.PUT("/programs/:id", middleware.RequireRole(q, authz.RoleUploader), adminProgramsHandler.UpdateProgram)
+	admin.DELETE("/programs/:id", middleware.RequireRole(q, authz.RoleAdmin), adminProgramsHandler.DeleteProgram)
+	admin.POST("/programs/:id/publish", middleware.RequireRole(q, authz.RoleModerator), adminProgramsHandler.PublishProgram)
+	admin.POST("/programs/:id/unpublish", middleware.RequireRole(q, authz.RoleModerator), adminProgramsHandler.UnpublishProgram)
+	admin.POST("/tags", middleware.RequireRole(q, authz.RoleUploader), adminTagsHandler.CreateTag)
+	admin.PUT("/tags/:id", middleware.RequireRole(q, authz.RoleUploader), adminTagsHandler.UpdateTag)
+	admin.DELETE("/tags/:id", middleware.RequireRole(q, authz.RoleAdmin), adminTagsHandler.DeleteTag)
+	admin.DELETE("/comments/:id", middleware.RequireRole(q, authz.RoleModerator), commentsHandler.DeleteComment)
+	admin.GET("/products", middleware.RequireRole(q, authz.RoleAdmin), adminProductsHandler.ListProducts)
+	admin.POST("/products", middleware.RequireRole(q, authz.RoleAdmin), adminProductsHandler.CreateProduct)
+	admin.PUT("/products/:id", middleware.RequireRole(q, authz.RoleAdmin), adminProductsHandler.UpdateProduct)
+	admin.GET("/coupons", middleware.RequireRole(q, authz.RoleAdmin), adminCouponsHandler.ListCoupons)
+	admin.POST("/coupons", middleware.RequireRole(q, authz.RoleAdmin), adminCouponsHandler.CreateCoupon)
+	admin.PUT("/coupons/:id", middleware.RequireRole(q, authz.RoleAdmin), adminCouponsHandler.UpdateCoupon)
+	admin.GET("/sales", middleware.RequireRole(q, authz.RoleAdmin), adminSalesHandler.GetSales)
+	admin.GET("/sales/export", middleware.RequireRole(q, authz.RoleAdmin), adminSalesHandler.ExportSales)
+	admin.GET("/sales/conversions", middleware.RequireRole(q, authz.RoleAdmin), adminSalesHandler.GetProgramConversions)
+	admin.GET("/paypay/topups/:merchantPaymentId/refunds", middleware.RequireRole(q, authz.RoleAdmin), adminPayPayHandler.ListRefunds)
+	admin.POST("/paypay/topups/:merchantPaymentId/refunds", middleware.RequireRole(q, authz.RoleAdmin), adminPayPayHandler.Refund)
+	admin.POST("/paypay/topups/:merchantPaymentId/cancel", middleware.RequireRole(q, authz.RoleAdmin), adminPayPayHandler.Cancel)
+	admin.POST("/paypay/topups/:merchantPaymentId/revoke", middleware.RequireRole(q, authz.RoleAdmin), adminPayPayHandler.Revoke)
+	admin.GET("/webhook-deliveries", middleware.RequireRole(q, authz.RoleAdmin), adminWebhookDeliveriesHandler.ListDeliveries)
+	admin.GET("/webhook-deliveries/:id", middleware.RequireRole(q, authz.RoleAdmin), adminWebhookDeliveriesHandler.GetDelivery)
+	admin.POST("/webhook-deliveries/:id/replay", middleware.RequireRole(q, authz.RoleAdmin), adminWebhookDeliveriesHandler.ReplayDelivery)
+	admin.GET("/users/:id/roles", middleware.RequireRole(q, authz.RoleAdmin), adminUsersHandler.ListUserRoles)
+	admin.PUT("/users/:id/roles", middleware.RequireRole(q, authz.RoleAdmin), adminUsersHandler.SetUserRoles)
 
 	return router
 }
+
+// trustedProxiesFromEnv はTRUSTED_PROXIES（IPまたはCIDRのカンマ区切り）を読み取る
+func trustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, v := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			proxies = append(proxies, v)
+		}
+	}
+	return proxies
+}
@@ -0,0 +1,201 @@
+// Package search は番組検索用の正規化・トークン化を行う。
+//
+// PostgreSQLの全文検索は日本語の分かち書きに対応していないため、
+// 日本語（ひらがな・カタカナ・漢字）はuni-gram/bi-gramに分割し、
+// 英数字は単語単位で索引する。トークンはDBに渡す前にGo側で生成し、
+// tsvector/tsqueryのリテラルとしてそのまま保存・照合する（辞書による変換はしない）。
+package search
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Weight はtsvectorの重み（ランキングに使う）
+type Weight byte
+
+const (
+	WeightA Weight = 'A'
+	WeightB Weight = 'B'
+	WeightC Weight = 'C'
+	WeightD Weight = 'D'
+)
+
+// Field は索引対象のテキストと重み
+type Field struct {
+	Text   string
+	Weight Weight
+}
+
+// tsvectorの位置情報の上限
+const maxPosition = 16383
+
+// Normalize は検索用にテキストを正規化する（NFKC、小文字化、カタカナ→ひらがな）
+func Normalize(s string) string {
+	s = norm.NFKC.String(s)
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		b.WriteRune(foldKana(unicode.ToLower(r)))
+	}
+	return b.String()
+}
+
+// ToKatakana はひらがなをカタカナに変換する（Normalize済みの文字列をカタカナ表記のカラムと照合する用）
+func ToKatakana(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if r >= 'ぁ' && r <= 'ゖ' {
+			r += 0x60
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// BuildDocument はフィールドからtsvectorのリテラルを組み立てる（例: 'やま':1A 'やまだ':1A）
+func BuildDocument(fields ...Field) string {
+	var b strings.Builder
+	pos := 1
+	for _, f := range fields {
+		for _, run := range splitRuns(Normalize(f.Text)) {
+			for _, tok := range documentTokens(run) {
+				if b.Len() > 0 {
+					b.WriteByte(' ')
+				}
+				b.WriteString(quoteLexeme(tok.text))
+				b.WriteByte(':')
+				b.WriteString(strconv.Itoa(min(pos+tok.offset, maxPosition)))
+				b.WriteByte(byte(f.Weight))
+			}
+			if run.cjk {
+				pos += len([]rune(run.text))
+			} else {
+				pos++
+			}
+		}
+	}
+	return b.String()
+}
+
+// BuildQuery は検索語からtsqueryのリテラルを組み立てる。
+// 空白区切りの各語をAND条件とし、英数字は前方一致、日本語はbi-gramのAND（1文字ならuni-gram）で照合する。
+// 検索可能な語がなければ空文字を返す
+func BuildQuery(q string) string {
+	var terms []string
+	for _, run := range splitRuns(Normalize(q)) {
+		if run.cjk {
+			runes := []rune(run.text)
+			if len(runes) == 1 {
+				terms = append(terms, quoteLexeme(run.text))
+				continue
+			}
+			for i := 0; i+1 < len(runes); i++ {
+				terms = append(terms, quoteLexeme(string(runes[i:i+2])))
+			}
+			continue
+		}
+		terms = append(terms, quoteLexeme(run.text)+":*")
+	}
+	return strings.Join(uniqueStrings(terms), " & ")
+}
+
+// private functions
+
+type run struct {
+	text string
+	cjk  bool
+}
+
+type token struct {
+	text   string
+	offset int
+}
+
+// splitRuns は正規化済みテキストを日本語の連続部分と英数字の連続部分に分ける（記号・空白は区切り）
+func splitRuns(s string) []run {
+	var runs []run
+	var cur []rune
+	curCJK := false
+	flush := func() {
+		if len(cur) > 0 {
+			runs = append(runs, run{text: string(cur), cjk: curCJK})
+			cur = cur[:0]
+		}
+	}
+	for _, r := range s {
+		switch {
+		case isCJK(r):
+			if !curCJK {
+				flush()
+			}
+			curCJK = true
+			cur = append(cur, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if curCJK {
+				flush()
+			}
+			curCJK = false
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return runs
+}
+
+// documentTokens は索引用のトークンを返す（日本語はuni-gramとbi-gram、英数字は単語そのもの）
+func documentTokens(r run) []token {
+	if !r.cjk {
+		return []token{{text: r.text}}
+	}
+	runes := []rune(r.text)
+	tokens := make([]token, 0, len(runes)*2)
+	for i := range runes {
+		tokens = append(tokens, token{text: string(runes[i]), offset: i})
+		if i+1 < len(runes) {
+			tokens = append(tokens, token{text: string(runes[i : i+2]), offset: i})
+		}
+	}
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		r == 'ー' || r == '々'
+}
+
+// foldKana はカタカナをひらがなに揃える（ァ〜ヶ）
+func foldKana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - 0x60
+	}
+	return r
+}
+
+// quoteLexeme はtsvector/tsqueryのリテラル用にトークンをクォートする
+func quoteLexeme(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `''`)
+	return "'" + s + "'"
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"ＡＢＣ１２３":  "abc123",
+		"ｶﾀｶﾅ":    "かたかな",
+		"ヤマダ タロウ": "やまだ たろう",
+		"Hello":   "hello",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, Normalize(in), in)
+	}
+}
+
+func TestToKatakana(t *testing.T) {
+	assert.Equal(t, "ヤマダ太郎abc", ToKatakana(Normalize("やまだ太郎ABC")))
+	assert.Equal(t, "ヤマダ", ToKatakana(Normalize("ﾔﾏﾀﾞ")))
+}
+
+func TestBuildDocument(t *testing.T) {
+	doc := BuildDocument(
+		Field{Text: "山田", Weight: WeightA},
+		Field{Text: "Live 2026", Weight: WeightC},
+	)
+	assert.Equal(t, "'山':1A '山田':1A '田':2A 'live':3C '2026':4C", doc)
+
+	// 記号は区切りとして扱う
+	assert.Equal(t, "'it':1B 's':2B", BuildDocument(Field{Text: "it's", Weight: WeightB}))
+	assert.Equal(t, "", BuildDocument(Field{Text: "!!", Weight: WeightA}))
+}
+
+func TestBuildQuery(t *testing.T) {
+	cases := map[string]string{
+		"":             "",
+		"   ":          "",
+		"山":            "'山'",
+		"山田太郎":         "'山田' & '田太' & '太郎'",
+		"ヤマダ":          "'やま' & 'まだ'",
+		"Live":         "'live':*",
+		"山田 live":      "'山田' & 'live':*",
+		"ＬＩＶＥ　山田":      "'live':* & '山田'",
+		"o'neil":       "'o':* & 'neil':*",
+		`a\b`:          "'a':* & 'b':*",
+		"ライブ2026":      "'らい' & 'いぶ' & '2026':*",
+		"まだまだ":         "'まだ' & 'だま'",
+		"(山田) & | ! :": "'山田'",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, BuildQuery(in), in)
+	}
+}
+
+func TestBuildQuery_MatchesDocument(t *testing.T) {
+	// カタカナの検索語でひらがな・カタカナ表記の索引にヒットする
+	doc := BuildDocument(Field{Text: "やまだ たろう", Weight: WeightB})
+	for _, lexeme := range []string{"'やま'", "'まだ'"} {
+		assert.Contains(t, doc, lexeme)
+	}
+	assert.Equal(t, "'やま' & 'まだ'", BuildQuery("ヤマダ"))
+}
+
+func TestQuoteLexeme(t *testing.T) {
+	assert.Equal(t, `'it''s'`, quoteLexeme("it's"))
+	assert.Equal(t, `'a\\b'`, quoteLexeme(`a\b`))
+}
@@ -0,0 +1,343 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/lib/pq"
+)
+
+var ErrProgramTitleRequired = errors.New("title is required")
+var ErrProgramVideoPathRequired = errors.New("video_path is required")
+var ErrInvalidProgramPrice = errors.New("invalid price")
+var ErrInvalidProgramRental = errors.New("invalid rental_price or rental_duration_hours")
+var ErrInvalidProgramRelation = errors.New("unknown category tag or performer")
+var ErrProgramHasPurchases = errors.New("program has purchases")
+
+// AdminProgramInput は管理画面から番組を作成・更新する際の入力
+type AdminProgramInput struct {
+	Title            string
+	VideoPath        string
+	ThumbnailPath    *string
+	Description      *string
+	IsLimitedRelease bool
+	Price            int32
+	// レンタル価格と期間（時間）は両方指定するか両方省略する（省略時はレンタル不可）
+	RentalPrice         *int32
+	RentalDurationHours *int32
+	// nilの場合は公開状態を変更しない（作成時は非公開）
+	IsPublic       *bool
+	CategoryTagIDs []int64
+	PerformerIDs   []int64
+}
+
+type AdminProgram struct {
+	ProgramID        int64   `json:"program_id"`
+	Title            string  `json:"title"`
+	VideoPath        string  `json:"video_path"`
+	ThumbnailPath    *string `json:"thumbnail_path"`
+	ThumbnailUrl     *string `json:"thumbnail_url"`
+	Description      *string `json:"description"`
+	ViewCount        int64   `json:"view_count"`
+	IsLimitedRelease bool    `json:"is_limited_release"`
+	Price            int32   `json:"price"`
+	RentalPrice      *int32  `json:"rental_price"`
+	// レンタル期間（時間）
+	RentalDurationHours *int32                      `json:"rental_duration_hours"`
+	IsPublic            bool                        `json:"is_public"`
+	ProgramCreatedAt    time.Time                   `json:"program_created_at"`
+	ProgramUpdatedAt    time.Time                   `json:"program_updated_at"`
+	CategoryTags        []ProgramDetailsCategoryTag `json:"category_tags"`
+	Performers          []ProgramDetailsPerformer   `json:"performers"`
+}
+
+type AdminProgramsUsecase struct {
+	conn *sql.DB
+	q    *db.Queries
+}
+
+func NewAdminProgramsUsecase(conn *sql.DB, q *db.Queries) *AdminProgramsUsecase {
+	return &AdminProgramsUsecase{conn: conn, q: q}
+}
+
+func (u *AdminProgramsUsecase) GetProgram(ctx context.Context, programID int64) (AdminProgram, error) {
+	return u.getProgram(ctx, u.q, programID)
+}
+
+func (u *AdminProgramsUsecase) CreateProgram(ctx context.Context, in AdminProgramInput) (AdminProgram, error) {
+	in, err := validateAdminProgramInput(in)
+	if err != nil {
+		return AdminProgram{}, err
+	}
+
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return AdminProgram{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := u.q.WithTx(tx)
+
+	program, err := qtx.CreateProgram(ctx, db.CreateProgramParams{
+		Title:               in.Title,
+		VideoPath:           in.VideoPath,
+		ThumbnailPath:       nullStringFromPtr(in.ThumbnailPath),
+		Description:         nullStringFromPtr(in.Description),
+		IsLimitedRelease:    in.IsLimitedRelease,
+		Price:               in.Price,
+		RentalPrice:         nullInt32FromPtr(in.RentalPrice),
+		RentalDurationHours: nullInt32FromPtr(in.RentalDurationHours),
+	})
+	if err != nil {
+		return AdminProgram{}, err
+	}
+
+	// 作成直後は下書き（非公開）扱い。明示的に指定された場合のみ公開する
+	isPublic := in.IsPublic != nil && *in.IsPublic
+	if _, err := qtx.UpdateProgramVisibility(ctx, db.UpdateProgramVisibilityParams{ID: program.ID, IsPublic: isPublic}); err != nil {
+		return AdminProgram{}, err
+	}
+
+	if err := replaceProgramRelations(ctx, qtx, program.ID, in.CategoryTagIDs, in.PerformerIDs); err != nil {
+		return AdminProgram{}, err
+	}
+	if err := reindexPrograms(ctx, qtx, []int64{program.ID}); err != nil {
+		return AdminProgram{}, err
+	}
+
+	res, err := u.getProgram(ctx, qtx, program.ID)
+	if err != nil {
+		return AdminProgram{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return AdminProgram{}, err
+	}
+	return res, nil
+}
+
+func (u *AdminProgramsUsecase) UpdateProgram(ctx context.Context, programID int64, in AdminProgramInput) (AdminProgram, error) {
+	in, err := validateAdminProgramInput(in)
+	if err != nil {
+		return AdminProgram{}, err
+	}
+
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return AdminProgram{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := u.q.WithTx(tx)
+
+	_, err = qtx.UpdateProgram(ctx, db.UpdateProgramParams{
+		ID:                  programID,
+		Title:               in.Title,
+		VideoPath:           in.VideoPath,
+		ThumbnailPath:       nullStringFromPtr(in.ThumbnailPath),
+		Description:         nullStringFromPtr(in.Description),
+		IsLimitedRelease:    in.IsLimitedRelease,
+		Price:               in.Price,
+		RentalPrice:         nullInt32FromPtr(in.RentalPrice),
+		RentalDurationHours: nullInt32FromPtr(in.RentalDurationHours),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return AdminProgram{}, ErrProgramNotFound
+		}
+		return AdminProgram{}, err
+	}
+
+	if in.IsPublic != nil {
+		if _, err := qtx.UpdateProgramVisibility(ctx, db.UpdateProgramVisibilityParams{ID: programID, IsPublic: *in.IsPublic}); err != nil {
+			return AdminProgram{}, err
+		}
+	}
+
+	if err := replaceProgramRelations(ctx, qtx, programID, in.CategoryTagIDs, in.PerformerIDs); err != nil {
+		return AdminProgram{}, err
+	}
+	if err := reindexPrograms(ctx, qtx, []int64{programID}); err != nil {
+		return AdminProgram{}, err
+	}
+
+	res, err := u.getProgram(ctx, qtx, programID)
+	if err != nil {
+		return AdminProgram{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return AdminProgram{}, err
+	}
+	return res, nil
+}
+
+// SetProgramVisibility は番組の公開/非公開を切り替える
+func (u *AdminProgramsUsecase) SetProgramVisibility(ctx context.Context, programID int64, isPublic bool) (AdminProgram, error) {
+	_, err := u.q.UpdateProgramVisibility(ctx, db.UpdateProgramVisibilityParams{ID: programID, IsPublic: isPublic})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return AdminProgram{}, ErrProgramNotFound
+		}
+		return AdminProgram{}, err
+	}
+	return u.getProgram(ctx, u.q, programID)
+}
+
+func (u *AdminProgramsUsecase) DeleteProgram(ctx context.Context, programID int64) error {
+	affected, err := u.q.DeleteProgram(ctx, programID)
+	if err != nil {
+		// 購入履歴（payments）から参照されている番組は削除できない
+		if isForeignKeyViolation(err) {
+			return ErrProgramHasPurchases
+		}
+		return err
+	}
+	if affected == 0 {
+		return ErrProgramNotFound
+	}
+	return nil
+}
+
+// private functions
+
+func (u *AdminProgramsUsecase) getProgram(ctx context.Context, q *db.Queries, programID int64) (AdminProgram, error) {
+	program, err := q.GetAdminProgramByID(ctx, programID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return AdminProgram{}, ErrProgramNotFound
+		}
+		return AdminProgram{}, err
+	}
+
+	categoryTagsJSON, err := normalizeJSONBytes(program.CategoryTags)
+	if err != nil {
+		return AdminProgram{}, err
+	}
+	performersJSON, err := normalizeJSONBytes(program.Performers)
+	if err != nil {
+		return AdminProgram{}, err
+	}
+
+	categoryTags := []ProgramDetailsCategoryTag{}
+	if err := json.Unmarshal(categoryTagsJSON, &categoryTags); err != nil {
+		return AdminProgram{}, err
+	}
+	var performersRaw []programDetailsPerformerRaw
+	if err := json.Unmarshal(performersJSON, &performersRaw); err != nil {
+		return AdminProgram{}, err
+	}
+
+	performers := make([]ProgramDetailsPerformer, 0, len(performersRaw))
+	for _, p := range performersRaw {
+		performers = append(performers, ProgramDetailsPerformer{
+			ID:           p.ID,
+			FullName:     p.LastName + p.FirstName,
+			FullNameKana: p.LastNameKana + p.FirstNameKana,
+			ImageUrl:     buildPublicFileURLPtr(p.ImagePath),
+		})
+	}
+
+	thumbnailPath := nullStringPtr(program.ThumbnailPath)
+	return AdminProgram{
+		ProgramID:           program.ProgramID,
+		Title:               program.Title,
+		VideoPath:           program.VideoPath,
+		ThumbnailPath:       thumbnailPath,
+		ThumbnailUrl:        buildPublicFileURLPtr(thumbnailPath),
+		Description:         nullStringPtr(program.Description),
+		ViewCount:           int64(program.ViewCount),
+		IsLimitedRelease:    program.IsLimitedRelease,
+		Price:               program.Price,
+		RentalPrice:         nullInt32Ptr(program.RentalPrice),
+		RentalDurationHours: nullInt32Ptr(program.RentalDurationHours),
+		IsPublic:            program.IsPublic,
+		ProgramCreatedAt:    program.ProgramCreatedAt,
+		ProgramUpdatedAt:    program.ProgramUpdatedAt,
+		CategoryTags:        categoryTags,
+		Performers:          performers,
+	}, nil
+}
+
+// replaceProgramRelations はタグ・出演者の紐づけを指定されたIDで置き換える
+func replaceProgramRelations(ctx context.Context, qtx *db.Queries, programID int64, tagIDs, performerIDs []int64) error {
+	if err := qtx.DeleteProgramCategoryTagsByProgramID(ctx, programID); err != nil {
+		return err
+	}
+	for _, tagID := range uniqueInt64s(tagIDs) {
+		err := qtx.CreateProgramCategoryTag(ctx, db.CreateProgramCategoryTagParams{ProgramID: programID, TagID: tagID})
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidProgramRelation
+			}
+			return err
+		}
+	}
+
+	if err := qtx.DeleteProgramPerformersByProgramID(ctx, programID); err != nil {
+		return err
+	}
+	for _, performerID := range uniqueInt64s(performerIDs) {
+		err := qtx.CreateProgramPerformer(ctx, db.CreateProgramPerformerParams{ProgramID: programID, PerformerID: performerID})
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidProgramRelation
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func validateAdminProgramInput(in AdminProgramInput) (AdminProgramInput, error) {
+	in.Title = strings.TrimSpace(in.Title)
+	in.VideoPath = strings.TrimSpace(in.VideoPath)
+
+	if in.Title == "" {
+		return in, ErrProgramTitleRequired
+	}
+	if in.VideoPath == "" {
+		return in, ErrProgramVideoPathRequired
+	}
+	if in.Price < 0 {
+		return in, ErrInvalidProgramPrice
+	}
+	// 限定公開は有料でないと購入できない
+	if in.IsLimitedRelease && in.Price <= 0 {
+		return in, ErrInvalidProgramPrice
+	}
+	// レンタルは限定公開の番組のみ
+	if (in.RentalPrice == nil) != (in.RentalDurationHours == nil) {
+		return in, ErrInvalidProgramRental
+	}
+	if in.RentalPrice != nil && (!in.IsLimitedRelease || *in.RentalPrice <= 0 || *in.RentalDurationHours <= 0) {
+		return in, ErrInvalidProgramRental
+	}
+	return in, nil
+}
+
+func nullStringFromPtr(s *string) sql.NullString {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func uniqueInt64s(ids []int64) []int64 {
+	seen := make(map[int64]struct{}, len(ids))
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
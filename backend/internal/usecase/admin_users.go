@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/authz"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+var ErrInvalidRole = errors.New("invalid role")
+
+type AdminUsersUsecase struct {
+	conn *sql.DB
+	q    *db.Queries
+}
+
+func NewAdminUsersUsecase(conn *sql.DB, q *db.Queries) *AdminUsersUsecase {
+	return &AdminUsersUsecase{conn: conn, q: q}
+}
+
+func (u *AdminUsersUsecase) ListUserRoles(ctx context.Context, userID string) ([]string, error) {
+	roles, err := u.q.ListUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	return roles, nil
+}
+
+// SetUserRoles はユーザーのロールを指定されたもので置き換える
+func (u *AdminUsersUsecase) SetUserRoles(ctx context.Context, userID string, roles []string) ([]string, error) {
+	for _, role := range roles {
+		if !authz.IsValidRole(role) {
+			return nil, ErrInvalidRole
+		}
+	}
+
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := u.q.WithTx(tx)
+
+	if err := qtx.DeleteUserRoles(ctx, userID); err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if err := qtx.AddUserRole(ctx, db.AddUserRoleParams{UserID: userID, Role: role}); err != nil {
+			if isForeignKeyViolation(err) {
+				return nil, ErrUserNotFound
+			}
+			return nil, err
+		}
+	}
+
+	res, err := qtx.ListUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if res == nil {
+		res = []string{}
+	}
+	return res, nil
+}
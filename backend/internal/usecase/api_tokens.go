@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/authz"
+)
+
+var ErrAPITokenNameRequired = errors.New("name is required")
+var ErrInvalidAPITokenScope = errors.New("invalid scope")
+var ErrInvalidAPITokenExpiry = errors.New("invalid expires_in_days")
+var ErrAPITokenNotFound = errors.New("api token not found")
+
+// トークン名の最大長・有効期限の上限（日）
+const maxAPITokenNameLength = 100
+const maxAPITokenExpiresInDays = 365
+
+// CreateAPITokenInput はトークン発行時の入力
+type CreateAPITokenInput struct {
+	Name   string
+	Scopes []string
+	// nilの場合は無期限
+	ExpiresInDays *int
+}
+
+type APIToken struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreatedAPIToken は発行直後のトークン（平文はこのレスポンスでのみ返す）
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+type APITokensUsecase struct {
+	q *db.Queries
+}
+
+func NewAPITokensUsecase(q *db.Queries) *APITokensUsecase {
+	return &APITokensUsecase{q: q}
+}
+
+func (u *APITokensUsecase) CreateToken(ctx context.Context, userID string, in CreateAPITokenInput) (CreatedAPIToken, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len([]rune(name)) > maxAPITokenNameLength {
+		return CreatedAPIToken{}, ErrAPITokenNameRequired
+	}
+
+	scopes := make([]string, 0, len(in.Scopes))
+	for _, scope := range in.Scopes {
+		if !authz.IsValidScope(scope) {
+			return CreatedAPIToken{}, ErrInvalidAPITokenScope
+		}
+		if !containsScope(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return CreatedAPIToken{}, ErrInvalidAPITokenScope
+	}
+	// adminスコープは管理系ロール（uploader/moderator/admin）を持つユーザーのみ発行できる
+	if containsScope(scopes, authz.ScopeAdmin) {
+		roles, err := u.q.ListUserRoles(ctx, userID)
+		if err != nil {
+			return CreatedAPIToken{}, err
+		}
+		if !containsScope(roles, authz.RoleUploader) &&
+			!containsScope(roles, authz.RoleModerator) &&
+			!containsScope(roles, authz.RoleAdmin) {
+			return CreatedAPIToken{}, ErrInvalidAPITokenScope
+		}
+	}
+
+	var expiresAt sql.NullTime
+	if in.ExpiresInDays != nil {
+		days := *in.ExpiresInDays
+		if days <= 0 || days > maxAPITokenExpiresInDays {
+			return CreatedAPIToken{}, ErrInvalidAPITokenExpiry
+		}
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, days), Valid: true}
+	}
+
+	token, hash, prefix, err := authz.GenerateAPIToken()
+	if err != nil {
+		return CreatedAPIToken{}, err
+	}
+
+	row, err := u.q.CreateAPIToken(ctx, db.CreateAPITokenParams{
+		UserID:      userID,
+		Name:        name,
+		TokenHash:   hash,
+		TokenPrefix: prefix,
+		Scopes:      scopes,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		return CreatedAPIToken{}, err
+	}
+	return CreatedAPIToken{APIToken: toAPIToken(row), Token: token}, nil
+}
+
+func (u *APITokensUsecase) ListTokens(ctx context.Context, userID string) ([]APIToken, error) {
+	rows, err := u.q.ListAPITokensByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]APIToken, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, toAPIToken(row))
+	}
+	return res, nil
+}
+
+// RevokeToken は自分のトークンを失効させる（他人のトークン・失効済みはErrAPITokenNotFound）
+func (u *APITokensUsecase) RevokeToken(ctx context.Context, userID string, tokenID int64) error {
+	affected, err := u.q.RevokeAPIToken(ctx, db.RevokeAPITokenParams{ID: tokenID, UserID: userID})
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// private functions
+
+func toAPIToken(row db.ApiToken) APIToken {
+	scopes := row.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return APIToken{
+		ID:          row.ID,
+		Name:        row.Name,
+		TokenPrefix: row.TokenPrefix,
+		Scopes:      scopes,
+		LastUsedAt:  nullTimePtr(row.LastUsedAt),
+		ExpiresAt:   nullTimePtr(row.ExpiresAt),
+		RevokedAt:   nullTimePtr(row.RevokedAt),
+		CreatedAt:   row.CreatedAt,
+	}
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
+
+func containsScope(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -3,10 +3,13 @@ package usecase
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/chan-shizu/SZer/db"
 )
 
+var ErrCommentNotFound = errors.New("comment not found")
+
 type CommentsUsecase struct {
 	db *db.Queries
 }
@@ -34,6 +37,18 @@ func (u *CommentsUsecase) PostComment(ctx context.Context, programID int64, user
 	})
 }
 
+// モデレーション用: コメントを削除する
+func (u *CommentsUsecase) DeleteComment(ctx context.Context, commentID int64) error {
+	affected, err := u.db.DeleteComment(ctx, commentID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
 func sqlNullString(s string) sql.NullString {
 	if s == "" {
 		return sql.NullString{Valid: false}
@@ -6,6 +6,34 @@ import (
 	"github.com/chan-shizu/SZer/db"
 )
 
-func (u *CommentsUsecase) ListCommentsByProgramID(ctx context.Context, programID int64) ([]db.ListCommentsByProgramIDRow, error) {
-	return u.db.ListCommentsByProgramID(ctx, programID)
+// CommentListPage はカーソルページングされたコメント一覧（新しい順）
+type CommentListPage struct {
+	Comments   []db.ListCommentsByProgramIDRow `json:"comments"`
+	NextCursor *string                         `json:"next_cursor"`
+}
+
+func (u *CommentsUsecase) ListCommentsByProgramID(ctx context.Context, programID int64, page PageRequest) (CommentListPage, error) {
+	cursorAt, cursorID, limit, err := page.cursorParams()
+	if err != nil {
+		return CommentListPage{}, err
+	}
+	rows, err := u.db.ListCommentsByProgramID(ctx, db.ListCommentsByProgramIDParams{
+		ProgramID:       programID,
+		CursorCreatedAt: cursorAt,
+		CursorID:        cursorID,
+		PageLimit:       limit,
+	})
+	if err != nil {
+		return CommentListPage{}, err
+	}
+	next := nextCursor(len(rows), page, func(i int) pageCursor {
+		return pageCursor{At: rows[i].CreatedAt, ID: rows[i].ID}
+	})
+	if next != nil {
+		rows = rows[:page.normalizedLimit()]
+	}
+	if rows == nil {
+		rows = []db.ListCommentsByProgramIDRow{}
+	}
+	return CommentListPage{Comments: rows, NextCursor: next}, nil
 }
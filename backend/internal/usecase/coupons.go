@@ -0,0 +1,435 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+// 割引の種類
+const (
+	// 価格のdiscount_value%を割り引く（1円未満は切り捨て）
+	CouponDiscountPercent = "percent"
+	// 価格からdiscount_value円を割り引く（価格を超える分は割り引かない）
+	CouponDiscountFixed = "fixed"
+)
+
+var ErrCouponNotFound = errors.New("coupon not found")
+var ErrCouponNotActive = errors.New("coupon is not active")
+var ErrCouponNotApplicable = errors.New("coupon is not applicable")
+var ErrCouponLimitReached = errors.New("coupon redemption limit reached")
+var ErrInvalidCoupon = errors.New("invalid coupon")
+var ErrCouponCodeTaken = errors.New("coupon code already exists")
+
+// 割引コードの最大長
+const maxCouponCodeLength = 32
+
+type CouponInput struct {
+	// 更新時は省略可（コードと割引内容は変更できない）
+	Code          string
+	DiscountType  string
+	DiscountValue int32
+	StartsAt      *time.Time
+	EndsAt        *time.Time
+	// nilなら無制限
+	MaxRedemptions        *int32
+	MaxRedemptionsPerUser *int32
+	// nilの場合は作成時は有効、更新時は変更しない
+	IsActive *bool
+	// どちらも空なら全ての番組・商品に使える
+	ProgramIDs []int64
+	TagIDs     []int64
+}
+
+// CouponUpdateInput は割引コードの更新内容。Has*がfalseの項目は変更しない
+// （trueで値がnil・空の場合は期間・上限・対象を外す）
+type CouponUpdateInput struct {
+	CouponInput
+	HasStartsAt              bool
+	HasEndsAt                bool
+	HasMaxRedemptions        bool
+	HasMaxRedemptionsPerUser bool
+	HasProgramIDs            bool
+	HasTagIDs                bool
+}
+
+type Coupon struct {
+	ID                    int64      `json:"id"`
+	Code                  string     `json:"code"`
+	DiscountType          string     `json:"discount_type"`
+	DiscountValue         int32      `json:"discount_value"`
+	StartsAt              *time.Time `json:"starts_at"`
+	EndsAt                *time.Time `json:"ends_at"`
+	MaxRedemptions        *int32     `json:"max_redemptions"`
+	MaxRedemptionsPerUser *int32     `json:"max_redemptions_per_user"`
+	IsActive              bool       `json:"is_active"`
+	ProgramIDs            []int64    `json:"program_ids"`
+	TagIDs                []int64    `json:"tag_ids"`
+	RedemptionCount       int32      `json:"redemption_count"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+type CouponsUsecase struct {
+	conn *sql.DB
+	q    *db.Queries
+}
+
+func NewCouponsUsecase(conn *sql.DB, q *db.Queries) *CouponsUsecase {
+	return &CouponsUsecase{conn: conn, q: q}
+}
+
+// ListCoupons は無効なものも含めて全ての割引コードを返す
+func (u *CouponsUsecase) ListCoupons(ctx context.Context) ([]Coupon, error) {
+	rows, err := u.q.ListCoupons(ctx)
+	if err != nil {
+		return nil, err
+	}
+	coupons := make([]Coupon, 0, len(rows))
+	for _, row := range rows {
+		coupon, err := loadCoupon(ctx, u.q, row)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, coupon)
+	}
+	return coupons, nil
+}
+
+func (u *CouponsUsecase) CreateCoupon(ctx context.Context, in CouponInput) (Coupon, error) {
+	in, err := validateCouponInput(in)
+	if err != nil {
+		return Coupon{}, err
+	}
+	isActive := in.IsActive == nil || *in.IsActive
+
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return Coupon{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := u.q.WithTx(tx)
+
+	row, err := qtx.CreateCoupon(ctx, db.CreateCouponParams{
+		Code:                  in.Code,
+		DiscountType:          in.DiscountType,
+		DiscountValue:         in.DiscountValue,
+		StartsAt:              nullTimeFromPtr(in.StartsAt),
+		EndsAt:                nullTimeFromPtr(in.EndsAt),
+		MaxRedemptions:        nullInt32FromPtr(in.MaxRedemptions),
+		MaxRedemptionsPerUser: nullInt32FromPtr(in.MaxRedemptionsPerUser),
+		IsActive:              isActive,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return Coupon{}, ErrCouponCodeTaken
+		}
+		return Coupon{}, err
+	}
+	if err := replaceCouponPrograms(ctx, qtx, row.ID, in.ProgramIDs); err != nil {
+		return Coupon{}, err
+	}
+	if err := replaceCouponTags(ctx, qtx, row.ID, in.TagIDs); err != nil {
+		return Coupon{}, err
+	}
+
+	coupon, err := loadCoupon(ctx, qtx, row)
+	if err != nil {
+		return Coupon{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Coupon{}, err
+	}
+	return coupon, nil
+}
+
+// UpdateCoupon は指定された項目だけを更新する（省略した期間・上限・対象は変更しない）
+func (u *CouponsUsecase) UpdateCoupon(ctx context.Context, couponID int64, in CouponUpdateInput) (Coupon, error) {
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return Coupon{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := u.q.WithTx(tx)
+
+	current, err := qtx.GetCoupon(ctx, couponID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Coupon{}, ErrCouponNotFound
+		}
+		return Coupon{}, err
+	}
+	// 利用済みの決済の割引額と食い違わないよう、コードと割引内容は変更できない
+	if (in.Code != "" && normalizeCouponCode(in.Code) != current.Code) ||
+		(in.DiscountType != "" && in.DiscountType != current.DiscountType) ||
+		(in.DiscountValue != 0 && in.DiscountValue != current.DiscountValue) {
+		return Coupon{}, ErrInvalidCoupon
+	}
+	merged, err := validateCouponInput(mergeCouponUpdate(current, in))
+	if err != nil {
+		return Coupon{}, err
+	}
+
+	row, err := qtx.UpdateCoupon(ctx, db.UpdateCouponParams{
+		ID:                    couponID,
+		StartsAt:              nullTimeFromPtr(merged.StartsAt),
+		EndsAt:                nullTimeFromPtr(merged.EndsAt),
+		MaxRedemptions:        nullInt32FromPtr(merged.MaxRedemptions),
+		MaxRedemptionsPerUser: nullInt32FromPtr(merged.MaxRedemptionsPerUser),
+		IsActive:              *merged.IsActive,
+	})
+	if err != nil {
+		return Coupon{}, err
+	}
+	if in.HasProgramIDs {
+		if err := replaceCouponPrograms(ctx, qtx, couponID, in.ProgramIDs); err != nil {
+			return Coupon{}, err
+		}
+	}
+	if in.HasTagIDs {
+		if err := replaceCouponTags(ctx, qtx, couponID, in.TagIDs); err != nil {
+			return Coupon{}, err
+		}
+	}
+
+	coupon, err := loadCoupon(ctx, qtx, row)
+	if err != nil {
+		return Coupon{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Coupon{}, err
+	}
+	return coupon, nil
+}
+
+// private functions
+
+func loadCoupon(ctx context.Context, q *db.Queries, row db.Coupon) (Coupon, error) {
+	coupon := Coupon{
+		ID:            row.ID,
+		Code:          row.Code,
+		DiscountType:  row.DiscountType,
+		DiscountValue: row.DiscountValue,
+		StartsAt:      nullTimePtr(row.StartsAt),
+		EndsAt:        nullTimePtr(row.EndsAt),
+		IsActive:      row.IsActive,
+		CreatedAt:     row.CreatedAt,
+		UpdatedAt:     row.UpdatedAt,
+	}
+	if row.MaxRedemptions.Valid {
+		coupon.MaxRedemptions = &row.MaxRedemptions.Int32
+	}
+	if row.MaxRedemptionsPerUser.Valid {
+		coupon.MaxRedemptionsPerUser = &row.MaxRedemptionsPerUser.Int32
+	}
+
+	programIDs, err := q.ListCouponProgramIDs(ctx, row.ID)
+	if err != nil {
+		return Coupon{}, err
+	}
+	tagIDs, err := q.ListCouponTagIDs(ctx, row.ID)
+	if err != nil {
+		return Coupon{}, err
+	}
+	counts, err := q.CountCouponRedemptions(ctx, couponRedemptionsParams(row.ID, "", time.Now()))
+	if err != nil {
+		return Coupon{}, err
+	}
+	coupon.ProgramIDs = append([]int64{}, programIDs...)
+	coupon.TagIDs = append([]int64{}, tagIDs...)
+	coupon.RedemptionCount = counts.Total
+	return coupon, nil
+}
+
+// mergeCouponUpdate は現在の割引コードに更新内容を重ねる（対象の番組・タグは含めない）
+func mergeCouponUpdate(current db.Coupon, in CouponUpdateInput) CouponInput {
+	merged := CouponInput{
+		Code:                  current.Code,
+		DiscountType:          current.DiscountType,
+		DiscountValue:         current.DiscountValue,
+		StartsAt:              nullTimePtr(current.StartsAt),
+		EndsAt:                nullTimePtr(current.EndsAt),
+		MaxRedemptions:        nullInt32Ptr(current.MaxRedemptions),
+		MaxRedemptionsPerUser: nullInt32Ptr(current.MaxRedemptionsPerUser),
+		IsActive:              &current.IsActive,
+	}
+	if in.HasStartsAt {
+		merged.StartsAt = in.StartsAt
+	}
+	if in.HasEndsAt {
+		merged.EndsAt = in.EndsAt
+	}
+	if in.HasMaxRedemptions {
+		merged.MaxRedemptions = in.MaxRedemptions
+	}
+	if in.HasMaxRedemptionsPerUser {
+		merged.MaxRedemptionsPerUser = in.MaxRedemptionsPerUser
+	}
+	if in.IsActive != nil {
+		merged.IsActive = in.IsActive
+	}
+	return merged
+}
+
+// replaceCouponPrograms は割引の対象の番組を指定されたIDで置き換える
+func replaceCouponPrograms(ctx context.Context, qtx *db.Queries, couponID int64, programIDs []int64) error {
+	if err := qtx.DeleteCouponPrograms(ctx, couponID); err != nil {
+		return err
+	}
+	for _, programID := range uniqueInt64s(programIDs) {
+		err := qtx.CreateCouponProgram(ctx, db.CreateCouponProgramParams{CouponID: couponID, ProgramID: programID})
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrProgramNotFound
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceCouponTags は割引の対象のタグを指定されたIDで置き換える
+func replaceCouponTags(ctx context.Context, qtx *db.Queries, couponID int64, tagIDs []int64) error {
+	if err := qtx.DeleteCouponTags(ctx, couponID); err != nil {
+		return err
+	}
+	for _, tagID := range uniqueInt64s(tagIDs) {
+		err := qtx.CreateCouponTag(ctx, db.CreateCouponTagParams{CouponID: couponID, TagID: tagID})
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrCategoryTagNotFound
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCouponInput はコードを正規化し、割引内容・有効期間・上限を検証する
+func validateCouponInput(in CouponInput) (CouponInput, error) {
+	in.Code = normalizeCouponCode(in.Code)
+	if in.Code == "" || len(in.Code) > maxCouponCodeLength {
+		return in, ErrInvalidCoupon
+	}
+	for _, r := range in.Code {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return in, ErrInvalidCoupon
+		}
+	}
+	switch in.DiscountType {
+	case CouponDiscountPercent:
+		if in.DiscountValue <= 0 || in.DiscountValue > 100 {
+			return in, ErrInvalidCoupon
+		}
+	case CouponDiscountFixed:
+		if in.DiscountValue <= 0 {
+			return in, ErrInvalidCoupon
+		}
+	default:
+		return in, ErrInvalidCoupon
+	}
+	if in.StartsAt != nil && in.EndsAt != nil && !in.StartsAt.Before(*in.EndsAt) {
+		return in, ErrInvalidCoupon
+	}
+	if (in.MaxRedemptions != nil && *in.MaxRedemptions <= 0) ||
+		(in.MaxRedemptionsPerUser != nil && *in.MaxRedemptionsPerUser <= 0) {
+		return in, ErrInvalidCoupon
+	}
+	return in, nil
+}
+
+// normalizeCouponCode は入力されたコードを保存時の形式（前後の空白を除いた大文字）にする
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// checkCouponUsable は割引コードが有効で、期間内かを返す
+func checkCouponUsable(c db.Coupon, now time.Time) error {
+	if !c.IsActive {
+		return ErrCouponNotFound
+	}
+	if (c.StartsAt.Valid && now.Before(c.StartsAt.Time)) || (c.EndsAt.Valid && !now.Before(c.EndsAt.Time)) {
+		return ErrCouponNotActive
+	}
+	return nil
+}
+
+// couponDiscount は価格に対する割引額を返す（価格を超えない）
+func couponDiscount(c db.Coupon, amount int32) int32 {
+	var discount int32
+	switch c.DiscountType {
+	case CouponDiscountPercent:
+		discount = int32(int64(amount) * int64(c.DiscountValue) / 100)
+	case CouponDiscountFixed:
+		discount = c.DiscountValue
+	}
+	if discount > amount {
+		return amount
+	}
+	if discount < 0 {
+		return 0
+	}
+	return discount
+}
+
+// applyCoupon は決済を作るトランザクション内で割引コードをロックし、
+// 期間・対象・利用回数を確認したうえで割引をargに反映する
+func applyCoupon(ctx context.Context, qtx *db.Queries, arg *db.CreatePaymentParams, code string, now time.Time) error {
+	coupon, err := qtx.GetCouponByCodeForUpdate(ctx, normalizeCouponCode(code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCouponNotFound
+		}
+		return err
+	}
+	if err := checkCouponUsable(coupon, now); err != nil {
+		return err
+	}
+
+	applicable, err := qtx.IsCouponApplicable(ctx, db.IsCouponApplicableParams{
+		CouponID:  coupon.ID,
+		ProgramID: arg.ProgramID,
+	})
+	if err != nil {
+		return err
+	}
+	if !applicable {
+		return ErrCouponNotApplicable
+	}
+
+	counts, err := qtx.CountCouponRedemptions(ctx, couponRedemptionsParams(coupon.ID, arg.UserID, now))
+	if err != nil {
+		return err
+	}
+	if (coupon.MaxRedemptions.Valid && counts.Total >= coupon.MaxRedemptions.Int32) ||
+		(coupon.MaxRedemptionsPerUser.Valid && counts.ByUser >= coupon.MaxRedemptionsPerUser.Int32) {
+		return ErrCouponLimitReached
+	}
+
+	discount := couponDiscount(coupon, arg.AmountYen)
+	arg.CouponID = sql.NullInt64{Int64: coupon.ID, Valid: true}
+	arg.DiscountYen = discount
+	arg.AmountYen -= discount
+	return nil
+}
+
+// couponRedemptionsParams は利用回数の集計条件を返す（有効期限の分からない支払い待ちは照合と同じ猶予で期限切れとみなす）
+func couponRedemptionsParams(couponID int64, userID string, now time.Time) db.CountCouponRedemptionsParams {
+	return db.CountCouponRedemptionsParams{
+		UserID:              userID,
+		CouponID:            couponID,
+		Now:                 now,
+		PendingCreatedAfter: now.Add(-checkoutExpiryFallback),
+	}
+}
+
+func nullTimeFromPtr(v *time.Time) sql.NullTime {
+	if v == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *v, Valid: true}
+}
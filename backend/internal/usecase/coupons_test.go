@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+func TestCouponDiscount(t *testing.T) {
+	tests := []struct {
+		name   string
+		coupon db.Coupon
+		amount int32
+		want   int32
+	}{
+		{"percent", db.Coupon{DiscountType: CouponDiscountPercent, DiscountValue: 30}, 1000, 300},
+		{"percent rounds down", db.Coupon{DiscountType: CouponDiscountPercent, DiscountValue: 15}, 999, 149},
+		{"percent full", db.Coupon{DiscountType: CouponDiscountPercent, DiscountValue: 100}, 980, 980},
+		{"fixed", db.Coupon{DiscountType: CouponDiscountFixed, DiscountValue: 200}, 1000, 200},
+		{"fixed over price", db.Coupon{DiscountType: CouponDiscountFixed, DiscountValue: 2000}, 1000, 1000},
+		{"unknown type", db.Coupon{DiscountType: "bogo", DiscountValue: 100}, 1000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := couponDiscount(tt.coupon, tt.amount); got != tt.want {
+				t.Errorf("couponDiscount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCouponUsable(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) sql.NullTime { return sql.NullTime{Time: now.Add(d), Valid: true} }
+
+	tests := []struct {
+		name   string
+		coupon db.Coupon
+		want   error
+	}{
+		{"no period", db.Coupon{IsActive: true}, nil},
+		{"within period", db.Coupon{IsActive: true, StartsAt: at(-time.Hour), EndsAt: at(time.Hour)}, nil},
+		{"not started", db.Coupon{IsActive: true, StartsAt: at(time.Hour)}, ErrCouponNotActive},
+		{"ended", db.Coupon{IsActive: true, EndsAt: at(0)}, ErrCouponNotActive},
+		{"inactive", db.Coupon{IsActive: false}, ErrCouponNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkCouponUsable(tt.coupon, now); !errors.Is(err, tt.want) {
+				t.Errorf("checkCouponUsable() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCouponInput(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	zero := int32(0)
+
+	tests := []struct {
+		name string
+		in   CouponInput
+		ok   bool
+	}{
+		{"percent", CouponInput{Code: " spring-10 ", DiscountType: CouponDiscountPercent, DiscountValue: 10}, true},
+		{"percent over 100", CouponInput{Code: "OVER", DiscountType: CouponDiscountPercent, DiscountValue: 101}, false},
+		{"fixed", CouponInput{Code: "YEN500", DiscountType: CouponDiscountFixed, DiscountValue: 500, StartsAt: &start, EndsAt: &end}, true},
+		{"zero value", CouponInput{Code: "ZERO", DiscountType: CouponDiscountFixed}, false},
+		{"reversed period", CouponInput{Code: "REV", DiscountType: CouponDiscountFixed, DiscountValue: 500, StartsAt: &end, EndsAt: &start}, false},
+		{"zero limit", CouponInput{Code: "LIMIT", DiscountType: CouponDiscountFixed, DiscountValue: 500, MaxRedemptions: &zero}, false},
+		{"empty code", CouponInput{Code: "  ", DiscountType: CouponDiscountFixed, DiscountValue: 500}, false},
+		{"invalid characters", CouponInput{Code: "割引", DiscountType: CouponDiscountFixed, DiscountValue: 500}, false},
+		{"unknown type", CouponInput{Code: "BOGO", DiscountType: "bogo", DiscountValue: 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateCouponInput(tt.in)
+			if tt.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidCoupon) {
+				t.Errorf("expected ErrInvalidCoupon, got %v", err)
+			}
+		})
+	}
+
+	in, _ := validateCouponInput(tests[0].in)
+	if in.Code != "SPRING-10" {
+		t.Errorf("expected normalized code, got %q", in.Code)
+	}
+}
+
+func TestMergeCouponUpdate(t *testing.T) {
+	end := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	current := db.Coupon{
+		Code:           "SCOPED",
+		DiscountType:   CouponDiscountFixed,
+		DiscountValue:  100,
+		EndsAt:         sql.NullTime{Time: end, Valid: true},
+		MaxRedemptions: sql.NullInt32{Int32: 5, Valid: true},
+		IsActive:       false,
+	}
+
+	active := true
+	merged := mergeCouponUpdate(current, CouponUpdateInput{CouponInput: CouponInput{IsActive: &active}})
+	if merged.EndsAt == nil || !merged.EndsAt.Equal(end) {
+		t.Errorf("ends_at should be kept, got %v", merged.EndsAt)
+	}
+	if merged.MaxRedemptions == nil || *merged.MaxRedemptions != 5 {
+		t.Errorf("max_redemptions should be kept, got %v", merged.MaxRedemptions)
+	}
+	if merged.IsActive == nil || !*merged.IsActive {
+		t.Errorf("is_active should be updated")
+	}
+
+	merged = mergeCouponUpdate(current, CouponUpdateInput{HasEndsAt: true, HasMaxRedemptions: true})
+	if merged.EndsAt != nil || merged.MaxRedemptions != nil {
+		t.Errorf("explicit null should clear, got ends_at=%v max_redemptions=%v", merged.EndsAt, merged.MaxRedemptions)
+	}
+	if merged.IsActive == nil || *merged.IsActive {
+		t.Errorf("is_active should be kept")
+	}
+}
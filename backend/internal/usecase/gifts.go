@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/payments"
+)
+
+// 贈り物のステータス
+const (
+	GiftRedeemable = "REDEEMABLE" // 支払い完了済みで受け取り待ち
+	GiftRedeemed   = "REDEEMED"
+	GiftRevoked    = "REVOKED" // 返金により無効
+)
+
+var ErrGiftNotFound = errors.New("gift not found")
+var ErrGiftAlreadyRedeemed = errors.New("gift already redeemed")
+var ErrGiftRevoked = errors.New("gift revoked")
+var ErrOwnGift = errors.New("cannot redeem own gift")
+
+type SentGift struct {
+	ID           int64      `json:"id"`
+	Code         string     `json:"code"`
+	ProgramID    int64      `json:"program_id"`
+	ProgramTitle string     `json:"program_title"`
+	Status       string     `json:"status"`
+	RedeemedAt   *time.Time `json:"redeemed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type ReceivedGift struct {
+	ID           int64      `json:"id"`
+	ProgramID    int64      `json:"program_id"`
+	ProgramTitle string     `json:"program_title"`
+	Status       string     `json:"status"`
+	RedeemedAt   *time.Time `json:"redeemed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Gifts は自分が贈った贈り物と受け取った贈り物
+type Gifts struct {
+	Sent     []SentGift     `json:"sent"`
+	Received []ReceivedGift `json:"received"`
+}
+
+type GiftsUsecase struct {
+	conn *sql.DB
+	q    *db.Queries
+}
+
+func NewGiftsUsecase(conn *sql.DB, q *db.Queries) *GiftsUsecase {
+	return &GiftsUsecase{conn: conn, q: q}
+}
+
+// CheckoutGift は番組を他のユーザーに贈るための決済を作成する。
+// 購入者には閲覧権限を付与せず、支払いが完了すると受け取り用のコードを発行する（買い切りのみ）
+func (p *PaymentsUsecase) CheckoutGift(ctx context.Context, userID string, programID int64, providerName, couponCode, redirectBaseURL string) (PaymentCheckoutResult, error) {
+	program, err := p.q.GetProgramForPurchase(ctx, programID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentCheckoutResult{}, ErrProgramNotFound
+		}
+		return PaymentCheckoutResult{}, err
+	}
+	if !program.IsLimitedRelease || program.Price <= 0 {
+		return PaymentCheckoutResult{}, ErrNotPurchasable
+	}
+
+	merchantPaymentID, err := payments.RandomMerchantPaymentID()
+	if err != nil {
+		return PaymentCheckoutResult{}, err
+	}
+
+	return p.checkout(ctx, providerName, couponCode, db.CreatePaymentParams{
+		UserID:            userID,
+		MerchantPaymentID: merchantPaymentID,
+		AmountYen:         program.Price,
+		ProgramID:         sql.NullInt64{Int64: programID, Valid: true},
+		PurchaseType:      PurchaseTypePurchase,
+		IsGift:            true,
+	}, "SZer program gift", fmt.Sprintf("%s/programs/%d/paypay/return?merchantPaymentId=%s", redirectBaseURL, programID, merchantPaymentID))
+}
+
+// giftCode は支払いが完了した贈り物の受け取り用のコードを返す（贈り物でなければ空）
+func (p *PaymentsUsecase) giftCode(ctx context.Context, payment db.Payment) (string, error) {
+	if !payment.IsGift || payment.Status != PaymentCompleted {
+		return "", nil
+	}
+	gift, err := p.q.GetGiftByPaymentID(ctx, payment.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return gift.Code, nil
+}
+
+// ListMyGifts はユーザーが贈った贈り物（受け取り用のコードを含む）と受け取った贈り物を返す
+func (u *GiftsUsecase) ListMyGifts(ctx context.Context, userID string) (Gifts, error) {
+	sentRows, err := u.q.ListSentGifts(ctx, userID)
+	if err != nil {
+		return Gifts{}, err
+	}
+	receivedRows, err := u.q.ListReceivedGifts(ctx, sql.NullString{String: userID, Valid: true})
+	if err != nil {
+		return Gifts{}, err
+	}
+
+	gifts := Gifts{
+		Sent:     make([]SentGift, 0, len(sentRows)),
+		Received: make([]ReceivedGift, 0, len(receivedRows)),
+	}
+	for _, row := range sentRows {
+		gifts.Sent = append(gifts.Sent, SentGift{
+			ID:           row.ID,
+			Code:         row.Code,
+			ProgramID:    row.ProgramID,
+			ProgramTitle: row.ProgramTitle,
+			Status:       row.Status,
+			RedeemedAt:   nullTimePtr(row.RedeemedAt),
+			CreatedAt:    row.CreatedAt,
+		})
+	}
+	for _, row := range receivedRows {
+		gifts.Received = append(gifts.Received, ReceivedGift{
+			ID:           row.ID,
+			ProgramID:    row.ProgramID,
+			ProgramTitle: row.ProgramTitle,
+			Status:       row.Status,
+			RedeemedAt:   nullTimePtr(row.RedeemedAt),
+			CreatedAt:    row.CreatedAt,
+		})
+	}
+	return gifts, nil
+}
+
+// RedeemGift は受け取り用のコードで贈り物を受け取り、番組の閲覧権限を付与する。
+// 同じコードを同時に受け取れないよう、贈り物をFOR UPDATEでロックして同一トランザクションで処理する
+func (u *GiftsUsecase) RedeemGift(ctx context.Context, userID, code string) (ReceivedGift, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return ReceivedGift{}, ErrGiftNotFound
+	}
+
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ReceivedGift{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := u.q.WithTx(tx)
+
+	gift, err := qtx.GetGiftByCodeForUpdate(ctx, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ReceivedGift{}, ErrGiftNotFound
+		}
+		return ReceivedGift{}, err
+	}
+	switch gift.Status {
+	case GiftRedeemed:
+		return ReceivedGift{}, ErrGiftAlreadyRedeemed
+	case GiftRevoked:
+		return ReceivedGift{}, ErrGiftRevoked
+	}
+	if gift.PurchaserID == userID {
+		return ReceivedGift{}, ErrOwnGift
+	}
+
+	// 購入済みの番組はコードを使わずに残す（レンタル中なら買い切りにする）
+	permitted, err := qtx.IsUserPermittedForProgram(ctx, db.IsUserPermittedForProgramParams{
+		UserID:    userID,
+		ProgramID: gift.ProgramID,
+	})
+	if err != nil {
+		return ReceivedGift{}, err
+	}
+	redeem := db.RedeemGiftParams{
+		ID:          gift.ID,
+		RecipientID: sql.NullString{String: userID, Valid: true},
+	}
+	if permitted {
+		rental, err := activeRental(ctx, qtx, userID, gift.ProgramID)
+		if err != nil {
+			return ReceivedGift{}, err
+		}
+		if rental == nil {
+			return ReceivedGift{}, ErrAlreadyPurchased
+		}
+		// 贈り物が返金されたときに元のレンタルを期限ごと戻せるよう記録する
+		redeem.ReplacedPaymentID = rental.PaymentID
+		redeem.ReplacedExpiresAt = rental.ExpiresAt
+	}
+
+	// 贈り物の決済が返金されたら一緒に取り消せるよう、決済IDを記録する
+	if err := qtx.AddPermittedProgramUser(ctx, db.AddPermittedProgramUserParams{
+		UserID:    userID,
+		ProgramID: gift.ProgramID,
+		PaymentID: sql.NullInt64{Int64: gift.PaymentID, Valid: true},
+	}); err != nil {
+		return ReceivedGift{}, err
+	}
+	if err := qtx.RedeemGift(ctx, redeem); err != nil {
+		return ReceivedGift{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ReceivedGift{}, err
+	}
+
+	now := time.Now()
+	return ReceivedGift{
+		ID:         gift.ID,
+		ProgramID:  gift.ProgramID,
+		Status:     GiftRedeemed,
+		RedeemedAt: &now,
+		CreatedAt:  gift.CreatedAt,
+	}, nil
+}
+
+// private functions
+
+// createGift は支払いが完了した贈り物の受け取り用のコードを発行する（決済はqtxのトランザクション内でロック済みであること）
+func createGift(ctx context.Context, qtx *db.Queries, payment db.Payment) error {
+	code, err := randomGiftCode()
+	if err != nil {
+		return err
+	}
+	_, err = qtx.CreateGift(ctx, db.CreateGiftParams{
+		Code:        code,
+		PaymentID:   payment.ID,
+		ProgramID:   payment.ProgramID.Int64,
+		PurchaserID: payment.UserID,
+	})
+	return err
+}
+
+// randomGiftCode は入力しやすい16文字の大文字英数字のコードを返す
+func randomGiftCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRandomGiftCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := randomGiftCode()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(code) != 16 {
+			t.Errorf("expected 16 chars, got %q", code)
+		}
+		// 受け取り時に大文字へ揃えるため、発行するコードは大文字英数字のみ
+		if strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567") != "" {
+			t.Errorf("unexpected characters in %q", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
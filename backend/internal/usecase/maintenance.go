@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+type MaintenanceUsecase struct {
+	q *db.Queries
+}
+
+func NewMaintenanceUsecase(q *db.Queries) *MaintenanceUsecase {
+	return &MaintenanceUsecase{q: q}
+}
+
+// PurgeExpiredSessions は期限切れのセッションを削除し、削除件数を返す
+func (u *MaintenanceUsecase) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	return u.q.DeleteExpiredSessions(ctx, time.Now())
+}
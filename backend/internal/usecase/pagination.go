@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// 一覧APIのページサイズ（limit未指定時の既定値と上限）
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+)
+
+// PageRequest は一覧APIのページング指定（Cursorは前ページのnext_cursor）
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// pageCursor はキーセットページングの位置（前ページ末尾の時刻またはソートキーとID）。
+// クライアントには不透明な文字列（base64url化したJSON）として渡す
+type pageCursor struct {
+	At time.Time `json:"at,omitempty"`
+	// 番組一覧のソート順とソートキー（並び順を変えて使い回されないようにソート順も持つ）
+	Sort string `json:"sort,omitempty"`
+	Key  int64  `json:"key,omitempty"`
+	// 名前順の一覧（出演者の五十音順など）のソートキー
+	Name string `json:"name,omitempty"`
+	ID   int64  `json:"id"`
+}
+
+func encodeCursor(c pageCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	if s == "" {
+		return pageCursor{}, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// cursorParams はページ指定をクエリ引数（カーソル位置と、次ページ有無判定用に1件多いlimit）に変換する
+func (p PageRequest) cursorParams() (sql.NullTime, sql.NullInt64, int32, error) {
+	c, err := decodeCursor(p.Cursor)
+	if err != nil {
+		return sql.NullTime{}, sql.NullInt64{}, 0, err
+	}
+	limit := p.normalizedLimit()
+	if c.ID == 0 {
+		return sql.NullTime{}, sql.NullInt64{}, int32(limit + 1), nil
+	}
+	if c.At.IsZero() {
+		return sql.NullTime{}, sql.NullInt64{}, 0, ErrInvalidCursor
+	}
+	return sql.NullTime{Time: c.At, Valid: true}, sql.NullInt64{Int64: c.ID, Valid: true}, int32(limit + 1), nil
+}
+
+// sortKeyCursorParams はソートキー方式のページ指定をクエリ引数に変換する。
+// カーソルが別のソート順で発行されたものならErrInvalidCursor
+func (p PageRequest) sortKeyCursorParams(sort string) (sql.NullInt64, sql.NullInt64, int32, error) {
+	c, err := decodeCursor(p.Cursor)
+	if err != nil {
+		return sql.NullInt64{}, sql.NullInt64{}, 0, err
+	}
+	limit := p.normalizedLimit()
+	if c.ID == 0 {
+		return sql.NullInt64{}, sql.NullInt64{}, int32(limit + 1), nil
+	}
+	if c.Sort != sort {
+		return sql.NullInt64{}, sql.NullInt64{}, 0, ErrInvalidCursor
+	}
+	return sql.NullInt64{Int64: c.Key, Valid: true}, sql.NullInt64{Int64: c.ID, Valid: true}, int32(limit + 1), nil
+}
+
+// nameCursorParams は名前順のページ指定をクエリ引数に変換する
+func (p PageRequest) nameCursorParams() (sql.NullString, sql.NullInt64, int32, error) {
+	c, err := decodeCursor(p.Cursor)
+	if err != nil {
+		return sql.NullString{}, sql.NullInt64{}, 0, err
+	}
+	limit := p.normalizedLimit()
+	if c.ID == 0 {
+		return sql.NullString{}, sql.NullInt64{}, int32(limit + 1), nil
+	}
+	if c.Name == "" {
+		return sql.NullString{}, sql.NullInt64{}, 0, ErrInvalidCursor
+	}
+	return sql.NullString{String: c.Name, Valid: true}, sql.NullInt64{Int64: c.ID, Valid: true}, int32(limit + 1), nil
+}
+
+func (p PageRequest) normalizedLimit() int {
+	if p.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return p.Limit
+}
+
+// nextCursor はlimit+1件取得した結果から次ページのカーソルを返す（次ページがなければnil）。
+// keyはi番目の行のカーソル位置を返す
+func nextCursor(fetched int, p PageRequest, key func(i int) pageCursor) *string {
+	limit := p.normalizedLimit()
+	if fetched <= limit {
+		return nil
+	}
+	s := encodeCursor(key(limit - 1))
+	return &s
+}
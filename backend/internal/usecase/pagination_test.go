@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 2, 1, 12, 34, 56, 123456000, time.UTC)
+	s := encodeCursor(pageCursor{At: at, ID: 42})
+
+	c, err := decodeCursor(s)
+	assert.NoError(t, err)
+	assert.True(t, at.Equal(c.At))
+	assert.Equal(t, int64(42), c.ID)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"!!!", "bm90LWpzb24", encodeCursor(pageCursor{At: time.Now()})} {
+		_, err := decodeCursor(s)
+		assert.ErrorIs(t, err, ErrInvalidCursor, s)
+	}
+}
+
+func TestPageRequest_CursorParams(t *testing.T) {
+	cursorAt, cursorID, limit, err := PageRequest{}.cursorParams()
+	assert.NoError(t, err)
+	assert.False(t, cursorAt.Valid)
+	assert.False(t, cursorID.Valid)
+	assert.Equal(t, int32(DefaultPageLimit+1), limit)
+
+	_, _, limit, _ = PageRequest{Limit: 1000}.cursorParams()
+	assert.Equal(t, int32(MaxPageLimit+1), limit)
+
+	at := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	cursorAt, cursorID, limit, err = PageRequest{Cursor: encodeCursor(pageCursor{At: at, ID: 7}), Limit: 10}.cursorParams()
+	assert.NoError(t, err)
+	assert.True(t, cursorAt.Valid)
+	assert.Equal(t, int64(7), cursorID.Int64)
+	assert.Equal(t, int32(11), limit)
+}
+
+func TestNextCursor(t *testing.T) {
+	at := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	key := func(i int) pageCursor { return pageCursor{At: at, ID: int64(100 - i)} }
+
+	assert.Nil(t, nextCursor(2, PageRequest{Limit: 2}, key))
+
+	next := nextCursor(3, PageRequest{Limit: 2}, key)
+	if assert.NotNil(t, next) {
+		c, err := decodeCursor(*next)
+		assert.NoError(t, err)
+		// 2件目（index 1）の位置から次ページを始める
+		assert.Equal(t, int64(99), c.ID)
+	}
+}
+
+func TestPageRequest_SortKeyCursorParams(t *testing.T) {
+	cursor := encodeCursor(pageCursor{Sort: ProgramSortMostViewed, Key: 0, ID: 3})
+
+	key, id, limit, err := PageRequest{Cursor: cursor, Limit: 5}.sortKeyCursorParams(ProgramSortMostViewed)
+	assert.NoError(t, err)
+	assert.True(t, key.Valid)
+	assert.Equal(t, int64(0), key.Int64)
+	assert.Equal(t, int64(3), id.Int64)
+	assert.Equal(t, int32(6), limit)
+
+	// 別のソート順のカーソルは使えない
+	_, _, _, err = PageRequest{Cursor: cursor}.sortKeyCursorParams(ProgramSortNewest)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// 時刻方式の一覧にソートキー方式のカーソルは使えない
+	_, _, _, err = PageRequest{Cursor: cursor}.cursorParams()
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+var ErrReceiptNotAvailable = errors.New("receipt not available")
+
+// PaymentHistoryItem は購入履歴の1件（未完了・失敗・返金済みの決済も含む）
+type PaymentHistoryItem struct {
+	MerchantPaymentID string  `json:"merchant_payment_id"`
+	Status            string  `json:"status"`
+	AmountYen         int32   `json:"amount_yen"` // 割引後の請求額
+	DiscountYen       int32   `json:"discount_yen"`
+	RefundedAmountYen int32   `json:"refunded_amount_yen"`
+	PurchaseType      string  `json:"purchase_type"`
+	IsGift            bool    `json:"is_gift"`
+	Provider          string  `json:"provider"`
+	ProviderPaymentID *string `json:"provider_payment_id"`
+	ProgramID         *int64  `json:"program_id"`
+	ProgramTitle      *string `json:"program_title"`
+	ProductID         *int64  `json:"product_id"`
+	ProductName       *string `json:"product_name"`
+	// 支払いが完了した決済のみ
+	ReceiptNumber *string    `json:"receipt_number"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type PaymentHistoryPage struct {
+	Payments   []PaymentHistoryItem `json:"payments"`
+	NextCursor *string              `json:"next_cursor"`
+}
+
+// PaymentReceipt は支払いが完了した決済の領収書の内容
+type PaymentReceipt struct {
+	ReceiptNumber     string
+	IssuedTo          string
+	MerchantPaymentID string
+	Description       string
+	Status            string
+	Provider          string
+	ProviderPaymentID string
+	SubtotalYen       int32 // 割引前の価格
+	DiscountYen       int32
+	AmountYen         int32
+	RefundedAmountYen int32
+	PaidAt            time.Time
+}
+
+// ListMyPayments はユーザーの決済を新しい順に返す
+func (p *PaymentsUsecase) ListMyPayments(ctx context.Context, userID string, page PageRequest) (PaymentHistoryPage, error) {
+	cursorAt, cursorID, limit, err := page.cursorParams()
+	if err != nil {
+		return PaymentHistoryPage{}, err
+	}
+	rows, err := p.q.ListPaymentsByUser(ctx, db.ListPaymentsByUserParams{UserID: userID, CursorAt: cursorAt, CursorID: cursorID, PageLimit: limit})
+	if err != nil {
+		return PaymentHistoryPage{}, err
+	}
+	next := nextCursor(len(rows), page, func(i int) pageCursor {
+		return pageCursor{At: rows[i].CreatedAt, ID: rows[i].ID}
+	})
+	if next != nil {
+		rows = rows[:page.normalizedLimit()]
+	}
+
+	items := make([]PaymentHistoryItem, 0, len(rows))
+	for _, row := range rows {
+		item := PaymentHistoryItem{
+			MerchantPaymentID: row.MerchantPaymentID,
+			Status:            row.Status,
+			AmountYen:         row.AmountYen,
+			DiscountYen:       row.DiscountYen,
+			RefundedAmountYen: row.RefundedAmountYen,
+			PurchaseType:      row.PurchaseType,
+			IsGift:            row.IsGift,
+			Provider:          row.Provider,
+			ProviderPaymentID: nullStringPtr(row.ProviderPaymentID),
+			ProgramTitle:      nullStringPtr(row.ProgramTitle),
+			ProductName:       nullStringPtr(row.ProductName),
+			CompletedAt:       nullTimePtr(row.CreditedAt),
+			CreatedAt:         row.CreatedAt,
+			UpdatedAt:         row.UpdatedAt,
+		}
+		if row.ProgramID.Valid {
+			item.ProgramID = &row.ProgramID.Int64
+		}
+		if row.ProductID.Valid {
+			item.ProductID = &row.ProductID.Int64
+		}
+		if row.ReceiptNumber.Valid {
+			number := formatReceiptNumber(row.ReceiptNumber.Int64)
+			item.ReceiptNumber = &number
+		}
+		items = append(items, item)
+	}
+	return PaymentHistoryPage{Payments: items, NextCursor: next}, nil
+}
+
+// GetReceipt はユーザーの支払いが完了した決済の領収書を返す（返金済みでも返金額を記載して発行する）
+func (p *PaymentsUsecase) GetReceipt(ctx context.Context, userID, merchantPaymentID string) (PaymentReceipt, error) {
+	row, err := p.q.GetPaymentReceipt(ctx, db.GetPaymentReceiptParams{UserID: userID, MerchantPaymentID: merchantPaymentID})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentReceipt{}, ErrPaymentNotFound
+		}
+		return PaymentReceipt{}, err
+	}
+	if !row.ReceiptNumber.Valid || !row.CreditedAt.Valid {
+		return PaymentReceipt{}, ErrReceiptNotAvailable
+	}
+
+	return PaymentReceipt{
+		ReceiptNumber:     formatReceiptNumber(row.ReceiptNumber.Int64),
+		IssuedTo:          row.UserName,
+		MerchantPaymentID: row.MerchantPaymentID,
+		Description:       receiptDescription(row),
+		Status:            row.Status,
+		Provider:          row.Provider,
+		ProviderPaymentID: row.ProviderPaymentID.String,
+		SubtotalYen:       row.AmountYen + row.DiscountYen,
+		DiscountYen:       row.DiscountYen,
+		AmountYen:         row.AmountYen,
+		RefundedAmountYen: row.RefundedAmountYen,
+		PaidAt:            row.CreditedAt.Time,
+	}, nil
+}
+
+// private functions
+
+// formatReceiptNumber は領収書番号を表示用の形式（SZ-00000001）にする
+func formatReceiptNumber(n int64) string {
+	return fmt.Sprintf("SZ-%08d", n)
+}
+
+// receiptDescription は領収書の但し書き（購入したもの）を返す
+func receiptDescription(row db.GetPaymentReceiptRow) string {
+	switch {
+	case row.ProductName.Valid:
+		return row.ProductName.String
+	case !row.ProgramTitle.Valid:
+		return "番組"
+	case row.IsGift:
+		return row.ProgramTitle.String + "（ギフト）"
+	case row.PurchaseType == PurchaseTypeRental:
+		return row.ProgramTitle.String + "（レンタル）"
+	}
+	return row.ProgramTitle.String
+}
@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+func TestFormatReceiptNumber(t *testing.T) {
+	if got := formatReceiptNumber(42); got != "SZ-00000042" {
+		t.Errorf("formatReceiptNumber(42) = %q", got)
+	}
+}
+
+func TestReceiptDescription(t *testing.T) {
+	title := sql.NullString{String: "番組A", Valid: true}
+	tests := []struct {
+		name string
+		row  db.GetPaymentReceiptRow
+		want string
+	}{
+		{"purchase", db.GetPaymentReceiptRow{ProgramTitle: title, PurchaseType: PurchaseTypePurchase}, "番組A"},
+		{"rental", db.GetPaymentReceiptRow{ProgramTitle: title, PurchaseType: PurchaseTypeRental}, "番組A（レンタル）"},
+		{"gift", db.GetPaymentReceiptRow{ProgramTitle: title, PurchaseType: PurchaseTypePurchase, IsGift: true}, "番組A（ギフト）"},
+		{"product", db.GetPaymentReceiptRow{ProductName: sql.NullString{String: "30日パス", Valid: true}, PurchaseType: PurchaseTypePurchase}, "30日パス"},
+		{"deleted program", db.GetPaymentReceiptRow{PurchaseType: PurchaseTypePurchase}, "番組"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := receiptDescription(tt.row); got != tt.want {
+				t.Errorf("receiptDescription() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
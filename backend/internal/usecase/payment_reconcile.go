@@ -0,0 +1,352 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/jobs"
+	"github.com/chan-shizu/SZer/internal/payments"
+)
+
+// 照合ジョブの種類
+const (
+	JobPaymentReconcile        = "payments.reconcile"
+	JobPaymentReconcilePending = "payments.reconcile_pending"
+	JobPaymentReconcileRefund  = "payments.reconcile_refund"
+)
+
+const (
+	// 作成からこの時間が経っても完了していない決済を照合対象にする（PAYPAY_RECONCILE_AFTER_MINUTESで上書き可）
+	defaultPaymentReconcileAfter = 10 * time.Minute
+	// 同じ決済を再照合するまでの間隔
+	paymentReconcileInterval = 5 * time.Minute
+	// 1回のスイープで照合する件数
+	paymentReconcileBatchSize = 50
+	// 支払いページの有効期限が取れていない場合、作成からこの時間で期限切れとみなす
+	checkoutExpiryFallback = 24 * time.Hour
+)
+
+// PaymentReconcilePayload は単一の決済の照合ジョブのペイロード
+type PaymentReconcilePayload struct {
+	MerchantPaymentID string `json:"merchant_payment_id"`
+}
+
+// PaymentRefundReconcilePayload は結果不明の返金の照合ジョブのペイロード
+type PaymentRefundReconcilePayload struct {
+	MerchantRefundID string `json:"merchant_refund_id"`
+}
+
+type PaymentReconcileResult struct {
+	MerchantPaymentID string
+	Status            string
+	Granted           bool
+	Expired           bool
+}
+
+// Configured はAPIを呼べる決済サービスがあるかを返す
+func (p *PaymentsUsecase) Configured() bool {
+	for _, provider := range p.providers.Providers() {
+		if provider.Configured() {
+			return true
+		}
+	}
+	return false
+}
+
+func paymentReconcileAfterFromEnv() time.Duration {
+	v := os.Getenv("PAYPAY_RECONCILE_AFTER_MINUTES")
+	if v == "" {
+		return defaultPaymentReconcileAfter
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("[PaymentReconcile] invalid PAYPAY_RECONCILE_AFTER_MINUTES=%q, using default", v)
+		return defaultPaymentReconcileAfter
+	}
+	return time.Duration(n) * time.Minute
+}
+
+// checkoutExpired は支払いページ・コードの有効期限が切れているかを返す
+func checkoutExpired(payment db.Payment, now time.Time) bool {
+	if payment.CheckoutExpiresAt.Valid {
+		return !now.Before(payment.CheckoutExpiresAt.Time)
+	}
+	return !now.Before(payment.CreatedAt.Add(checkoutExpiryFallback))
+}
+
+// enqueueReconcile はCheckout後、Webhookや確認APIが来なかった場合に備えて遅延照合ジョブを登録する
+func (p *PaymentsUsecase) enqueueReconcile(ctx context.Context, merchantPaymentID string) {
+	if p.jobs == nil {
+		return
+	}
+	_, err := p.jobs.Enqueue(ctx, JobPaymentReconcile, PaymentReconcilePayload{MerchantPaymentID: merchantPaymentID},
+		jobs.Delay(p.reconcileAfter),
+		jobs.DedupeKey("payments.reconcile:"+merchantPaymentID),
+	)
+	if err != nil {
+		log.Printf("[PaymentReconcile] enqueue failed: merchant_payment_id=%s, err=%v", merchantPaymentID, err)
+	}
+}
+
+// ReconcilePayment は未完了の決済を決済サービス側の状態と突き合わせ、完了していれば権限を付与し、
+// 支払いページの有効期限を過ぎても支払われていなければEXPIREDにする
+func (p *PaymentsUsecase) ReconcilePayment(ctx context.Context, merchantPaymentID string) (PaymentReconcileResult, error) {
+	existing, err := p.q.GetPaymentByMerchantPaymentID(ctx, merchantPaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentReconcileResult{}, ErrPaymentNotFound
+		}
+		return PaymentReconcileResult{}, err
+	}
+	provider, err := p.provider(existing.Provider)
+	if err != nil {
+		return PaymentReconcileResult{}, err
+	}
+
+	// 外部APIはトランザクションの外で呼ぶ
+	st, apiErr := provider.GetStatus(ctx, merchantPaymentID)
+
+	tx, err := p.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return PaymentReconcileResult{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := p.q.WithTx(tx)
+
+	payment, err := qtx.GetPaymentByMerchantPaymentIDForUpdate(ctx, merchantPaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentReconcileResult{}, ErrPaymentNotFound
+		}
+		return PaymentReconcileResult{}, err
+	}
+	result := PaymentReconcileResult{MerchantPaymentID: merchantPaymentID, Status: payment.Status}
+
+	if err := qtx.MarkPaymentReconciled(ctx, merchantPaymentID); err != nil {
+		return PaymentReconcileResult{}, err
+	}
+
+	// 照合中に確認API・Webhookで確定した
+	if !isPaymentPending(payment.Status) {
+		if apiErr == nil {
+			if st.Status != payment.Status {
+				log.Printf("[PaymentReconcile] discrepancy: merchant_payment_id=%s, local=%s, %s=%s", merchantPaymentID, payment.Status, payment.Provider, st.RawStatus)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return PaymentReconcileResult{}, err
+		}
+		return result, nil
+	}
+
+	expired := checkoutExpired(payment, time.Now())
+	var status string
+	var granted bool
+	if apiErr != nil {
+		if !expired {
+			// 照合した記録だけ残し、次回のスイープで再試行する
+			if err := tx.Commit(); err != nil {
+				return PaymentReconcileResult{}, err
+			}
+			return result, apiErr
+		}
+		// 決済サービス側で参照できないまま有効期限を過ぎた
+		log.Printf("[PaymentReconcile] payment details unavailable after code expiry: merchant_payment_id=%s, err=%v", merchantPaymentID, apiErr)
+		if _, err := transitionPayment(ctx, qtx, payment, paymentTransition{
+			To:     PaymentExpired,
+			Source: PaymentSourceReconcile,
+			Detail: "payment details unavailable after code expiry",
+		}); err != nil {
+			return PaymentReconcileResult{}, err
+		}
+		status = PaymentExpired
+	} else {
+		if st.AmountYen != 0 && st.AmountYen != payment.AmountYen {
+			log.Printf("[PaymentReconcile] discrepancy: amount mismatch merchant_payment_id=%s, local=%d, %s=%d", merchantPaymentID, payment.AmountYen, payment.Provider, st.AmountYen)
+		}
+		if st.Status == PaymentCompleted {
+			if payment.ProgramID.Valid || payment.ProductID.Valid {
+				log.Printf("[PaymentReconcile] recovered completed payment: merchant_payment_id=%s, user_id=%s, program_id=%d, product_id=%d", merchantPaymentID, payment.UserID, payment.ProgramID.Int64, payment.ProductID.Int64)
+			} else {
+				log.Printf("[PaymentReconcile] discrepancy: completed without program merchant_payment_id=%s, user_id=%s", merchantPaymentID, payment.UserID)
+			}
+		}
+
+		status, granted, err = applyProviderStatus(ctx, qtx, payment, st, PaymentSourceReconcile)
+		if err != nil {
+			return PaymentReconcileResult{}, err
+		}
+		// 支払われないままコードの有効期限を過ぎた
+		if isPaymentPending(status) && expired {
+			payment.Status = status
+			if _, err := transitionPayment(ctx, qtx, payment, paymentTransition{
+				To:     PaymentExpired,
+				Source: PaymentSourceReconcile,
+				Detail: "code expired without payment",
+			}); err != nil {
+				return PaymentReconcileResult{}, err
+			}
+			status = PaymentExpired
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PaymentReconcileResult{}, err
+	}
+
+	result.Status = status
+	result.Granted = granted
+	result.Expired = status == PaymentExpired
+	return result, nil
+}
+
+// ReconcilePendingPayments は一定時間以上未完了の決済をまとめて照合し、照合した件数を返す
+func (p *PaymentsUsecase) ReconcilePendingPayments(ctx context.Context) (int, error) {
+	now := time.Now()
+	ids, err := p.q.ListPaymentsToReconcile(ctx, db.ListPaymentsToReconcileParams{
+		CreatedBefore:    now.Add(-p.reconcileAfter),
+		ReconciledBefore: now.Add(-paymentReconcileInterval),
+		BatchSize:        paymentReconcileBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		res, err := p.ReconcilePayment(ctx, id)
+		if err != nil {
+			log.Printf("[PaymentReconcile] reconcile failed: merchant_payment_id=%s, err=%v", id, err)
+			continue
+		}
+		n++
+		if res.Granted || res.Expired {
+			log.Printf("[PaymentReconcile] merchant_payment_id=%s, status=%s, granted=%v", id, res.Status, res.Granted)
+		}
+	}
+	return n, nil
+}
+
+// enqueueRefundReconcile は結果が確定していない返金の照合ジョブを登録する
+func (p *PaymentsUsecase) enqueueRefundReconcile(ctx context.Context, merchantRefundID string) {
+	if p.jobs == nil {
+		return
+	}
+	_, err := p.jobs.Enqueue(ctx, JobPaymentReconcileRefund, PaymentRefundReconcilePayload{MerchantRefundID: merchantRefundID},
+		jobs.Delay(paymentReconcileInterval),
+		jobs.DedupeKey("payments.reconcile_refund:"+merchantRefundID),
+	)
+	if err != nil {
+		log.Printf("[PaymentReconcile] enqueue refund failed: merchant_refund_id=%s, err=%v", merchantRefundID, err)
+	}
+}
+
+// ReconcileRefund は処理中（CREATED）の返金を決済サービス側の返金状態と突き合わせる。
+// 決済サービスが返金を受け付けていないと確定した場合だけFAILEDにする
+func (p *PaymentsUsecase) ReconcileRefund(ctx context.Context, merchantRefundID string) (PaymentRefund, error) {
+	providerName, err := p.q.GetPaymentRefundProvider(ctx, merchantRefundID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentRefund{}, ErrPaymentRefundNotFound
+		}
+		return PaymentRefund{}, err
+	}
+	provider, err := p.provider(providerName)
+	if err != nil {
+		return PaymentRefund{}, err
+	}
+
+	// 外部APIはトランザクションの外で呼ぶ
+	details, apiErr := provider.GetRefundStatus(ctx, merchantRefundID)
+
+	tx, err := p.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return PaymentRefund{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := p.q.WithTx(tx)
+
+	refund, err := qtx.GetPaymentRefundByMerchantRefundIDForUpdate(ctx, merchantRefundID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentRefund{}, ErrPaymentRefundNotFound
+		}
+		return PaymentRefund{}, err
+	}
+	// 照合中にWebhookで確定した
+	if refund.Status != PaymentRefundCreated {
+		if err := tx.Commit(); err != nil {
+			return PaymentRefund{}, err
+		}
+		return toPaymentRefund(refund), nil
+	}
+
+	if err := qtx.MarkPaymentRefundReconciled(ctx, merchantRefundID); err != nil {
+		return PaymentRefund{}, err
+	}
+
+	status := PaymentRefundCreated
+	var errMsg string
+	switch {
+	case apiErr == nil:
+		status = details
+	case errors.Is(apiErr, payments.ErrNotFound) && time.Since(refund.CreatedAt) >= paymentReconcileInterval:
+		// 返金APIの呼び出しが決済サービスに届いていなかった
+		status = PaymentRefundFailed
+		errMsg = "refund not found at " + providerName
+	default:
+		// 照合した記録だけ残し、次回のスイープで再試行する
+		if err := tx.Commit(); err != nil {
+			return PaymentRefund{}, err
+		}
+		return toPaymentRefund(refund), apiErr
+	}
+
+	updated, err := applyPaymentRefundStatus(ctx, qtx, merchantRefundID, status, errMsg, PaymentSourceReconcile)
+	if err != nil {
+		return PaymentRefund{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return PaymentRefund{}, err
+	}
+	return toPaymentRefund(updated), nil
+}
+
+// ReconcilePendingRefunds は処理中のまま残っている返金をまとめて照合し、照合した件数を返す
+func (p *PaymentsUsecase) ReconcilePendingRefunds(ctx context.Context) (int, error) {
+	ids, err := p.q.ListPaymentRefundsToReconcile(ctx, db.ListPaymentRefundsToReconcileParams{
+		UpdatedBefore: time.Now().Add(-paymentReconcileInterval),
+		BatchSize:     paymentReconcileBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		res, err := p.ReconcileRefund(ctx, id)
+		if err != nil {
+			log.Printf("[PaymentReconcile] refund reconcile failed: merchant_refund_id=%s, err=%v", id, err)
+			continue
+		}
+		n++
+		if res.Status != PaymentRefundCreated {
+			log.Printf("[PaymentReconcile] merchant_refund_id=%s, status=%s", id, res.Status)
+		}
+	}
+	return n, nil
+}
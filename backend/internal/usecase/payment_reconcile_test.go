@@ -0,0 +1,29 @@
+package usecase
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckoutExpired(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	// 支払いページの有効期限があればそれで判定する
+	payment := db.Payment{
+		CreatedAt:         now.Add(-48 * time.Hour),
+		CheckoutExpiresAt: sql.NullTime{Time: now.Add(time.Minute), Valid: true},
+	}
+	assert.False(t, checkoutExpired(payment, now))
+	payment.CheckoutExpiresAt.Time = now
+	assert.True(t, checkoutExpired(payment, now))
+
+	// 有効期限が無ければ作成から24時間で期限切れ
+	payment = db.Payment{CreatedAt: now.Add(-23 * time.Hour)}
+	assert.False(t, checkoutExpired(payment, now))
+	payment.CreatedAt = now.Add(-24 * time.Hour)
+	assert.True(t, checkoutExpired(payment, now))
+}
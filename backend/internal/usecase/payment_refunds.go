@@ -0,0 +1,389 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/payments"
+)
+
+var ErrPaymentNotRefundable = errors.New("payment is not refundable")
+var ErrPaymentNotCancelable = errors.New("payment is not cancelable")
+var ErrPaymentNotRevocable = errors.New("payment is not revocable")
+var ErrInvalidRefundAmount = errors.New("invalid refund amount")
+var ErrPaymentRefundFailed = errors.New("payment refund failed")
+var ErrPaymentRefundNotFound = errors.New("payment refund not found")
+
+// 返金のステータス
+const (
+	PaymentRefundCreated  = payments.RefundCreated // 決済サービスで処理中
+	PaymentRefundRefunded = payments.RefundRefunded
+	PaymentRefundFailed   = payments.RefundFailed
+)
+
+const maxRefundReasonLength = 200
+
+type PaymentRefund struct {
+	MerchantRefundID string     `json:"merchant_refund_id"`
+	AmountYen        int32      `json:"amount_yen"`
+	Status           string     `json:"status"`
+	Reason           string     `json:"reason"`
+	RequestedBy      *string    `json:"requested_by"`
+	Error            *string    `json:"error,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	RefundedAt       *time.Time `json:"refunded_at"`
+}
+
+type PaymentRefunds struct {
+	MerchantPaymentID string          `json:"merchant_payment_id"`
+	Status            string          `json:"status"`
+	AmountYen         int32           `json:"amount_yen"`
+	RefundedAmountYen int32           `json:"refunded_amount_yen"`
+	Refunds           []PaymentRefund `json:"refunds"`
+}
+
+func toPaymentRefund(r db.PaymentRefund) PaymentRefund {
+	refund := PaymentRefund{
+		MerchantRefundID: r.MerchantRefundID,
+		AmountYen:        r.AmountYen,
+		Status:           r.Status,
+		Reason:           r.Reason,
+		CreatedAt:        r.CreatedAt,
+	}
+	if r.RequestedBy.Valid {
+		refund.RequestedBy = &r.RequestedBy.String
+	}
+	if r.Error.Valid {
+		refund.Error = &r.Error.String
+	}
+	if r.RefundedAt.Valid {
+		refund.RefundedAt = &r.RefundedAt.Time
+	}
+	return refund
+}
+
+// ListPaymentRefunds は決済の返金履歴を返す
+func (p *PaymentsUsecase) ListPaymentRefunds(ctx context.Context, merchantPaymentID string) (PaymentRefunds, error) {
+	payment, err := p.q.GetPaymentByMerchantPaymentID(ctx, merchantPaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentRefunds{}, ErrPaymentNotFound
+		}
+		return PaymentRefunds{}, err
+	}
+
+	rows, err := p.q.ListPaymentRefundsByPayment(ctx, payment.ID)
+	if err != nil {
+		return PaymentRefunds{}, err
+	}
+	refunds := make([]PaymentRefund, 0, len(rows))
+	for _, r := range rows {
+		refunds = append(refunds, toPaymentRefund(r))
+	}
+
+	return PaymentRefunds{
+		MerchantPaymentID: payment.MerchantPaymentID,
+		Status:            payment.Status,
+		AmountYen:         payment.AmountYen,
+		RefundedAmountYen: payment.RefundedAmountYen,
+		Refunds:           refunds,
+	}, nil
+}
+
+// RefundPayment は完了済みの決済を返金する。amountYenが0なら残額を全額返金する。
+// 全額返金が完了すると閲覧権限を取り消す
+func (p *PaymentsUsecase) RefundPayment(ctx context.Context, adminUserID, merchantPaymentID string, amountYen int32, reason string) (PaymentRefund, error) {
+	reason = strings.TrimSpace(reason)
+	if amountYen < 0 || len([]rune(reason)) > maxRefundReasonLength {
+		return PaymentRefund{}, ErrInvalidRefundAmount
+	}
+
+	// 返金額を確保する前に、決済サービスのAPIを呼べることを確認する
+	existing, err := p.q.GetPaymentByMerchantPaymentID(ctx, merchantPaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentRefund{}, ErrPaymentNotFound
+		}
+		return PaymentRefund{}, err
+	}
+	if existing.Provider == PaymentProviderFree {
+		// 請求額が0円の決済は返金するものが無い（取り消しはRevokeFreePayment）
+		return PaymentRefund{}, ErrPaymentNotRefundable
+	}
+	provider, err := p.provider(existing.Provider)
+	if err != nil {
+		return PaymentRefund{}, err
+	}
+
+	refund, providerPaymentID, err := p.createRefund(ctx, adminUserID, merchantPaymentID, amountYen, reason)
+	if err != nil {
+		return PaymentRefund{}, err
+	}
+
+	status := PaymentRefundCreated
+	var errMsg string
+	refundStatus, apiErr := provider.Refund(ctx, payments.RefundRequest{
+		MerchantRefundID:  refund.MerchantRefundID,
+		MerchantPaymentID: merchantPaymentID,
+		ProviderPaymentID: providerPaymentID,
+		AmountYen:         refund.AmountYen,
+		Reason:            reason,
+	})
+	switch {
+	case apiErr == nil:
+		status = refundStatus
+	case payments.IsRejected(apiErr):
+		// 決済サービスが返金を受け付けなかった
+		status = PaymentRefundFailed
+		errMsg = apiErr.Error()
+	default:
+		// タイムアウト等で結果が分からない場合があるため、返金の状態を問い合わせる
+		log.Printf("[PaymentRefund] refund api failed: merchant_refund_id=%s, err=%v", refund.MerchantRefundID, apiErr)
+		details, detailsErr := provider.GetRefundStatus(ctx, refund.MerchantRefundID)
+		if detailsErr == nil {
+			status = details
+		} else {
+			// 結果が確定しないため処理中のまま残し、照合ジョブで確定させる
+			log.Printf("[PaymentRefund] refund details unavailable: merchant_refund_id=%s, err=%v", refund.MerchantRefundID, detailsErr)
+		}
+	}
+
+	tx, err := p.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return PaymentRefund{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := p.q.WithTx(tx)
+
+	updated, err := applyPaymentRefundStatus(ctx, qtx, refund.MerchantRefundID, status, errMsg, PaymentSourceAdmin)
+	if err != nil {
+		return PaymentRefund{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PaymentRefund{}, err
+	}
+
+	if updated.Status == PaymentRefundCreated {
+		p.enqueueRefundReconcile(ctx, updated.MerchantRefundID)
+	}
+	if updated.Status == PaymentRefundFailed {
+		return toPaymentRefund(updated), ErrPaymentRefundFailed
+	}
+	return toPaymentRefund(updated), nil
+}
+
+// createRefund は返金額を確定させて返金レコードを作る（返金APIを呼ぶ前に残額を確保する）
+func (p *PaymentsUsecase) createRefund(ctx context.Context, adminUserID, merchantPaymentID string, amountYen int32, reason string) (db.PaymentRefund, string, error) {
+	tx, err := p.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return db.PaymentRefund{}, "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := p.q.WithTx(tx)
+
+	payment, err := qtx.GetPaymentByMerchantPaymentIDForUpdate(ctx, merchantPaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.PaymentRefund{}, "", ErrPaymentNotFound
+		}
+		return db.PaymentRefund{}, "", err
+	}
+	if payment.Status != PaymentCompleted || !payment.ProviderPaymentID.Valid {
+		return db.PaymentRefund{}, "", ErrPaymentNotRefundable
+	}
+
+	// 処理中の返金も含めて残額を計算する
+	active, err := qtx.SumActivePaymentRefunds(ctx, payment.ID)
+	if err != nil {
+		return db.PaymentRefund{}, "", err
+	}
+	remaining := payment.AmountYen - active
+	if remaining <= 0 {
+		return db.PaymentRefund{}, "", ErrPaymentNotRefundable
+	}
+	if amountYen == 0 {
+		amountYen = remaining
+	}
+	if amountYen > remaining {
+		return db.PaymentRefund{}, "", ErrInvalidRefundAmount
+	}
+
+	merchantRefundID, err := payments.RandomMerchantRefundID()
+	if err != nil {
+		return db.PaymentRefund{}, "", err
+	}
+
+	refund, err := qtx.CreatePaymentRefund(ctx, db.CreatePaymentRefundParams{
+		PaymentID:        payment.ID,
+		MerchantRefundID: merchantRefundID,
+		AmountYen:        amountYen,
+		Reason:           reason,
+		RequestedBy:      sql.NullString{String: adminUserID, Valid: adminUserID != ""},
+	})
+	if err != nil {
+		return db.PaymentRefund{}, "", err
+	}
+
+	if err := qtx.InsertPaymentEvent(ctx, db.InsertPaymentEventParams{
+		PaymentID:  payment.ID,
+		FromStatus: sql.NullString{String: payment.Status, Valid: true},
+		ToStatus:   payment.Status,
+		Source:     PaymentSourceAdmin,
+		Detail:     fmt.Sprintf("refund requested: merchant_refund_id=%s, amount=%d", merchantRefundID, amountYen),
+	}); err != nil {
+		return db.PaymentRefund{}, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.PaymentRefund{}, "", err
+	}
+	return refund, payment.ProviderPaymentID.String, nil
+}
+
+// applyPaymentRefundStatus は返金の状態を反映する。REFUNDEDになったら返金済み額を加算し、
+// 全額に達したら決済をREFUNDEDへ遷移させる（閲覧権限も取り消される）。
+// 返金APIの結果とWebhookの両方から呼ばれるため、確定済みの返金は変更しない
+func applyPaymentRefundStatus(ctx context.Context, qtx *db.Queries, merchantRefundID, status, errMsg, source string) (db.PaymentRefund, error) {
+	refund, err := qtx.GetPaymentRefundByMerchantRefundIDForUpdate(ctx, merchantRefundID)
+	if err != nil {
+		return db.PaymentRefund{}, err
+	}
+	if refund.Status == status || refund.Status == PaymentRefundRefunded {
+		return refund, nil
+	}
+	switch status {
+	case PaymentRefundRefunded, PaymentRefundFailed:
+	case PaymentRefundCreated:
+		// 処理中のまま
+		return refund, nil
+	default:
+		log.Printf("[PaymentRefund] unknown refund state ignored: merchant_refund_id=%s, state=%s, source=%s", merchantRefundID, status, source)
+		return refund, nil
+	}
+
+	payment, err := qtx.GetPaymentByIDForUpdate(ctx, refund.PaymentID)
+	if err != nil {
+		return db.PaymentRefund{}, err
+	}
+
+	if err := qtx.UpdatePaymentRefundStatus(ctx, db.UpdatePaymentRefundStatusParams{
+		Status: status,
+		Error:  sql.NullString{String: errMsg, Valid: errMsg != ""},
+		ID:     refund.ID,
+	}); err != nil {
+		return db.PaymentRefund{}, err
+	}
+
+	if status == PaymentRefundFailed {
+		if err := qtx.InsertPaymentEvent(ctx, db.InsertPaymentEventParams{
+			PaymentID:  payment.ID,
+			FromStatus: sql.NullString{String: payment.Status, Valid: true},
+			ToStatus:   payment.Status,
+			Source:     source,
+			Detail:     fmt.Sprintf("refund failed: merchant_refund_id=%s, error=%s", merchantRefundID, errMsg),
+		}); err != nil {
+			return db.PaymentRefund{}, err
+		}
+		return qtx.GetPaymentRefundByMerchantRefundIDForUpdate(ctx, merchantRefundID)
+	}
+
+	refunded, err := qtx.AddPaymentRefundedAmount(ctx, db.AddPaymentRefundedAmountParams{
+		AmountYen: refund.AmountYen,
+		ID:        payment.ID,
+	})
+	if err != nil {
+		return db.PaymentRefund{}, err
+	}
+	if err := qtx.InsertPaymentEvent(ctx, db.InsertPaymentEventParams{
+		PaymentID:  payment.ID,
+		FromStatus: sql.NullString{String: payment.Status, Valid: true},
+		ToStatus:   payment.Status,
+		Source:     source,
+		Detail:     fmt.Sprintf("refunded: merchant_refund_id=%s, amount=%d, total=%d", merchantRefundID, refund.AmountYen, refunded),
+	}); err != nil {
+		return db.PaymentRefund{}, err
+	}
+
+	if refunded >= payment.AmountYen && payment.Status == PaymentCompleted {
+		if _, err := transitionPayment(ctx, qtx, payment, paymentTransition{
+			To:     PaymentRefunded,
+			Source: source,
+			Detail: "fully refunded: merchant_refund_id=" + merchantRefundID,
+		}); err != nil {
+			return db.PaymentRefund{}, err
+		}
+	}
+
+	return qtx.GetPaymentRefundByMerchantRefundIDForUpdate(ctx, merchantRefundID)
+}
+
+// CancelPayment は支払い待ちの決済を決済サービス側で取り消し、CANCELEDにする
+func (p *PaymentsUsecase) CancelPayment(ctx context.Context, merchantPaymentID string) (string, error) {
+	payment, err := p.q.GetPaymentByMerchantPaymentID(ctx, merchantPaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrPaymentNotFound
+		}
+		return "", err
+	}
+	if !isPaymentPending(payment.Status) {
+		return "", ErrPaymentNotCancelable
+	}
+	provider, err := p.provider(payment.Provider)
+	if err != nil {
+		return "", err
+	}
+
+	if err := provider.Cancel(ctx, merchantPaymentID); err != nil {
+		return "", err
+	}
+
+	if _, err := p.transitionPaymentTx(ctx, merchantPaymentID, paymentTransition{
+		To:     PaymentCanceled,
+		Source: PaymentSourceAdmin,
+		Detail: "canceled by admin",
+	}); err != nil {
+		if errors.Is(err, ErrInvalidPaymentTransition) {
+			// キャンセル中に完了した
+			return "", ErrPaymentNotCancelable
+		}
+		return "", err
+	}
+	return PaymentCanceled, nil
+}
+
+// RevokeFreePayment は割引で請求額が0円になった決済を取り消し、閲覧権限を取り消す。
+// 決済サービスを通していないため返金はできず、REFUNDEDへ直接遷移させる
+func (p *PaymentsUsecase) RevokeFreePayment(ctx context.Context, adminUserID, merchantPaymentID string) (string, error) {
+	payment, err := p.q.GetPaymentByMerchantPaymentID(ctx, merchantPaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrPaymentNotFound
+		}
+		return "", err
+	}
+	if payment.Provider != PaymentProviderFree || payment.Status != PaymentCompleted {
+		return "", ErrPaymentNotRevocable
+	}
+
+	if _, err := p.transitionPaymentTx(ctx, merchantPaymentID, paymentTransition{
+		To:     PaymentRefunded,
+		Source: PaymentSourceAdmin,
+		Detail: "free payment revoked by admin: user_id=" + adminUserID,
+	}); err != nil {
+		if errors.Is(err, ErrInvalidPaymentTransition) {
+			return "", ErrPaymentNotRevocable
+		}
+		return "", err
+	}
+	return PaymentRefunded, nil
+}
@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/payments"
+)
+
+// 決済のステータス
+const (
+	PaymentCreated   = "CREATED"               // 決済作成直後（支払いページ未発行）
+	PaymentAwaiting  = payments.StatusAwaiting // 支払いページ発行済みで支払い待ち
+	PaymentCompleted = payments.StatusCompleted
+	PaymentFailed    = payments.StatusFailed
+	PaymentCanceled  = payments.StatusCanceled
+	PaymentExpired   = payments.StatusExpired
+	PaymentRefunded  = payments.StatusRefunded
+)
+
+// ステータス遷移の発生元（監査ログ用）
+const (
+	PaymentSourceCheckout  = "checkout"
+	PaymentSourceConfirm   = "confirm"
+	PaymentSourceWebhook   = "webhook"
+	PaymentSourceReconcile = "reconcile"
+	PaymentSourceAdmin     = "admin"
+)
+
+var ErrInvalidPaymentTransition = errors.New("invalid payment transition")
+
+// 許可する遷移。ここに無い遷移は拒否する
+var paymentTransitions = map[string][]string{
+	PaymentCreated:  {PaymentAwaiting, PaymentCompleted, PaymentFailed, PaymentCanceled, PaymentExpired},
+	PaymentAwaiting: {PaymentCompleted, PaymentFailed, PaymentCanceled, PaymentExpired},
+	// EXPIREDは照合ジョブの判定で付くため、その後決済サービス側で完了していた場合は完了を優先する
+	PaymentExpired:   {PaymentCompleted},
+	PaymentCompleted: {PaymentRefunded},
+}
+
+func canTransitionPayment(from, to string) bool {
+	for _, s := range paymentTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// isPaymentPending は支払い待ち（照合対象）のステータスかを返す
+func isPaymentPending(status string) bool {
+	return status == PaymentCreated || status == PaymentAwaiting
+}
+
+type paymentTransition struct {
+	To                string
+	Source            string
+	ProviderPaymentID string // 決済サービス側の決済ID（分かる場合）
+	Detail            string
+}
+
+// transitionPayment は決済のステータスを遷移させて監査ログを記録し、COMPLETEDへの遷移では番組・商品の閲覧権限を付与
+// （贈り物は受け取り用のコードを発行）、REFUNDEDへの遷移では閲覧権限を取り消す。
+// 決済はqtxのトランザクション内でFOR UPDATE取得済みであること。同じステータスへの遷移は何もしない。
+// 戻り値は閲覧権限を新たに付与したかどうか
+func transitionPayment(ctx context.Context, qtx *db.Queries, payment db.Payment, tr paymentTransition) (bool, error) {
+	if tr.To == payment.Status {
+		return false, nil
+	}
+	if !canTransitionPayment(payment.Status, tr.To) {
+		return false, fmt.Errorf("%w: %s -> %s", ErrInvalidPaymentTransition, payment.Status, tr.To)
+	}
+
+	providerPaymentID := sql.NullString{String: tr.ProviderPaymentID, Valid: tr.ProviderPaymentID != ""}
+	affected, err := qtx.TransitionPaymentStatus(ctx, db.TransitionPaymentStatusParams{
+		ToStatus:          tr.To,
+		ProviderPaymentID: providerPaymentID,
+		MerchantPaymentID: payment.MerchantPaymentID,
+		FromStatus:        payment.Status,
+	})
+	if err != nil {
+		return false, err
+	}
+	if affected != 1 {
+		return false, fmt.Errorf("payment status changed concurrently: merchant_payment_id=%s", payment.MerchantPaymentID)
+	}
+
+	if err := qtx.InsertPaymentEvent(ctx, db.InsertPaymentEventParams{
+		PaymentID:         payment.ID,
+		FromStatus:        sql.NullString{String: payment.Status, Valid: true},
+		ToStatus:          tr.To,
+		Source:            tr.Source,
+		ProviderPaymentID: providerPaymentID,
+		Detail:            tr.Detail,
+	}); err != nil {
+		return false, err
+	}
+
+	// 全額返金されたら閲覧権限を取り消す
+	if tr.To == PaymentRefunded {
+		if payment.IsGift {
+			// 受け取りで買い切りに置き換えたレンタルは元の期限で戻す（下の取り消しの対象外になる）
+			if err := qtx.RestoreRentalReplacedByGift(ctx, payment.ID); err != nil {
+				return false, err
+			}
+			if err := qtx.RevokeGiftByPayment(ctx, payment.ID); err != nil {
+				return false, err
+			}
+		}
+		if payment.ProgramID.Valid {
+			// 後の購入・レンタルで上書きされた閲覧権限は残す
+			if err := qtx.RemovePermittedProgramUserByPayment(ctx, sql.NullInt64{Int64: payment.ID, Valid: true}); err != nil {
+				return false, err
+			}
+		}
+		if payment.ProductID.Valid {
+			if err := qtx.RevokeUserProductGrantByPayment(ctx, sql.NullInt64{Int64: payment.ID, Valid: true}); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	// 閲覧権限を付与（credited_atは上のUPDATEで記録済み）
+	if tr.To != PaymentCompleted || payment.CreditedAt.Valid {
+		return false, nil
+	}
+	// 初めて支払いが完了した決済に領収書番号を振る
+	if err := qtx.AssignPaymentReceiptNumber(ctx, payment.ID); err != nil {
+		return false, err
+	}
+	switch {
+	case payment.IsGift:
+		// 購入者には付与せず、受け取った人に付与する
+		if err := createGift(ctx, qtx, payment); err != nil {
+			return false, err
+		}
+		return false, nil
+	case payment.ProgramID.Valid && payment.PurchaseType == PurchaseTypeRental:
+		// レンタル期間は支払い完了時点から数える
+		if err := qtx.AddProgramRental(ctx, db.AddProgramRentalParams{
+			UserID:        payment.UserID,
+			ProgramID:     payment.ProgramID.Int64,
+			DurationHours: payment.RentalDurationHours.Int32,
+			PaymentID:     sql.NullInt64{Int64: payment.ID, Valid: true},
+		}); err != nil {
+			return false, err
+		}
+	case payment.ProgramID.Valid:
+		if err := qtx.AddPermittedProgramUser(ctx, db.AddPermittedProgramUserParams{
+			UserID:    payment.UserID,
+			ProgramID: payment.ProgramID.Int64,
+			PaymentID: sql.NullInt64{Int64: payment.ID, Valid: true},
+		}); err != nil {
+			return false, err
+		}
+	case payment.ProductID.Valid:
+		// passの有効期限は支払い完了時点から数える
+		if err := qtx.CreateUserProductGrant(ctx, db.CreateUserProductGrantParams{
+			UserID:    payment.UserID,
+			PaymentID: sql.NullInt64{Int64: payment.ID, Valid: true},
+			ProductID: payment.ProductID.Int64,
+		}); err != nil {
+			return false, err
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// transitionPaymentTx はトランザクションを張って決済をロックし、ステータスを遷移させる
+func (p *PaymentsUsecase) transitionPaymentTx(ctx context.Context, merchantPaymentID string, tr paymentTransition) (bool, error) {
+	tx, err := p.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := p.q.WithTx(tx)
+
+	payment, err := qtx.GetPaymentByMerchantPaymentIDForUpdate(ctx, merchantPaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrPaymentNotFound
+		}
+		return false, err
+	}
+
+	granted, err := transitionPayment(ctx, qtx, payment, tr)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return granted, nil
+}
+
+// applyProviderStatus は決済サービスのAPI・Webhookで得た状態を決済へ反映し、反映後のステータスと権限付与の有無を返す。
+// 変換できない状態や許可されない遷移はエラーにせずログに残す（決済サービス側の再送で詰まらないように）
+func applyProviderStatus(ctx context.Context, qtx *db.Queries, payment db.Payment, st payments.PaymentStatus, source string) (string, bool, error) {
+	if st.Status == "" {
+		log.Printf("[Payment] unknown provider state ignored: merchant_payment_id=%s, provider=%s, state=%s, source=%s", payment.MerchantPaymentID, payment.Provider, st.RawStatus, source)
+		return payment.Status, false, nil
+	}
+
+	granted, err := transitionPayment(ctx, qtx, payment, paymentTransition{
+		To:                st.Status,
+		Source:            source,
+		ProviderPaymentID: st.ProviderPaymentID,
+		Detail:            payment.Provider + " state " + st.RawStatus,
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidPaymentTransition) {
+			log.Printf("[Payment] transition rejected: merchant_payment_id=%s, source=%s, err=%v", payment.MerchantPaymentID, source, err)
+			return payment.Status, false, nil
+		}
+		return "", false, err
+	}
+	return st.Status, granted, nil
+}
@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionPayment(t *testing.T) {
+	// 正常系
+	assert.True(t, canTransitionPayment(PaymentCreated, PaymentAwaiting))
+	assert.True(t, canTransitionPayment(PaymentCreated, PaymentFailed))
+	assert.True(t, canTransitionPayment(PaymentAwaiting, PaymentCompleted))
+	assert.True(t, canTransitionPayment(PaymentAwaiting, PaymentExpired))
+	assert.True(t, canTransitionPayment(PaymentCompleted, PaymentRefunded))
+	// 照合ジョブで期限切れにした後の完了は受け付ける
+	assert.True(t, canTransitionPayment(PaymentExpired, PaymentCompleted))
+
+	// 確定後の巻き戻りは拒否する
+	assert.False(t, canTransitionPayment(PaymentCompleted, PaymentAwaiting))
+	assert.False(t, canTransitionPayment(PaymentCompleted, PaymentFailed))
+	assert.False(t, canTransitionPayment(PaymentFailed, PaymentCompleted))
+	assert.False(t, canTransitionPayment(PaymentCanceled, PaymentCompleted))
+	assert.False(t, canTransitionPayment(PaymentRefunded, PaymentCompleted))
+	assert.False(t, canTransitionPayment(PaymentAwaiting, PaymentRefunded))
+	assert.False(t, canTransitionPayment("UNKNOWN", PaymentCompleted))
+}
+
+func TestIsPaymentPending(t *testing.T) {
+	assert.True(t, isPaymentPending(PaymentCreated))
+	assert.True(t, isPaymentPending(PaymentAwaiting))
+	assert.False(t, isPaymentPending(PaymentCompleted))
+	assert.False(t, isPaymentPending(PaymentExpired))
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/payments"
+)
+
+// PaymentWebhookEventHandler は決済サービスのWebhookイベントごとの処理を行う
+func PaymentWebhookEventHandler(ctx context.Context, dbConn *sql.DB, q *db.Queries, provider payments.Provider, eventBody []byte) error {
+	ev, err := provider.ParseWebhook(eventBody)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[PaymentWebhook] received: provider=%s, type=%s, state=%s, merchant_payment_id=%s, provider_payment_id=%s",
+		provider.Name(), ev.Type, ev.RawStatus, ev.MerchantPaymentID, ev.ProviderPaymentID)
+
+	switch ev.Type {
+	case payments.WebhookPayment:
+	case payments.WebhookRefund:
+		return handlePaymentRefundNotification(ctx, dbConn, q, ev)
+	default:
+		log.Printf("[PaymentWebhook] ignoring notification: provider=%s", provider.Name())
+		return nil
+	}
+
+	tx, err := dbConn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := q.WithTx(tx)
+
+	payment, err := qtx.GetPaymentByMerchantPaymentIDForUpdate(ctx, ev.MerchantPaymentID)
+	if err != nil {
+		return fmt.Errorf("payment not found for merchant_payment_id=%s: %w", ev.MerchantPaymentID, err)
+	}
+	if payment.Provider != provider.Name() {
+		return fmt.Errorf("payment provider mismatch: merchant_payment_id=%s, payment=%s, webhook=%s", ev.MerchantPaymentID, payment.Provider, provider.Name())
+	}
+
+	_, granted, err := applyProviderStatus(ctx, qtx, payment, payments.PaymentStatus{
+		Status:            ev.Status,
+		RawStatus:         ev.RawStatus,
+		ProviderPaymentID: ev.ProviderPaymentID,
+	}, PaymentSourceWebhook)
+	if err != nil {
+		return err
+	}
+	if granted {
+		log.Printf("[PaymentWebhook] granted: merchant_payment_id=%s, user_id=%s, program_id=%d, product_id=%d", ev.MerchantPaymentID, payment.UserID, payment.ProgramID.Int64, payment.ProductID.Int64)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// handlePaymentRefundNotification は返金の完了・失敗通知を反映する
+func handlePaymentRefundNotification(ctx context.Context, dbConn *sql.DB, q *db.Queries, ev payments.WebhookEvent) error {
+	tx, err := dbConn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := q.WithTx(tx)
+
+	status := ev.Status
+	if status == "" {
+		// 変換できない状態はそのまま渡し、ログに残す
+		status = ev.RawStatus
+	}
+	refund, err := applyPaymentRefundStatus(ctx, qtx, ev.MerchantRefundID, status, "", PaymentSourceWebhook)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// 管理画面以外（決済サービスの加盟店ツール等）で行われた返金は記録が無い
+			log.Printf("[PaymentWebhook] unknown refund ignored: merchant_refund_id=%s, state=%s", ev.MerchantRefundID, ev.RawStatus)
+			return nil
+		}
+		return err
+	}
+	log.Printf("[PaymentWebhook] refund: merchant_refund_id=%s, status=%s", ev.MerchantRefundID, refund.Status)
+
+	return tx.Commit()
+}
@@ -0,0 +1,473 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/jobs"
+	"github.com/chan-shizu/SZer/internal/payments"
+)
+
+var ErrPaymentNotConfigured = payments.ErrNotConfigured
+var ErrUnknownPaymentProvider = payments.ErrUnknownProvider
+var ErrPaymentNotFound = errors.New("payment not found")
+var ErrNotPurchasable = errors.New("program is not purchasable")
+var ErrAlreadyPurchased = errors.New("already purchased")
+var ErrInvalidPurchaseType = errors.New("invalid purchase_type")
+
+// PaymentProviderFree は割引で請求額が0円になった決済の提供元（決済サービスを通さずに完了させる）
+const PaymentProviderFree = "free"
+
+// 番組単体の購入種別
+const (
+	PurchaseTypePurchase = "purchase" // 買い切り
+	PurchaseTypeRental   = "rental"   // 期間限定のレンタル
+)
+
+type PaymentsUsecase struct {
+	conn      *sql.DB
+	q         *db.Queries
+	providers *payments.Registry
+	jobs      jobs.Enqueuer // 決済後の非同期処理（照合など）を登録する
+
+	reconcileAfter time.Duration // 未完了の決済を照合対象にするまでの時間
+}
+
+// NewPaymentsUsecase は環境変数の設定で決済サービスを登録する
+func NewPaymentsUsecase(conn *sql.DB, q *db.Queries, enqueuer jobs.Enqueuer) *PaymentsUsecase {
+	return NewPaymentsUsecaseWithProviders(conn, q, enqueuer, NewPaymentProvidersFromEnv())
+}
+
+func NewPaymentsUsecaseWithProviders(conn *sql.DB, q *db.Queries, enqueuer jobs.Enqueuer, providers *payments.Registry) *PaymentsUsecase {
+	return &PaymentsUsecase{conn: conn, q: q, providers: providers, jobs: enqueuer, reconcileAfter: paymentReconcileAfterFromEnv()}
+}
+
+// NewPaymentProvidersFromEnv は利用する決済サービスを登録したRegistryを返す。
+// PayPayは設定が無くてもWebhookを解析できるよう常に登録する
+func NewPaymentProvidersFromEnv() *payments.Registry {
+	return payments.NewRegistry(payments.NewPayPayProviderFromEnv())
+}
+
+// provider はAPIを呼べる決済サービスを返す（nameが空ならPayPay）
+func (p *PaymentsUsecase) provider(name string) (payments.Provider, error) {
+	if name == "" {
+		name = payments.ProviderPayPay
+	}
+	provider, err := p.providers.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !provider.Configured() {
+		return nil, fmt.Errorf("%w: %s", ErrPaymentNotConfigured, name)
+	}
+	return provider, nil
+}
+
+type PaymentCheckoutResult struct {
+	MerchantPaymentID string
+	URL               string
+	Deeplink          string
+	AmountYen         int32 // 割引後の請求額
+	DiscountYen       int32
+	// 請求額が0円で、決済サービスを通さずに閲覧権限を付与した
+	Granted bool
+	// 請求額が0円の贈り物で発行した受け取り用のコード
+	GiftCode string
+}
+
+// Checkout は番組を購入（purchaseTypeが空なら買い切り）するための決済を作成する。providerNameが空ならPayPayで支払う。
+// couponCodeを指定すると割引を適用する
+func (p *PaymentsUsecase) Checkout(ctx context.Context, userID string, programID int64, purchaseType, providerName, couponCode, redirectBaseURL string) (PaymentCheckoutResult, error) {
+	if purchaseType == "" {
+		purchaseType = PurchaseTypePurchase
+	}
+	if purchaseType != PurchaseTypePurchase && purchaseType != PurchaseTypeRental {
+		return PaymentCheckoutResult{}, ErrInvalidPurchaseType
+	}
+
+	// 番組情報を取得
+	program, err := p.q.GetProgramForPurchase(ctx, programID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentCheckoutResult{}, ErrProgramNotFound
+		}
+		return PaymentCheckoutResult{}, err
+	}
+
+	// 購入可能かチェック（レンタルは価格と期間が設定されている番組のみ）
+	if !program.IsLimitedRelease || program.Price <= 0 {
+		return PaymentCheckoutResult{}, ErrNotPurchasable
+	}
+	amount := program.Price
+	var rentalHours sql.NullInt32
+	if purchaseType == PurchaseTypeRental {
+		if !program.RentalPrice.Valid || !program.RentalDurationHours.Valid {
+			return PaymentCheckoutResult{}, ErrNotPurchasable
+		}
+		amount = program.RentalPrice.Int32
+		rentalHours = program.RentalDurationHours
+	}
+
+	// 既に購入済みかチェック
+	permitted, err := p.q.IsUserPermittedForProgram(ctx, db.IsUserPermittedForProgramParams{
+		UserID:    userID,
+		ProgramID: programID,
+	})
+	if err != nil {
+		return PaymentCheckoutResult{}, err
+	}
+	if permitted {
+		// レンタル中の番組は買い切りにできる（商品による閲覧権限がある場合は購入済み扱い）
+		upgrade, err := isRentingProgram(ctx, p.q, userID, programID)
+		if err != nil {
+			return PaymentCheckoutResult{}, err
+		}
+		if purchaseType == PurchaseTypeRental || !upgrade {
+			return PaymentCheckoutResult{}, ErrAlreadyPurchased
+		}
+	}
+
+	merchantPaymentID, err := payments.RandomMerchantPaymentID()
+	if err != nil {
+		return PaymentCheckoutResult{}, err
+	}
+
+	description := "SZer program purchase"
+	if purchaseType == PurchaseTypeRental {
+		description = "SZer program rental"
+	}
+	return p.checkout(ctx, providerName, couponCode, db.CreatePaymentParams{
+		UserID:              userID,
+		MerchantPaymentID:   merchantPaymentID,
+		AmountYen:           amount,
+		ProgramID:           sql.NullInt64{Int64: programID, Valid: true},
+		PurchaseType:        purchaseType,
+		RentalDurationHours: rentalHours,
+	}, description, fmt.Sprintf("%s/programs/%d/paypay/return?merchantPaymentId=%s", redirectBaseURL, programID, merchantPaymentID))
+}
+
+// CheckoutProduct は商品（bundle・performer_pack・pass）を購入するための決済を作成する
+func (p *PaymentsUsecase) CheckoutProduct(ctx context.Context, userID string, productID int64, providerName, couponCode, redirectBaseURL string) (PaymentCheckoutResult, error) {
+	product, err := p.q.GetProduct(ctx, productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentCheckoutResult{}, ErrProductNotFound
+		}
+		return PaymentCheckoutResult{}, err
+	}
+	if !product.IsActive {
+		return PaymentCheckoutResult{}, ErrNotPurchasable
+	}
+
+	// 期限内の同じ商品を持っていれば購入できない
+	granted, err := p.q.HasActiveUserProductGrant(ctx, db.HasActiveUserProductGrantParams{
+		UserID:    userID,
+		ProductID: productID,
+	})
+	if err != nil {
+		return PaymentCheckoutResult{}, err
+	}
+	if granted {
+		return PaymentCheckoutResult{}, ErrAlreadyPurchased
+	}
+
+	merchantPaymentID, err := payments.RandomMerchantPaymentID()
+	if err != nil {
+		return PaymentCheckoutResult{}, err
+	}
+
+	return p.checkout(ctx, providerName, couponCode, db.CreatePaymentParams{
+		UserID:            userID,
+		MerchantPaymentID: merchantPaymentID,
+		AmountYen:         product.Price,
+		ProductID:         sql.NullInt64{Int64: productID, Valid: true},
+		PurchaseType:      PurchaseTypePurchase,
+	}, "SZer product purchase", fmt.Sprintf("%s/products/%d/paypay/return?merchantPaymentId=%s", redirectBaseURL, productID, merchantPaymentID))
+}
+
+// checkout は決済を作成して決済サービスの支払いページ・コードを発行する。
+// 割引で請求額が0円になった場合は決済サービスを通さずに閲覧権限を付与する
+func (p *PaymentsUsecase) checkout(ctx context.Context, providerName, couponCode string, arg db.CreatePaymentParams, description, redirectURL string) (PaymentCheckoutResult, error) {
+	merchantPaymentID := arg.MerchantPaymentID
+	provider, err := p.provider(providerName)
+	if err != nil {
+		// 全額割引なら決済サービスの設定が無くても購入できるため、請求額が決まるまで判定を保留する
+		if couponCode == "" || !errors.Is(err, ErrPaymentNotConfigured) {
+			return PaymentCheckoutResult{}, err
+		}
+		arg.Provider = ""
+	} else {
+		arg.Provider = provider.Name()
+	}
+
+	payment, granted, err := p.createPayment(ctx, arg, couponCode, err)
+	if err != nil {
+		return PaymentCheckoutResult{}, err
+	}
+	if payment.Provider == PaymentProviderFree {
+		giftCode, err := p.giftCode(ctx, payment)
+		if err != nil {
+			return PaymentCheckoutResult{}, err
+		}
+		return PaymentCheckoutResult{
+			MerchantPaymentID: merchantPaymentID,
+			DiscountYen:       payment.DiscountYen,
+			Granted:           granted,
+			GiftCode:          giftCode,
+		}, nil
+	}
+
+	checkout, err := provider.CreateCheckout(ctx, payments.CheckoutRequest{
+		MerchantPaymentID: merchantPaymentID,
+		AmountYen:         payment.AmountYen,
+		Description:       description,
+		RedirectURL:       redirectURL,
+	})
+	if err != nil {
+		if _, terr := p.transitionPaymentTx(ctx, merchantPaymentID, paymentTransition{
+			To:     PaymentFailed,
+			Source: PaymentSourceCheckout,
+			Detail: "create checkout failed: " + err.Error(),
+		}); terr != nil {
+			log.Printf("[PaymentCheckout] failed to mark payment failed: merchant_payment_id=%s, err=%v", merchantPaymentID, terr)
+		}
+		return PaymentCheckoutResult{}, err
+	}
+
+	// 支払いページは発行済みなので、記録に失敗しても照合ジョブで拾えるよう先に登録しておく
+	p.enqueueReconcile(ctx, merchantPaymentID)
+
+	if err := p.markPaymentAwaiting(ctx, arg.UserID, merchantPaymentID, checkout); err != nil {
+		return PaymentCheckoutResult{}, err
+	}
+
+	return PaymentCheckoutResult{
+		MerchantPaymentID: merchantPaymentID,
+		URL:               checkout.URL,
+		Deeplink:          checkout.Deeplink,
+		AmountYen:         payment.AmountYen,
+		DiscountYen:       payment.DiscountYen,
+	}, nil
+}
+
+type PaymentConfirmResult struct {
+	Status    string
+	ProgramID int64
+	ProductID int64
+	Granted   bool
+	GiftCode  string // 支払いが完了した贈り物の受け取り用のコード
+}
+
+func (p *PaymentsUsecase) ConfirmAndGrant(ctx context.Context, userID, merchantPaymentID string) (PaymentConfirmResult, error) {
+	if merchantPaymentID == "" {
+		return PaymentConfirmResult{}, errors.New("merchantPaymentId is required")
+	}
+
+	// 外部APIはトランザクションの外で呼ぶため、先に決済サービスを確認する
+	existing, err := p.q.GetPaymentByMerchantPaymentID(ctx, merchantPaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentConfirmResult{}, ErrPaymentNotFound
+		}
+		return PaymentConfirmResult{}, err
+	}
+	if existing.UserID != userID {
+		return PaymentConfirmResult{}, ErrPaymentNotFound
+	}
+	if existing.Provider == PaymentProviderFree {
+		// 作成時に完了済み（決済サービスには問い合わせない）
+		giftCode, err := p.giftCode(ctx, existing)
+		if err != nil {
+			return PaymentConfirmResult{}, err
+		}
+		return PaymentConfirmResult{
+			Status:    existing.Status,
+			ProgramID: existing.ProgramID.Int64,
+			ProductID: existing.ProductID.Int64,
+			GiftCode:  giftCode,
+		}, nil
+	}
+	provider, err := p.provider(existing.Provider)
+	if err != nil {
+		return PaymentConfirmResult{}, err
+	}
+	st, err := provider.GetStatus(ctx, merchantPaymentID)
+	if err != nil {
+		return PaymentConfirmResult{}, err
+	}
+
+	tx, err := p.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return PaymentConfirmResult{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := p.q.WithTx(tx)
+
+	payment, err := qtx.GetPaymentForUpdate(ctx, db.GetPaymentForUpdateParams{
+		UserID:            userID,
+		MerchantPaymentID: merchantPaymentID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PaymentConfirmResult{}, ErrPaymentNotFound
+		}
+		return PaymentConfirmResult{}, err
+	}
+
+	status, granted, err := applyProviderStatus(ctx, qtx, payment, st, PaymentSourceConfirm)
+	if err != nil {
+		return PaymentConfirmResult{}, err
+	}
+	programID := int64(0)
+	if payment.ProgramID.Valid {
+		programID = payment.ProgramID.Int64
+	}
+	productID := int64(0)
+	if payment.ProductID.Valid {
+		productID = payment.ProductID.Int64
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PaymentConfirmResult{}, err
+	}
+
+	payment.Status = status
+	giftCode, err := p.giftCode(ctx, payment)
+	if err != nil {
+		return PaymentConfirmResult{}, err
+	}
+
+	return PaymentConfirmResult{Status: status, ProgramID: programID, ProductID: productID, Granted: granted, GiftCode: giftCode}, nil
+}
+
+// isRentingProgram は番組単体の閲覧権限が期限内のレンタルかを返す
+func isRentingProgram(ctx context.Context, q *db.Queries, userID string, programID int64) (bool, error) {
+	rental, err := activeRental(ctx, q, userID, programID)
+	return rental != nil, err
+}
+
+// activeRental は期限内のレンタルの閲覧権限を返す（レンタル中でなければnil）
+func activeRental(ctx context.Context, q *db.Queries, userID string, programID int64) (*db.PermittedProgramUser, error) {
+	ppu, err := q.GetActivePermittedProgramUser(ctx, db.GetActivePermittedProgramUserParams{
+		UserID:    userID,
+		ProgramID: programID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if ppu.PurchaseType != PurchaseTypeRental {
+		return nil, nil
+	}
+	return &ppu, nil
+}
+
+// markPaymentAwaiting は発行した支払いページ・コードを記録し、決済を支払い待ちへ遷移させる（同一トランザクション）
+func (p *PaymentsUsecase) markPaymentAwaiting(ctx context.Context, userID, merchantPaymentID string, checkout payments.Checkout) error {
+	tx, err := p.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := p.q.WithTx(tx)
+
+	payment, err := qtx.GetPaymentForUpdate(ctx, db.GetPaymentForUpdateParams{
+		UserID:            userID,
+		MerchantPaymentID: merchantPaymentID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPaymentNotFound
+		}
+		return err
+	}
+
+	if checkout.CheckoutID != "" {
+		if err := qtx.SetPaymentCheckout(ctx, db.SetPaymentCheckoutParams{
+			UserID:             userID,
+			MerchantPaymentID:  merchantPaymentID,
+			ProviderCheckoutID: sql.NullString{String: checkout.CheckoutID, Valid: true},
+			CheckoutExpiresAt:  sql.NullTime{Time: checkout.ExpiresAt, Valid: !checkout.ExpiresAt.IsZero()},
+		}); err != nil {
+			return err
+		}
+	}
+
+	// 発行中にWebhook等で確定していれば、そちらを優先する
+	if isPaymentPending(payment.Status) {
+		if _, err := transitionPayment(ctx, qtx, payment, paymentTransition{
+			To:     PaymentAwaiting,
+			Source: PaymentSourceCheckout,
+			Detail: "checkout created",
+		}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// createPayment は決済を作成し、作成イベントを記録する。couponCodeを指定すると割引コードをロックして割引を適用し、
+// 請求額が0円になれば同じトランザクションで完了させて閲覧権限を付与する（戻り値は付与したかどうか）。
+// 決済サービスを使えない場合（arg.Providerが空）はproviderErrを返す
+func (p *PaymentsUsecase) createPayment(ctx context.Context, arg db.CreatePaymentParams, couponCode string, providerErr error) (db.Payment, bool, error) {
+	tx, err := p.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return db.Payment{}, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := p.q.WithTx(tx)
+
+	if couponCode != "" {
+		if err := applyCoupon(ctx, qtx, &arg, couponCode, time.Now()); err != nil {
+			return db.Payment{}, false, err
+		}
+	}
+	free := arg.AmountYen == 0
+	if free {
+		arg.Provider = PaymentProviderFree
+	} else if arg.Provider == "" {
+		return db.Payment{}, false, providerErr
+	}
+
+	payment, err := qtx.CreatePayment(ctx, arg)
+	if err != nil {
+		return db.Payment{}, false, err
+	}
+	if err := qtx.InsertPaymentEvent(ctx, db.InsertPaymentEventParams{
+		PaymentID: payment.ID,
+		ToStatus:  payment.Status,
+		Source:    PaymentSourceCheckout,
+		Detail:    "payment created via " + payment.Provider,
+	}); err != nil {
+		return db.Payment{}, false, err
+	}
+
+	granted := false
+	if free {
+		granted, err = transitionPayment(ctx, qtx, payment, paymentTransition{
+			To:     PaymentCompleted,
+			Source: PaymentSourceCheckout,
+			Detail: "fully discounted by coupon",
+		})
+		if err != nil {
+			return db.Payment{}, false, err
+		}
+		payment.Status = PaymentCompleted
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.Payment{}, false, err
+	}
+	return payment, granted, nil
+}
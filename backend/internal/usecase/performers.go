@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/search"
+)
+
+var ErrPerformerNotFound = errors.New("performer not found")
+
+type PerformerListItem struct {
+	ID           int64   `json:"id"`
+	FullName     string  `json:"full_name"`
+	FullNameKana string  `json:"full_name_kana"`
+	ImageUrl     *string `json:"image_url"`
+	ProgramCount int64   `json:"program_count"`
+}
+
+// PerformerListPage はカーソルページングされた出演者一覧（五十音順）
+type PerformerListPage struct {
+	Performers []PerformerListItem `json:"performers"`
+	NextCursor *string             `json:"next_cursor"`
+}
+
+type PerformerDetail struct {
+	ID            int64     `json:"id"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	FirstNameKana string    `json:"first_name_kana"`
+	LastNameKana  string    `json:"last_name_kana"`
+	FullName      string    `json:"full_name"`
+	FullNameKana  string    `json:"full_name_kana"`
+	ImageUrl      *string   `json:"image_url"`
+	ProgramCount  int64     `json:"program_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type PerformersUsecase struct {
+	q *db.Queries
+}
+
+func NewPerformersUsecase(q *db.Queries) *PerformersUsecase {
+	return &PerformersUsecase{q: q}
+}
+
+// ListPerformers は出演者を五十音順に返す。nameは氏名（漢字・かな・カナ）の部分一致
+func (u *PerformersUsecase) ListPerformers(ctx context.Context, name string, page PageRequest) (PerformerListPage, error) {
+	cursorKana, cursorID, limit, err := page.nameCursorParams()
+	if err != nil {
+		return PerformerListPage{}, err
+	}
+	arg := db.ListPerformersParams{CursorKana: cursorKana, CursorID: cursorID, PageLimit: limit}
+
+	// 「山田 太郎」「ﾔﾏﾀﾞ」なども一致するよう正規化し、空白を除く
+	normalized := strings.Join(strings.Fields(search.Normalize(name)), "")
+	if normalized != "" {
+		escaped := escapeLikePattern(normalized)
+		arg.Name = sql.NullString{String: escaped, Valid: true}
+		arg.KanaHiragana = sql.NullString{String: escaped, Valid: true}
+		arg.KanaKatakana = sql.NullString{String: search.ToKatakana(escaped), Valid: true}
+	}
+
+	rows, err := u.q.ListPerformers(ctx, arg)
+	if err != nil {
+		return PerformerListPage{}, err
+	}
+	next := nextCursor(len(rows), page, func(i int) pageCursor {
+		return pageCursor{Name: rows[i].SortKana, ID: rows[i].ID}
+	})
+	if next != nil {
+		rows = rows[:page.normalizedLimit()]
+	}
+
+	results := make([]PerformerListItem, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, PerformerListItem{
+			ID:           row.ID,
+			FullName:     row.LastName + row.FirstName,
+			FullNameKana: row.LastNameKana + row.FirstNameKana,
+			ImageUrl:     buildPublicFileURLPtr(nullStringPtr(row.ImagePath)),
+			ProgramCount: row.ProgramCount,
+		})
+	}
+	return PerformerListPage{Performers: results, NextCursor: next}, nil
+}
+
+func (u *PerformersUsecase) GetPerformer(ctx context.Context, performerID int64) (PerformerDetail, error) {
+	row, err := u.q.GetPerformerByID(ctx, performerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PerformerDetail{}, ErrPerformerNotFound
+		}
+		return PerformerDetail{}, err
+	}
+	return PerformerDetail{
+		ID:            row.ID,
+		FirstName:     row.FirstName,
+		LastName:      row.LastName,
+		FirstNameKana: row.FirstNameKana,
+		LastNameKana:  row.LastNameKana,
+		FullName:      row.LastName + row.FirstName,
+		FullNameKana:  row.LastNameKana + row.FirstNameKana,
+		ImageUrl:      buildPublicFileURLPtr(nullStringPtr(row.ImagePath)),
+		ProgramCount:  row.ProgramCount,
+		CreatedAt:     row.CreatedAt,
+		UpdatedAt:     row.UpdatedAt,
+	}, nil
+}
+
+// ListPerformerPrograms は出演者の公開番組を新しい順に返す
+func (u *PerformersUsecase) ListPerformerPrograms(ctx context.Context, performerID int64, page PageRequest) (ProgramListPage, error) {
+	cursorAt, cursorID, limit, err := page.cursorParams()
+	if err != nil {
+		return ProgramListPage{}, err
+	}
+	if _, err := u.q.GetPerformerByID(ctx, performerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ProgramListPage{}, ErrPerformerNotFound
+		}
+		return ProgramListPage{}, err
+	}
+
+	rows, err := u.q.ListProgramsByPerformerID(ctx, db.ListProgramsByPerformerIDParams{
+		PerformerID:     performerID,
+		CursorCreatedAt: cursorAt,
+		CursorID:        cursorID,
+		PageLimit:       limit,
+	})
+	if err != nil {
+		return ProgramListPage{}, err
+	}
+	next := nextCursor(len(rows), page, func(i int) pageCursor {
+		return pageCursor{At: rows[i].ProgramCreatedAt, ID: rows[i].ProgramID}
+	})
+	if next != nil {
+		rows = rows[:page.normalizedLimit()]
+	}
+
+	results := make([]ProgramListItem, 0, len(rows))
+	for _, row := range rows {
+		categoryTagsJSON, err := normalizeJSONBytes(row.CategoryTags)
+		if err != nil {
+			return ProgramListPage{}, err
+		}
+		var categoryTags []ProgramDetailsCategoryTag
+		if err := json.Unmarshal(categoryTagsJSON, &categoryTags); err != nil {
+			return ProgramListPage{}, err
+		}
+
+		results = append(results, ProgramListItem{
+			ProgramID:        row.ProgramID,
+			Title:            row.Title,
+			ViewCount:        int64(row.ViewCount),
+			LikeCount:        row.LikeCount,
+			IsLimitedRelease: row.IsLimitedRelease,
+			Price:            row.Price,
+			ThumbnailUrl:     buildPublicFileURLPtr(nullStringPtr(row.ThumbnailPath)),
+			CategoryTags:     categoryTags,
+		})
+	}
+	return ProgramListPage{Programs: results, NextCursor: next}, nil
+}
+
+// private functions
+
+// escapeLikePattern はILIKEの部分一致用に % _ \ をエスケープする
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
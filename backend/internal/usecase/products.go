@@ -0,0 +1,315 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+// 商品の種類
+const (
+	// 指定した番組をまとめた商品
+	ProductKindBundle = "bundle"
+	// 出演者の限定公開番組すべて（購入後に追加された番組も含む）
+	ProductKindPerformerPack = "performer_pack"
+	// 期間中すべての限定公開番組
+	ProductKindPass = "pass"
+)
+
+var ErrProductNotFound = errors.New("product not found")
+var ErrInvalidProduct = errors.New("invalid product")
+
+// 商品名の最大長
+const maxProductNameLength = 100
+
+type ProductInput struct {
+	// 更新時は省略可（種類は変更できない）
+	Kind         string
+	Name         string
+	Description  string
+	Price        int32
+	PerformerID  *int64
+	DurationDays *int32
+	// nilの場合は作成時は販売中、更新時は変更しない
+	IsActive   *bool
+	ProgramIDs []int64
+}
+
+type Product struct {
+	ID           int64     `json:"id"`
+	Kind         string    `json:"kind"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	Price        int32     `json:"price"`
+	PerformerID  *int64    `json:"performer_id"`
+	DurationDays *int32    `json:"duration_days"`
+	IsActive     bool      `json:"is_active"`
+	ProgramIDs   []int64   `json:"program_ids,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type UserProductGrant struct {
+	GrantID   int64      `json:"grant_id"`
+	Product   Product    `json:"product"`
+	GrantedAt time.Time  `json:"granted_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ProductsUsecase struct {
+	conn *sql.DB
+	q    *db.Queries
+}
+
+func NewProductsUsecase(conn *sql.DB, q *db.Queries) *ProductsUsecase {
+	return &ProductsUsecase{conn: conn, q: q}
+}
+
+func toProduct(p db.Product) Product {
+	product := Product{
+		ID:          p.ID,
+		Kind:        p.Kind,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		IsActive:    p.IsActive,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+	if p.PerformerID.Valid {
+		product.PerformerID = &p.PerformerID.Int64
+	}
+	if p.DurationDays.Valid {
+		product.DurationDays = &p.DurationDays.Int32
+	}
+	return product
+}
+
+// ListProducts は商品を返す（activeOnlyなら販売中のもののみ）
+func (u *ProductsUsecase) ListProducts(ctx context.Context, activeOnly bool) ([]Product, error) {
+	rows, err := u.q.ListProducts(ctx, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	products := make([]Product, 0, len(rows))
+	for _, row := range rows {
+		products = append(products, toProduct(row))
+	}
+	return products, nil
+}
+
+// GetProduct は商品を返す。bundleは含まれる番組IDも返す（activeOnlyなら販売停止中の商品は見つからない扱い）
+func (u *ProductsUsecase) GetProduct(ctx context.Context, productID int64, activeOnly bool) (Product, error) {
+	product, err := getProduct(ctx, u.q, productID)
+	if err != nil {
+		return Product{}, err
+	}
+	if activeOnly && !product.IsActive {
+		return Product{}, ErrProductNotFound
+	}
+	return product, nil
+}
+
+// ListMyProducts はユーザーが閲覧権限を持っている（期限内で返金されていない）商品を返す
+func (u *ProductsUsecase) ListMyProducts(ctx context.Context, userID string) ([]UserProductGrant, error) {
+	rows, err := u.q.ListActiveUserProductGrants(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	grants := make([]UserProductGrant, 0, len(rows))
+	for _, row := range rows {
+		grant := UserProductGrant{
+			GrantID:   row.GrantID,
+			Product:   toProduct(row.Product),
+			GrantedAt: row.GrantedAt,
+		}
+		if row.ExpiresAt.Valid {
+			grant.ExpiresAt = &row.ExpiresAt.Time
+		}
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+func (u *ProductsUsecase) CreateProduct(ctx context.Context, in ProductInput) (Product, error) {
+	in, err := validateProductInput(in)
+	if err != nil {
+		return Product{}, err
+	}
+	isActive := in.IsActive == nil || *in.IsActive
+
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return Product{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := u.q.WithTx(tx)
+
+	product, err := qtx.CreateProduct(ctx, db.CreateProductParams{
+		Kind:         in.Kind,
+		Name:         in.Name,
+		Description:  in.Description,
+		Price:        in.Price,
+		PerformerID:  nullInt64FromPtr(in.PerformerID),
+		DurationDays: nullInt32FromPtr(in.DurationDays),
+		IsActive:     isActive,
+	})
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return Product{}, ErrPerformerNotFound
+		}
+		return Product{}, err
+	}
+	if err := replaceProductPrograms(ctx, qtx, product.ID, in.ProgramIDs); err != nil {
+		return Product{}, err
+	}
+
+	res, err := getProduct(ctx, qtx, product.ID)
+	if err != nil {
+		return Product{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Product{}, err
+	}
+	return res, nil
+}
+
+func (u *ProductsUsecase) UpdateProduct(ctx context.Context, productID int64, in ProductInput) (Product, error) {
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return Product{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := u.q.WithTx(tx)
+
+	current, err := qtx.GetProduct(ctx, productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Product{}, ErrProductNotFound
+		}
+		return Product{}, err
+	}
+	// 購入済みの閲覧権限の範囲が変わらないよう、種類は変更できない
+	if in.Kind != "" && in.Kind != current.Kind {
+		return Product{}, ErrInvalidProduct
+	}
+	in.Kind = current.Kind
+	in, err = validateProductInput(in)
+	if err != nil {
+		return Product{}, err
+	}
+	isActive := current.IsActive
+	if in.IsActive != nil {
+		isActive = *in.IsActive
+	}
+
+	if _, err := qtx.UpdateProduct(ctx, db.UpdateProductParams{
+		ID:           productID,
+		Name:         in.Name,
+		Description:  in.Description,
+		Price:        in.Price,
+		PerformerID:  nullInt64FromPtr(in.PerformerID),
+		DurationDays: nullInt32FromPtr(in.DurationDays),
+		IsActive:     isActive,
+	}); err != nil {
+		if isForeignKeyViolation(err) {
+			return Product{}, ErrPerformerNotFound
+		}
+		return Product{}, err
+	}
+	if err := replaceProductPrograms(ctx, qtx, productID, in.ProgramIDs); err != nil {
+		return Product{}, err
+	}
+
+	res, err := getProduct(ctx, qtx, productID)
+	if err != nil {
+		return Product{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Product{}, err
+	}
+	return res, nil
+}
+
+// private functions
+
+func getProduct(ctx context.Context, q *db.Queries, productID int64) (Product, error) {
+	row, err := q.GetProduct(ctx, productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Product{}, ErrProductNotFound
+		}
+		return Product{}, err
+	}
+	product := toProduct(row)
+	if product.Kind == ProductKindBundle {
+		ids, err := q.ListProductProgramIDs(ctx, productID)
+		if err != nil {
+			return Product{}, err
+		}
+		product.ProgramIDs = ids
+	}
+	return product, nil
+}
+
+// replaceProductPrograms はbundleに含まれる番組を指定されたIDで置き換える
+func replaceProductPrograms(ctx context.Context, qtx *db.Queries, productID int64, programIDs []int64) error {
+	if err := qtx.DeleteProductPrograms(ctx, productID); err != nil {
+		return err
+	}
+	for _, programID := range uniqueInt64s(programIDs) {
+		err := qtx.CreateProductProgram(ctx, db.CreateProductProgramParams{ProductID: productID, ProgramID: programID})
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrProgramNotFound
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProductInput は種類ごとに必要な項目だけが指定されているかを検証する
+func validateProductInput(in ProductInput) (ProductInput, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Description = strings.TrimSpace(in.Description)
+
+	if in.Name == "" || len([]rune(in.Name)) > maxProductNameLength || in.Price <= 0 {
+		return in, ErrInvalidProduct
+	}
+	switch in.Kind {
+	case ProductKindBundle:
+		if len(in.ProgramIDs) == 0 || in.PerformerID != nil || in.DurationDays != nil {
+			return in, ErrInvalidProduct
+		}
+	case ProductKindPerformerPack:
+		if in.PerformerID == nil || len(in.ProgramIDs) > 0 || in.DurationDays != nil {
+			return in, ErrInvalidProduct
+		}
+	case ProductKindPass:
+		if in.DurationDays == nil || *in.DurationDays <= 0 || len(in.ProgramIDs) > 0 || in.PerformerID != nil {
+			return in, ErrInvalidProduct
+		}
+	default:
+		return in, ErrInvalidProduct
+	}
+	return in, nil
+}
+
+func nullInt64FromPtr(v *int64) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *v, Valid: true}
+}
+
+func nullInt32FromPtr(v *int32) sql.NullInt32 {
+	if v == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *v, Valid: true}
+}
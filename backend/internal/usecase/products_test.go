@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateProductInput(t *testing.T) {
+	performerID := int64(1)
+	days := int32(30)
+	zeroDays := int32(0)
+
+	tests := []struct {
+		name string
+		in   ProductInput
+		ok   bool
+	}{
+		{"bundle", ProductInput{Kind: ProductKindBundle, Name: " まとめ買い ", Price: 1000, ProgramIDs: []int64{1, 2}}, true},
+		{"bundle without programs", ProductInput{Kind: ProductKindBundle, Name: "まとめ買い", Price: 1000}, false},
+		{"bundle with duration", ProductInput{Kind: ProductKindBundle, Name: "まとめ買い", Price: 1000, ProgramIDs: []int64{1}, DurationDays: &days}, false},
+		{"performer pack", ProductInput{Kind: ProductKindPerformerPack, Name: "出演者パック", Price: 2000, PerformerID: &performerID}, true},
+		{"performer pack without performer", ProductInput{Kind: ProductKindPerformerPack, Name: "出演者パック", Price: 2000}, false},
+		{"pass", ProductInput{Kind: ProductKindPass, Name: "30日パス", Price: 3000, DurationDays: &days}, true},
+		{"pass without duration", ProductInput{Kind: ProductKindPass, Name: "30日パス", Price: 3000}, false},
+		{"pass with zero duration", ProductInput{Kind: ProductKindPass, Name: "30日パス", Price: 3000, DurationDays: &zeroDays}, false},
+		{"free", ProductInput{Kind: ProductKindPass, Name: "30日パス", Price: 0, DurationDays: &days}, false},
+		{"empty name", ProductInput{Kind: ProductKindPass, Name: "  ", Price: 3000, DurationDays: &days}, false},
+		{"unknown kind", ProductInput{Kind: "subscription", Name: "サブスク", Price: 3000}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateProductInput(tt.in)
+			if tt.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidProduct) {
+				t.Errorf("expected ErrInvalidProduct, got %v", err)
+			}
+		})
+	}
+
+	in, _ := validateProductInput(tests[0].in)
+	if in.Name != "まとめ買い" {
+		t.Errorf("expected trimmed name, got %q", in.Name)
+	}
+}
@@ -15,6 +15,43 @@ import (
 )
 
 var ErrProgramNotFound = errors.New("program not found")
+var ErrInvalidProgramSort = errors.New("invalid sort")
+var ErrInvalidPriceType = errors.New("invalid price_type")
+var ErrInvalidTagMatch = errors.New("invalid tag_match")
+var ErrInvalidCreatedRange = errors.New("invalid created range")
+
+// 番組一覧の並び順
+const (
+	ProgramSortNewest     = "newest"
+	ProgramSortMostViewed = "most_viewed"
+	ProgramSortMostLiked  = "most_liked"
+	ProgramSortPriceAsc   = "price_asc"
+	ProgramSortPriceDesc  = "price_desc"
+)
+
+// 番組一覧の料金フィルタ（paid: 購入が必要な限定公開、free: それ以外）
+const (
+	PriceTypeFree = "free"
+	PriceTypePaid = "paid"
+)
+
+// 番組一覧のタグの絞り込み方（all: 全てのタグを持つ、any: いずれかのタグを持つ）
+const (
+	TagMatchAll = "all"
+	TagMatchAny = "any"
+)
+
+// ProgramListFilter は番組一覧の絞り込み・並び順（空文字・nilは指定なし）
+type ProgramListFilter struct {
+	Title        string
+	TagIDs       []int64
+	TagMatch     string
+	PerformerIDs []int64
+	PriceType    string
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	Sort         string
+}
 
 type ProgramDetailsCategoryTag struct {
 	ID   int64  `json:"id"`
@@ -59,6 +96,10 @@ type ProgramDetail struct {
 	CategoryTags     []ProgramDetailsCategoryTag `json:"category_tags"`
 	Performers       []ProgramDetailsPerformer   `json:"performers"`
 	WatchHistory     *ProgramWatchHistory        `json:"watch_history"`
+
+	// レンタル不可の番組はnull
+	RentalPrice         *int32 `json:"rental_price"`
+	RentalDurationHours *int32 `json:"rental_duration_hours"`
 }
 
 type ProgramListItem struct {
@@ -70,6 +111,21 @@ type ProgramListItem struct {
 	Price            int32                       `json:"price"`
 	ThumbnailUrl     *string                     `json:"thumbnail_url"`
 	CategoryTags     []ProgramDetailsCategoryTag `json:"category_tags"`
+	// 購入済み一覧のみ（purchase / rental、レンタルは期限も返す）
+	PurchaseType string     `json:"purchase_type,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// ProgramRental は期限内のレンタルの残り時間
+type ProgramRental struct {
+	ExpiresAt        time.Time `json:"expires_at"`
+	RemainingSeconds int64     `json:"remaining_seconds"`
+}
+
+// ProgramListPage はカーソルページングされた番組一覧
+type ProgramListPage struct {
+	Programs   []ProgramListItem `json:"programs"`
+	NextCursor *string           `json:"next_cursor"`
 }
 
 type TopProgramItem struct {
@@ -80,15 +136,19 @@ type TopProgramItem struct {
 	IsLimitedRelease bool    `json:"is_limited_release"`
 	Price            int32   `json:"price"`
 	ThumbnailUrl     *string `json:"thumbnail_url"`
+	// 期間指定ランキングのみ（期間内の視聴数/いいね数、トレンドは減衰後のスコア）
+	Score *float64 `json:"score,omitempty"`
 }
 
 type ProgramsUsecase struct {
 	q      *db.Queries
 	signer *cfutil.VideoURLSigner
+	// バックグラウンドで記録中の詳細閲覧（RecordDetailView参照）
+	detailViewSlots chan struct{}
 }
 
 func NewProgramsUsecase(q *db.Queries, signer *cfutil.VideoURLSigner) *ProgramsUsecase {
-	return &ProgramsUsecase{q: q, signer: signer}
+	return &ProgramsUsecase{q: q, signer: signer, detailViewSlots: make(chan struct{}, maxPendingDetailViews)}
 }
 
 func (u *ProgramsUsecase) UpsertWatchHistory(ctx context.Context, userID string, programID int64, positionSeconds int32, isCompleted bool) (db.WatchHistory, error) {
@@ -118,7 +178,7 @@ func (u *ProgramsUsecase) UpsertWatchHistory(ctx context.Context, userID string,
 		PositionSeconds: positionSeconds,
 		IsCompleted:     isCompleted,
 	})
-	
+
 	if err != nil {
 		log.Printf("[UpsertWatchHistory] UPDATE失敗: %v", err)
 	} else {
@@ -183,21 +243,23 @@ func (u *ProgramsUsecase) GetProgramDetails(ctx context.Context, userID string,
 	}
 
 	resp := ProgramDetail{
-		ProgramID:        program.ProgramID,
-		Title:            program.Title,
-		VideoURL:         u.buildVideoURL(program.VideoPath),
-		ViewCount:        int64(program.ViewCount),
-		LikeCount:        program.LikeCount,
-		Liked:            program.Liked,
-		IsLimitedRelease: program.IsLimitedRelease,
-		Price:            program.Price,
-		ThumbnailUrl:     buildPublicFileURLPtr(nullStringPtr(program.ThumbnailPath)),
-		Description:      nullStringPtr(program.Description),
-		ProgramCreatedAt: program.ProgramCreatedAt,
-		ProgramUpdatedAt: program.ProgramUpdatedAt,
-		CategoryTags:     categoryTags,
-		Performers:       performers,
-		WatchHistory:     watchHistory,
+		ProgramID:           program.ProgramID,
+		Title:               program.Title,
+		VideoURL:            u.buildVideoURL(program.VideoPath),
+		ViewCount:           int64(program.ViewCount),
+		LikeCount:           program.LikeCount,
+		Liked:               program.Liked,
+		IsLimitedRelease:    program.IsLimitedRelease,
+		Price:               program.Price,
+		RentalPrice:         nullInt32Ptr(program.RentalPrice),
+		RentalDurationHours: nullInt32Ptr(program.RentalDurationHours),
+		ThumbnailUrl:        buildPublicFileURLPtr(nullStringPtr(program.ThumbnailPath)),
+		Description:         nullStringPtr(program.Description),
+		ProgramCreatedAt:    program.ProgramCreatedAt,
+		ProgramUpdatedAt:    program.ProgramUpdatedAt,
+		CategoryTags:        categoryTags,
+		Performers:          performers,
+		WatchHistory:        watchHistory,
 	}
 	return resp, nil
 }
@@ -242,44 +304,90 @@ func (u *ProgramsUsecase) UnlikeProgram(ctx context.Context, userID string, prog
 	return false, likeCount, nil
 }
 
-func (u *ProgramsUsecase) ListPrograms(ctx context.Context, title string, tagIDs []int64) ([]ProgramListItem, error) {
-	arg := db.GetProgramsParams{}
-	if title != "" {
-		arg.Title = sql.NullString{String: title, Valid: true}
+func (u *ProgramsUsecase) ListPrograms(ctx context.Context, filter ProgramListFilter, page PageRequest) (ProgramListPage, error) {
+	arg, err := buildGetProgramsParams(filter)
+	if err != nil {
+		return ProgramListPage{}, err
 	}
-	if len(tagIDs) > 0 {
-		arg.TagIds = tagIDs
+	if arg.Sort == ProgramSortNewest {
+		return u.listProgramsNewest(ctx, arg, page)
+	}
+	arg.CursorSortKey, arg.CursorID, arg.PageLimit, err = page.sortKeyCursorParams(arg.Sort)
+	if err != nil {
+		return ProgramListPage{}, err
 	}
 
 	programs, err := u.q.GetPrograms(ctx, arg)
 	if err != nil {
-		return nil, err
+		return ProgramListPage{}, err
+	}
+	next := nextCursor(len(programs), page, func(i int) pageCursor {
+		return pageCursor{Sort: arg.Sort, Key: programs[i].SortKey, ID: programs[i].ProgramID}
+	})
+	if next != nil {
+		programs = programs[:page.normalizedLimit()]
 	}
 
-	var results []ProgramListItem
+	results := make([]ProgramListItem, 0, len(programs))
 	for _, program := range programs {
-		categoryTagsJSON, err := normalizeJSONBytes(program.CategoryTags)
-		if err != nil {
-			return nil, err
-		}
-		var categoryTags []ProgramDetailsCategoryTag
-		if err := json.Unmarshal(categoryTagsJSON, &categoryTags); err != nil {
-			return nil, err // またはログを出して空配列にするなど、エラーハンドリングポリシーによる
-		}
-
-		results = append(results, ProgramListItem{
+		item, err := toProgramListItem(db.GetProgramsNewestRow{
 			ProgramID:        program.ProgramID,
 			Title:            program.Title,
-			ViewCount:        int64(program.ViewCount),
-			LikeCount:        program.LikeCount,
+			ThumbnailPath:    program.ThumbnailPath,
+			ViewCount:        program.ViewCount,
 			IsLimitedRelease: program.IsLimitedRelease,
 			Price:            program.Price,
-			ThumbnailUrl:     buildPublicFileURLPtr(nullStringPtr(program.ThumbnailPath)),
-			CategoryTags:     categoryTags,
+			ProgramCreatedAt: program.ProgramCreatedAt,
+			LikeCount:        program.LikeCount,
+			CategoryTags:     program.CategoryTags,
 		})
+		if err != nil {
+			return ProgramListPage{}, err
+		}
+		results = append(results, item)
 	}
 
-	return results, nil
+	return ProgramListPage{Programs: results, NextCursor: next}, nil
+}
+
+// listProgramsNewest は新着順の一覧を (created_at, id) のカーソルで返す
+func (u *ProgramsUsecase) listProgramsNewest(ctx context.Context, filter db.GetProgramsParams, page PageRequest) (ProgramListPage, error) {
+	arg := db.GetProgramsNewestParams{
+		Title:        filter.Title,
+		TagIds:       filter.TagIds,
+		TagMatchAny:  filter.TagMatchAny,
+		PerformerIds: filter.PerformerIds,
+		IsPaid:       filter.IsPaid,
+		CreatedFrom:  filter.CreatedFrom,
+		CreatedTo:    filter.CreatedTo,
+	}
+	var err error
+	arg.CursorCreatedAt, arg.CursorID, arg.PageLimit, err = page.cursorParams()
+	if err != nil {
+		return ProgramListPage{}, err
+	}
+
+	programs, err := u.q.GetProgramsNewest(ctx, arg)
+	if err != nil {
+		return ProgramListPage{}, err
+	}
+	next := nextCursor(len(programs), page, func(i int) pageCursor {
+		return pageCursor{Sort: ProgramSortNewest, At: programs[i].ProgramCreatedAt, ID: programs[i].ProgramID}
+	})
+	if next != nil {
+		programs = programs[:page.normalizedLimit()]
+	}
+
+	results := make([]ProgramListItem, 0, len(programs))
+	for _, program := range programs {
+		item, err := toProgramListItem(program)
+		if err != nil {
+			return ProgramListPage{}, err
+		}
+		results = append(results, item)
+	}
+
+	return ProgramListPage{Programs: results, NextCursor: next}, nil
 }
 
 func (u *ProgramsUsecase) ListTopPrograms(ctx context.Context) ([]TopProgramItem, error) {
@@ -348,21 +456,31 @@ func (u *ProgramsUsecase) ListTopViewedPrograms(ctx context.Context) ([]TopProgr
 	return results, nil
 }
 
-func (u *ProgramsUsecase) ListWatchingPrograms(ctx context.Context, userID string) ([]ProgramListItem, error) {
-	rows, err := u.q.ListWatchingProgramsByUser(ctx, db.ListWatchingProgramsByUserParams{UserID: userID})
+func (u *ProgramsUsecase) ListWatchingPrograms(ctx context.Context, userID string, page PageRequest) (ProgramListPage, error) {
+	cursorAt, cursorID, limit, err := page.cursorParams()
 	if err != nil {
-		return nil, err
+		return ProgramListPage{}, err
+	}
+	rows, err := u.q.ListWatchingProgramsByUser(ctx, db.ListWatchingProgramsByUserParams{UserID: userID, CursorAt: cursorAt, CursorID: cursorID, PageLimit: limit})
+	if err != nil {
+		return ProgramListPage{}, err
+	}
+	next := nextCursor(len(rows), page, func(i int) pageCursor {
+		return pageCursor{At: rows[i].CursorAt, ID: rows[i].CursorID}
+	})
+	if next != nil {
+		rows = rows[:page.normalizedLimit()]
 	}
 
 	results := make([]ProgramListItem, 0, len(rows))
 	for _, row := range rows {
 		categoryTagsJSON, err := normalizeJSONBytes(row.CategoryTags)
 		if err != nil {
-			return nil, err
+			return ProgramListPage{}, err
 		}
 		var categoryTags []ProgramDetailsCategoryTag
 		if err := json.Unmarshal(categoryTagsJSON, &categoryTags); err != nil {
-			return nil, err
+			return ProgramListPage{}, err
 		}
 
 		results = append(results, ProgramListItem{
@@ -377,24 +495,34 @@ func (u *ProgramsUsecase) ListWatchingPrograms(ctx context.Context, userID strin
 		})
 	}
 
-	return results, nil
+	return ProgramListPage{Programs: results, NextCursor: next}, nil
 }
 
-func (u *ProgramsUsecase) ListLikedPrograms(ctx context.Context, userID string) ([]ProgramListItem, error) {
-	rows, err := u.q.ListLikedProgramsByUser(ctx, db.ListLikedProgramsByUserParams{UserID: userID})
+func (u *ProgramsUsecase) ListLikedPrograms(ctx context.Context, userID string, page PageRequest) (ProgramListPage, error) {
+	cursorAt, cursorID, limit, err := page.cursorParams()
 	if err != nil {
-		return nil, err
+		return ProgramListPage{}, err
+	}
+	rows, err := u.q.ListLikedProgramsByUser(ctx, db.ListLikedProgramsByUserParams{UserID: userID, CursorAt: cursorAt, CursorID: cursorID, PageLimit: limit})
+	if err != nil {
+		return ProgramListPage{}, err
+	}
+	next := nextCursor(len(rows), page, func(i int) pageCursor {
+		return pageCursor{At: rows[i].CursorAt, ID: rows[i].CursorID}
+	})
+	if next != nil {
+		rows = rows[:page.normalizedLimit()]
 	}
 
 	results := make([]ProgramListItem, 0, len(rows))
 	for _, row := range rows {
 		categoryTagsJSON, err := normalizeJSONBytes(row.CategoryTags)
 		if err != nil {
-			return nil, err
+			return ProgramListPage{}, err
 		}
 		var categoryTags []ProgramDetailsCategoryTag
 		if err := json.Unmarshal(categoryTagsJSON, &categoryTags); err != nil {
-			return nil, err
+			return ProgramListPage{}, err
 		}
 
 		results = append(results, ProgramListItem{
@@ -409,24 +537,39 @@ func (u *ProgramsUsecase) ListLikedPrograms(ctx context.Context, userID string)
 		})
 	}
 
-	return results, nil
+	return ProgramListPage{Programs: results, NextCursor: next}, nil
 }
 
-func (u *ProgramsUsecase) ListPurchasedPrograms(ctx context.Context, userID string) ([]ProgramListItem, error) {
-	rows, err := u.q.ListPurchasedProgramsByUser(ctx, db.ListPurchasedProgramsByUserParams{UserID: userID})
+func (u *ProgramsUsecase) ListPurchasedPrograms(ctx context.Context, userID string, page PageRequest) (ProgramListPage, error) {
+	cursorAt, cursorID, limit, err := page.cursorParams()
 	if err != nil {
-		return nil, err
+		return ProgramListPage{}, err
+	}
+	rows, err := u.q.ListPurchasedProgramsByUser(ctx, db.ListPurchasedProgramsByUserParams{UserID: userID, CursorAt: cursorAt, CursorID: cursorID, PageLimit: limit})
+	if err != nil {
+		return ProgramListPage{}, err
+	}
+	next := nextCursor(len(rows), page, func(i int) pageCursor {
+		return pageCursor{At: rows[i].CursorAt, ID: rows[i].CursorID}
+	})
+	if next != nil {
+		rows = rows[:page.normalizedLimit()]
 	}
 
 	results := make([]ProgramListItem, 0, len(rows))
 	for _, row := range rows {
 		categoryTagsJSON, err := normalizeJSONBytes(row.CategoryTags)
 		if err != nil {
-			return nil, err
+			return ProgramListPage{}, err
 		}
 		var categoryTags []ProgramDetailsCategoryTag
 		if err := json.Unmarshal(categoryTagsJSON, &categoryTags); err != nil {
-			return nil, err
+			return ProgramListPage{}, err
+		}
+
+		var expiresAt *time.Time
+		if row.ExpiresAt.Valid {
+			expiresAt = &row.ExpiresAt.Time
 		}
 
 		results = append(results, ProgramListItem{
@@ -438,15 +581,12 @@ func (u *ProgramsUsecase) ListPurchasedPrograms(ctx context.Context, userID stri
 			Price:            row.Price,
 			ThumbnailUrl:     buildPublicFileURLPtr(nullStringPtr(row.ThumbnailPath)),
 			CategoryTags:     categoryTags,
+			PurchaseType:     row.PurchaseType,
+			ExpiresAt:        expiresAt,
 		})
 	}
 
-	return results, nil
-}
-
-// 視聴回数をインクリメントするメソッドを追加
-func (u *ProgramsUsecase) IncrementViewCount(ctx context.Context, programID int64) error {
-	return u.q.IncrementProgramViewCount(ctx, programID)
+	return ProgramListPage{Programs: results, NextCursor: next}, nil
 }
 
 // 限定公開動画の閲覧権限チェック
@@ -460,8 +600,106 @@ func (u *ProgramsUsecase) IsUserPermittedForProgram(ctx context.Context, userID
 	})
 }
 
+// GetProgramRental は期限内のレンタルの残り時間を返す（レンタル中でなければnil）
+func (u *ProgramsUsecase) GetProgramRental(ctx context.Context, userID string, programID int64) (*ProgramRental, error) {
+	if userID == "" {
+		return nil, nil
+	}
+	ppu, err := u.q.GetActivePermittedProgramUser(ctx, db.GetActivePermittedProgramUserParams{
+		UserID:    userID,
+		ProgramID: programID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if ppu.PurchaseType != PurchaseTypeRental || !ppu.ExpiresAt.Valid {
+		return nil, nil
+	}
+	return &ProgramRental{
+		ExpiresAt:        ppu.ExpiresAt.Time,
+		RemainingSeconds: int64(time.Until(ppu.ExpiresAt.Time).Seconds()),
+	}, nil
+}
+
 // private functions
 
+// buildGetProgramsParams は一覧の絞り込み条件を検証し、GetProgramsの引数に変換する
+func buildGetProgramsParams(filter ProgramListFilter) (db.GetProgramsParams, error) {
+	arg := db.GetProgramsParams{Sort: filter.Sort}
+
+	switch filter.Sort {
+	case "":
+		arg.Sort = ProgramSortNewest
+	case ProgramSortNewest, ProgramSortMostViewed, ProgramSortMostLiked, ProgramSortPriceAsc, ProgramSortPriceDesc:
+	default:
+		return arg, ErrInvalidProgramSort
+	}
+
+	switch filter.TagMatch {
+	case "", TagMatchAll:
+	case TagMatchAny:
+		arg.TagMatchAny = true
+	default:
+		return arg, ErrInvalidTagMatch
+	}
+
+	switch filter.PriceType {
+	case "":
+	case PriceTypeFree:
+		arg.IsPaid = sql.NullBool{Bool: false, Valid: true}
+	case PriceTypePaid:
+		arg.IsPaid = sql.NullBool{Bool: true, Valid: true}
+	default:
+		return arg, ErrInvalidPriceType
+	}
+
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return arg, ErrInvalidCreatedRange
+	}
+	if filter.CreatedFrom != nil {
+		arg.CreatedFrom = sql.NullTime{Time: *filter.CreatedFrom, Valid: true}
+	}
+	if filter.CreatedTo != nil {
+		arg.CreatedTo = sql.NullTime{Time: *filter.CreatedTo, Valid: true}
+	}
+
+	if filter.Title != "" {
+		arg.Title = sql.NullString{String: filter.Title, Valid: true}
+	}
+	if len(filter.TagIDs) > 0 {
+		arg.TagIds = filter.TagIDs
+	}
+	if len(filter.PerformerIDs) > 0 {
+		arg.PerformerIds = filter.PerformerIDs
+	}
+	return arg, nil
+}
+
+func toProgramListItem(program db.GetProgramsNewestRow) (ProgramListItem, error) {
+	categoryTagsJSON, err := normalizeJSONBytes(program.CategoryTags)
+	if err != nil {
+		return ProgramListItem{}, err
+	}
+	var categoryTags []ProgramDetailsCategoryTag
+	if err := json.Unmarshal(categoryTagsJSON, &categoryTags); err != nil {
+		return ProgramListItem{}, err
+	}
+
+	return ProgramListItem{
+		ProgramID:        program.ProgramID,
+		Title:            program.Title,
+		ViewCount:        int64(program.ViewCount),
+		LikeCount:        program.LikeCount,
+		IsLimitedRelease: program.IsLimitedRelease,
+		Price:            program.Price,
+		ThumbnailUrl:     buildPublicFileURLPtr(nullStringPtr(program.ThumbnailPath)),
+		CategoryTags:     categoryTags,
+	}, nil
+}
+
 func (u *ProgramsUsecase) buildVideoURL(videoPath string) string {
 	if strings.HasPrefix(videoPath, "http://") || strings.HasPrefix(videoPath, "https://") {
 		return videoPath
@@ -488,6 +726,14 @@ func (u *ProgramsUsecase) buildVideoURL(videoPath string) string {
 	return base + "/" + path
 }
 
+func nullInt32Ptr(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	v := n.Int32
+	return &v
+}
+
 func nullStringPtr(ns sql.NullString) *string {
 	if !ns.Valid {
 		return nil
@@ -549,4 +795,4 @@ func normalizeJSONBytes(v interface{}) ([]byte, error) {
 		}
 		return b, nil
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+var ErrInvalidSalesGroupBy = errors.New("invalid group_by")
+var ErrInvalidSalesRange = errors.New("invalid sales range")
+
+// 売上の集計単位
+const (
+	SalesGroupByDay       = "day"
+	SalesGroupByMonth     = "month"
+	SalesGroupByProgram   = "program"
+	SalesGroupByPerformer = "performer"
+	SalesGroupByTag       = "tag"
+
+	// 期間の指定がない場合は今日を含む直近30日（JST）
+	defaultSalesRangeDays = 30
+)
+
+// SalesRange は集計期間 [From, To)
+type SalesRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// SalesAmounts は支払いが完了した決済の件数と金額（返金済みを含む）。
+// NetYen は請求額から返金額を差し引いた売上
+type SalesAmounts struct {
+	Payments    int64 `json:"payments"`
+	GrossYen    int64 `json:"gross_yen"`
+	DiscountYen int64 `json:"discount_yen"`
+	RefundedYen int64 `json:"refunded_yen"`
+	NetYen      int64 `json:"net_yen"`
+}
+
+// SalesRow は集計単位ごとの売上。
+// Key は日別・月別なら日付（2026-10-17 / 2026-10）、それ以外は番組・出演者・タグのID
+type SalesRow struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	SalesAmounts
+}
+
+type SalesReport struct {
+	GroupBy string     `json:"group_by"`
+	From    time.Time  `json:"from"`
+	To      time.Time  `json:"to"`
+	Rows    []SalesRow `json:"rows"`
+	// 日別・月別の合計（番組・出演者・タグ別は1件の売上を複数に計上するため合計しない）
+	Total *SalesAmounts `json:"total"`
+}
+
+// ProgramConversion は番組ごとの詳細閲覧 → 決済開始 → 支払い完了の件数
+type ProgramConversion struct {
+	ProgramID   int64  `json:"program_id"`
+	Title       string `json:"title"`
+	DetailViews int64  `json:"detail_views"`
+	Checkouts   int64  `json:"checkouts"`
+	Completed   int64  `json:"completed"`
+	// 分母が0の場合はnull
+	CheckoutRate   *float64 `json:"checkout_rate"`
+	CompletionRate *float64 `json:"completion_rate"`
+	ConversionRate *float64 `json:"conversion_rate"`
+}
+
+type ProgramConversionReport struct {
+	From     time.Time           `json:"from"`
+	To       time.Time           `json:"to"`
+	Programs []ProgramConversion `json:"programs"`
+}
+
+type SalesUsecase struct {
+	q *db.Queries
+}
+
+func NewSalesUsecase(q *db.Queries) *SalesUsecase {
+	return &SalesUsecase{q: q}
+}
+
+// GetSalesReport は期間内に支払いが完了した決済をgroupByの単位で集計する（返金額は元の売上から差し引く）
+func (u *SalesUsecase) GetSalesReport(ctx context.Context, groupBy string, r SalesRange, now time.Time) (SalesReport, error) {
+	from, to, err := r.resolve(now)
+	if err != nil {
+		return SalesReport{}, err
+	}
+	if groupBy == "" {
+		groupBy = SalesGroupByDay
+	}
+	report := SalesReport{GroupBy: groupBy, From: from, To: to, Rows: []SalesRow{}}
+
+	switch groupBy {
+	case SalesGroupByDay, SalesGroupByMonth:
+		rows, err := u.q.GetSalesByPeriod(ctx, db.GetSalesByPeriodParams{Period: groupBy, FromAt: from, ToAt: to})
+		if err != nil {
+			return SalesReport{}, err
+		}
+		layout := "2006-01-02"
+		if groupBy == SalesGroupByMonth {
+			layout = "2006-01"
+		}
+		var total SalesAmounts
+		for _, row := range rows {
+			amounts := SalesAmounts{Payments: row.Payments, GrossYen: row.GrossYen, DiscountYen: row.DiscountYen, RefundedYen: row.RefundedYen, NetYen: row.NetYen}
+			total = total.add(amounts)
+			report.Rows = append(report.Rows, SalesRow{Key: row.PeriodStart.Format(layout), SalesAmounts: amounts})
+		}
+		report.Total = &total
+	case SalesGroupByProgram:
+		rows, err := u.q.GetSalesByProgram(ctx, db.GetSalesByProgramParams{FromAt: from, ToAt: to})
+		if err != nil {
+			return SalesReport{}, err
+		}
+		for _, row := range rows {
+			report.Rows = append(report.Rows, salesRow(row.ID, row.Name, SalesAmounts{Payments: row.Payments, GrossYen: row.GrossYen, DiscountYen: row.DiscountYen, RefundedYen: row.RefundedYen, NetYen: row.NetYen}))
+		}
+	case SalesGroupByPerformer:
+		rows, err := u.q.GetSalesByPerformer(ctx, db.GetSalesByPerformerParams{FromAt: from, ToAt: to})
+		if err != nil {
+			return SalesReport{}, err
+		}
+		for _, row := range rows {
+			report.Rows = append(report.Rows, salesRow(row.ID, row.Name, SalesAmounts{Payments: row.Payments, GrossYen: row.GrossYen, DiscountYen: row.DiscountYen, RefundedYen: row.RefundedYen, NetYen: row.NetYen}))
+		}
+	case SalesGroupByTag:
+		rows, err := u.q.GetSalesByTag(ctx, db.GetSalesByTagParams{FromAt: from, ToAt: to})
+		if err != nil {
+			return SalesReport{}, err
+		}
+		for _, row := range rows {
+			report.Rows = append(report.Rows, salesRow(row.ID, row.Name, SalesAmounts{Payments: row.Payments, GrossYen: row.GrossYen, DiscountYen: row.DiscountYen, RefundedYen: row.RefundedYen, NetYen: row.NetYen}))
+		}
+	default:
+		return SalesReport{}, ErrInvalidSalesGroupBy
+	}
+	return report, nil
+}
+
+// GetProgramConversions は番組ごとに、未購入のユーザーによる詳細閲覧数・期間内に開始した決済数・そのうち支払いが完了した数を返す。
+// 詳細閲覧数は日次集計のため、期間はJSTの日付単位で丸めて数える
+func (u *SalesUsecase) GetProgramConversions(ctx context.Context, r SalesRange, now time.Time) (ProgramConversionReport, error) {
+	from, to, err := r.resolve(now)
+	if err != nil {
+		return ProgramConversionReport{}, err
+	}
+	rows, err := u.q.GetProgramConversions(ctx, db.GetProgramConversionsParams{
+		FromDate: jstDate(from),
+		ToDate:   jstDate(to.Add(-time.Nanosecond)),
+		FromAt:   from,
+		ToAt:     to,
+	})
+	if err != nil {
+		return ProgramConversionReport{}, err
+	}
+
+	programs := make([]ProgramConversion, 0, len(rows))
+	for _, row := range rows {
+		programs = append(programs, ProgramConversion{
+			ProgramID:      row.ProgramID,
+			Title:          row.Title,
+			DetailViews:    row.DetailViews,
+			Checkouts:      row.Checkouts,
+			Completed:      row.Completed,
+			CheckoutRate:   ratio(row.Checkouts, row.DetailViews),
+			CompletionRate: ratio(row.Completed, row.Checkouts),
+			ConversionRate: ratio(row.Completed, row.DetailViews),
+		})
+	}
+	return ProgramConversionReport{From: from, To: to, Programs: programs}, nil
+}
+
+// private functions
+
+// resolve は集計期間を決める（未指定なら今日を含む直近defaultSalesRangeDays日）
+func (r SalesRange) resolve(now time.Time) (time.Time, time.Time, error) {
+	to := jstDate(now).AddDate(0, 0, 1)
+	if r.To != nil {
+		to = *r.To
+	}
+	from := to.AddDate(0, 0, -defaultSalesRangeDays)
+	if r.From != nil {
+		from = *r.From
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, ErrInvalidSalesRange
+	}
+	return from, to, nil
+}
+
+func (a SalesAmounts) add(b SalesAmounts) SalesAmounts {
+	return SalesAmounts{
+		Payments:    a.Payments + b.Payments,
+		GrossYen:    a.GrossYen + b.GrossYen,
+		DiscountYen: a.DiscountYen + b.DiscountYen,
+		RefundedYen: a.RefundedYen + b.RefundedYen,
+		NetYen:      a.NetYen + b.NetYen,
+	}
+}
+
+func salesRow(id int64, label string, amounts SalesAmounts) SalesRow {
+	return SalesRow{Key: strconv.FormatInt(id, 10), Label: label, SalesAmounts: amounts}
+}
+
+// ratio はn/dを返す（dが0ならnil）
+func ratio(n, d int64) *float64 {
+	if d == 0 {
+		return nil
+	}
+	v := float64(n) / float64(d)
+	return &v
+}
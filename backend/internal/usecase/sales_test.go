@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSalesRangeResolve(t *testing.T) {
+	// UTC 15:30 は JST では 10/17 0:30
+	now := time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)
+
+	from, to, err := SalesRange{}.resolve(now)
+	require.NoError(t, err)
+	assert.True(t, to.Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, JST)))
+	assert.True(t, from.Equal(time.Date(2026, 9, 18, 0, 0, 0, 0, JST)))
+
+	f := time.Date(2026, 10, 1, 0, 0, 0, 0, JST)
+	from, to, err = SalesRange{From: &f}.resolve(now)
+	require.NoError(t, err)
+	assert.True(t, from.Equal(f))
+	assert.True(t, to.Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, JST)))
+
+	_, _, err = SalesRange{From: &f, To: &f}.resolve(now)
+	assert.ErrorIs(t, err, ErrInvalidSalesRange)
+}
+
+func TestGetSalesReport_InvalidGroupBy(t *testing.T) {
+	u := &SalesUsecase{}
+	_, err := u.GetSalesReport(context.Background(), "year", SalesRange{}, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidSalesGroupBy)
+}
+
+func TestRatio(t *testing.T) {
+	assert.Nil(t, ratio(1, 0))
+	r := ratio(1, 4)
+	require.NotNil(t, r)
+	assert.InDelta(t, 0.25, *r, 1e-9)
+}
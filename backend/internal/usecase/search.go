@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/search"
+)
+
+var ErrSearchQueryRequired = errors.New("q is required")
+
+// 検索結果の件数（limit未指定時の既定値と上限）
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchProgramItem は検索結果の番組（rankは関連度。大きいほど上位）
+type SearchProgramItem struct {
+	ProgramListItem
+	Rank float32 `json:"rank"`
+}
+
+type SearchUsecase struct {
+	q *db.Queries
+}
+
+func NewSearchUsecase(q *db.Queries) *SearchUsecase {
+	return &SearchUsecase{q: q}
+}
+
+// SearchPrograms はタイトル・説明・タグ・出演者名から公開番組を関連度順に検索する
+func (u *SearchUsecase) SearchPrograms(ctx context.Context, query string, limit int) ([]SearchProgramItem, error) {
+	tsquery := search.BuildQuery(query)
+	if tsquery == "" {
+		return nil, ErrSearchQueryRequired
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	rows, err := u.q.SearchPrograms(ctx, db.SearchProgramsParams{Query: tsquery, PageLimit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchProgramItem, 0, len(rows))
+	for _, row := range rows {
+		categoryTagsJSON, err := normalizeJSONBytes(row.CategoryTags)
+		if err != nil {
+			return nil, err
+		}
+		var categoryTags []ProgramDetailsCategoryTag
+		if err := json.Unmarshal(categoryTagsJSON, &categoryTags); err != nil {
+			return nil, err
+		}
+
+		results = append(results, SearchProgramItem{
+			ProgramListItem: ProgramListItem{
+				ProgramID:        row.ProgramID,
+				Title:            row.Title,
+				ViewCount:        int64(row.ViewCount),
+				LikeCount:        row.LikeCount,
+				IsLimitedRelease: row.IsLimitedRelease,
+				Price:            row.Price,
+				ThumbnailUrl:     buildPublicFileURLPtr(nullStringPtr(row.ThumbnailPath)),
+				CategoryTags:     categoryTags,
+			},
+			Rank: row.Rank,
+		})
+	}
+	return results, nil
+}
+
+// ReindexMissing は索引が未作成の番組（マイグレーション前から存在する番組など）を索引する
+func (u *SearchUsecase) ReindexMissing(ctx context.Context) (int, error) {
+	ids, err := u.q.ListProgramIDsWithoutSearchDocument(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := reindexPrograms(ctx, u.q, ids); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// private functions
+
+// reindexPrograms は番組・タグ・出演者の変更後に検索索引を作り直す（トランザクション内のqを渡すこと）
+func reindexPrograms(ctx context.Context, q *db.Queries, programIDs []int64) error {
+	for _, id := range programIDs {
+		src, err := q.GetProgramSearchSource(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// 重み: タイトル > 出演者・タグ > 説明
+		fields := []search.Field{{Text: src.Title, Weight: search.WeightA}}
+		fields = append(fields, search.Field{Text: strings.Join(src.PerformerNames, " "), Weight: search.WeightB})
+		fields = append(fields, search.Field{Text: strings.Join(src.TagNames, " "), Weight: search.WeightB})
+		if src.Description.Valid {
+			fields = append(fields, search.Field{Text: src.Description.String, Weight: search.WeightC})
+		}
+
+		if err := q.UpsertProgramSearchDocument(ctx, db.UpsertProgramSearchDocumentParams{
+			ProgramID: id,
+			Document:  search.BuildDocument(fields...),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+var ErrInvalidRankingWindow = errors.New("invalid ranking window")
+
+const (
+	// 期間はJSTの暦日単位（1dは今日、7dは今日を含む直近7日）
+	RankingWindow1d       = "1d"
+	RankingWindow7d       = "7d"
+	RankingWindow30d      = "30d"
+	RankingWindowTrending = "trending"
+
+	RankingMetricViews = "views"
+	RankingMetricLikes = "likes"
+	topProgramsLimit   = 7
+
+	// トレンドは直近30日を半減期3日で減衰させて評価する
+	trendingHalfLifeDays = 3.0
+	trendingLookbackDays = 30
+
+	// 集計済み視聴イベントの保持期間（重複判定には直近の時間枠しか使わない）
+	viewEventRetention = 24 * time.Hour
+	viewAggregateBatch = 1000
+	// いいね解除等を反映するため、likes/completions/purchasesは直近この日数分を毎回再集計する
+	dailyStatsRefreshDays = 2
+)
+
+// JST は日次集計・日付のみの指定・定期ジョブの基準タイムゾーン
+var JST = time.FixedZone("Asia/Tokyo", 9*60*60)
+
+// ListTopRankedPrograms は日次集計を使って期間内の視聴数/いいね数でランキングする（期間内に実績のない番組は含めない）
+func (u *ProgramsUsecase) ListTopRankedPrograms(ctx context.Context, metric, window string, now time.Time) ([]TopProgramItem, error) {
+	today := jstDate(now)
+	params := db.GetTopProgramsByDailyStatsParams{
+		PageLimit: topProgramsLimit,
+		Metric:    metric,
+		Today:     today,
+	}
+	switch window {
+	case RankingWindow1d:
+		params.FromDate = today
+	case RankingWindow7d:
+		params.FromDate = today.AddDate(0, 0, -6)
+	case RankingWindow30d:
+		params.FromDate = today.AddDate(0, 0, -29)
+	case RankingWindowTrending:
+		params.FromDate = today.AddDate(0, 0, -(trendingLookbackDays - 1))
+		params.HalfLifeDays = trendingHalfLifeDays
+	default:
+		return nil, ErrInvalidRankingWindow
+	}
+
+	rows, err := u.q.GetTopProgramsByDailyStats(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TopProgramItem, 0, len(rows))
+	for _, row := range rows {
+		score := row.Score
+		results = append(results, TopProgramItem{
+			ProgramID:        row.ProgramID,
+			Title:            row.Title,
+			ViewCount:        int64(row.ViewCount),
+			LikeCount:        row.LikeCount,
+			IsLimitedRelease: row.IsLimitedRelease,
+			Price:            row.Price,
+			ThumbnailUrl:     buildPublicFileURLPtr(nullStringPtr(row.ThumbnailPath)),
+			Score:            &score,
+		})
+	}
+	return results, nil
+}
+
+// StatsAggregator は視聴イベントと日次集計を非同期に更新する
+type StatsAggregator struct {
+	conn *sql.DB
+	q    *db.Queries
+}
+
+func NewStatsAggregator(conn *sql.DB, q *db.Queries) *StatsAggregator {
+	return &StatsAggregator{conn: conn, q: q}
+}
+
+// AggregateOnce は未集計の視聴イベントをview_countと日次集計に反映し、
+// 直近の日次集計（いいね・完走・購入）を作り直す。反映した視聴イベント数を返す
+func (a *StatsAggregator) AggregateOnce(ctx context.Context) (int64, error) {
+	var total int64
+	for {
+		n, err := a.q.AggregateProgramViewEvents(ctx, viewAggregateBatch)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < viewAggregateBatch {
+			break
+		}
+	}
+
+	if _, err := a.q.DeleteAggregatedProgramViewEvents(ctx, time.Now().Add(-viewEventRetention)); err != nil {
+		return total, err
+	}
+	if err := a.refreshDailyStats(ctx, jstDate(time.Now()).AddDate(0, 0, -(dailyStatsRefreshDays-1))); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+// private functions
+
+func (a *StatsAggregator) refreshDailyStats(ctx context.Context, from time.Time) error {
+	tx, err := a.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := a.q.WithTx(tx)
+
+	if err := qtx.ResetProgramDailyStatsCounters(ctx, from); err != nil {
+		return err
+	}
+	if err := qtx.RefreshProgramDailyStatsCounters(ctx, from); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// jstDate はtのJSTでの日付（0時）を返す
+func jstDate(t time.Time) time.Time {
+	y, m, d := t.In(JST).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, JST)
+}
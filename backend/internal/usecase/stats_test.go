@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSTDate(t *testing.T) {
+	// UTC 15:30 は JST では翌日 0:30
+	got := jstDate(time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC))
+	assert.Equal(t, "2026-10-17", got.Format("2006-01-02"))
+	assert.Equal(t, 0, got.Hour())
+
+	got = jstDate(time.Date(2026, 10, 16, 14, 59, 0, 0, time.UTC))
+	assert.Equal(t, "2026-10-16", got.Format("2006-01-02"))
+}
+
+func TestListTopRankedPrograms_InvalidWindow(t *testing.T) {
+	u := &ProgramsUsecase{}
+	_, err := u.ListTopRankedPrograms(context.Background(), RankingMetricViews, "1y", time.Now())
+	assert.ErrorIs(t, err, ErrInvalidRankingWindow)
+}
@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/lib/pq"
+)
+
+var ErrCategoryTagNotFound = errors.New("category tag not found")
+var ErrInvalidCategoryTagName = errors.New("tag name must be 1-50 characters")
+var ErrCategoryTagNameTaken = errors.New("category tag name already exists")
+
+// タグ名の最大長
+const maxCategoryTagNameLength = 50
+
+type CategoryTagListItem struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	ProgramCount int64  `json:"program_count"`
+}
+
+type CategoryTag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TagsUsecase struct {
+	conn *sql.DB
+	q    *db.Queries
+}
+
+func NewTagsUsecase(conn *sql.DB, q *db.Queries) *TagsUsecase {
+	return &TagsUsecase{conn: conn, q: q}
+}
+
+// ListTags は全タグを公開番組数付きで名前順に返す
+func (u *TagsUsecase) ListTags(ctx context.Context) ([]CategoryTagListItem, error) {
+	rows, err := u.q.ListCategoryTagsWithProgramCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]CategoryTagListItem, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, CategoryTagListItem{ID: row.ID, Name: row.Name, ProgramCount: row.ProgramCount})
+	}
+	return results, nil
+}
+
+// ListTagPrograms はタグが付いた公開番組を新しい順に返す
+func (u *TagsUsecase) ListTagPrograms(ctx context.Context, tagID int64, page PageRequest) (ProgramListPage, error) {
+	cursorAt, cursorID, limit, err := page.cursorParams()
+	if err != nil {
+		return ProgramListPage{}, err
+	}
+	if _, err := u.q.GetCategoryTagByID(ctx, tagID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ProgramListPage{}, ErrCategoryTagNotFound
+		}
+		return ProgramListPage{}, err
+	}
+
+	rows, err := u.q.ListProgramsByTagID(ctx, db.ListProgramsByTagIDParams{
+		TagID:           tagID,
+		CursorCreatedAt: cursorAt,
+		CursorID:        cursorID,
+		PageLimit:       limit,
+	})
+	if err != nil {
+		return ProgramListPage{}, err
+	}
+	next := nextCursor(len(rows), page, func(i int) pageCursor {
+		return pageCursor{At: rows[i].ProgramCreatedAt, ID: rows[i].ProgramID}
+	})
+	if next != nil {
+		rows = rows[:page.normalizedLimit()]
+	}
+
+	results := make([]ProgramListItem, 0, len(rows))
+	for _, row := range rows {
+		categoryTagsJSON, err := normalizeJSONBytes(row.CategoryTags)
+		if err != nil {
+			return ProgramListPage{}, err
+		}
+		var categoryTags []ProgramDetailsCategoryTag
+		if err := json.Unmarshal(categoryTagsJSON, &categoryTags); err != nil {
+			return ProgramListPage{}, err
+		}
+
+		results = append(results, ProgramListItem{
+			ProgramID:        row.ProgramID,
+			Title:            row.Title,
+			ViewCount:        int64(row.ViewCount),
+			LikeCount:        row.LikeCount,
+			IsLimitedRelease: row.IsLimitedRelease,
+			Price:            row.Price,
+			ThumbnailUrl:     buildPublicFileURLPtr(nullStringPtr(row.ThumbnailPath)),
+			CategoryTags:     categoryTags,
+		})
+	}
+	return ProgramListPage{Programs: results, NextCursor: next}, nil
+}
+
+func (u *TagsUsecase) CreateTag(ctx context.Context, name string) (CategoryTag, error) {
+	name, err := validateCategoryTagName(name)
+	if err != nil {
+		return CategoryTag{}, err
+	}
+	tag, err := u.q.CreateCategoryTag(ctx, name)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return CategoryTag{}, ErrCategoryTagNameTaken
+		}
+		return CategoryTag{}, err
+	}
+	return toCategoryTag(tag), nil
+}
+
+// UpdateTag はタグ名を変更し、タグが付いた番組の検索索引を作り直す
+func (u *TagsUsecase) UpdateTag(ctx context.Context, tagID int64, name string) (CategoryTag, error) {
+	name, err := validateCategoryTagName(name)
+	if err != nil {
+		return CategoryTag{}, err
+	}
+
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return CategoryTag{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := u.q.WithTx(tx)
+
+	tag, err := qtx.UpdateCategoryTag(ctx, db.UpdateCategoryTagParams{ID: tagID, Name: name})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return CategoryTag{}, ErrCategoryTagNotFound
+		}
+		if isUniqueViolation(err) {
+			return CategoryTag{}, ErrCategoryTagNameTaken
+		}
+		return CategoryTag{}, err
+	}
+
+	programIDs, err := qtx.ListProgramIDsByTagID(ctx, tagID)
+	if err != nil {
+		return CategoryTag{}, err
+	}
+	if err := reindexPrograms(ctx, qtx, programIDs); err != nil {
+		return CategoryTag{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return CategoryTag{}, err
+	}
+	return toCategoryTag(tag), nil
+}
+
+// DeleteTag はタグを削除する（番組との紐づけも削除され、検索索引を作り直す）
+func (u *TagsUsecase) DeleteTag(ctx context.Context, tagID int64) error {
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := u.q.WithTx(tx)
+
+	programIDs, err := qtx.ListProgramIDsByTagID(ctx, tagID)
+	if err != nil {
+		return err
+	}
+	affected, err := qtx.DeleteCategoryTag(ctx, tagID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCategoryTagNotFound
+	}
+	if err := reindexPrograms(ctx, qtx, programIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// private functions
+
+func validateCategoryTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxCategoryTagNameLength {
+		return "", ErrInvalidCategoryTagName
+	}
+	return name, nil
+}
+
+func toCategoryTag(tag db.CategoryTag) CategoryTag {
+	return CategoryTag{ID: tag.ID, Name: tag.Name, CreatedAt: tag.CreatedAt, UpdatedAt: tag.UpdatedAt}
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
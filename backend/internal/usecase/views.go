@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+)
+
+// 同一視聴者の再生は、前回記録した再生開始からこの時間内なら数えない（詳細閲覧も同様）
+const ViewDedupWindow = 30 * time.Minute
+
+// 視聴イベントの種類
+const (
+	viewKindPlay   = "play"
+	viewKindDetail = "detail"
+)
+
+const (
+	// バックグラウンドで同時に記録する詳細閲覧の上限（超えた分は記録しない）
+	maxPendingDetailViews   = 64
+	detailViewRecordTimeout = 5 * time.Second
+)
+
+// ViewerKey は視聴イベントの重複判定に使うキーを返す。
+// ログインユーザーはユーザーID、未ログインはfingerprint（IP/User-Agent等）のハッシュを使う
+func ViewerKey(userID, fingerprint string) string {
+	if userID != "" {
+		return "user:" + userID
+	}
+	sum := sha256.Sum256([]byte(fingerprint))
+	return "anon:" + hex.EncodeToString(sum[:])
+}
+
+// RecordView は再生開始時に視聴イベントを記録する。
+// 非公開番組・視聴権限のない限定公開番組・直近ViewDedupWindow以内に記録済みの場合は記録せずfalseを返す
+func (u *ProgramsUsecase) RecordView(ctx context.Context, programID int64, userID, viewerKey string) (bool, error) {
+	program, err := u.q.GetProgramForPurchase(ctx, programID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	if program.IsLimitedRelease {
+		permitted, err := u.IsUserPermittedForProgram(ctx, userID, programID)
+		if err != nil {
+			return false, err
+		}
+		if !permitted {
+			return false, nil
+		}
+	}
+
+	return u.insertViewEvent(ctx, programID, viewerKey, viewKindPlay)
+}
+
+// RecordDetailView は未購入のユーザーが限定公開番組の詳細を開いたことを視聴イベントとして記録する（購入転換率の分母）。
+// 再生開始と同じく同一視聴者はViewDedupWindow内で1回だけ数え、集計はStatsAggregatorがdetail_viewsに反映する。
+// 詳細の応答を待たせないようバックグラウンドで記録し、記録中の件数が上限に達している場合は記録せずfalseを返す
+func (u *ProgramsUsecase) RecordDetailView(programID int64, viewerKey string) bool {
+	select {
+	case u.detailViewSlots <- struct{}{}:
+	default:
+		return false
+	}
+	go func() {
+		defer func() { <-u.detailViewSlots }()
+		ctx, cancel := context.WithTimeout(context.Background(), detailViewRecordTimeout)
+		defer cancel()
+		if _, err := u.insertViewEvent(ctx, programID, viewerKey, viewKindDetail); err != nil {
+			log.Printf("[RecordDetailView] failed to record detail view. programID=%d, err=%v", programID, err)
+		}
+	}()
+	return true
+}
+
+// private functions
+
+func (u *ProgramsUsecase) insertViewEvent(ctx context.Context, programID int64, viewerKey, kind string) (bool, error) {
+	now := time.Now()
+	n, err := u.q.InsertProgramViewEvent(ctx, db.InsertProgramViewEventParams{
+		ProgramID:   programID,
+		ViewerKey:   viewerKey,
+		Kind:        kind,
+		BucketStart: now.Truncate(ViewDedupWindow),
+		ViewedAt:    now,
+		DedupAfter:  now.Add(-ViewDedupWindow),
+	})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewerKey(t *testing.T) {
+	// ログインユーザーはfingerprintに関係なく同一キー
+	assert.Equal(t, "user:u1", ViewerKey("u1", "1.2.3.4\x00browser-a"))
+	assert.Equal(t, "user:u1", ViewerKey("u1", "5.6.7.8\x00browser-b"))
+
+	// 未ログインはfingerprintのハッシュ（生のIPは保存しない）
+	a := ViewerKey("", "1.2.3.4\x00browser-a")
+	assert.True(t, strings.HasPrefix(a, "anon:"))
+	assert.NotContains(t, a, "1.2.3.4")
+	assert.Equal(t, a, ViewerKey("", "1.2.3.4\x00browser-a"))
+	assert.NotEqual(t, a, ViewerKey("", "1.2.3.4\x00browser-b"))
+}
@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chan-shizu/SZer/db"
+	"github.com/chan-shizu/SZer/internal/jobs"
+	"github.com/chan-shizu/SZer/internal/payments"
+)
+
+const WebhookProviderPayPay = payments.ProviderPayPay
+
+// JobWebhookProcessDelivery は保存したWebhookを処理するジョブ
+const JobWebhookProcessDelivery = "webhook.process_delivery"
+
+// Webhookの処理状況
+const (
+	WebhookDeliveryReceived  = "received"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+var ErrWebhookDeliveryNotReplayable = errors.New("webhook delivery is not replayable")
+var ErrInvalidWebhookDeliveryStatus = errors.New("invalid webhook delivery status")
+var ErrInvalidWebhookBody = errors.New("invalid webhook body")
+
+// WebhookDeliveryPayload は処理ジョブのペイロード
+type WebhookDeliveryPayload struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+type WebhookDelivery struct {
+	ID               int64      `json:"id"`
+	Provider         string     `json:"provider"`
+	DedupeKey        string     `json:"dedupe_key"`
+	NotificationType string     `json:"notification_type"`
+	Body             string     `json:"body"`
+	RemoteIP         string     `json:"remote_ip"`
+	Status           string     `json:"status"`
+	Attempts         int32      `json:"attempts"`
+	LastError        *string    `json:"last_error"`
+	ReceivedAt       time.Time  `json:"received_at"`
+	ProcessedAt      *time.Time `json:"processed_at"`
+}
+
+type WebhookDeliveryPage struct {
+	Deliveries []WebhookDelivery
+	NextCursor *string
+}
+
+type WebhookDeliveriesUsecase struct {
+	conn      *sql.DB
+	q         *db.Queries
+	providers *payments.Registry // 保存したWebhookを解析する決済サービス
+}
+
+func NewWebhookDeliveriesUsecase(conn *sql.DB, q *db.Queries) *WebhookDeliveriesUsecase {
+	return NewWebhookDeliveriesUsecaseWithProviders(conn, q, NewPaymentProvidersFromEnv())
+}
+
+func NewWebhookDeliveriesUsecaseWithProviders(conn *sql.DB, q *db.Queries, providers *payments.Registry) *WebhookDeliveriesUsecase {
+	return &WebhookDeliveriesUsecase{conn: conn, q: q, providers: providers}
+}
+
+func toWebhookDelivery(d db.WebhookDelivery) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:               d.ID,
+		Provider:         d.Provider,
+		DedupeKey:        d.DedupeKey,
+		NotificationType: d.NotificationType,
+		Body:             d.Body,
+		RemoteIP:         d.RemoteIp,
+		Status:           d.Status,
+		Attempts:         d.Attempts,
+		ReceivedAt:       d.ReceivedAt,
+	}
+	if d.LastError.Valid {
+		delivery.LastError = &d.LastError.String
+	}
+	if d.ProcessedAt.Valid {
+		delivery.ProcessedAt = &d.ProcessedAt.Time
+	}
+	return delivery
+}
+
+// payPayWebhookDedupeKey はPayPay Webhookの重複判定キーを返す（決済はorder_id、返金はmerchant_refund_idと状態の組）。
+// 解析できない・通知の種類や対象の決済が分からないボディは処理できないためErrInvalidWebhookBodyを返す
+func payPayWebhookDedupeKey(body []byte) (string, string, error) {
+	var payload struct {
+		NotificationType string `json:"notification_type"`
+		OrderID          string `json:"order_id"`
+		MerchantOrderID  string `json:"merchant_order_id"`
+		MerchantRefundID string `json:"merchant_refund_id"`
+		State            string `json:"state"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.NotificationType == "" {
+		return "", "", ErrInvalidWebhookBody
+	}
+
+	id := payload.OrderID
+	if payload.NotificationType == "Refund" {
+		id = payload.MerchantRefundID
+	}
+	// 支払い前の期限切れなどorder_idが無い通知
+	if id == "" {
+		id = payload.MerchantOrderID
+	}
+	if id == "" {
+		return "", "", ErrInvalidWebhookBody
+	}
+	return payload.NotificationType, payload.NotificationType + ":" + id + ":" + payload.State, nil
+}
+
+// ReceivePayPay は認証済みのPayPay Webhookを保存し、処理ジョブを登録する。
+// 同じイベントが保存済みならduplicate=trueを返す。処理できないボディは保存せずErrInvalidWebhookBodyを返す
+func (u *WebhookDeliveriesUsecase) ReceivePayPay(ctx context.Context, body []byte, remoteIP string) (int64, bool, error) {
+	notificationType, dedupeKey, err := payPayWebhookDedupeKey(body)
+	if err != nil {
+		return 0, false, err
+	}
+
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := u.q.WithTx(tx)
+
+	id, err := qtx.InsertWebhookDelivery(ctx, db.InsertWebhookDeliveryParams{
+		Provider:         WebhookProviderPayPay,
+		DedupeKey:        dedupeKey,
+		NotificationType: notificationType,
+		Body:             string(body),
+		RemoteIp:         remoteIP,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, true, nil
+		}
+		return 0, false, err
+	}
+
+	// 保存と同じトランザクションで登録し、保存したのに処理されない状態を作らない
+	if err := enqueueWebhookDelivery(ctx, qtx, id); err != nil {
+		return 0, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return id, false, nil
+}
+
+func enqueueWebhookDelivery(ctx context.Context, qtx *db.Queries, id int64) error {
+	_, err := jobs.NewQueue(qtx).Enqueue(ctx, JobWebhookProcessDelivery, WebhookDeliveryPayload{DeliveryID: id},
+		jobs.DedupeKey(fmt.Sprintf("webhook.delivery:%d", id)),
+	)
+	return err
+}
+
+// ProcessDelivery は保存したWebhookを処理して結果を記録する。
+// 処理に失敗した場合はfailedを記録してエラーを返す（ジョブとして再試行される）
+func (u *WebhookDeliveriesUsecase) ProcessDelivery(ctx context.Context, id int64) error {
+	d, err := u.q.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWebhookDeliveryNotFound
+		}
+		return err
+	}
+	if d.Status == WebhookDeliverySucceeded {
+		return nil
+	}
+
+	provider, procErr := u.providers.Get(d.Provider)
+	if procErr == nil {
+		procErr = PaymentWebhookEventHandler(ctx, u.conn, u.q, provider, []byte(d.Body))
+	}
+
+	if procErr != nil {
+		if err := u.q.MarkWebhookDeliveryFailed(ctx, db.MarkWebhookDeliveryFailedParams{
+			ID:        id,
+			LastError: sql.NullString{String: procErr.Error(), Valid: true},
+		}); err != nil {
+			return err
+		}
+		return procErr
+	}
+	return u.q.MarkWebhookDeliverySucceeded(ctx, id)
+}
+
+// ListDeliveries は受信したWebhookを新しい順に返す（statusが空なら全件）
+func (u *WebhookDeliveriesUsecase) ListDeliveries(ctx context.Context, status string, page PageRequest) (WebhookDeliveryPage, error) {
+	var statusParam sql.NullString
+	switch status {
+	case "":
+	case WebhookDeliveryReceived, WebhookDeliverySucceeded, WebhookDeliveryFailed:
+		statusParam = sql.NullString{String: status, Valid: true}
+	default:
+		return WebhookDeliveryPage{}, ErrInvalidWebhookDeliveryStatus
+	}
+
+	cursorAt, cursorID, limit, err := page.cursorParams()
+	if err != nil {
+		return WebhookDeliveryPage{}, err
+	}
+	rows, err := u.q.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		Status:    statusParam,
+		CursorAt:  cursorAt,
+		CursorID:  cursorID,
+		PageLimit: limit,
+	})
+	if err != nil {
+		return WebhookDeliveryPage{}, err
+	}
+	next := nextCursor(len(rows), page, func(i int) pageCursor {
+		return pageCursor{At: rows[i].ReceivedAt, ID: rows[i].ID}
+	})
+	if next != nil {
+		rows = rows[:page.normalizedLimit()]
+	}
+
+	deliveries := make([]WebhookDelivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, toWebhookDelivery(row))
+	}
+	return WebhookDeliveryPage{Deliveries: deliveries, NextCursor: next}, nil
+}
+
+func (u *WebhookDeliveriesUsecase) GetDelivery(ctx context.Context, id int64) (WebhookDelivery, error) {
+	d, err := u.q.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return WebhookDelivery{}, ErrWebhookDeliveryNotFound
+		}
+		return WebhookDelivery{}, err
+	}
+	return toWebhookDelivery(d), nil
+}
+
+// ReplayDelivery は処理に失敗したWebhookを処理待ちに戻し、処理ジョブを登録し直す
+func (u *WebhookDeliveriesUsecase) ReplayDelivery(ctx context.Context, id int64) (WebhookDelivery, error) {
+	tx, err := u.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := u.q.WithTx(tx)
+
+	d, err := qtx.GetWebhookDeliveryForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return WebhookDelivery{}, ErrWebhookDeliveryNotFound
+		}
+		return WebhookDelivery{}, err
+	}
+	if d.Status != WebhookDeliveryFailed {
+		return WebhookDelivery{}, ErrWebhookDeliveryNotReplayable
+	}
+
+	if err := qtx.ResetWebhookDelivery(ctx, id); err != nil {
+		return WebhookDelivery{}, err
+	}
+	if err := enqueueWebhookDelivery(ctx, qtx, id); err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	d.Status = WebhookDeliveryReceived
+	return toWebhookDelivery(d), nil
+}
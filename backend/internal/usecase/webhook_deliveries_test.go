@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPayPayWebhookDedupeKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantType string
+		wantKey  string
+	}{
+		{
+			name:     "transaction",
+			body:     `{"notification_type":"Transaction","order_id":"pay-1","merchant_order_id":"m-1","state":"COMPLETED"}`,
+			wantType: "Transaction",
+			wantKey:  "Transaction:pay-1:COMPLETED",
+		},
+		{
+			name:     "transaction without order_id",
+			body:     `{"notification_type":"Transaction","merchant_order_id":"m-1","state":"EXPIRED"}`,
+			wantType: "Transaction",
+			wantKey:  "Transaction:m-1:EXPIRED",
+		},
+		{
+			name:     "refund",
+			body:     `{"notification_type":"Refund","merchant_refund_id":"r-1","merchant_order_id":"m-1","state":"REFUNDED"}`,
+			wantType: "Refund",
+			wantKey:  "Refund:r-1:REFUNDED",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotKey, err := payPayWebhookDedupeKey([]byte(tt.body))
+			if err != nil || gotType != tt.wantType || gotKey != tt.wantKey {
+				t.Errorf("got (%q, %q, %v), want (%q, %q)", gotType, gotKey, err, tt.wantType, tt.wantKey)
+			}
+		})
+	}
+
+	// 解析できない・対象が分からないボディは保存しない
+	for _, body := range []string{"not json", `{"order_id":"pay-1","state":"COMPLETED"}`, `{"notification_type":"Transaction","state":"COMPLETED"}`} {
+		if _, _, err := payPayWebhookDedupeKey([]byte(body)); !errors.Is(err, ErrInvalidWebhookBody) {
+			t.Errorf("body %s: expected ErrInvalidWebhookBody, got %v", body, err)
+		}
+	}
+}
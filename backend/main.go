@@ -2,23 +2,34 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/chan-shizu/SZer/db"
 	"github.com/chan-shizu/SZer/internal/dbconn"
+	"github.com/chan-shizu/SZer/internal/jobs"
 	"github.com/chan-shizu/SZer/internal/router"
 	"github.com/joho/godotenv"
 )
 
+// シャットダウン時に処理中のリクエスト・ジョブの完了を待つ時間
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// .envファイルを読み込む（環境変数として使えるようにする）
 	if err := godotenv.Load(".env"); err != nil {
 		fmt.Printf(".env読み込み失敗: %v\n", err)
 	}
 
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	conn, err := dbconn.Open(ctx)
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
@@ -26,11 +37,42 @@ func main() {
 	defer conn.Close()
 
 	q := db.New(conn)
-	r := router.NewRouter(conn, q)
+	queue := jobs.NewQueue(q)
+	r := router.NewRouter(conn, q, queue)
+
+	runner, err := router.NewJobRunner(conn, q)
+	if err != nil {
+		log.Fatalf("failed to set up jobs: %v", err)
+	}
+	if err := runner.Start(ctx); err != nil {
+		log.Fatalf("failed to start jobs: %v", err)
+	}
+
+	// 検索索引が未作成の番組があれば起動時に作成する（サーバー起動は待たない）
+	if _, err := queue.Enqueue(ctx, router.JobSearchReindexMissing, nil, jobs.DedupeKey(router.JobSearchReindexMissing)); err != nil {
+		log.Printf("[search] failed to enqueue reindex: %v", err)
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
-	r.Run(":" + port)
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("failed to run server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to shut down server: %v", err)
+	}
+	if err := runner.Stop(shutdownCtx); err != nil {
+		log.Printf("failed to stop jobs: %v", err)
+	}
 }
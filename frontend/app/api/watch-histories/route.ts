@@ -5,11 +5,23 @@ import { backendFetch } from "@/lib/api/server";
 export async function POST(request: NextRequest) {
   const body = await request.text();
 
+  // 未ログイン視聴者の重複判定に使うため、ブラウザのIP/User-Agentを引き継ぐ
+  const headers = new Headers({
+    "Content-Type": request.headers.get("content-type") || "application/json",
+  });
+  // 左側の値はブラウザが自由に付けられるため、直前のプロキシが付けた右端のIPだけを渡す
+  const forwardedFor = request.headers.get("x-forwarded-for")?.split(",").pop()?.trim();
+  if (forwardedFor) {
+    headers.set("X-Forwarded-For", forwardedFor);
+  }
+  const userAgent = request.headers.get("user-agent");
+  if (userAgent) {
+    headers.set("User-Agent", userAgent);
+  }
+
   const res = await backendFetch("/watch-histories", {
     method: "POST",
-    headers: {
-      "Content-Type": request.headers.get("content-type") || "application/json",
-    },
+    headers,
     body,
   });
 
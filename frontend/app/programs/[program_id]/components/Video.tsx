@@ -15,6 +15,7 @@ type Props = {
 export const Video = ({ programId, videoUrl, startPositionSeconds }: Props) => {
   const ref = useRef<HTMLVideoElement | null>(null);
   const sentRef = useRef(false);
+  const playStartSentRef = useRef(false);
   const appliedStartRef = useRef(false);
   const router = useRouter();
 
@@ -23,6 +24,7 @@ export const Video = ({ programId, videoUrl, startPositionSeconds }: Props) => {
     if (!video) return;
 
     appliedStartRef.current = false;
+    playStartSentRef.current = false;
 
     const start =
       typeof startPositionSeconds === "number" && Number.isFinite(startPositionSeconds) ? startPositionSeconds : 0;
@@ -91,6 +93,20 @@ export const Video = ({ programId, videoUrl, startPositionSeconds }: Props) => {
       }
     };
 
+    // 再生開始を通知する（視聴回数の記録用。未ログインでも送る）
+    const onPlaying = () => {
+      if (playStartSentRef.current) return;
+      playStartSentRef.current = true;
+      void upsertWatchHistory({
+        program_id: programId,
+        position_seconds: Math.max(Math.floor(video.currentTime || 0), 0),
+        is_completed: false,
+        started: true,
+      }).catch(() => {
+        // ignore
+      });
+    };
+
     const onEnded = () => send(true);
 
     // Page leave signals (browser back / close tab)
@@ -105,6 +121,7 @@ export const Video = ({ programId, videoUrl, startPositionSeconds }: Props) => {
     // Some environments only become seekable around canplay.
     video.addEventListener("canplay", tryApplyStart);
     video.addEventListener("seeked", onSeeked);
+    video.addEventListener("playing", onPlaying);
     video.addEventListener("ended", onEnded);
     window.addEventListener("pagehide", onPageHide);
     document.addEventListener("visibilitychange", onVisibilityChange);
@@ -121,6 +138,7 @@ export const Video = ({ programId, videoUrl, startPositionSeconds }: Props) => {
       video.removeEventListener("loadedmetadata", tryApplyStart);
       video.removeEventListener("canplay", tryApplyStart);
       video.removeEventListener("seeked", onSeeked);
+      video.removeEventListener("playing", onPlaying);
       video.removeEventListener("ended", onEnded);
       window.removeEventListener("pagehide", onPageHide);
       document.removeEventListener("visibilitychange", onVisibilityChange);
@@ -1,4 +1,4 @@
-import { backendFetchJson } from "./server";
+import { backendFetchAllPages, backendFetchJson } from "./server";
 
 export type Comment = {
   id: number;
@@ -12,6 +12,7 @@ export type Comment = {
 
 export type GetCommentsResponse = {
   comments: Comment[];
+  next_cursor?: string | null;
 };
 
 export type PostCommentRequest = {
@@ -30,7 +31,10 @@ function parseNullString(val: any): string | null {
 
 export async function getComments(programId: number | string): Promise<GetCommentsResponse> {
   const encodedId = encodeURIComponent(String(programId));
-  const res = await backendFetchJson<any>(`/programs/${encodedId}/comments`, { method: "GET", cache: "no-store" });
+  const res = await backendFetchAllPages<any, "comments">(`/programs/${encodedId}/comments`, "comments", {
+    method: "GET",
+    cache: "no-store",
+  });
   // 型変換
   const comments: Comment[] = (res.comments || []).map((c: any) => ({
     id: c.id,
@@ -1,9 +1,9 @@
-import { backendFetchJson } from "./server";
+import { backendFetchAllPages } from "./server";
 import type { GetProgramsResponse } from "./programs";
 
 export async function getWatchingPrograms(): Promise<GetProgramsResponse> {
   try {
-    return await backendFetchJson<GetProgramsResponse>("/me/watching-programs", { method: "GET", cache: "no-store" });
+    return await backendFetchAllPages<GetProgramsResponse, "programs">("/me/watching-programs", "programs", { method: "GET", cache: "no-store" });
   } catch (err) {
     console.error(`[API通信エラー] getWatchingPrograms:`, { err });
     throw err;
@@ -12,7 +12,7 @@ export async function getWatchingPrograms(): Promise<GetProgramsResponse> {
 
 export async function getLikedPrograms(): Promise<GetProgramsResponse> {
   try {
-    return await backendFetchJson<GetProgramsResponse>("/me/liked-programs", { method: "GET", cache: "no-store" });
+    return await backendFetchAllPages<GetProgramsResponse, "programs">("/me/liked-programs", "programs", { method: "GET", cache: "no-store" });
   } catch (err) {
     console.error(`[API通信エラー] getLikedPrograms:`, { err });
     throw err;
@@ -21,7 +21,7 @@ export async function getLikedPrograms(): Promise<GetProgramsResponse> {
 
 export async function getPurchasedPrograms(): Promise<GetProgramsResponse> {
   try {
-    return await backendFetchJson<GetProgramsResponse>("/me/purchased-programs", { method: "GET", cache: "no-store" });
+    return await backendFetchAllPages<GetProgramsResponse, "programs">("/me/purchased-programs", "programs", { method: "GET", cache: "no-store" });
   } catch (err) {
     console.error(`[API通信エラー] getPurchasedPrograms:`, { err });
     throw err;
@@ -52,6 +52,7 @@ export type ProgramListItem = {
 
 export type GetProgramsResponse = {
   programs: ProgramListItem[];
+  next_cursor?: string | null;
 };
 
 export type GetTopProgramsResponse = {
@@ -76,7 +77,7 @@ export type TopProgramItem = {
   thumbnail_url: string | null;
 };
 
-import { backendFetchJson } from "./server";
+import { backendFetchAllPages, backendFetchJson } from "./server";
 
 export async function getProgramDetail(id: number | string): Promise<GetProgramDetailResponse> {
   const encodedId = encodeURIComponent(String(id));
@@ -109,7 +110,7 @@ export async function getPrograms(title?: string, tagIds?: Array<number | string
   const queryString = params.toString();
   const path = queryString ? `/programs?${queryString}` : "/programs";
   try {
-    return await backendFetchJson<GetProgramsResponse>(path, { method: "GET", cache: "no-store" });
+    return await backendFetchAllPages<GetProgramsResponse, "programs">(path, "programs", { method: "GET", cache: "no-store" });
   } catch (err) {
     console.error(`[API通信エラー] getPrograms:`, { title, tagIds, err });
     throw err;
@@ -74,3 +74,31 @@ export async function backendFetchJson<T>(path: string, init: RequestInit = {}):
 
   return res.json() as Promise<T>;
 }
+
+type PagedResponse = { next_cursor?: string | null };
+
+// バックエンドの一覧APIは1ページの件数に上限があるため、next_cursorを辿って全件取得する
+export async function backendFetchAllPages<T extends PagedResponse, K extends keyof T>(
+  path: string,
+  key: K,
+  init: RequestInit = {},
+): Promise<T> {
+  const pageLimit = 100;
+  const [basePath, baseQuery = ""] = path.split("?", 2);
+  const items: unknown[] = [];
+  let cursor: string | null | undefined;
+  let last: T | undefined;
+
+  do {
+    const params = new URLSearchParams(baseQuery);
+    params.set("limit", String(pageLimit));
+    if (cursor) {
+      params.set("cursor", cursor);
+    }
+    last = await backendFetchJson<T>(`${basePath}?${params.toString()}`, init);
+    items.push(...((last[key] as unknown[] | null) ?? []));
+    cursor = last.next_cursor;
+  } while (cursor);
+
+  return { ...last, [key]: items, next_cursor: null } as T;
+}
@@ -2,6 +2,8 @@ export type UpsertWatchHistoryRequest = {
   program_id: number;
   position_seconds: number;
   is_completed: boolean;
+  // 再生開始の通知（視聴回数はこの通知でのみ数える）
+  started?: boolean;
 };
 
 export type WatchHistory = {
@@ -15,7 +17,8 @@ export type WatchHistory = {
 };
 
 export type UpsertWatchHistoryResponse = {
-  watch_history: WatchHistory;
+  // 未ログイン時は視聴回数のみ記録されnull
+  watch_history: WatchHistory | null;
 };
 
 export async function upsertWatchHistory(req: UpsertWatchHistoryRequest): Promise<UpsertWatchHistoryResponse> {